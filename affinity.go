@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+
+	"github.com/roadrunner-server/goridge/v3/pkg/relay"
+	"github.com/roadrunner-server/pool/fsm"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/roadrunner-server/pool/worker"
+	"go.uber.org/zap"
+)
+
+// affinity pins the workers to the configured CPU sets when they are spawned, every worker takes the set with the
+// fewest workers, so the sets of the replaced workers are reused.
+type affinity struct {
+	mu   sync.Mutex
+	sets [][]int
+	// workers pinned to every set
+	pinned []map[int64]*worker.Process
+	log    *zap.Logger
+}
+
+func newAffinity(sets [][]int, log *zap.Logger) *affinity {
+	pinned := make([]map[int64]*worker.Process, len(sets))
+	for i := 0; i < len(pinned); i++ {
+		pinned[i] = make(map[int64]*worker.Process)
+	}
+
+	return &affinity{sets: sets, pinned: pinned, log: log}
+}
+
+// pin assigns the free set to the worker.
+func (a *affinity) pin(w *worker.Process) {
+	if len(a.sets) == 0 {
+		return
+	}
+
+	a.mu.Lock()
+	idx := 0
+	for i := 0; i < len(a.pinned); i++ {
+		// the workers of the destroyed pools do not close the relay
+		for pid, pw := range a.pinned[i] {
+			if pw.State().Compare(fsm.StateDestroyed) {
+				delete(a.pinned[i], pid)
+			}
+		}
+
+		if len(a.pinned[i]) < len(a.pinned[idx]) {
+			idx = i
+		}
+	}
+	a.pinned[idx][w.Pid()] = w
+	a.mu.Unlock()
+
+	set := a.sets[idx]
+	err := setAffinity(int(w.Pid()), set)
+	if err != nil {
+		a.log.Warn("failed to pin the worker", zap.Int64("pid", w.Pid()), zap.Ints("cpus", set), zap.Error(err))
+		return
+	}
+
+	a.log.Debug("worker pinned", zap.Int64("pid", w.Pid()), zap.Ints("cpus", set))
+}
+
+// release frees the set of the stopped worker.
+func (a *affinity) release(pid int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := 0; i < len(a.pinned); i++ {
+		delete(a.pinned[i], pid)
+	}
+}
+
+// affinityFactory pins the workers right after they are spawned, before they receive the requests.
+type affinityFactory struct {
+	pool.Factory
+	affinity *affinity
+}
+
+// SpawnWorkerWithContext spawns the worker pinned to the CPU set, the set is released when the worker is stopped.
+func (f *affinityFactory) SpawnWorkerWithContext(ctx context.Context, cmd *exec.Cmd, options ...worker.Options) (*worker.Process, error) {
+	w, err := f.Factory.SpawnWorkerWithContext(ctx, cmd, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	f.affinity.pin(w)
+	w.AttachRelay(&affinityRelay{Relay: w.Relay(), pid: w.Pid(), affinity: f.affinity})
+	return w, nil
+}
+
+// affinityRelay releases the CPU set of the worker when the worker relay is closed.
+type affinityRelay struct {
+	relay.Relay
+	pid      int64
+	affinity *affinity
+}
+
+func (r *affinityRelay) Close() error {
+	r.affinity.release(r.pid)
+	return r.Relay.Close()
+}
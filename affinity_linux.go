@@ -0,0 +1,16 @@
+//go:build linux
+
+package http
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func setAffinity(pid int, cpus []int) error {
+	var set unix.CPUSet
+	for i := 0; i < len(cpus); i++ {
+		set.Set(cpus[i])
+	}
+
+	return unix.SchedSetaffinity(pid, &set)
+}
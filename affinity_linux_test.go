@@ -0,0 +1,70 @@
+//go:build linux
+
+package http
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+func TestAffinity(t *testing.T) {
+	// two sets of the same CPU, the test does not depend on the number of CPUs
+	cfg := &config.Config{
+		Relay:    &config.PoolRelay{Relay: config.RelayPipes},
+		Affinity: &config.Affinity{Mode: config.AffinityMasks, CPUs: []string{"0", "0"}},
+		Pool:     &pool.Config{NumWorkers: 2, AllocateTimeout: time.Second, DestroyTimeout: time.Second},
+	}
+	require.NoError(t, cfg.Affinity.InitDefaults())
+	p := &Plugin{log: zap.NewNop(), cfg: cfg, server: &testServer{t: t}, affinity: newAffinity(cfg.Affinity.Sets, zap.NewNop())}
+
+	require.NoError(t, p.initRelay())
+	pl, err := p.newPool(cfg.Pool)
+	require.NoError(t, err)
+	defer pl.Destroy(context.Background())
+
+	// the pids pinned to every set
+	pinned := func() [][]int64 {
+		p.affinity.mu.Lock()
+		defer p.affinity.mu.Unlock()
+		out := make([][]int64, len(p.affinity.pinned))
+		for i := 0; i < len(p.affinity.pinned); i++ {
+			for pid := range p.affinity.pinned[i] {
+				out[i] = append(out[i], pid)
+			}
+		}
+		return out
+	}
+
+	// the workers are pinned when spawned, every set is taken once
+	sets := pinned()
+	require.Len(t, sets, 2)
+	assert.Len(t, sets[0], 1)
+	assert.Len(t, sets[1], 1)
+	for _, w := range pl.Workers() {
+		var set unix.CPUSet
+		require.NoError(t, unix.SchedGetaffinity(int(w.Pid()), &set))
+		assert.Equal(t, 1, set.Count())
+		assert.True(t, set.IsSet(0))
+	}
+
+	// the replacement takes the set of the killed worker
+	killed := sets[0][0]
+	for _, w := range pl.Workers() {
+		if w.Pid() == killed {
+			require.NoError(t, w.Kill())
+		}
+	}
+	require.Eventually(t, func() bool {
+		sets = pinned()
+		return len(sets[0]) == 1 && sets[0][0] != killed
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Len(t, sets[1], 1)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package http
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+func setAffinity(_ int, _ []int) error {
+	return errors.Str("cpu affinity is supported only on linux")
+}
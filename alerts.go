@@ -0,0 +1,175 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	"go.uber.org/zap"
+)
+
+const (
+	alertFiring   string = "firing"
+	alertResolved string = "resolved"
+)
+
+// alert is the webhook payload
+type alert struct {
+	Status    string    `json:"status"`
+	Alert     string    `json:"alert"`
+	Rate      float64   `json:"rate"`
+	Threshold float64   `json:"threshold"`
+	Requests  uint64    `json:"requests"`
+	Failed    uint64    `json:"failed"`
+	Window    string    `json:"window"`
+	Host      string    `json:"host"`
+	Time      time.Time `json:"time"`
+}
+
+// rateCheck compares the rate of the counter over the window with the threshold
+type rateCheck struct {
+	name      string
+	threshold float64
+	counter   *atomic.Uint64
+	prev      uint64
+	firing    bool
+}
+
+// check returns the alert when the rate crosses the threshold in either direction, nil otherwise. The state is kept
+// when there were not enough requests in the window, and is changed by the delivered alert only: the undelivered
+// alert is checked again in the next window.
+func (c *rateCheck) check(requests, minRequests uint64) *alert {
+	failed := c.counter.Load()
+	delta := failed - c.prev
+	c.prev = failed
+
+	if requests == 0 || requests < minRequests {
+		return nil
+	}
+
+	rate := float64(delta) / float64(requests)
+	a := &alert{
+		Alert:     c.name,
+		Rate:      rate,
+		Threshold: c.threshold,
+		Requests:  requests,
+		Failed:    delta,
+	}
+
+	switch {
+	case !c.firing && rate >= c.threshold:
+		a.Status = alertFiring
+		return a
+	case c.firing && rate < c.threshold:
+		a.Status = alertResolved
+		return a
+	default:
+		return nil
+	}
+}
+
+// delivered changes the state after the alert is posted to the webhook.
+func (c *rateCheck) delivered(a *alert) {
+	c.firing = a.Status == alertFiring
+}
+
+// errorRate applies the responses counting middleware if the alerts are enabled
+func (p *Plugin) errorRate(next http.Handler) http.Handler {
+	if p.errorCounters == nil {
+		return next
+	}
+
+	return bundledMw.ErrorRate(next, p.errorCounters)
+}
+
+// alerts periodically checks the error rates and posts the alerts to the webhook.
+func (p *Plugin) alerts(cfg *config.Alerts, stopCh chan struct{}) {
+	tt := time.NewTicker(cfg.Window)
+	defer tt.Stop()
+
+	checks := make([]*rateCheck, 0, 2)
+	if cfg.ErrorRate > 0 {
+		checks = append(checks, &rateCheck{name: "error_rate", threshold: cfg.ErrorRate, counter: &p.errorCounters.ServerErrors})
+	}
+
+	if cfg.NoWorkersRate > 0 {
+		checks = append(checks, &rateCheck{name: "no_workers_rate", threshold: cfg.NoWorkersRate, counter: &p.errorCounters.NoWorkers})
+	}
+
+	client := &http.Client{Timeout: cfg.Timeout}
+	host, _ := os.Hostname()
+
+	var prev uint64
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-tt.C:
+			total := p.errorCounters.Requests.Load()
+			requests := total - prev
+			prev = total
+
+			for i := 0; i < len(checks); i++ {
+				a := checks[i].check(requests, cfg.MinRequests)
+				if a == nil {
+					continue
+				}
+
+				a.Window = cfg.Window.String()
+				a.Host = host
+				a.Time = time.Now().UTC()
+
+				if a.Status == alertFiring {
+					p.log.Warn("error rate threshold exceeded", zap.String("alert", a.Alert), zap.Float64("rate", a.Rate), zap.Float64("threshold", a.Threshold))
+				} else {
+					p.log.Info("error rate is back below the threshold", zap.String("alert", a.Alert), zap.Float64("rate", a.Rate))
+				}
+
+				err := postAlert(client, cfg, a)
+				if err != nil {
+					p.log.Error("failed to post the alert, retrying in the next window", zap.String("alert", a.Alert), zap.Error(err))
+					continue
+				}
+
+				checks[i].delivered(a)
+			}
+		}
+	}
+}
+
+func postAlert(client *http.Client, cfg *config.Alerts, a *alert) error {
+	const op = errors.Op("http_post_alert")
+	body, err := json.Marshal(a)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, cfg.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.E(op, errors.Errorf("webhook responded with %d", resp.StatusCode))
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateCheck(t *testing.T) {
+	counter := &atomic.Uint64{}
+	c := &rateCheck{name: "error_rate", threshold: 0.5, counter: counter}
+
+	counter.Add(6)
+	a := c.check(10, 5)
+	require.NotNil(t, a)
+	assert.Equal(t, alertFiring, a.Status)
+
+	// the alert was not delivered, the next window fires again
+	counter.Add(6)
+	a = c.check(10, 5)
+	require.NotNil(t, a)
+	assert.Equal(t, alertFiring, a.Status)
+	c.delivered(a)
+
+	counter.Add(6)
+	assert.Nil(t, c.check(10, 5))
+	// not enough requests
+	assert.Nil(t, c.check(1, 5))
+
+	a = c.check(10, 5)
+	require.NotNil(t, a)
+	assert.Equal(t, alertResolved, a.Status)
+	c.delivered(a)
+	assert.Nil(t, c.check(10, 5))
+}
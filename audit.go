@@ -0,0 +1,40 @@
+package http
+
+import (
+	"go.uber.org/zap"
+)
+
+// auditChannel is the logger channel of the management operations, a dedicated sink might be configured in the
+// `logs.channels.http_audit` section
+const auditChannel string = "http_audit"
+
+const (
+	callerRPC     string = "rpc"
+	callerPlugin  string = "plugin"
+	callerWatch   string = "watch"
+	callerUnknown string = "unknown"
+)
+
+// audit logs the management operation which affects the traffic. The RPC protocol does not carry the caller
+// identity, so the caller is the entry point of the operation (RPC or the other plugin, e.g. resetter or informer).
+func (p *Plugin) audit(operation, caller string, err error, params ...zap.Field) {
+	if p.auditLog == nil {
+		return
+	}
+
+	if caller == "" {
+		caller = callerUnknown
+	}
+
+	fields := make([]zap.Field, 0, len(params)+3)
+	fields = append(fields, zap.String("operation", operation), zap.String("caller", caller))
+	fields = append(fields, params...)
+
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+		p.auditLog.Warn("management operation failed", fields...)
+		return
+	}
+
+	p.auditLog.Info("management operation", fields...)
+}
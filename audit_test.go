@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAudit(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	p := &Plugin{log: zap.NewNop(), auditLog: zap.New(core)}
+
+	p.audit("purge_static_cache", callerRPC, nil, zap.Int64("purged", 3))
+	p.audit("swap_pool", "", errors.Str("no prepared pool"))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.Equal(t, map[string]any{"operation": "purge_static_cache", "caller": callerRPC, "purged": int64(3)}, entries[0].ContextMap())
+
+	// the failed operations are warnings, the caller is always set
+	assert.Equal(t, zapcore.WarnLevel, entries[1].Level)
+	assert.Equal(t, callerUnknown, entries[1].ContextMap()["caller"])
+	assert.Equal(t, "no prepared pool", entries[1].ContextMap()["error"])
+
+	// disabled
+	(&Plugin{}).audit("reset", callerPlugin, nil)
+}
+
+func TestAuditWorkers(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	pl := newTestPool(t, "ok", 200, &pool.Config{NumWorkers: 1, AllocateTimeout: time.Second})
+	p := &Plugin{log: zap.NewNop(), auditLog: zap.New(core)}
+	p.pool.Store(pl)
+
+	require.NoError(t, p.AddWorker())
+	require.NoError(t, p.RemoveWorker(context.Background()))
+
+	operations := logs.FilterField(zap.String("caller", callerPlugin)).AllUntimed()
+	require.Len(t, operations, 2)
+	assert.Equal(t, "add_worker", operations[0].ContextMap()["operation"])
+	assert.Equal(t, "remove_worker", operations[1].ContextMap()["operation"])
+}
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/fsm"
+	"go.uber.org/zap"
+)
+
+// deployment pools names, used as the pool metrics label
+const (
+	greenPoolName    string = "green"
+	previousPoolName string = "previous"
+)
+
+// PreparePool creates the green pool with the command (the server command if empty) next to the serving pool and
+// checks that all its workers are ready. The green pool doesn't receive the traffic until it's swapped, the
+// previously prepared green pool is destroyed.
+func (p *Plugin) PreparePool(command []string) error {
+	const op = errors.Op("http_prepare_pool")
+	p.deployMu.Lock()
+	defer p.deployMu.Unlock()
+
+	cfg := *p.cfg.Pool
+	if len(command) > 0 {
+		cfg.Command = command
+	}
+
+	// the workers are started without blocking the traffic
+	green, err := p.newPool(&cfg)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	err = poolReady(green, cfg.Debug, p.cfg.NumWorkers())
+	if err == nil {
+		if h := p.currentHandler(); h != nil {
+			err = p.probeWorkers(green, h)
+		}
+	}
+	if err != nil {
+		green.Destroy(context.Background())
+		return errors.E(op, err)
+	}
+
+	prepared := p.green.Load()
+	p.green.Store(green)
+
+	if prepared != nil {
+		prepared.Destroy(context.Background())
+	}
+
+	p.log.Info("green pool prepared", zap.Strings("command", cfg.Command), zap.Uint64("workers", cfg.NumWorkers))
+	return nil
+}
+
+// SwapPool switches the traffic to the green pool, the serving pool is kept for the rollback until the commit. The
+// swap doesn't wait for the requests: the requests in flight are completed by the serving pool, the new requests are
+// dispatched to the green pool.
+func (p *Plugin) SwapPool() error {
+	const op = errors.Op("http_swap_pool")
+	p.deployMu.Lock()
+	defer p.deployMu.Unlock()
+
+	h := p.currentHandler()
+	green := p.green.Load()
+	if h == nil || green == nil {
+		return errors.E(op, errors.Str("no green pool prepared"))
+	}
+
+	retired := p.previous.Load()
+	p.previous.Store(p.pool.Load())
+	p.pool.Store(green)
+	p.green.Store(nil)
+	h.SetPool(green)
+
+	// the swap was not committed, the pool is drained by the destroy
+	if retired != nil {
+		retired.Destroy(context.Background())
+	}
+
+	p.log.Info("traffic swapped to the green pool")
+	return nil
+}
+
+// RollbackPool switches the traffic back to the previous pool, the rolled back pool becomes the previous one.
+func (p *Plugin) RollbackPool() error {
+	const op = errors.Op("http_rollback_pool")
+	p.deployMu.Lock()
+	defer p.deployMu.Unlock()
+
+	h := p.currentHandler()
+	previous := p.previous.Load()
+	if h == nil || previous == nil {
+		return errors.E(op, errors.Str("no previous pool"))
+	}
+
+	p.previous.Store(p.pool.Load())
+	p.pool.Store(previous)
+	h.SetPool(previous)
+
+	p.log.Info("traffic rolled back to the previous pool")
+	return nil
+}
+
+// CommitPool destroys the previous pool, the rollback is not possible after the commit.
+func (p *Plugin) CommitPool() error {
+	const op = errors.Op("http_commit_pool")
+	p.deployMu.Lock()
+	defer p.deployMu.Unlock()
+
+	previous := p.previous.Load()
+	p.previous.Store(nil)
+
+	if previous == nil {
+		return errors.E(op, errors.Str("no previous pool"))
+	}
+
+	previous.Destroy(context.Background())
+	p.log.Info("previous pool destroyed")
+	return nil
+}
+
+// poolReady checks that the pool has all the workers ready, the debug pools start the workers per request.
+func poolReady(pl common.Pool, debug bool, numWorkers uint64) error {
+	if debug {
+		return nil
+	}
+
+	workers := pl.Workers()
+	if uint64(len(workers)) < numWorkers {
+		return errors.Errorf("%d of %d workers started", len(workers), numWorkers)
+	}
+
+	for i := 0; i < len(workers); i++ {
+		if !workers[i].State().Compare(fsm.StateReady) {
+			return errors.Errorf("worker %d is not ready: %s", workers[i].Pid(), workers[i].State().String())
+		}
+	}
+
+	return nil
+}
+
+// currentHandler returns the handler of the main pool, nil before Serve.
+func (p *Plugin) currentHandler() *handler.Handler {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.handler
+}
+
+// poolRef holds the pool replaced by the blue/green deployment while the requests are served, the replacements are
+// serialized by the deployment lock.
+type poolRef struct {
+	v atomic.Pointer[common.Pool]
+}
+
+// Load returns the pool, nil if not set.
+func (r *poolRef) Load() common.Pool {
+	if pl := r.v.Load(); pl != nil {
+		return *pl
+	}
+
+	return nil
+}
+
+// Store sets the pool, nil clears it.
+func (r *poolRef) Store(pl common.Pool) {
+	r.v.Store(&pl)
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/fsm"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newDeployPlugin(t *testing.T, probe *config.ReadinessProbe) *Plugin {
+	cfg := &config.Config{
+		Uploads:           &config.Uploads{},
+		InternalErrorCode: 500,
+		ReadinessProbe:    probe,
+		Pool:              &pool.Config{Command: []string{"blue"}, NumWorkers: 1, AllocateTimeout: time.Second},
+	}
+
+	srv := &testServer{t: t}
+	pl, err := srv.NewPool(context.Background(), cfg.Pool, nil, zap.NewNop())
+	require.NoError(t, err)
+	h, err := handler.NewHandler(cfg, pl, zap.NewNop())
+	require.NoError(t, err)
+
+	p := &Plugin{log: zap.NewNop(), cfg: cfg, server: srv, handler: h}
+	p.pool.Store(pl)
+	return p
+}
+
+// serveBody sends the request through the plugin handler, the body of the serving pool is returned.
+func serveBody(t *testing.T, p *Plugin) string {
+	w := httptest.NewRecorder()
+	p.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestBlueGreenSwap(t *testing.T) {
+	p := newDeployPlugin(t, nil)
+	blue := p.pool.Load()
+
+	assert.Error(t, p.SwapPool())
+	assert.Error(t, p.RollbackPool())
+	assert.Error(t, p.CommitPool())
+
+	// the green pool doesn't receive the traffic until the swap
+	require.NoError(t, p.PreparePool([]string{"green"}))
+	assert.Equal(t, "blue", serveBody(t, p))
+
+	require.NoError(t, p.SwapPool())
+	assert.Equal(t, "green", serveBody(t, p))
+	assert.Same(t, blue, p.previous.Load())
+
+	// the rolled back pool is kept as the previous one
+	require.NoError(t, p.RollbackPool())
+	assert.Equal(t, "blue", serveBody(t, p))
+	require.NoError(t, p.RollbackPool())
+	assert.Equal(t, "green", serveBody(t, p))
+
+	require.NoError(t, p.CommitPool())
+	assert.Nil(t, p.previous.Load())
+	assert.Error(t, p.RollbackPool())
+	assert.Empty(t, blue.Workers())
+	assert.Equal(t, "green", serveBody(t, p))
+}
+
+func TestBlueGreenProbe(t *testing.T) {
+	probe := &config.ReadinessProbe{Path: "/ready", Attempts: 1}
+	require.NoError(t, probe.InitDefaults())
+	p := newDeployPlugin(t, probe)
+
+	// the green pool failing the readiness probe is destroyed, the traffic stays on the serving pool
+	assert.Error(t, p.PreparePool([]string{"broken"}))
+	assert.Nil(t, p.green.Load())
+	assert.Error(t, p.SwapPool())
+	assert.Equal(t, "blue", serveBody(t, p))
+
+	require.NoError(t, p.PreparePool([]string{"green"}))
+	require.NoError(t, p.SwapPool())
+	assert.Equal(t, "green", serveBody(t, p))
+}
+
+func TestBlueGreenSwapInFlight(t *testing.T) {
+	p := newDeployPlugin(t, nil)
+	blue := p.pool.Load()
+	require.NoError(t, p.PreparePool([]string{"green"}))
+
+	// the request in flight holds the handler lock until the hung worker is killed
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hang", nil))
+	}()
+	require.Eventually(t, func() bool {
+		workers := blue.Workers()
+		return len(workers) == 1 && workers[0].State().Compare(fsm.StateWorking)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	swapped := make(chan error, 1)
+	go func() { swapped <- p.SwapPool() }()
+	select {
+	case err := <-swapped:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the swap waits for the requests in flight")
+	}
+
+	assert.Equal(t, "green", serveBody(t, p))
+
+	require.NoError(t, blue.Workers()[0].Kill())
+	<-done
+}
@@ -1,8 +1,12 @@
 package common
 
 import (
+	"bufio"
 	"context"
+	"io/fs"
+	"net"
 	"net/http"
+	"os/exec"
 
 	"github.com/roadrunner-server/pool/payload"
 	"github.com/roadrunner-server/pool/pool"
@@ -33,12 +37,36 @@ type Server interface {
 	NewPool(ctx context.Context, cfg *pool.Config, env map[string]string, _ *zap.Logger) (*staticPool.Pool, error)
 }
 
+// CmdFactory is implemented by the server plugin, the workers commands are prepared with the server command, env
+// and user.
+type CmdFactory interface {
+	CmdFactory(env map[string]string) func() *exec.Cmd
+}
+
 // Middleware represents http stdlib middleware interface
 type Middleware interface {
 	Middleware(f http.Handler) http.Handler
 	Name() string
 }
 
+// StaticFS provides the file system used as the static root, e.g. the embed.FS with the frontend assets
+type StaticFS interface {
+	StaticFS() fs.FS
+	Name() string
+}
+
+// Upgrader takes over the HTTP/1.1 connections upgraded to the custom protocols, e.g. Upgrade: mqtt, so the protocol
+// shares the port with the application. The server responds with 101 Switching Protocols and passes the hijacked
+// connection to the plugin claiming the protocol.
+type Upgrader interface {
+	// Protocols claimed by the plugin, case-insensitive names without the version, e.g. mqtt.
+	Protocols() []string
+	// Upgrade serves the connection and closes it. The data already read by the server is buffered in the rw, the
+	// request context is canceled after Upgrade returns.
+	Upgrade(conn net.Conn, rw *bufio.ReadWriter, r *http.Request)
+	Name() string
+}
+
 type Configurer interface {
 	// Experimental checks if RR runs in experimental mode.
 	Experimental() bool
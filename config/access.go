@@ -0,0 +1,129 @@
+package config
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// regexpPrefix marks the regular expression patterns, all other patterns are globs.
+const regexpPrefix = "re:"
+
+// Patterns are the compiled path patterns.
+type Patterns []*regexp.Regexp
+
+// CompilePatterns compiles the glob or regexp (`re:` prefix) patterns of the relative paths. In the globs `*` and `?`
+// match within the path segment, `**` matches any number of the segments, and the pattern without a slash matches
+// the file name at any level, e.g. `*.log`.
+func CompilePatterns(patterns []string) (Patterns, error) {
+	const op = errors.Op("compile_patterns")
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	compiled := make(Patterns, 0, len(patterns))
+	for i := 0; i < len(patterns); i++ {
+		expr := globRegexp(patterns[i])
+		if re, ok := strings.CutPrefix(patterns[i], regexpPrefix); ok {
+			expr = re
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.E(op, errors.Errorf("bad pattern %s: %v", patterns[i], err))
+		}
+
+		compiled = append(compiled, re)
+	}
+
+	return compiled, nil
+}
+
+// Match returns true if any of the patterns matches the relative path.
+func (p Patterns) Match(rel string) bool {
+	for i := 0; i < len(p); i++ {
+		if p[i].MatchString(rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// globRegexp converts the glob into the anchored regular expression.
+func globRegexp(glob string) string {
+	glob = strings.TrimPrefix(glob, "/")
+
+	var sb strings.Builder
+	sb.WriteByte('^')
+	if !strings.Contains(glob, "/") {
+		// file name at any level
+		sb.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "/**":
+			sb.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			sb.WriteString("[^/]*")
+		case glob[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	sb.WriteByte('$')
+	return sb.String()
+}
+
+// Access checks the relative file paths against the forbidden and allowed extensions and patterns.
+type Access struct {
+	forbidden      map[string]struct{}
+	allowed        map[string]struct{}
+	forbidPatterns Patterns
+	allowPatterns  Patterns
+}
+
+// NewAccess creates the access checker, the extensions are matched case-insensitively.
+func NewAccess(forbidden, allowed map[string]struct{}, forbidPatterns, allowPatterns Patterns) *Access {
+	return &Access{
+		forbidden:      forbidden,
+		allowed:        allowed,
+		forbidPatterns: forbidPatterns,
+		allowPatterns:  allowPatterns,
+	}
+}
+
+// Allow returns true if the file is not forbidden and is allowed, all not forbidden files are allowed if there are
+// no allowed extensions and patterns.
+func (a *Access) Allow(rel string) bool {
+	if a.Forbidden(rel) {
+		return false
+	}
+
+	if len(a.allowed) == 0 && len(a.allowPatterns) == 0 {
+		return true
+	}
+
+	rel = strings.TrimPrefix(rel, "/")
+	_, ok := a.allowed[strings.ToLower(path.Ext(rel))]
+	return ok || a.allowPatterns.Match(rel)
+}
+
+// Forbidden returns true if the path has the forbidden extension or matches the forbidden pattern.
+func (a *Access) Forbidden(rel string) bool {
+	rel = strings.TrimPrefix(rel, "/")
+	_, ok := a.forbidden[strings.ToLower(path.Ext(rel))]
+	return ok || a.forbidPatterns.Match(rel)
+}
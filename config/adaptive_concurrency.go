@@ -0,0 +1,102 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// ConcurrencyAlgorithm is the algorithm of the adaptive concurrency limit.
+type ConcurrencyAlgorithm string
+
+const (
+	// ConcurrencyGradient compares the latest dispatch latency with the long-term one: the limit shrinks when the
+	// latency grows above the tolerance and grows while the latency is stable.
+	ConcurrencyGradient ConcurrencyAlgorithm = "gradient"
+	// ConcurrencyAIMD adds 1 to the limit while the latency is below the threshold and multiplies it by the backoff
+	// ratio otherwise.
+	ConcurrencyAIMD ConcurrencyAlgorithm = "aimd"
+)
+
+// AdaptiveConcurrency adjusts the number of the requests dispatched to the pool at once by the observed dispatch
+// latency (the wait for a free worker and the execution up to the first response frame), so the limit converges on the
+// pool throughput knee. The requests above the limit wait in the dispatch queue (see the priority and queue options).
+type AdaptiveConcurrency struct {
+	// Algorithm is gradient or aimd, defaults to gradient.
+	Algorithm ConcurrencyAlgorithm `mapstructure:"algorithm"`
+	// InitialLimit defaults to the number of workers.
+	InitialLimit int64 `mapstructure:"initial_limit"`
+	// MinLimit defaults to 1.
+	MinLimit int64 `mapstructure:"min_limit"`
+	// MaxLimit defaults to 10 times the initial limit.
+	MaxLimit int64 `mapstructure:"max_limit"`
+	// Tolerance is the latency growth ratio tolerated by the gradient algorithm, defaults to 1.5.
+	Tolerance float64 `mapstructure:"tolerance"`
+	// Smoothing of the gradient limit changes, 0-1, defaults to 0.2.
+	Smoothing float64 `mapstructure:"smoothing"`
+	// LatencyThreshold of the aimd algorithm, required for aimd.
+	LatencyThreshold time.Duration `mapstructure:"latency_threshold"`
+	// BackoffRatio of the aimd algorithm, 0-1, defaults to 0.9.
+	BackoffRatio float64 `mapstructure:"backoff_ratio"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (a *AdaptiveConcurrency) InitDefaults(numWorkers uint64) error {
+	if a.Algorithm == "" {
+		a.Algorithm = ConcurrencyGradient
+	}
+
+	if a.InitialLimit == 0 {
+		a.InitialLimit = int64(numWorkers) //nolint:gosec
+	}
+
+	if a.MinLimit == 0 {
+		a.MinLimit = 1
+	}
+
+	if a.MaxLimit == 0 {
+		a.MaxLimit = a.InitialLimit * 10
+	}
+
+	if a.Tolerance == 0 {
+		a.Tolerance = 1.5
+	}
+
+	if a.Smoothing == 0 {
+		a.Smoothing = 0.2
+	}
+
+	if a.BackoffRatio == 0 {
+		a.BackoffRatio = 0.9
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (a *AdaptiveConcurrency) Valid() error {
+	const op = errors.Op("adaptive_concurrency_validation")
+	switch a.Algorithm {
+	case ConcurrencyGradient:
+	case ConcurrencyAIMD:
+		if a.LatencyThreshold <= 0 {
+			return errors.E(op, errors.Str("latency_threshold is required for the aimd algorithm"))
+		}
+	default:
+		return errors.E(op, errors.Errorf("unknown algorithm %q, should be gradient or aimd", a.Algorithm))
+	}
+
+	if a.MinLimit < 1 || a.MinLimit > a.InitialLimit || a.InitialLimit > a.MaxLimit {
+		return errors.E(op, errors.Str("limits should satisfy 1 <= min_limit <= initial_limit <= max_limit"))
+	}
+
+	if a.Tolerance < 1 {
+		return errors.E(op, errors.Str("tolerance should be at least 1"))
+	}
+
+	if a.Smoothing <= 0 || a.Smoothing > 1 || a.BackoffRatio <= 0 || a.BackoffRatio >= 1 {
+		return errors.E(op, errors.Str("smoothing should be in (0, 1], backoff_ratio in (0, 1)"))
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// AffinityMode defines how the workers are distributed across the CPUs.
+type AffinityMode string
+
+const (
+	// AffinityRoundRobin pins every worker to a single CPU, one after another.
+	AffinityRoundRobin AffinityMode = "round_robin"
+	// AffinityMasks pins every worker to one of the configured CPU sets, one after another.
+	AffinityMasks AffinityMode = "masks"
+)
+
+// Affinity configures the pinning of the workers processes to CPU sets (linux only). The workers are pinned when they
+// are spawned, every worker takes the set with the fewest workers. The workers are spawned by the plugin, the pools
+// without the relay use the own pipes relay.
+type Affinity struct {
+	// Mode is round_robin or masks, defaults to round_robin.
+	Mode AffinityMode `mapstructure:"mode"`
+	// CPUs is the list of CPU sets in the cpuset format, e.g. `0-3,8`. In the round_robin mode all CPUs from the sets
+	// are used one by one, all online CPUs are used when empty. In the masks mode every set is used as a whole.
+	CPUs []string `mapstructure:"cpus"`
+	// Sets is the parsed list of the CPU sets to assign to the workers.
+	Sets [][]int `mapstructure:"-"`
+}
+
+// InitDefaults sets missing values to their default values and parses the CPU sets.
+func (a *Affinity) InitDefaults() error {
+	if a.Mode == "" {
+		a.Mode = AffinityRoundRobin
+	}
+
+	a.Sets = a.Sets[:0]
+	for i := 0; i < len(a.CPUs); i++ {
+		set, err := parseCPUList(a.CPUs[i])
+		if err != nil {
+			return err
+		}
+
+		switch a.Mode {
+		case AffinityRoundRobin:
+			for j := 0; j < len(set); j++ {
+				a.Sets = append(a.Sets, []int{set[j]})
+			}
+		default:
+			a.Sets = append(a.Sets, set)
+		}
+	}
+
+	if len(a.Sets) == 0 && a.Mode == AffinityRoundRobin {
+		for i := 0; i < runtime.NumCPU(); i++ {
+			a.Sets = append(a.Sets, []int{i})
+		}
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (a *Affinity) Valid() error {
+	const op = errors.Op("affinity_validation")
+	switch a.Mode {
+	case AffinityRoundRobin:
+	case AffinityMasks:
+		if len(a.CPUs) == 0 {
+			return errors.E(op, errors.Str("cpus should be set in the masks mode"))
+		}
+	default:
+		return errors.E(op, errors.Errorf("unknown affinity mode: %s", a.Mode))
+	}
+
+	return nil
+}
+
+// parseCPUList parses the cpuset list format: `0-3,8,10-11`.
+func parseCPUList(list string) ([]int, error) {
+	const op = errors.Op("parse_cpu_list")
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil || start < 0 {
+			return nil, errors.E(op, errors.Errorf("invalid cpu: %s", part))
+		}
+
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(to)
+			if err != nil || end < start {
+				return nil, errors.E(op, errors.Errorf("invalid cpu range: %s", part))
+			}
+		}
+
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	if len(cpus) == 0 {
+		return nil, errors.E(op, errors.Errorf("empty cpu set: %q", list))
+	}
+
+	return cpus, nil
+}
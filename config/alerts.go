@@ -0,0 +1,72 @@
+package config
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Alerts posts a JSON alert to the webhook when the rate of the 5xx responses or of the requests rejected because
+// there were no free workers crosses the threshold over the window, and another one when the rate is back below the
+// threshold. Basic alerting for the deployments without a metrics stack.
+type Alerts struct {
+	// Webhook is the http(s) URL the alerts are posted to. Required.
+	Webhook string `mapstructure:"webhook"`
+	// Headers are added to the webhook requests, e.g. Authorization.
+	Headers map[string]string `mapstructure:"headers"`
+	// Window is the interval the rates are computed over, defaults to 1m.
+	Window time.Duration `mapstructure:"window"`
+	// ErrorRate is the fraction (0-1] of the 5xx responses, 0 - disabled.
+	ErrorRate float64 `mapstructure:"error_rate"`
+	// NoWorkersRate is the fraction (0-1] of the requests rejected because there were no free workers, 0 - disabled.
+	NoWorkersRate float64 `mapstructure:"no_workers_rate"`
+	// MinRequests is the number of the requests in the window below which the rates are not checked, defaults to 10.
+	MinRequests uint64 `mapstructure:"min_requests"`
+	// Timeout of the webhook request, defaults to 5s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (a *Alerts) InitDefaults() error {
+	if a.Window == 0 {
+		a.Window = time.Minute
+	}
+
+	if a.MinRequests == 0 {
+		a.MinRequests = 10
+	}
+
+	if a.Timeout == 0 {
+		a.Timeout = 5 * time.Second
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (a *Alerts) Valid() error {
+	const op = errors.Op("alerts_validation")
+	if a.Webhook == "" {
+		return errors.E(op, errors.Str("webhook is required"))
+	}
+
+	u, err := url.Parse(a.Webhook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.E(op, errors.Errorf("invalid webhook URL %q", a.Webhook))
+	}
+
+	if a.ErrorRate == 0 && a.NoWorkersRate == 0 {
+		return errors.E(op, errors.Str("at least one of the error_rate or no_workers_rate thresholds should be set"))
+	}
+
+	if a.ErrorRate < 0 || a.ErrorRate > 1 || a.NoWorkersRate < 0 || a.NoWorkersRate > 1 {
+		return errors.E(op, errors.Str("error_rate and no_workers_rate should be in the (0, 1] range"))
+	}
+
+	if a.Window < 0 || a.Timeout < 0 {
+		return errors.E(op, errors.Str("window and timeout should be positive"))
+	}
+
+	return nil
+}
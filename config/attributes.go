@@ -0,0 +1,37 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Attributes controls which request attributes set by the Go middleware are sent to the workers. The entries are the
+// attribute names or the prefixes ending with *, e.g. tls_*. When the allow list is set, only the matching attributes
+// are sent; the deny list is applied after it. The attributes set by the handler (request_id, deadline, header_env,
+// rr_body_*) are always sent.
+type Attributes struct {
+	// Allow is the list of the attributes sent to the workers, empty - all.
+	Allow []string `mapstructure:"allow"`
+	// Deny is the list of the attributes never sent to the workers.
+	Deny []string `mapstructure:"deny"`
+}
+
+// Valid validates the configuration.
+func (a *Attributes) Valid() error {
+	const op = errors.Op("attributes_validation")
+	if len(a.Allow) == 0 && len(a.Deny) == 0 {
+		return errors.E(op, errors.Str("allow or deny list is required"))
+	}
+
+	for _, list := range [][]string{a.Allow, a.Deny} {
+		for i := 0; i < len(list); i++ {
+			name := strings.TrimSuffix(list[i], "*")
+			if strings.TrimSpace(list[i]) == "" || strings.Contains(name, "*") {
+				return errors.E(op, errors.Errorf("invalid attribute %q, the * is allowed only at the end", list[i]))
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"net/http"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Backpressure configures the rejection of the requests when too many of them are waiting for the workers.
+type Backpressure struct {
+	// MaxPending is the max number of requests dispatched to the pool and waiting for the response, 0 - unlimited.
+	MaxPending int64 `mapstructure:"max_pending"`
+	// Status code for the rejected requests, 429 or 503, defaults to 503.
+	Status int `mapstructure:"status"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (b *Backpressure) InitDefaults() error {
+	if b.Status == 0 {
+		b.Status = http.StatusServiceUnavailable
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (b *Backpressure) Valid() error {
+	const op = errors.Op("backpressure_validation")
+	if b.MaxPending < 0 {
+		return errors.E(op, errors.Str("max_pending should not be negative"))
+	}
+
+	if b.Status != http.StatusTooManyRequests && b.Status != http.StatusServiceUnavailable {
+		return errors.E(op, errors.Errorf("status should be 429 or 503, got: %d", b.Status))
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// CacheControl configures the Cache-Control and Expires headers of the static responses and, optionally, of the
+// worker responses without the cache headers.
+type CacheControl struct {
+	// Rules are checked in order, the first matching rule is applied.
+	Rules []*CacheControlRule `mapstructure:"rules"`
+	// Workers applies the rules to the worker responses (status below 400) without the Cache-Control and Expires
+	// headers.
+	Workers bool `mapstructure:"workers"`
+}
+
+// CacheControlRule sets the cache headers of the responses for the matching paths.
+type CacheControlRule struct {
+	// Extensions is the list of the file extensions, e.g. .css.
+	Extensions []string `mapstructure:"extensions"`
+	// Paths is the list of the path globs (path.Match syntax), the `/**` suffix matches everything under the prefix.
+	Paths []string `mapstructure:"paths"`
+	// Value is the Cache-Control header value, e.g. `public, max-age=31536000, immutable`.
+	Value string `mapstructure:"value"`
+	// Expires sets the Expires header to the response time plus the duration.
+	Expires time.Duration `mapstructure:"expires"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (c *CacheControl) InitDefaults() error {
+	for i := 0; i < len(c.Rules); i++ {
+		if c.Rules[i] == nil {
+			continue
+		}
+
+		for j := 0; j < len(c.Rules[i].Extensions); j++ {
+			c.Rules[i].Extensions[j] = strings.ToLower(c.Rules[i].Extensions[j])
+		}
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (c *CacheControl) Valid() error {
+	const op = errors.Op("cache_control_validation")
+	for i := 0; i < len(c.Rules); i++ {
+		rule := c.Rules[i]
+		if rule == nil || len(rule.Extensions) == 0 && len(rule.Paths) == 0 {
+			return errors.E(op, errors.Errorf("cache_control rule %d should have extensions or paths", i))
+		}
+
+		if rule.Value == "" && rule.Expires <= 0 {
+			return errors.E(op, errors.Errorf("cache_control rule %d should have value or expires", i))
+		}
+
+		for j := 0; j < len(rule.Paths); j++ {
+			_, err := path.Match(strings.TrimSuffix(rule.Paths[j], "/**"), "")
+			if err != nil {
+				return errors.E(op, errors.Errorf("cache_control rule %d, bad path %s: %v", i, rule.Paths[j], err))
+			}
+		}
+	}
+
+	return nil
+}
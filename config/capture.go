@@ -0,0 +1,85 @@
+package config
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Capture configures the recording of the worker requests to the disk. Every matching request is saved with the
+// headers, body, response status and the handling time into a separate JSON file, the files can be replayed against
+// the current pool with the `http.Replay` RPC method.
+type Capture struct {
+	// Dir is the directory for the captured requests.
+	Dir string `mapstructure:"dir"`
+	// Methods limits the capture to the request methods, all methods by default.
+	Methods []string `mapstructure:"methods"`
+	// Paths limits the capture to the path globs (path.Match syntax), the `/**` suffix matches everything under the
+	// prefix. All paths by default.
+	Paths []string `mapstructure:"paths"`
+	// Statuses limits the capture to the response statuses, all statuses by default.
+	Statuses []int `mapstructure:"statuses"`
+	// MaxBodySize is the max size of the captured request body, requests with the larger bodies are not captured.
+	// Defaults to 1MB.
+	MaxBodySize int64 `mapstructure:"max_body_size"`
+	// MaxFiles stops the capture after the number of files is written, 0 - unlimited. Defaults to 1000.
+	MaxFiles int64 `mapstructure:"max_files"`
+	// Redact is the list of the request headers with the hidden values, in addition to the Authorization,
+	// Proxy-Authorization and Cookie headers. The redacted headers are not sent on replay.
+	Redact []string `mapstructure:"redact"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (c *Capture) InitDefaults() error {
+	if c.MaxBodySize == 0 {
+		c.MaxBodySize = 1024 * 1024
+	}
+
+	if c.MaxFiles == 0 {
+		c.MaxFiles = 1000
+	}
+
+	for i := 0; i < len(c.Methods); i++ {
+		c.Methods[i] = strings.ToUpper(c.Methods[i])
+	}
+
+	c.Redact = append(c.Redact, "Authorization", "Proxy-Authorization", "Cookie")
+	for i := 0; i < len(c.Redact); i++ {
+		c.Redact[i] = http.CanonicalHeaderKey(c.Redact[i])
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (c *Capture) Valid() error {
+	const op = errors.Op("capture_validation")
+	if c.Dir == "" {
+		return errors.E(op, errors.Str("capture dir should be set"))
+	}
+
+	st, err := os.Stat(c.Dir)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	if !st.IsDir() {
+		return errors.E(op, errors.Errorf("capture dir %s is not a directory", c.Dir))
+	}
+
+	if c.MaxBodySize < 0 || c.MaxFiles < 0 {
+		return errors.E(op, errors.Str("capture max_body_size and max_files should not be negative"))
+	}
+
+	for i := 0; i < len(c.Paths); i++ {
+		_, err = path.Match(strings.TrimSuffix(c.Paths[i], "/**"), "")
+		if err != nil {
+			return errors.E(op, errors.Errorf("bad path %s: %v", c.Paths[i], err))
+		}
+	}
+
+	return nil
+}
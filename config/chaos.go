@@ -0,0 +1,66 @@
+package config
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Chaos configures the fault injection for the testing of the clients' retries (staging only). Only the requests with
+// the Header from the trusted peers (trusted_subnets) are affected. The latency is added first, then the request is
+// rejected with the error status, the connection is dropped or the request is handled as usual. The percentages are
+// 0-100 of the matching requests.
+type Chaos struct {
+	// Header of the requests to inject the faults into, required.
+	Header string `mapstructure:"header"`
+	// Value of the Header, any value matches when empty.
+	Value string `mapstructure:"value"`
+	// Latency added to the requests.
+	Latency time.Duration `mapstructure:"latency"`
+	// LatencyPercentage of the requests delayed by the Latency.
+	LatencyPercentage float64 `mapstructure:"latency_percentage"`
+	// ErrorStatus of the rejected requests, defaults to 503.
+	ErrorStatus int `mapstructure:"error_status"`
+	// ErrorPercentage of the requests rejected with the ErrorStatus.
+	ErrorPercentage float64 `mapstructure:"error_percentage"`
+	// DropPercentage of the requests which connection (HTTP/1) or stream (HTTP/2, HTTP/3) is reset without a response.
+	DropPercentage float64 `mapstructure:"drop_percentage"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (c *Chaos) InitDefaults() error {
+	if c.ErrorStatus == 0 {
+		c.ErrorStatus = http.StatusServiceUnavailable
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (c *Chaos) Valid() error {
+	const op = errors.Op("chaos_validation")
+	if c.Header == "" {
+		return errors.E(op, errors.Str("header is required"))
+	}
+
+	for _, pct := range []float64{c.LatencyPercentage, c.ErrorPercentage, c.DropPercentage} {
+		if pct < 0 || pct > 100 {
+			return errors.E(op, errors.Str("percentages should be between 0 and 100"))
+		}
+	}
+
+	if c.ErrorPercentage+c.DropPercentage > 100 {
+		return errors.E(op, errors.Str("error_percentage and drop_percentage should not exceed 100 in total"))
+	}
+
+	if c.Latency < 0 || (c.LatencyPercentage > 0 && c.Latency == 0) {
+		return errors.E(op, errors.Str("latency should be positive"))
+	}
+
+	if c.ErrorStatus < 500 || c.ErrorStatus > 599 {
+		return errors.E(op, errors.Str("error_status should be 5xx"))
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package config
+
+// PayloadCodec is the encoding of the request and response context (headers, status, attributes) exchanged with the
+// workers.
+type PayloadCodec string
+
+const (
+	// CodecProto is the protobuf encoding of the http.v1 messages, used by the PHP workers.
+	CodecProto PayloadCodec = "proto"
+	// CodecMsgpack is the MessagePack map with the same field names as the http.v1 messages (remote_addr, header,
+	// etc.), the headers, cookies and attributes are maps of the string arrays. The responses are decoded by the
+	// codec set by the worker, so both codecs are accepted in the responses.
+	CodecMsgpack PayloadCodec = "msgpack"
+)
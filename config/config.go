@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net"
 	"runtime"
 	"strings"
 	"time"
@@ -13,6 +14,14 @@ import (
 	"github.com/roadrunner-server/pool/pool"
 )
 
+// listener names of the listener_middleware option
+const (
+	ListenerHTTP  string = "http"
+	ListenerHTTPS string = "https"
+	ListenerFCGI  string = "fcgi"
+	ListenerHTTP3 string = "http3"
+)
+
 // Config configures RoadRunner HTTP server.
 type Config struct {
 	// RawBody if turned on, RR will not parse the incoming HTTP body and will send it as is
@@ -21,10 +30,37 @@ type Config struct {
 	Address string `mapstructure:"address"`
 	// AccessLogs turn on/off, logged at Info log level, default: false
 	AccessLogs bool `mapstructure:"access_logs"`
+	// AccessLogFormat is the access log line template (Apache mod_log_config placeholders), the structured access log
+	// is written when empty.
+	AccessLogFormat string `mapstructure:"access_log_format"`
+	// OtelMetrics records the request metrics with the OpenTelemetry meter provider of the plugin, pushed via
+	// OTLP/HTTP, in addition to the Prometheus collector.
+	OtelMetrics bool `mapstructure:"otel_metrics"`
+	// OtelMetricsExporter configures the OTLP exporter of the otel_metrics.
+	OtelMetricsExporter *OtelMetricsExporter `mapstructure:"otel_metrics_exporter"`
+	// TracePropagators are the trace context formats extracted from the requests and injected toward the workers,
+	// defaults to tracecontext, baggage and jaeger.
+	TracePropagators []Propagator `mapstructure:"trace_propagators"`
 	// List of the middleware names (order will be preserved)
 	Middleware []string `mapstructure:"middleware"`
+	// ListenerMiddleware replaces the middleware list for the listeners by the name: http, https, fcgi or http3, e.g.
+	// the empty list for the internal listener. The listeners not in the map use the middleware list.
+	ListenerMiddleware map[string][]string `mapstructure:"listener_middleware"`
+	// ListenerProtocols is the HTTP version policy of the http and https listeners, e.g. HTTP/2 only for the clients
+	// known to support it. The HTTP/3 listener is enabled by the http3 section.
+	ListenerProtocols map[string]*https.Protocols `mapstructure:"listener_protocols"`
 	// Pool configures worker pool.
 	Pool *pool.Config `mapstructure:"pool"`
+	// Debug configures the pool debug mode.
+	Debug *Debug `mapstructure:"debug"`
+	// Supervisor contains worker limits declared directly in the pool section, merged into Pool.Supervisor.
+	Supervisor *Supervisor `mapstructure:"-"`
+	// Relay overrides the server relay for the HTTP pool, declared in the pool section.
+	Relay *PoolRelay `mapstructure:"-"`
+	// RoutePools serve the requests under the path prefixes with the own worker pools.
+	RoutePools []*RoutePool `mapstructure:"route_pools"`
+	// Embedded is set when the handler is used without the plugin servers, the addresses are not required.
+	Embedded bool `mapstructure:"-"`
 	// InternalErrorCode used to override default 500 (InternalServerError) http code
 	InternalErrorCode uint64 `mapstructure:"internal_error_code"`
 	// MaxRequestSize specified max size for payload body in megabytes, set 0 to unlimited.
@@ -38,10 +74,148 @@ type Config struct {
 	HTTP3Config *http3.Config `mapstructure:"http3"`
 	// Uploads configures uploads configuration.
 	Uploads *Uploads `mapstructure:"uploads"`
+	// ResponseBuffer configures the buffered response writer.
+	ResponseBuffer *ResponseBuffer `mapstructure:"response_buffer"`
+	// Backpressure configures the rejection of the requests when the pool is overloaded.
+	Backpressure *Backpressure `mapstructure:"backpressure"`
+	// TrustedSubnets declare IP subnets which are allowed to set the proxy-related headers, defaults to loopback and
+	// private networks. The trusted peers also set the priority, the tenant, the chaos and the request start headers
+	// and use the forward proxy, the self-test and the queue state endpoints: with the defaults every host of the
+	// private network (e.g. every pod of the cluster) is trusted, list the subnets of the proxies only if the private
+	// network is shared.
+	TrustedSubnets []string `mapstructure:"trusted_subnets"`
+	// Priority configures the header-based prioritization of the requests.
+	Priority *Priority `mapstructure:"priority"`
+	// AdaptiveConcurrency adjusts the number of the requests dispatched to the pool at once by the latency.
+	AdaptiveConcurrency *AdaptiveConcurrency `mapstructure:"adaptive_concurrency"`
+	// Queue configures the max time the request may wait for a free worker.
+	Queue *Queue `mapstructure:"queue"`
+	// RequestID configures the request ID propagation.
+	RequestID *RequestID `mapstructure:"request_id"`
+	// Codec of the request context sent to the workers: proto or msgpack, defaults to proto.
+	Codec PayloadCodec `mapstructure:"codec"`
+	// PayloadCompression configures the compression of the payload bodies between RR and the workers.
+	PayloadCompression *PayloadCompression `mapstructure:"payload_compression"`
+	// RequestDecompression decodes the compressed request bodies with the decompression bomb protections.
+	RequestDecompression *RequestDecompression `mapstructure:"request_decompression"`
+	// Liveness configures the periodic ping of the idle workers.
+	Liveness *Liveness `mapstructure:"liveness"`
+	// RequestTimeoutResponse responds with 408 when the request headers are not received in time (plain HTTP
+	// listener only), the connection is closed silently otherwise.
+	RequestTimeoutResponse bool `mapstructure:"request_timeout_response"`
+	// Preload translates the `Link: rel=preload` response headers into the HTTP/2 push or 103 Early Hints.
+	Preload *Preload `mapstructure:"preload"`
+	// MaxKeepAliveRequests is the max number of the requests served over a single HTTP/1.1 connection, 0 - unlimited.
+	MaxKeepAliveRequests uint64 `mapstructure:"max_keepalive_requests"`
+	// ConnMetadata passes the negotiated TLS version, cipher suite, ALPN protocol, the TLS session resumption and the
+	// connection reuse to the workers as the request attributes.
+	ConnMetadata bool `mapstructure:"conn_metadata"`
+	// ConnectionInfo sends the connection info with the request context: the local and remote addresses, the listener
+	// name, the HTTP version and the TLS details, see the connection field in protofiles/http.proto.
+	ConnectionInfo bool `mapstructure:"connection_info"`
+	// Static configures the static files serving.
+	Static *Static `mapstructure:"static"`
+	// ConditionalResponses converts the successful GET and HEAD worker responses to 304 Not Modified when their ETag
+	// or Last-Modified match the If-None-Match or If-Modified-Since request headers, the body is not sent.
+	ConditionalResponses bool `mapstructure:"conditional_responses"`
+	// ServerTiming adds the Server-Timing header with the middleware, queue and worker execution times to the worker
+	// responses.
+	ServerTiming bool `mapstructure:"server_timing"`
+	// SignedURLs protects the paths with the HMAC-signed time-limited URLs.
+	SignedURLs *SignedURLs `mapstructure:"signed_urls"`
+	// XSendfile sends the files from the X-Sendfile header of the worker responses, nil - the header is passed as is.
+	XSendfile *XSendfile `mapstructure:"x_sendfile"`
+	// Capture records the matching worker requests to the disk to be replayed later.
+	Capture *Capture `mapstructure:"capture"`
+	// ResponseCache configures the shared cache of the worker responses.
+	ResponseCache *ResponseCache `mapstructure:"response_cache"`
+	// CacheControl configures the cache headers of the static and, optionally, worker responses per path.
+	CacheControl *CacheControl `mapstructure:"cache_control"`
+	// CancelOnDisconnect stops the worker when the client disconnects: stream responses are stopped, requests
+	// waiting for a free worker are canceled, and the worker is recycled if the exec_ttl is set.
+	CancelOnDisconnect bool `mapstructure:"cancel_on_disconnect"`
+	// DrainTimeout is the max time to wait for the worker to finish the stream stopped by the server (client
+	// disconnect, write error), the rest of the stream is discarded and the worker is killed after it. The workers are
+	// spawned by the plugin to know the worker of the stream, the pools without the relay use the own pipes relay.
+	// 0 - wait for the worker.
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// ErrorStatuses maps the pool errors to the HTTP statuses.
+	ErrorStatuses *ErrorStatuses `mapstructure:"error_statuses"`
+	// ErrorPages configures the body of the internal error responses.
+	ErrorPages *ErrorPages `mapstructure:"error_pages"`
+	// DefaultCharset is appended to the text response Content-Type without the charset parameter, e.g. ISO-8859-1.
+	DefaultCharset string `mapstructure:"default_charset"`
+	// RawBodyRoutes is the list of the path prefixes to pass the urlencoded and multipart bodies to the worker as is.
+	RawBodyRoutes []string `mapstructure:"raw_body_routes"`
+	// QueryLimits limits the query string.
+	QueryLimits *QueryLimits `mapstructure:"query_limits"`
+	// FormLimits limits the parsed forms.
+	FormLimits *FormLimits `mapstructure:"form_limits"`
+	// StrictValidation rejects requests with invalid UTF-8 or control characters in the URI, headers or multipart
+	// field names.
+	StrictValidation bool `mapstructure:"strict_validation"`
+	// DuplicateHeaders configures the handling of the repeated request headers.
+	DuplicateHeaders *DuplicateHeaders `mapstructure:"duplicate_headers"`
+	// Cookies configures the request cookies forwarding.
+	Cookies *Cookies `mapstructure:"cookies"`
+	// ProxyScheme takes the URI scheme from the Forwarded or X-Forwarded-Proto headers sent by the trusted_subnets.
+	ProxyScheme bool `mapstructure:"proxy_scheme"`
+	// Forwarded configures the forwarding headers passed to the worker.
+	Forwarded *Forwarded `mapstructure:"forwarded"`
+	// AllowedHosts is the list of the allowed Host header values, exact or wildcard (*.example.com). Empty - all hosts.
+	AllowedHosts []string `mapstructure:"allowed_hosts"`
+	// Affinity pins the workers processes to CPU sets.
+	Affinity *Affinity `mapstructure:"cpu_affinity"`
+	// LoadShedding rejects a fraction of the low-priority requests under the host resources pressure.
+	LoadShedding *LoadShedding `mapstructure:"load_shedding"`
+	// Tenants configures the per-tenant limit profiles.
+	Tenants *Tenants `mapstructure:"tenants"`
+	// Deadline passes the request time budget to the workers.
+	Deadline *Deadline `mapstructure:"deadline"`
+	// Idempotency stores the responses of the requests with the Idempotency-Key header for the retries.
+	Idempotency *Idempotency `mapstructure:"idempotency"`
+	// Streams limits the number of the stream responses sent at once.
+	Streams *Streams `mapstructure:"streams"`
+	// LargeBody configures the by-reference transfer of large request bodies.
+	LargeBody *LargeBody `mapstructure:"large_body"`
+	// SelfTest configures the internal endpoint which checks the request path to the workers.
+	SelfTest *SelfTest `mapstructure:"self_test"`
+	// ReadinessProbe configures the request the workers must answer before receiving the traffic.
+	ReadinessProbe *ReadinessProbe `mapstructure:"readiness_probe"`
+	// QueueState configures the queue state endpoint for the autoscalers.
+	QueueState *QueueState `mapstructure:"queue_state"`
+	// Inspector keeps the last requests in memory and lists them on the internal endpoint (development).
+	Inspector *Inspector `mapstructure:"inspector"`
+	// Alerts posts the webhook alerts on the error rate spikes, disabled by default.
+	Alerts *Alerts `mapstructure:"alerts"`
+	// Chaos injects the latency, errors and dropped connections to the matching requests, disabled by default.
+	Chaos *Chaos `mapstructure:"chaos"`
+	// HeaderEnv maps the request headers to the worker attributes, e.g. X-Tenant: TENANT. Only the listed headers are
+	// passed, the attribute is not set when the header is missing.
+	HeaderEnv map[string]string `mapstructure:"header_env"`
+	// JSONSchema validates the JSON request bodies against the per-route schemas, disabled by default.
+	JSONSchema *JSONSchema `mapstructure:"json_schema"`
+	// Attributes controls which middleware attributes are sent to the workers, all by default.
+	Attributes *Attributes `mapstructure:"attributes"`
+	// Watch resets the workers when the source files change (development).
+	Watch *Watch `mapstructure:"watch"`
+	// ForwardProxy proxies the absolute-form requests of the trusted_subnets to the allowed origins.
+	ForwardProxy *ForwardProxy `mapstructure:"forward_proxy"`
+	// RootSpan samples the requests without the incoming trace context, the otel plugin sampler by default.
+	RootSpan *RootSpan `mapstructure:"root_span"`
+	// PanicReports writes the reports of the recovered panics to the dedicated sink, disabled by default.
+	PanicReports *PanicReports `mapstructure:"panic_reports"`
+	// LongRequests sends the keepalive signals to the clients of the long-running requests, disabled by default.
+	LongRequests *LongRequests `mapstructure:"long_requests"`
+	// RequestStart sets the header with the request start time for the APM agents, disabled by default.
+	RequestStart *RequestStart `mapstructure:"request_start"`
+	// ShutdownDrain flips the drain status to 503 at the start of the shutdown, disabled by default.
+	ShutdownDrain *ShutdownDrain `mapstructure:"shutdown_drain"`
 
 	// private
-	UID int
-	GID int
+	UID         int
+	GID         int
+	TrustedNets []*net.IPNet `mapstructure:"-"`
 }
 
 // EnableHTTP is true when http server must run.
@@ -73,6 +247,16 @@ func (c *Config) EnableFCGI() bool {
 	return c.FCGIConfig.Address != ""
 }
 
+// NumWorkers returns the effective number of the pool workers, the pool starts runtime.NumCPU() workers when
+// num_workers is not set.
+func (c *Config) NumWorkers() uint64 {
+	if c.Pool != nil && c.Pool.NumWorkers > 0 {
+		return c.Pool.NumWorkers
+	}
+
+	return uint64(runtime.NumCPU())
+}
+
 // InitDefaults must populate HTTP values using given HTTP source. Must return error if HTTP is not valid.
 func (c *Config) InitDefaults() error {
 	if c.Pool == nil {
@@ -87,6 +271,28 @@ func (c *Config) InitDefaults() error {
 		}
 	}
 
+	if c.Supervisor != nil {
+		err := c.Supervisor.Valid()
+		if err != nil {
+			return err
+		}
+
+		c.Supervisor.apply(c.Pool)
+	}
+
+	if c.Debug != nil {
+		c.Debug.apply(c.Pool)
+	}
+
+	if c.Relay.Empty() {
+		c.Relay = nil
+	}
+
+	// the worker stderr is read and the respawned workers are probed by the plugin, the server relay can't be used
+	if c.Relay == nil && c.ownWorkers() {
+		c.Relay = &PoolRelay{Relay: RelayPipes}
+	}
+
 	if c.InternalErrorCode == 0 {
 		c.InternalErrorCode = 500
 	}
@@ -96,6 +302,10 @@ func (c *Config) InitDefaults() error {
 		c.MaxRequestSize = 1000
 	}
 
+	if c.Codec == "" {
+		c.Codec = CodecProto
+	}
+
 	if c.HTTP2Config != nil {
 		err := c.HTTP2Config.InitDefaults()
 		if err != nil {
@@ -110,6 +320,19 @@ func (c *Config) InitDefaults() error {
 		}
 	}
 
+	for _, proto := range c.ListenerProtocols {
+		if proto != nil {
+			err := proto.InitDefaults()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.HTTP3Config != nil {
+		c.HTTP3Config.InitDefaults()
+	}
+
 	if c.Uploads == nil {
 		c.Uploads = &Uploads{}
 	}
@@ -119,34 +342,771 @@ func (c *Config) InitDefaults() error {
 		return err
 	}
 
-	return c.Valid()
-}
+	if c.ResponseBuffer != nil {
+		err = c.ResponseBuffer.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
 
-// Valid validates the configuration.
-func (c *Config) Valid() error {
-	const op = errors.Op("validation")
-	if c.Uploads == nil {
-		return errors.E(op, errors.Str("malformed uploads config"))
+	if c.Backpressure != nil {
+		err = c.Backpressure.InitDefaults()
+		if err != nil {
+			return err
+		}
 	}
 
-	if c.Pool == nil {
-		return errors.E(op, "malformed pool config")
+	if len(c.TrustedSubnets) == 0 {
+		c.TrustedSubnets = defaultTrustedSubnets()
 	}
 
-	if !c.EnableHTTP() && !c.EnableTLS() && !c.EnableFCGI() {
-		return errors.E(op, errors.Str("unable to run http service, no method has been specified (http, https, http/2 or FastCGI)"))
+	if len(c.TracePropagators) == 0 {
+		c.TracePropagators = defaultPropagators()
 	}
 
-	if c.Address != "" && !strings.Contains(c.Address, ":") {
-		return errors.E(op, errors.Str("malformed http server address"))
+	for i := 0; i < len(c.TracePropagators); i++ {
+		c.TracePropagators[i] = Propagator(strings.ToLower(string(c.TracePropagators[i])))
 	}
 
-	if c.EnableTLS() {
-		err := c.SSLConfig.Valid()
+	c.TrustedNets, err = parseSubnets(c.TrustedSubnets)
+	if err != nil {
+		return err
+	}
+
+	if c.Priority != nil {
+		err = c.Priority.InitDefaults(c.NumWorkers())
 		if err != nil {
-			return errors.E(op, err)
+			return err
 		}
 	}
 
-	return nil
+	if c.AdaptiveConcurrency != nil {
+		err = c.AdaptiveConcurrency.InitDefaults(c.NumWorkers())
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Queue != nil {
+		err = c.Queue.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.RequestID != nil {
+		err = c.RequestID.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.PayloadCompression != nil {
+		err = c.PayloadCompression.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.RequestDecompression != nil {
+		err = c.RequestDecompression.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.OtelMetrics && c.OtelMetricsExporter == nil {
+		c.OtelMetricsExporter = &OtelMetricsExporter{}
+	}
+
+	if c.OtelMetricsExporter != nil {
+		err = c.OtelMetricsExporter.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Liveness != nil {
+		err = c.Liveness.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Static != nil {
+		err = c.Static.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.SignedURLs != nil {
+		err = c.SignedURLs.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.XSendfile != nil {
+		err = c.XSendfile.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Capture != nil {
+		err = c.Capture.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.ResponseCache != nil {
+		err = c.ResponseCache.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.CacheControl != nil {
+		err = c.CacheControl.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Preload != nil {
+		err = c.Preload.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.ErrorStatuses != nil {
+		err = c.ErrorStatuses.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.ErrorPages != nil {
+		err = c.ErrorPages.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.QueryLimits != nil {
+		err = c.QueryLimits.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.FormLimits != nil {
+		err = c.FormLimits.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.DuplicateHeaders != nil {
+		err = c.DuplicateHeaders.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Cookies != nil {
+		err = c.Cookies.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Forwarded != nil {
+		err = c.Forwarded.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Affinity != nil {
+		err = c.Affinity.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.LoadShedding != nil {
+		err = c.LoadShedding.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Tenants != nil {
+		err = c.Tenants.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Deadline != nil {
+		err = c.Deadline.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Idempotency != nil {
+		err = c.Idempotency.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < len(c.RoutePools); i++ {
+		err = c.RoutePools[i].InitDefaults()
+		if err != nil {
+			return err
+		}
+
+		if c.RoutePools[i].Relay == "" && c.ownWorkers() {
+			c.RoutePools[i].Relay = RelayPipes
+		}
+	}
+
+	if c.Streams != nil {
+		err = c.Streams.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.LargeBody != nil {
+		err = c.LargeBody.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.SelfTest != nil {
+		err = c.SelfTest.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.ReadinessProbe != nil {
+		err = c.ReadinessProbe.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.QueueState != nil {
+		err = c.QueueState.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Inspector != nil {
+		err = c.Inspector.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.JSONSchema != nil {
+		err = c.JSONSchema.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.RootSpan != nil {
+		err = c.RootSpan.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.LongRequests != nil {
+		err = c.LongRequests.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.RequestStart != nil {
+		err = c.RequestStart.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.ShutdownDrain != nil {
+		err = c.ShutdownDrain.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Alerts != nil {
+		err = c.Alerts.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Chaos != nil {
+		err = c.Chaos.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.ForwardProxy != nil {
+		err = c.ForwardProxy.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Watch != nil {
+		err = c.Watch.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.Valid()
+}
+
+// Valid validates the configuration.
+func (c *Config) Valid() error {
+	const op = errors.Op("validation")
+	if c.Uploads == nil {
+		return errors.E(op, errors.Str("malformed uploads config"))
+	}
+
+	if c.Pool == nil {
+		return errors.E(op, "malformed pool config")
+	}
+
+	if c.Relay != nil {
+		err := c.Relay.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+
+	}
+
+	names := make(map[string]struct{}, len(c.RoutePools))
+	for i := 0; i < len(c.RoutePools); i++ {
+		err := c.RoutePools[i].Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+
+		if _, ok := names[c.RoutePools[i].Name]; ok {
+			return errors.E(op, errors.Errorf("duplicate route pool name: %s", c.RoutePools[i].Name))
+		}
+		names[c.RoutePools[i].Name] = struct{}{}
+	}
+
+	if !c.Embedded && !c.EnableHTTP() && !c.EnableTLS() && !c.EnableFCGI() {
+		return errors.E(op, errors.Str("unable to run http service, no method has been specified (http, https, http/2 or FastCGI)"))
+	}
+
+	err := validPropagators(c.TracePropagators)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	for name := range c.ListenerMiddleware {
+		switch name {
+		case ListenerHTTP, ListenerHTTPS, ListenerFCGI, ListenerHTTP3:
+		default:
+			return errors.E(op, errors.Errorf("unknown listener %q in the listener_middleware, should be http, https, fcgi or http3", name))
+		}
+	}
+
+	for name, proto := range c.ListenerProtocols {
+		if proto == nil {
+			continue
+		}
+
+		var err error
+		switch name {
+		case ListenerHTTP:
+			err = proto.Valid(https.H2C)
+			if err == nil && proto.Enabled(https.H2C) && len(proto.Versions) > 0 && !c.HTTP2Config.EnableHTTP2() {
+				err = errors.Str("the h2c version requires the http2.h2c option")
+			}
+		case ListenerHTTPS:
+			err = proto.Valid(https.H2)
+		default:
+			err = errors.Errorf("unknown listener %q in the listener_protocols, should be http or https", name)
+		}
+
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	switch c.Codec {
+	case "", CodecProto, CodecMsgpack:
+	default:
+		return errors.E(op, errors.Errorf("unknown codec %q, should be proto or msgpack", c.Codec))
+	}
+
+	if c.Address != "" && !strings.Contains(c.Address, ":") {
+		return errors.E(op, errors.Str("malformed http server address"))
+	}
+
+	if c.Priority != nil {
+		err := c.Priority.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.AdaptiveConcurrency != nil {
+		err := c.AdaptiveConcurrency.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Queue != nil {
+		err := c.Queue.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.PayloadCompression != nil {
+		err := c.PayloadCompression.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.RequestDecompression != nil {
+		err := c.RequestDecompression.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Static != nil {
+		err := c.Static.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.SignedURLs != nil {
+		err := c.SignedURLs.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.XSendfile != nil {
+		err := c.XSendfile.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Capture != nil {
+		err := c.Capture.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.ResponseCache != nil {
+		err := c.ResponseCache.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.CacheControl != nil {
+		err := c.CacheControl.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Preload != nil {
+		err := c.Preload.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.ErrorStatuses != nil {
+		err := c.ErrorStatuses.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.ErrorPages != nil {
+		err := c.ErrorPages.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.QueryLimits != nil {
+		err := c.QueryLimits.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.FormLimits != nil {
+		err := c.FormLimits.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.DuplicateHeaders != nil {
+		err := c.DuplicateHeaders.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Cookies != nil {
+		err := c.Cookies.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Forwarded != nil {
+		err := c.Forwarded.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Affinity != nil {
+		err := c.Affinity.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.LoadShedding != nil {
+		err := c.LoadShedding.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Tenants != nil {
+		err := c.Tenants.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Deadline != nil {
+		err := c.Deadline.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Idempotency != nil {
+		err := c.Idempotency.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Streams != nil {
+		err := c.Streams.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.SelfTest != nil {
+		err := c.SelfTest.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.ReadinessProbe != nil {
+		err := c.ReadinessProbe.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.OtelMetricsExporter != nil {
+		err := c.OtelMetricsExporter.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Liveness != nil {
+		err := c.Liveness.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+
+		// the pool can't stop the hung worker without the exec_ttl, the ping would wait for it forever
+		if c.Pool.Supervisor == nil || c.Pool.Supervisor.ExecTTL == 0 {
+			return errors.E(op, errors.Str("liveness requires the pool exec_ttl, the hung worker is killed by it"))
+		}
+	}
+
+	if c.QueueState != nil {
+		err := c.QueueState.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Inspector != nil {
+		err := c.Inspector.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Alerts != nil {
+		err := c.Alerts.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Chaos != nil {
+		err := c.Chaos.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.ForwardProxy != nil {
+		err := c.ForwardProxy.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if len(c.HeaderEnv) > 0 {
+		err := validHeaderEnv(c.HeaderEnv)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.JSONSchema != nil {
+		err := c.JSONSchema.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.RootSpan != nil {
+		err := c.RootSpan.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.PanicReports != nil {
+		err := c.PanicReports.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.LongRequests != nil {
+		err := c.LongRequests.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.RequestStart != nil {
+		err := c.RequestStart.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.ShutdownDrain != nil {
+		err := c.ShutdownDrain.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Attributes != nil {
+		err := c.Attributes.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Watch != nil {
+		err := c.Watch.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.Backpressure != nil {
+		err := c.Backpressure.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.EnableTLS() {
+		err := c.SSLConfig.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.EnableHTTP3() {
+		err := c.HTTP3Config.Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	return nil
+}
+
+// ownWorkers returns true if the workers are spawned by the plugin with the own relay: the worker stderr is read, the
+// workers are pinned to the CPU sets, the respawned workers are probed before they are returned to the pool, the
+// workers of the stuck streams are killed and the payload compression is negotiated with the workers.
+func (c *Config) ownWorkers() bool {
+	return c.RequestID.Stderr() || c.ReadinessProbe != nil || c.DrainTimeout > 0 || c.Affinity != nil ||
+		c.PayloadCompression != nil
+}
+
+// ExecWindows returns true if the requests are correlated with the workers executing them: the worker stderr is
+// captured or the workers of the streams are killed after the drain timeout.
+func (c *Config) ExecWindows() bool {
+	return c.RequestID.Stderr() || c.DrainTimeout > 0
 }
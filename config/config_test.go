@@ -0,0 +1,339 @@
+package config
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/servers/https"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisorApply(t *testing.T) {
+	cfg := &Config{
+		Address: ":8080",
+		Pool: &pool.Config{
+			NumWorkers: 1,
+			Supervisor: &pool.SupervisorConfig{
+				TTL: time.Second,
+			},
+		},
+		Supervisor: &Supervisor{
+			TTL:             time.Minute,
+			ExecTTL:         time.Second * 10,
+			MaxWorkerMemory: 128,
+		},
+	}
+
+	require.NoError(t, cfg.InitDefaults())
+	// the nested supervisor block wins
+	assert.Equal(t, time.Second, cfg.Pool.Supervisor.TTL)
+	assert.Equal(t, time.Second*10, cfg.Pool.Supervisor.ExecTTL)
+	assert.Equal(t, uint64(128), cfg.Pool.Supervisor.MaxWorkerMemory)
+	assert.Equal(t, time.Second*5, cfg.Pool.Supervisor.WatchTick)
+}
+
+func TestSupervisorEmpty(t *testing.T) {
+	cfg := &Config{
+		Address:    ":8080",
+		Pool:       &pool.Config{NumWorkers: 1},
+		Supervisor: &Supervisor{},
+	}
+
+	require.NoError(t, cfg.InitDefaults())
+	assert.Nil(t, cfg.Pool.Supervisor)
+
+	cfg.Supervisor = &Supervisor{IdleTTL: -time.Second}
+	assert.Error(t, cfg.InitDefaults())
+}
+
+func TestDebugOnDemandWorkers(t *testing.T) {
+	cfg := &Config{
+		Address: ":8080",
+		Pool:    &pool.Config{Debug: true, MaxJobs: 1},
+		Debug:   &Debug{Workers: 2},
+	}
+
+	require.NoError(t, cfg.InitDefaults())
+	assert.True(t, cfg.Debug.Active)
+	assert.False(t, cfg.Pool.Debug)
+	assert.Equal(t, uint64(2), cfg.Pool.NumWorkers)
+	assert.Equal(t, uint64(0), cfg.Pool.MaxJobs)
+	// logged by the plugin
+	assert.Equal(t, uint64(1), cfg.Debug.MaxJobs)
+	assert.Equal(t, time.Second*10, cfg.Pool.Supervisor.TTL)
+}
+
+func TestDebugKeepsSupervisorTTL(t *testing.T) {
+	cfg := &Config{
+		Address: ":8080",
+		Pool: &pool.Config{Debug: true, Supervisor: &pool.SupervisorConfig{
+			TTL:     time.Minute,
+			IdleTTL: time.Second * 30,
+		}},
+		Debug: &Debug{Workers: 2},
+	}
+
+	require.NoError(t, cfg.InitDefaults())
+	assert.Equal(t, time.Minute, cfg.Pool.Supervisor.TTL)
+	assert.Equal(t, time.Second*30, cfg.Pool.Supervisor.IdleTTL)
+	assert.Equal(t, time.Second, cfg.Pool.Supervisor.WatchTick)
+}
+
+func TestAffinitySets(t *testing.T) {
+	a := &Affinity{CPUs: []string{"0-2,5"}}
+	require.NoError(t, a.InitDefaults())
+	assert.Equal(t, [][]int{{0}, {1}, {2}, {5}}, a.Sets)
+
+	a = &Affinity{Mode: AffinityMasks, CPUs: []string{"0-1", "2,3"}}
+	require.NoError(t, a.InitDefaults())
+	assert.Equal(t, [][]int{{0, 1}, {2, 3}}, a.Sets)
+
+	a = &Affinity{CPUs: []string{"3-1"}}
+	assert.Error(t, a.InitDefaults())
+}
+
+func TestAccessPatterns(t *testing.T) {
+	cfg := &Static{
+		Dir:            ".",
+		ForbidPatterns: []string{"**/.git/**", "re:(^|/)secret"},
+		AllowPatterns:  []string{"media/**.pdf", "*.css"},
+		Allow:          []string{".PNG"},
+	}
+
+	require.NoError(t, cfg.InitDefaults())
+	access := cfg.Access()
+
+	tests := map[string]bool{
+		"/media/docs/a.pdf":  true,
+		"/media/a.pdf":       true,
+		"/a.pdf":             false,
+		"/css/app.css":       true,
+		"/img/logo.png":      true,
+		"/.git/config":       false,
+		"/vendor/.git/HEAD":  false,
+		"/media/secret.pdf":  false,
+		"/index.php":         false,
+		"/media/index.php":   false,
+		"/media/docs/a.pdfx": false,
+	}
+
+	for rel, allowed := range tests {
+		assert.Equal(t, allowed, access.Allow(rel), rel)
+	}
+
+	_, err := CompilePatterns([]string{"re:("})
+	assert.Error(t, err)
+}
+
+func TestPoolRelay(t *testing.T) {
+	tests := map[string]bool{
+		"pipes":                true,
+		"unix:///tmp/rr.sock":  true,
+		"tcp://10.0.0.1:6001":  true,
+		"tcp://:6001":          true,
+		"tcp://10.0.0.1":       false,
+		"unix://":              false,
+		"udp://127.0.0.1:6001": false,
+		"/tmp/rr.sock":         false,
+	}
+
+	for relay, valid := range tests {
+		err := (&PoolRelay{Relay: relay}).Valid()
+		assert.Equal(t, valid, err == nil, relay)
+	}
+
+	network, address := (&PoolRelay{Relay: "tcp://10.0.0.1:6001"}).Network()
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "10.0.0.1:6001", address)
+
+	// the server plugin prepares the workers command
+	cfg := &Config{Pool: &pool.Config{}, Uploads: &Uploads{}, Address: ":8080", Relay: &PoolRelay{Relay: "pipes"}}
+	assert.NoError(t, cfg.Valid())
+}
+
+func TestRoutePools(t *testing.T) {
+	cfg := &Config{Address: ":8080", RoutePools: []*RoutePool{{Name: "reports", Prefixes: []string{"/reports/"}, Relay: "tcp://10.0.0.1:6001"}}}
+	require.NoError(t, cfg.InitDefaults())
+	assert.Equal(t, uint64(runtime.NumCPU()), cfg.RoutePools[0].Pool.NumWorkers)
+
+	cfg.RoutePools = append(cfg.RoutePools, &RoutePool{Name: "reports", Prefixes: []string{"/other/"}})
+	assert.Error(t, cfg.Valid())
+
+	for _, rp := range []*RoutePool{
+		{Prefixes: []string{"/a/"}},
+		{Name: "a"},
+		{Name: "a", Prefixes: []string{"a/"}},
+		{Name: "a", Prefixes: []string{"/a/"}, Relay: "udp://:6001"},
+	} {
+		assert.Error(t, rp.Valid())
+	}
+}
+
+func TestWorkerStderrRelay(t *testing.T) {
+	cfg := &Config{
+		Address:    ":8080",
+		RequestID:  &RequestID{WorkerStderr: true},
+		RoutePools: []*RoutePool{{Name: "local", Prefixes: []string{"/local/"}}, {Name: "remote", Prefixes: []string{"/remote/"}, Relay: "tcp://10.0.0.1:6001"}},
+	}
+	require.NoError(t, cfg.InitDefaults())
+
+	// the stderr is read by the plugin, the server relay is replaced with the pipes
+	assert.Equal(t, RelayPipes, cfg.Relay.Relay)
+	assert.Equal(t, RelayPipes, cfg.RoutePools[0].Relay)
+	assert.Equal(t, "tcp://10.0.0.1:6001", cfg.RoutePools[1].Relay)
+
+	// the respawned workers are probed by the plugin
+	cfg = &Config{Address: ":8080", ReadinessProbe: &ReadinessProbe{Path: "/ready"}}
+	require.NoError(t, cfg.InitDefaults())
+	assert.Equal(t, RelayPipes, cfg.Relay.Relay)
+
+	// the spawned workers are pinned by the plugin
+	cfg = &Config{Address: ":8080", Affinity: &Affinity{}}
+	require.NoError(t, cfg.InitDefaults())
+	assert.Equal(t, RelayPipes, cfg.Relay.Relay)
+
+	// the payload compression is negotiated by the plugin
+	cfg = &Config{Address: ":8080", PayloadCompression: &PayloadCompression{}}
+	require.NoError(t, cfg.InitDefaults())
+	assert.Equal(t, RelayPipes, cfg.Relay.Relay)
+}
+
+func TestOtelMetricsExporter(t *testing.T) {
+	cfg := &Config{Address: ":8080", OtelMetrics: true}
+	require.NoError(t, cfg.InitDefaults())
+	assert.Equal(t, 10*time.Second, cfg.OtelMetricsExporter.Interval)
+	assert.Equal(t, "roadrunner", cfg.OtelMetricsExporter.ServiceName)
+
+	cfg.OtelMetricsExporter.Interval = -time.Second
+	assert.Error(t, cfg.Valid())
+}
+
+func TestAttributesPolicy(t *testing.T) {
+	assert.NoError(t, (&Attributes{Allow: []string{"tls_*", "user_id"}}).Valid())
+	assert.Error(t, (&Attributes{}).Valid())
+	assert.Error(t, (&Attributes{Deny: []string{"*_token"}}).Valid())
+	assert.Error(t, (&Attributes{Deny: []string{""}}).Valid())
+}
+
+func TestListenerMiddleware(t *testing.T) {
+	cfg := &Config{Address: ":8080", ListenerMiddleware: map[string][]string{ListenerHTTPS: {"gzip"}, ListenerFCGI: {}}}
+	require.NoError(t, cfg.InitDefaults())
+
+	cfg.ListenerMiddleware["grpc"] = []string{"gzip"}
+	assert.Error(t, cfg.Valid())
+}
+
+func TestListenerProtocols(t *testing.T) {
+	cfg := &Config{Address: ":8080", ListenerProtocols: map[string]*https.Protocols{
+		ListenerHTTP: {Versions: []string{https.HTTP1}},
+	}}
+	require.NoError(t, cfg.InitDefaults())
+	require.NoError(t, cfg.Valid())
+
+	cfg.ListenerProtocols[ListenerHTTP] = &https.Protocols{Versions: []string{https.HTTP1, https.H2C}}
+	assert.Error(t, cfg.Valid())
+
+	cfg.HTTP2Config = &https.HTTP2{H2C: true}
+	assert.NoError(t, cfg.Valid())
+
+	cfg.ListenerProtocols[ListenerHTTP] = &https.Protocols{Versions: []string{https.H2}}
+	assert.Error(t, cfg.Valid())
+
+	cfg.ListenerProtocols[ListenerHTTP] = &https.Protocols{Versions: []string{https.HTTP1}, H2Clients: []string{"10.0.0.0/8"}}
+	assert.Error(t, cfg.Valid())
+
+	cfg.ListenerProtocols = map[string]*https.Protocols{ListenerHTTP3: {Versions: []string{https.HTTP1}}}
+	assert.Error(t, cfg.Valid())
+
+	cfg.ListenerProtocols = map[string]*https.Protocols{ListenerHTTPS: {H2Clients: []string{"10.0.0.0"}}}
+	assert.Error(t, cfg.InitDefaults())
+}
+
+func TestRootSpan(t *testing.T) {
+	rs := &RootSpan{}
+	require.NoError(t, rs.InitDefaults())
+	assert.Equal(t, SamplerParentBased, rs.Sampler)
+	assert.NoError(t, rs.Valid())
+
+	assert.NoError(t, (&RootSpan{Sampler: SamplerRatio, Ratio: 0.1}).Valid())
+	assert.Error(t, (&RootSpan{Sampler: SamplerRatio, Ratio: 1.5}).Valid())
+	assert.Error(t, (&RootSpan{Sampler: SamplerRateLimited}).Valid())
+	assert.Error(t, (&RootSpan{Sampler: "traceidratio"}).Valid())
+}
+
+func TestPanicReports(t *testing.T) {
+	assert.Error(t, (&PanicReports{}).Valid())
+	assert.NoError(t, (&PanicReports{Path: PanicReportsStderr}).Valid())
+}
+
+func TestRequestStart(t *testing.T) {
+	rs := &RequestStart{Header: "x-queue-start"}
+	require.NoError(t, rs.InitDefaults())
+	assert.Equal(t, "X-Queue-Start", rs.Header)
+	assert.Equal(t, UnitMicroseconds, rs.Unit)
+	assert.NoError(t, rs.Valid())
+	assert.Error(t, (&RequestStart{Header: "X-Request-Start", Unit: "nanoseconds"}).Valid())
+}
+
+func TestShutdownDrain(t *testing.T) {
+	sd := &ShutdownDrain{}
+	require.NoError(t, sd.InitDefaults())
+	assert.Equal(t, "/drain-status", sd.Path)
+	assert.Equal(t, 5*time.Second, sd.Delay)
+	assert.NoError(t, sd.Valid())
+	assert.Error(t, (&ShutdownDrain{Path: "drain", Delay: time.Second}).Valid())
+	assert.Error(t, (&ShutdownDrain{Path: "/drain", Delay: -time.Second}).Valid())
+}
+
+func TestInspectorAddress(t *testing.T) {
+	i := &Inspector{}
+	require.NoError(t, i.InitDefaults())
+	assert.Error(t, i.Valid())
+
+	i.Address = "127.0.0.1:2115"
+	assert.NoError(t, i.Valid())
+}
+
+func TestLivenessExecTTL(t *testing.T) {
+	cfg := &Config{Address: ":8080", Pool: &pool.Config{NumWorkers: 1}, Liveness: &Liveness{}}
+	assert.Error(t, cfg.InitDefaults())
+
+	cfg = &Config{Address: ":8080", Pool: &pool.Config{NumWorkers: 1}, Liveness: &Liveness{}, Supervisor: &Supervisor{ExecTTL: time.Minute}}
+	require.NoError(t, cfg.InitDefaults())
+	assert.Equal(t, "/.rr/liveness", cfg.Liveness.Path)
+}
+
+func TestPriorityDefaultWorkers(t *testing.T) {
+	cfg := &Config{
+		Address:  ":8080",
+		Pool:     &pool.Config{},
+		Priority: &Priority{},
+	}
+
+	require.NoError(t, cfg.InitDefaults())
+	assert.Equal(t, int64(runtime.NumCPU()), cfg.Priority.MaxConcurrency)
+}
+
+func TestStreamsBufferSize(t *testing.T) {
+	s := &Streams{Mode: StreamsBuffer}
+	require.NoError(t, s.InitDefaults())
+	require.NoError(t, s.Valid())
+	assert.Equal(t, int64(1024*1024), s.MaxBufferSize)
+
+	s.MaxBufferSize = -1
+	assert.Error(t, s.Valid())
+}
+
+func TestXSendfile(t *testing.T) {
+	assert.Error(t, (&XSendfile{}).Valid())
+	assert.Error(t, (&XSendfile{Root: filepath.Join(t.TempDir(), "missing")}).Valid())
+
+	x := &XSendfile{Root: t.TempDir() + "/files/.."}
+	require.NoError(t, x.InitDefaults())
+	assert.NotContains(t, x.Root, "..")
+	assert.NoError(t, x.Valid())
+}
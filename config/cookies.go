@@ -0,0 +1,49 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// CookiesMode defines how the request cookies are passed to the worker.
+type CookiesMode string
+
+const (
+	// CookiesParsed parses the cookies into the cookies map, the Cookie header is passed as well.
+	CookiesParsed CookiesMode = "parsed"
+	// CookiesRaw passes the cookies only in the raw Cookie header.
+	CookiesRaw CookiesMode = "raw"
+)
+
+// Cookies configures the request cookies forwarding. Requests with the cookies above the limits are rejected with 431.
+type Cookies struct {
+	// Mode is parsed or raw, defaults to parsed.
+	Mode CookiesMode `mapstructure:"mode"`
+	// MaxSize is the max total size of the Cookie headers in bytes, 0 - unlimited.
+	MaxSize int `mapstructure:"max_size"`
+	// MaxCount is the max number of the cookies, 0 - unlimited.
+	MaxCount int `mapstructure:"max_count"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (c *Cookies) InitDefaults() error {
+	if c.Mode == "" {
+		c.Mode = CookiesParsed
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (c *Cookies) Valid() error {
+	const op = errors.Op("cookies_validation")
+	if c.MaxSize < 0 || c.MaxCount < 0 {
+		return errors.E(op, errors.Str("cookies limits should not be negative"))
+	}
+
+	switch c.Mode {
+	case CookiesParsed, CookiesRaw:
+		return nil
+	default:
+		return errors.E(op, errors.Errorf("unknown cookies mode: %s", c.Mode))
+	}
+}
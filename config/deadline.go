@@ -0,0 +1,63 @@
+package config
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Deadline configures the request time budget passed to the workers, so they can set their own timeouts (database
+// statements, HTTP clients). The budget is the route or the default timeout, limited by the pool exec_ttl and the
+// request context deadline. The remaining time is sent in the header (milliseconds) and the absolute deadline in the
+// `deadline` attribute (unix milliseconds).
+type Deadline struct {
+	// Timeout is the default request budget, the exec_ttl is used if not set.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Routes are checked in order, the first matching route timeout is used.
+	Routes []*RouteTimeout `mapstructure:"routes"`
+	// Header with the remaining budget in milliseconds, defaults to X-Request-Budget-Ms.
+	Header string `mapstructure:"header"`
+}
+
+// RouteTimeout is the request budget of the matching paths.
+type RouteTimeout struct {
+	// Paths is the list of the path globs (path.Match syntax), the `/**` suffix matches everything under the prefix.
+	Paths []string `mapstructure:"paths"`
+	// Timeout is the request budget.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (d *Deadline) InitDefaults() error {
+	if d.Header == "" {
+		d.Header = "X-Request-Budget-Ms"
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (d *Deadline) Valid() error {
+	const op = errors.Op("deadline_validation")
+	if d.Timeout < 0 {
+		return errors.E(op, errors.Str("timeout should be positive"))
+	}
+
+	for i := 0; i < len(d.Routes); i++ {
+		route := d.Routes[i]
+		if route == nil || len(route.Paths) == 0 || route.Timeout <= 0 {
+			return errors.E(op, errors.Errorf("deadline route %d should have paths and a positive timeout", i))
+		}
+
+		for j := 0; j < len(route.Paths); j++ {
+			_, err := path.Match(strings.TrimSuffix(route.Paths[j], "/**"), "")
+			if err != nil {
+				return errors.E(op, errors.Errorf("deadline route %d, bad path %s: %v", i, route.Paths[j], err))
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/pool/pool"
+)
+
+// Debug configures the pool debug mode (`pool.debug: true`). By default, the debug mode creates a fresh worker for
+// every request. When Workers is set, a small pool of workers with a short TTL is used instead, so the code changes
+// are picked up after the TTL without paying the boot time on every request.
+type Debug struct {
+	// Workers is the number of the on-demand workers, 0 - fresh worker per request.
+	Workers uint64 `mapstructure:"workers"`
+	// TTL of the on-demand workers, defaults to 10s. The pool supervisor ttl and idle_ttl take precedence when set.
+	TTL time.Duration `mapstructure:"ttl"`
+	// Header enables the debug output in the response only when present in the request. When empty, the debug
+	// output is always written. The debug output is the error text of the failed requests, the worker stdout is never
+	// written to the response: with the pipes relay the stdout is the relay itself, with the socket relays it's logged.
+	Header string `mapstructure:"header"`
+
+	// Active is true when the pool runs in the debug mode
+	Active bool `mapstructure:"-"`
+	// MaxJobs is the pool max_jobs ignored by the on-demand workers, logged by the plugin
+	MaxJobs uint64 `mapstructure:"-"`
+}
+
+// apply turns the pool debug mode into the on-demand workers pool.
+func (d *Debug) apply(cfg *pool.Config) {
+	if !cfg.Debug {
+		return
+	}
+
+	d.Active = true
+	if d.Workers == 0 {
+		return
+	}
+
+	if d.TTL == 0 {
+		d.TTL = time.Second * 10
+	}
+
+	cfg.Debug = false
+	cfg.NumWorkers = d.Workers
+	// the workers are replaced by the TTL
+	d.MaxJobs = cfg.MaxJobs
+	cfg.MaxJobs = 0
+
+	if cfg.Supervisor == nil {
+		cfg.Supervisor = &pool.SupervisorConfig{}
+	}
+
+	if cfg.Supervisor.TTL == 0 {
+		cfg.Supervisor.TTL = d.TTL
+	}
+
+	if cfg.Supervisor.IdleTTL == 0 {
+		cfg.Supervisor.IdleTTL = d.TTL
+	}
+
+	if cfg.Supervisor.WatchTick == 0 || cfg.Supervisor.WatchTick > cfg.Supervisor.TTL {
+		cfg.Supervisor.WatchTick = time.Second
+	}
+}
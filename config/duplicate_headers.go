@@ -0,0 +1,51 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// DuplicatePolicy defines how the repeated request headers are treated.
+type DuplicatePolicy string
+
+const (
+	// DuplicateReject rejects the request with 400.
+	DuplicateReject DuplicatePolicy = "reject"
+	// DuplicateFirst keeps only the first header value.
+	DuplicateFirst DuplicatePolicy = "first"
+	// DuplicateJoin joins the values into a single comma separated value.
+	DuplicateJoin DuplicatePolicy = "join"
+)
+
+// DuplicateHeaders configures the handling of the repeated request headers. The policy is applied before the
+// request is processed, so the handler and the worker see the same values. Repeated Host headers are always rejected
+// by the server.
+type DuplicateHeaders struct {
+	// Policy is reject, first or join, defaults to reject.
+	Policy DuplicatePolicy `mapstructure:"policy"`
+	// Headers to apply the policy to, defaults to Content-Length and X-Forwarded-For.
+	Headers []string `mapstructure:"headers"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (d *DuplicateHeaders) InitDefaults() error {
+	if d.Policy == "" {
+		d.Policy = DuplicateReject
+	}
+
+	if len(d.Headers) == 0 {
+		d.Headers = []string{"Content-Length", "X-Forwarded-For"}
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (d *DuplicateHeaders) Valid() error {
+	const op = errors.Op("duplicate_headers_validation")
+	switch d.Policy {
+	case DuplicateReject, DuplicateFirst, DuplicateJoin:
+		return nil
+	default:
+		return errors.E(op, errors.Errorf("unknown duplicate headers policy: %s", d.Policy))
+	}
+}
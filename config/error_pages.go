@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// ErrorFormat defines the format of the internal error responses.
+type ErrorFormat string
+
+const (
+	// ErrorFormatAuto negotiates JSON or HTML by the Accept header.
+	ErrorFormatAuto ErrorFormat = "auto"
+	// ErrorFormatJSON always responds with JSON.
+	ErrorFormatJSON ErrorFormat = "json"
+	// ErrorFormatHTML always responds with HTML.
+	ErrorFormatHTML ErrorFormat = "html"
+)
+
+// ErrorPages configures the body of the internal error responses (worker errors, no free workers, etc.). The body
+// contains the status code, the request ID (if enabled) and the timestamp.
+type ErrorPages struct {
+	// Format is auto, json or html, defaults to auto.
+	Format ErrorFormat `mapstructure:"format"`
+	// Template is the path to the html/template file used for the HTML responses. The template receives the Code,
+	// Status, RequestID, Timestamp and Message fields.
+	Template string `mapstructure:"template"`
+	// Fallback maps the status codes to the pre-rendered HTML pages served when no workers are available (no free
+	// workers, worker allocation failures, full queue), e.g. 503: /var/www/maintenance.html. The pages are loaded on
+	// start and sent to the clients accepting HTML.
+	Fallback map[string]string `mapstructure:"fallback"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (e *ErrorPages) InitDefaults() error {
+	if e.Format == "" {
+		e.Format = ErrorFormatAuto
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (e *ErrorPages) Valid() error {
+	const op = errors.Op("error_pages_validation")
+	switch e.Format {
+	case ErrorFormatAuto, ErrorFormatJSON, ErrorFormatHTML:
+	default:
+		return errors.E(op, errors.Errorf("unknown error pages format: %s", e.Format))
+	}
+
+	if e.Template != "" {
+		if _, err := os.Stat(e.Template); err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	for code, file := range e.Fallback {
+		status, err := strconv.Atoi(code)
+		if err != nil || status < 400 || status > 599 {
+			return errors.E(op, errors.Errorf("bad fallback page status: %s", code))
+		}
+
+		if _, err := os.Stat(file); err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	return nil
+}
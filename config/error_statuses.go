@@ -0,0 +1,54 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// ErrorStatuses maps the pool errors to the HTTP statuses, errors without the status use the internal_error_code.
+type ErrorStatuses struct {
+	// ExecTTL is the status for the requests which exceeded the exec_ttl, defaults to 504.
+	ExecTTL int `mapstructure:"exec_ttl"`
+	// NoFreeWorkers is the status for the requests which did not get a free worker in time, defaults to 503.
+	NoFreeWorkers int `mapstructure:"no_free_workers"`
+	// WorkerAllocate is the status for the requests failed due to the worker allocation error, defaults to 503.
+	WorkerAllocate int `mapstructure:"worker_allocate"`
+	// SoftJob is the status for the errors returned by the worker, defaults to the internal_error_code.
+	SoftJob int `mapstructure:"soft_job"`
+	// RetryAfter is sent in the Retry-After header with the 503 responses, defaults to 1s.
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (e *ErrorStatuses) InitDefaults() error {
+	if e.ExecTTL == 0 {
+		e.ExecTTL = 504
+	}
+
+	if e.NoFreeWorkers == 0 {
+		e.NoFreeWorkers = 503
+	}
+
+	if e.WorkerAllocate == 0 {
+		e.WorkerAllocate = 503
+	}
+
+	if e.RetryAfter <= 0 {
+		e.RetryAfter = time.Second
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (e *ErrorStatuses) Valid() error {
+	const op = errors.Op("error_statuses_validation")
+	for _, status := range [...]int{e.ExecTTL, e.NoFreeWorkers, e.WorkerAllocate, e.SoftJob} {
+		if status != 0 && (status < 400 || status > 599) {
+			return errors.E(op, errors.Errorf("error status should be in range 400-599: %d", status))
+		}
+	}
+
+	return nil
+}
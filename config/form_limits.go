@@ -0,0 +1,39 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// maxFormDepth is the max tree depth of the parsed form fields (handler.MaxLevel).
+const maxFormDepth int = 127
+
+// FormLimits limits the parsed urlencoded and multipart forms. Requests above the limits are rejected with 400.
+type FormLimits struct {
+	// MaxDepth is the max bracket nesting of the field names (`a[b][c]` is 3), defaults to 127.
+	MaxDepth int `mapstructure:"max_depth"`
+	// MaxKeys is the max number of the fields, 0 - unlimited.
+	MaxKeys int `mapstructure:"max_keys"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (f *FormLimits) InitDefaults() error {
+	if f.MaxDepth == 0 {
+		f.MaxDepth = maxFormDepth
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (f *FormLimits) Valid() error {
+	const op = errors.Op("form_limits_validation")
+	if f.MaxDepth < 0 || f.MaxDepth > maxFormDepth {
+		return errors.E(op, errors.Errorf("max_depth should be in range 1-%d", maxFormDepth))
+	}
+
+	if f.MaxKeys < 0 {
+		return errors.E(op, errors.Str("max_keys should not be negative"))
+	}
+
+	return nil
+}
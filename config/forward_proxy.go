@@ -0,0 +1,54 @@
+package config
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// ForwardProxy accepts the absolute-form request targets (GET http://example.com/hook HTTP/1.1) and proxies them to
+// the named origin, e.g. for the workers' webhook callbacks with HTTP_PROXY pointing to the server. Only the
+// trusted_subnets may use the proxy and only the listed origins are reachable. CONNECT is not supported.
+type ForwardProxy struct {
+	// Hosts is the allow-list of the origins, exact (api.example.com) or wildcard (*.example.com, subdomains only).
+	// The port is matched only when it's specified, e.g. api.example.com:8443. Required.
+	Hosts []string `mapstructure:"hosts"`
+	// Timeout to wait for the origin response headers, defaults to 30s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (f *ForwardProxy) InitDefaults() error {
+	if f.Timeout == 0 {
+		f.Timeout = 30 * time.Second
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (f *ForwardProxy) Valid() error {
+	const op = errors.Op("forward_proxy_validation")
+	if len(f.Hosts) == 0 {
+		return errors.E(op, errors.Str("hosts are required"))
+	}
+
+	for i := 0; i < len(f.Hosts); i++ {
+		host := strings.TrimSpace(f.Hosts[i])
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if host == "" || host == "*" || host == "*." {
+			return errors.E(op, errors.Errorf("invalid host %q, the origins should be listed explicitly", f.Hosts[i]))
+		}
+	}
+
+	if f.Timeout < 0 {
+		return errors.E(op, errors.Str("timeout should be positive"))
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// ForwardedFormat defines which forwarding headers are passed to the worker.
+type ForwardedFormat string
+
+const (
+	// ForwardedRFC7239 passes the standard Forwarded header.
+	ForwardedRFC7239 ForwardedFormat = "forwarded"
+	// ForwardedX passes the X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host headers.
+	ForwardedX ForwardedFormat = "x-forwarded"
+	// ForwardedBoth passes both representations.
+	ForwardedBoth ForwardedFormat = "both"
+)
+
+// Forwarded configures the forwarding headers passed to the worker. Incoming Forwarded or X-Forwarded-* headers are
+// accepted only from the trusted subnets, the current hop is appended to the chain and the chain is sent to the
+// worker in the configured format.
+type Forwarded struct {
+	// Format is forwarded, x-forwarded or both, defaults to both.
+	Format ForwardedFormat `mapstructure:"format"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (f *Forwarded) InitDefaults() error {
+	if f.Format == "" {
+		f.Format = ForwardedBoth
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (f *Forwarded) Valid() error {
+	const op = errors.Op("forwarded_validation")
+	switch f.Format {
+	case ForwardedRFC7239, ForwardedX, ForwardedBoth:
+		return nil
+	default:
+		return errors.E(op, errors.Errorf("unknown forwarded format: %s", f.Format))
+	}
+}
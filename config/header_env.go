@@ -0,0 +1,37 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// validHeaderEnv checks the header_env mapping: the attribute names are upper case environment-style names
+// (A-Z, 0-9, _), so they don't collide with the built-in attributes.
+func validHeaderEnv(mapping map[string]string) error {
+	const op = errors.Op("header_env_validation")
+	for header, name := range mapping {
+		if header == "" {
+			return errors.E(op, errors.Str("empty header name"))
+		}
+
+		if !envName(name) {
+			return errors.E(op, errors.Errorf("bad attribute name %q of the header %s, should match [A-Z_][A-Z0-9_]*", name, header))
+		}
+	}
+
+	return nil
+}
+
+func envName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+
+	return true
+}
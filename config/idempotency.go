@@ -0,0 +1,99 @@
+package config
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Idempotency configures the Idempotency-Key handling (draft-ietf-httpapi-idempotency-key-header). The responses of
+// the requests with the key are stored for the TTL, the retries with the same key and payload get the stored
+// response without reaching the workers. The keys are scoped by the host and the Scope headers.
+type Idempotency struct {
+	// Header with the idempotency key, defaults to Idempotency-Key.
+	Header string `mapstructure:"header"`
+	// Methods handled with the key, defaults to POST and PATCH.
+	Methods []string `mapstructure:"methods"`
+	// Required is the list of the path globs (path.Match syntax, the `/**` suffix matches everything under the
+	// prefix) where the requests without the key are rejected with 400.
+	Required []string `mapstructure:"required"`
+	// Scope is the list of the request headers identifying the client, the keys of the different clients never
+	// share the stored responses. Defaults to Authorization and Cookie, add the session header when the clients are
+	// identified by it.
+	Scope []string `mapstructure:"scope"`
+	// TTL of the stored responses, defaults to 24h.
+	TTL time.Duration `mapstructure:"ttl"`
+	// MaxBodySize is the max request body size with the key in bytes, the body is hashed before the request is sent
+	// to the worker, defaults to 1MB.
+	MaxBodySize int64 `mapstructure:"max_body_size"`
+	// MaxSize is the max total size of the stored bodies in bytes, defaults to 64MB. The oldest responses are evicted
+	// first.
+	MaxSize int64 `mapstructure:"max_size"`
+	// MaxEntrySize is the max size of the stored body in bytes, the larger responses are not stored, defaults to 1MB.
+	MaxEntrySize int64 `mapstructure:"max_entry_size"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (i *Idempotency) InitDefaults() error {
+	if i.Header == "" {
+		i.Header = "Idempotency-Key"
+	}
+
+	if len(i.Methods) == 0 {
+		i.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+
+	for j := 0; j < len(i.Methods); j++ {
+		i.Methods[j] = strings.ToUpper(i.Methods[j])
+	}
+
+	if len(i.Scope) == 0 {
+		i.Scope = []string{"Authorization", "Cookie"}
+	}
+
+	for j := 0; j < len(i.Scope); j++ {
+		i.Scope[j] = http.CanonicalHeaderKey(i.Scope[j])
+	}
+
+	if i.TTL == 0 {
+		i.TTL = 24 * time.Hour
+	}
+
+	if i.MaxBodySize == 0 {
+		i.MaxBodySize = 1024 * 1024
+	}
+
+	if i.MaxSize == 0 {
+		i.MaxSize = 64 * 1024 * 1024
+	}
+
+	if i.MaxEntrySize == 0 {
+		i.MaxEntrySize = 1024 * 1024
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (i *Idempotency) Valid() error {
+	const op = errors.Op("idempotency_validation")
+	if i.TTL < 0 || i.MaxBodySize < 0 {
+		return errors.E(op, errors.Str("ttl and max_body_size should not be negative"))
+	}
+
+	if i.MaxEntrySize <= 0 || i.MaxSize < i.MaxEntrySize {
+		return errors.E(op, errors.Str("idempotency max_size should be greater or equal to max_entry_size"))
+	}
+
+	for j := 0; j < len(i.Required); j++ {
+		_, err := path.Match(strings.TrimSuffix(i.Required[j], "/**"), "")
+		if err != nil {
+			return errors.E(op, errors.Errorf("bad required path %s: %v", i.Required[j], err))
+		}
+	}
+
+	return nil
+}
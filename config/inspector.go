@@ -0,0 +1,66 @@
+package config
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Inspector keeps the last requests with their timings, headers and statuses in memory for the local debugging
+// without a tracing backend. The requests are listed in JSON, or in HTML for the browsers, on the own listener like the
+// status port, the application listeners don't serve them: the URIs and the headers of the other clients are exposed.
+type Inspector struct {
+	// Address of the inspector listener, required, e.g. 127.0.0.1:2115.
+	Address string `mapstructure:"address"`
+	// Path of the endpoint, defaults to /.rr/inspector.
+	Path string `mapstructure:"path"`
+	// Size is the number of the kept requests, defaults to 100.
+	Size int `mapstructure:"size"`
+	// PIDHeader is the response header with the worker PID set by the worker, e.g. X-Worker-PID.
+	PIDHeader string `mapstructure:"pid_header"`
+	// Redact is the list of the request and response headers with the hidden values, in addition to the
+	// Authorization, Proxy-Authorization, Cookie and Set-Cookie headers.
+	Redact []string `mapstructure:"redact"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (i *Inspector) InitDefaults() error {
+	if i.Path == "" {
+		i.Path = "/.rr/inspector"
+	}
+
+	if i.Size == 0 {
+		i.Size = 100
+	}
+
+	i.Redact = append(i.Redact, "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie")
+	for j := 0; j < len(i.Redact); j++ {
+		i.Redact[j] = http.CanonicalHeaderKey(i.Redact[j])
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (i *Inspector) Valid() error {
+	const op = errors.Op("inspector_validation")
+	if i.Address == "" {
+		return errors.E(op, errors.Str("address is required, e.g. 127.0.0.1:2115"))
+	}
+
+	if _, _, err := net.SplitHostPort(i.Address); err != nil {
+		return errors.E(op, err)
+	}
+
+	if !strings.HasPrefix(i.Path, "/") {
+		return errors.E(op, errors.Str("path should start with /"))
+	}
+
+	if i.Size < 1 || i.Size > 10000 {
+		return errors.E(op, errors.Str("size should be between 1 and 10000"))
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// JSONSchema validates the JSON request bodies against the per-route schemas before the requests reach the workers.
+// The invalid bodies are rejected with 422 and the list of the errors, the malformed JSON with 400. The schemas are
+// loaded from the files or from the request bodies of the OpenAPI 3 document operations. The requests with the other
+// content types are passed as is, the empty bodies are rejected only when the OpenAPI request body is required.
+type JSONSchema struct {
+	// Routes maps the routes to the schema files.
+	Routes []*SchemaRoute `mapstructure:"routes"`
+	// OpenAPI is the path of the OpenAPI 3 document, JSON or YAML. The routes take precedence over the operations.
+	OpenAPI string `mapstructure:"openapi"`
+	// MaxBodySize is the max size of the validated bodies in bytes, the larger ones are rejected with 413. Defaults to
+	// 1MB.
+	MaxBodySize int64 `mapstructure:"max_body_size"`
+}
+
+// SchemaRoute is the schema of the request body for the route.
+type SchemaRoute struct {
+	// Method of the requests, empty - any method.
+	Method string `mapstructure:"method"`
+	// Path of the requests, the {name} segments match any segment, e.g. /users/{id}.
+	Path string `mapstructure:"path"`
+	// Schema is the path of the JSON schema file.
+	Schema string `mapstructure:"schema"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (j *JSONSchema) InitDefaults() error {
+	if j.MaxBodySize == 0 {
+		j.MaxBodySize = 1 << 20
+	}
+
+	for i := 0; i < len(j.Routes); i++ {
+		if j.Routes[i] != nil {
+			j.Routes[i].Method = strings.ToUpper(j.Routes[i].Method)
+		}
+	}
+
+	return nil
+}
+
+// Valid validates the configuration, the schemas are loaded when the plugin starts.
+func (j *JSONSchema) Valid() error {
+	const op = errors.Op("json_schema_validation")
+	if len(j.Routes) == 0 && j.OpenAPI == "" {
+		return errors.E(op, errors.Str("routes or openapi document is required"))
+	}
+
+	for i := 0; i < len(j.Routes); i++ {
+		r := j.Routes[i]
+		if r == nil || !strings.HasPrefix(r.Path, "/") || r.Schema == "" {
+			return errors.E(op, errors.Str("the route should have the absolute path and the schema file"))
+		}
+
+		switch r.Method {
+		case "", http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return errors.E(op, errors.Errorf("the requests with the %s method have no body to validate", r.Method))
+		}
+	}
+
+	if j.MaxBodySize < 0 {
+		return errors.E(op, errors.Str("max_body_size should be positive"))
+	}
+
+	return nil
+}
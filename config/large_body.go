@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+)
+
+const shmDir string = "/dev/shm"
+
+// LargeBody configures the by-reference transfer of large request bodies. Instead of framing the body into the
+// payload, the body is streamed into a file inside Dir (tmpfs by default) and the file path is passed to the worker
+// in the `rr_body_file` attribute. Requests with a body smaller than Threshold use the regular payload framing, the
+// bodies of the unknown length (chunked) are read up to the Threshold to decide. The files are owned by the workers
+// user.
+type LargeBody struct {
+	// Dir is the directory to store the bodies, defaults to /dev/shm (or the os temp dir if /dev/shm is not available).
+	Dir string `mapstructure:"dir"`
+	// Threshold in bytes, defaults to 1MB.
+	Threshold int64 `mapstructure:"threshold"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (lb *LargeBody) InitDefaults() error {
+	if lb.Dir == "" {
+		lb.Dir = os.TempDir()
+		if st, err := os.Stat(shmDir); err == nil && st.IsDir() {
+			lb.Dir = shmDir
+		}
+	}
+
+	if lb.Threshold <= 0 {
+		lb.Threshold = 1024 * 1024
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Liveness configures the periodic ping of the idle workers. The ping request is sent through the pool to each idle
+// worker at once, the worker not responding is killed by the pool exec_ttl (required) and replaced before it receives
+// a real request. The exec_ttl is counted from the moment the worker is taken by the ping, the ping not getting a free
+// worker in the allocate_timeout is skipped. The pings are not sent while the requests wait for the workers or for the
+// concurrency limit.
+type Liveness struct {
+	// Interval between the pings, defaults to 10s.
+	Interval time.Duration `mapstructure:"interval"`
+	// Path of the GET ping request, the response status is not checked, defaults to /.rr/liveness.
+	Path string `mapstructure:"path"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (l *Liveness) InitDefaults() error {
+	if l.Interval <= 0 {
+		l.Interval = time.Second * 10
+	}
+
+	if l.Path == "" {
+		l.Path = "/.rr/liveness"
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (l *Liveness) Valid() error {
+	const op = errors.Op("liveness_validation")
+	if !strings.HasPrefix(l.Path, "/") {
+		return errors.E(op, errors.Str("path should start with /"))
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// LoadShedding configures the rejection of the low-priority requests under the host resources pressure. When any of
+// the resources is above the threshold, a fraction of the low-priority requests is rejected with 503 before they
+// reach the workers. The fraction grows linearly from 0 at the threshold to MaxFraction at the full usage (twice the
+// threshold for the heap).
+type LoadShedding struct {
+	// Interval between the resources checks, defaults to 1s.
+	Interval time.Duration `mapstructure:"interval"`
+	// CPU is the host CPU usage threshold in percents, 0 - disabled.
+	CPU float64 `mapstructure:"cpu"`
+	// Memory is the host memory usage threshold in percents, 0 - disabled.
+	Memory float64 `mapstructure:"memory"`
+	// Heap is the Go heap size threshold in bytes, 0 - disabled.
+	Heap uint64 `mapstructure:"heap"`
+	// MaxFraction is the max fraction of the low-priority requests to reject, defaults to 0.5.
+	MaxFraction float64 `mapstructure:"max_fraction"`
+	// Priority is the max priority (see the priority header) of the requests which might be rejected, defaults to 0,
+	// the requests without the priority header.
+	Priority int `mapstructure:"priority"`
+	// RetryAfter is sent with the rejected requests, defaults to 1s.
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (l *LoadShedding) InitDefaults() error {
+	if l.Interval <= 0 {
+		l.Interval = time.Second
+	}
+
+	if l.MaxFraction == 0 {
+		l.MaxFraction = 0.5
+	}
+
+	if l.RetryAfter == 0 {
+		l.RetryAfter = time.Second
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (l *LoadShedding) Valid() error {
+	const op = errors.Op("load_shedding_validation")
+	if l.CPU == 0 && l.Memory == 0 && l.Heap == 0 {
+		return errors.E(op, errors.Str("at least one of the cpu, memory or heap thresholds should be set"))
+	}
+
+	if l.CPU < 0 || l.CPU > 100 || l.Memory < 0 || l.Memory > 100 {
+		return errors.E(op, errors.Str("cpu and memory thresholds should be in the 0-100 range"))
+	}
+
+	if l.MaxFraction <= 0 || l.MaxFraction > 1 {
+		return errors.E(op, errors.Str("max_fraction should be in the (0, 1] range"))
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// KeepaliveMode defines the signals sent while the long request is executed.
+type KeepaliveMode string
+
+const (
+	// KeepaliveProcessing sends the 102 Processing interim responses, the HTTP/1.0 clients get no signals.
+	KeepaliveProcessing KeepaliveMode = "processing"
+	// KeepaliveWhitespace sends the 200 status and flushes a space to the body, for the clients and the proxies not
+	// supporting the interim responses. The worker status and headers are not sent.
+	KeepaliveWhitespace KeepaliveMode = "whitespace"
+)
+
+// LongRequests keeps the connections of the long-running requests alive with the periodic signals to the client,
+// so the load balancers with the idle timeouts don't close them while the worker is executing the request, e.g.
+// generating a report.
+type LongRequests struct {
+	// After is the execution time the signals are sent after, defaults to 30s.
+	After time.Duration `mapstructure:"after"`
+	// Interval between the signals, defaults to 15s.
+	Interval time.Duration `mapstructure:"interval"`
+	// Mode is processing or whitespace, defaults to processing.
+	Mode KeepaliveMode `mapstructure:"mode"`
+	// ContentType of the responses in the whitespace mode, defaults to text/html; charset=utf-8.
+	ContentType string `mapstructure:"content_type"`
+	// Paths of the long requests, the trailing * matches the prefix, e.g. /reports/*. Empty - all the requests.
+	Paths []string `mapstructure:"paths"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (lr *LongRequests) InitDefaults() error {
+	if lr.After == 0 {
+		lr.After = 30 * time.Second
+	}
+
+	if lr.Interval == 0 {
+		lr.Interval = 15 * time.Second
+	}
+
+	if lr.Mode == "" {
+		lr.Mode = KeepaliveProcessing
+	}
+
+	if lr.ContentType == "" {
+		lr.ContentType = "text/html; charset=utf-8"
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (lr *LongRequests) Valid() error {
+	const op = errors.Op("long_requests_validation")
+	if lr.After < 0 || lr.Interval < 0 {
+		return errors.E(op, errors.Str("after and interval should be positive"))
+	}
+
+	switch lr.Mode {
+	case KeepaliveProcessing, KeepaliveWhitespace:
+	default:
+		return errors.E(op, errors.Errorf("unknown mode %q, should be processing or whitespace", lr.Mode))
+	}
+
+	for i := 0; i < len(lr.Paths); i++ {
+		if !strings.HasPrefix(lr.Paths[i], "/") {
+			return errors.E(op, errors.Errorf("paths should be absolute, got: %s", lr.Paths[i]))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// OtelMetricsExporter configures the OTLP/HTTP exporter of the request metrics enabled by the `otel_metrics` option.
+// The empty values are taken from the standard OTEL_EXPORTER_OTLP_* env variables, e.g. the endpoint defaults to
+// OTEL_EXPORTER_OTLP_METRICS_ENDPOINT or localhost:4318.
+type OtelMetricsExporter struct {
+	// Endpoint is the host:port of the collector.
+	Endpoint string `mapstructure:"endpoint"`
+	// Insecure sends the metrics over the plain HTTP.
+	Insecure bool `mapstructure:"insecure"`
+	// Headers are sent with the exports, e.g. the authentication token.
+	Headers map[string]string `mapstructure:"headers"`
+	// Interval between the exports, defaults to 10s.
+	Interval time.Duration `mapstructure:"interval"`
+	// ServiceName is the service.name resource attribute, defaults to roadrunner.
+	ServiceName string `mapstructure:"service_name"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (o *OtelMetricsExporter) InitDefaults() error {
+	if o.Interval == 0 {
+		o.Interval = time.Second * 10
+	}
+
+	if o.ServiceName == "" {
+		o.ServiceName = "roadrunner"
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (o *OtelMetricsExporter) Valid() error {
+	const op = errors.Op("otel_metrics_exporter_validation")
+	if o.Interval < 0 {
+		return errors.E(op, errors.Str("interval should be positive"))
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// PanicReportsStderr writes the panic reports to the standard error.
+const PanicReportsStderr string = "stderr"
+
+// PanicReports recovers the panics in the handler and the middleware and writes the structured report (the stack,
+// the request summary, the worker PID and the build info) to the dedicated sink as a JSON line, instead of the stack
+// interleaved with the logs. The requests get 500 if the response is not started, otherwise the connection is
+// aborted. The panics are counted by the rr_http_panics_total metric.
+type PanicReports struct {
+	// Path of the file the reports are appended to, or stderr. Required.
+	Path string `mapstructure:"path"`
+	// PIDHeader is the response header with the worker PID set by the worker, e.g. X-Worker-PID.
+	PIDHeader string `mapstructure:"pid_header"`
+}
+
+// Valid validates the configuration.
+func (pr *PanicReports) Valid() error {
+	const op = errors.Op("panic_reports_validation")
+	if pr.Path == "" {
+		return errors.E(op, errors.Str("path of the reports file is required"))
+	}
+
+	return nil
+}
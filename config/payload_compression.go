@@ -0,0 +1,81 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// PayloadCompression configures the zstd compression of the payload bodies between RR and the workers, useful when
+// the workers are connected over the network. The compression is negotiated per worker by the workers relay (pipes by
+// default): after the spawn RR repeats the pid handshake with the `"encodings": ["zstd"]` offer, the worker
+// supporting the compression replies with the pid and the same encodings, the other workers reply with the pid only
+// and receive the bodies as is. The compressed request bodies are marked with the 0x02 frame flag, the compressed
+// response bodies should be marked by the worker with the `X-Rr-Body-Encoding: zstd` header. The already compressed
+// request bodies, e.g. the images and the archives, are excluded by the content type.
+type PayloadCompression struct {
+	// MinSize in bytes, smaller bodies are not compressed, defaults to 1KB.
+	MinSize int `mapstructure:"min_size"`
+	// Level is the zstd encoder level: 1 (fastest) - 4 (best compression), defaults to 1.
+	Level int `mapstructure:"level"`
+	// ExcludeContentTypes are the media types of the request bodies sent as is, the trailing * matches the prefix,
+	// e.g. image/*. Defaults to the common compressed formats.
+	ExcludeContentTypes []string `mapstructure:"exclude_content_types"`
+	// ExcludePaths are the request paths with the bodies sent as is, the trailing * matches the prefix, e.g. /upload/*.
+	ExcludePaths []string `mapstructure:"exclude_paths"`
+}
+
+// compressedContentTypes are the formats not worth compressing again
+var compressedContentTypes = []string{ //nolint:gochecknoglobals
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"image/webp",
+	"image/avif",
+	"video/*",
+	"audio/*",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+}
+
+// InitDefaults sets missing values to their default values.
+func (pc *PayloadCompression) InitDefaults() error {
+	if pc.MinSize <= 0 {
+		pc.MinSize = 1024
+	}
+
+	if pc.Level == 0 {
+		pc.Level = 1
+	}
+
+	if pc.ExcludeContentTypes == nil {
+		pc.ExcludeContentTypes = append([]string(nil), compressedContentTypes...)
+	}
+
+	for i := 0; i < len(pc.ExcludeContentTypes); i++ {
+		pc.ExcludeContentTypes[i] = strings.ToLower(pc.ExcludeContentTypes[i])
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (pc *PayloadCompression) Valid() error {
+	const op = errors.Op("payload_compression_validation")
+	if pc.Level < 1 || pc.Level > 4 {
+		return errors.E(op, errors.Errorf("level should be in the 1-4 range, got: %d", pc.Level))
+	}
+
+	for i := 0; i < len(pc.ExcludePaths); i++ {
+		if !strings.HasPrefix(pc.ExcludePaths[i], "/") {
+			return errors.E(op, errors.Errorf("exclude_paths should be absolute, got: %s", pc.ExcludePaths[i]))
+		}
+	}
+
+	return nil
+}
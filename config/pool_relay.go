@@ -0,0 +1,67 @@
+package config
+
+import (
+	"net"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+const (
+	// RelayPipes is the stdin/stdout relay of the worker process.
+	RelayPipes string = "pipes"
+
+	relayUnix string = "unix://"
+	relayTCP  string = "tcp://"
+)
+
+// PoolRelay overrides the server relay for the HTTP pool, declared in the `http.pool` section next to the
+// `num_workers` option: pipes, unix:///path/rr.sock or tcp://address:port. The workers are prepared by the server
+// plugin (command, env and user) and connect to the relay address from the RR_RELAY env variable. The socket
+// connections are matched to the started processes by the PID reported by the worker.
+type PoolRelay struct {
+	// Relay is the worker transport, empty - the server relay is used.
+	Relay string `mapstructure:"relay"`
+}
+
+// Empty returns true if the relay is not overridden.
+func (r *PoolRelay) Empty() bool {
+	return r == nil || r.Relay == ""
+}
+
+// Network returns the network and the address of the socket relay, empty network for the pipes.
+func (r *PoolRelay) Network() (string, string) {
+	switch {
+	case strings.HasPrefix(r.Relay, relayUnix):
+		return "unix", strings.TrimPrefix(r.Relay, relayUnix)
+	case strings.HasPrefix(r.Relay, relayTCP):
+		return "tcp", strings.TrimPrefix(r.Relay, relayTCP)
+	default:
+		return "", ""
+	}
+}
+
+// Valid validates the relay.
+func (r *PoolRelay) Valid() error {
+	const op = errors.Op("pool_relay_validation")
+	if r.Relay == RelayPipes {
+		return nil
+	}
+
+	network, address := r.Network()
+	switch network {
+	case "unix":
+		if address == "" {
+			return errors.E(op, errors.Errorf("empty unix socket path in the relay %q", r.Relay))
+		}
+	case "tcp":
+		_, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return errors.E(op, errors.Errorf("invalid tcp relay %q: %v", r.Relay, err))
+		}
+	default:
+		return errors.E(op, errors.Errorf("unknown relay %q, should be pipes, unix:// or tcp://", r.Relay))
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// PreloadMode defines what to do with the `Link: rel=preload` headers from the worker responses.
+type PreloadMode string
+
+const (
+	// PreloadPush performs the HTTP/2 server push of the preloaded resources.
+	PreloadPush PreloadMode = "push"
+	// PreloadHints remembers the preloaded resources per path and sends them in the 103 Early Hints response
+	// before the next request to the same path is sent to the worker.
+	PreloadHints PreloadMode = "hints"
+)
+
+// Preload configures the translation of the `Link: rel=preload` response headers.
+type Preload struct {
+	// Mode is push or hints, defaults to hints.
+	Mode PreloadMode `mapstructure:"mode"`
+	// MaxPaths is the max number of the remembered paths in the hints mode, defaults to 1000.
+	MaxPaths int `mapstructure:"max_paths"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (p *Preload) InitDefaults() error {
+	if p.Mode == "" {
+		p.Mode = PreloadHints
+	}
+
+	if p.MaxPaths <= 0 {
+		p.MaxPaths = 1000
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (p *Preload) Valid() error {
+	const op = errors.Op("preload_validation")
+	switch p.Mode {
+	case PreloadPush, PreloadHints:
+		return nil
+	default:
+		return errors.E(op, errors.Errorf("unknown preload mode: %s", p.Mode))
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// Priority configures the header-based prioritization of the requests waiting for the workers. The header is honored
+// only for the requests coming from the trusted_subnets, which default to all private networks: restrict them to the
+// proxies setting the header, otherwise any client of the private network can jump the queue.
+type Priority struct {
+	// Header contains the request priority, higher value means higher priority, defaults to X-RR-Priority.
+	Header string `mapstructure:"header"`
+	// MaxConcurrency is the number of requests dispatched to the pool at once, defaults to the number of workers.
+	MaxConcurrency int64 `mapstructure:"max_concurrency"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (p *Priority) InitDefaults(numWorkers uint64) error {
+	if p.Header == "" {
+		p.Header = "X-RR-Priority"
+	}
+
+	if p.MaxConcurrency == 0 {
+		p.MaxConcurrency = int64(numWorkers) //nolint:gosec
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (p *Priority) Valid() error {
+	const op = errors.Op("priority_validation")
+	if p.MaxConcurrency < 0 {
+		return errors.E(op, errors.Str("max_concurrency should not be negative"))
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// Propagator is the trace context propagation format, the names follow the OTEL_PROPAGATORS values.
+type Propagator string
+
+const (
+	// PropagatorTraceContext is the W3C Trace Context (traceparent, tracestate).
+	PropagatorTraceContext Propagator = "tracecontext"
+	// PropagatorBaggage is the W3C Baggage.
+	PropagatorBaggage Propagator = "baggage"
+	// PropagatorB3 is the B3 single header format (b3).
+	PropagatorB3 Propagator = "b3"
+	// PropagatorB3Multi is the B3 multiple headers format (X-B3-TraceId, X-B3-SpanId, ...).
+	PropagatorB3Multi Propagator = "b3multi"
+	// PropagatorJaeger is the Jaeger format (uber-trace-id).
+	PropagatorJaeger Propagator = "jaeger"
+	// PropagatorDatadog is the Datadog format (x-datadog-trace-id, x-datadog-parent-id, ...).
+	PropagatorDatadog Propagator = "datadog"
+)
+
+// defaultPropagators keeps the formats used before the propagators were configurable.
+func defaultPropagators() []Propagator {
+	return []Propagator{PropagatorTraceContext, PropagatorBaggage, PropagatorJaeger}
+}
+
+// validPropagators checks the propagators names.
+func validPropagators(props []Propagator) error {
+	const op = errors.Op("trace_propagators_validation")
+	for i := 0; i < len(props); i++ {
+		switch props[i] {
+		case PropagatorTraceContext, PropagatorBaggage, PropagatorB3, PropagatorB3Multi, PropagatorJaeger, PropagatorDatadog:
+		default:
+			return errors.E(op, errors.Errorf("unknown trace propagator %s, supported: tracecontext, baggage, b3, b3multi, jaeger, datadog", props[i]))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// QueryLimits limits the query string. The query is passed to the worker as is (RawQuery) and parsed on the PHP side,
+// the limits are checked before the request is sent to the worker. Requests above the limits are rejected with 400.
+type QueryLimits struct {
+	// MaxParams is the max number of the query parameters, 0 - unlimited.
+	MaxParams int `mapstructure:"max_params"`
+	// MaxKeyLength is the max length of the decoded parameter name, 0 - unlimited.
+	MaxKeyLength int `mapstructure:"max_key_length"`
+	// MaxValueLength is the max length of the decoded parameter value, 0 - unlimited.
+	MaxValueLength int `mapstructure:"max_value_length"`
+	// MaxDepth is the max bracket nesting of the parameter names, defaults to 127.
+	MaxDepth int `mapstructure:"max_depth"`
+	// RawRoutes is the list of the path prefixes to skip the checks for, the query is passed to the worker unchecked.
+	RawRoutes []string `mapstructure:"raw_routes"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (q *QueryLimits) InitDefaults() error {
+	if q.MaxDepth == 0 {
+		q.MaxDepth = maxFormDepth
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (q *QueryLimits) Valid() error {
+	const op = errors.Op("query_limits_validation")
+	if q.MaxParams < 0 || q.MaxKeyLength < 0 || q.MaxValueLength < 0 || q.MaxDepth < 0 {
+		return errors.E(op, errors.Str("query limits should not be negative"))
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Queue configures how long the request may wait for a free worker.
+type Queue struct {
+	// MaxWait is the max time the request may wait for a free worker, 503 is returned when exceeded.
+	MaxWait time.Duration `mapstructure:"max_wait"`
+	// RetryAfter is sent in the Retry-After header of the 503 response, defaults to 1s.
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (q *Queue) InitDefaults() error {
+	if q.RetryAfter == 0 {
+		q.RetryAfter = time.Second
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (q *Queue) Valid() error {
+	const op = errors.Op("queue_validation")
+	if q.MaxWait < 0 || q.RetryAfter < 0 {
+		return errors.E(op, errors.Str("queue durations should not be negative"))
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// QueueState configures the endpoint with the queue depth, the oldest waiting request age and the pools utilization
+// in JSON, polled by the autoscalers. The endpoint is served only to the trusted_subnets.
+type QueueState struct {
+	// Path of the endpoint, defaults to /.rr/queue.
+	Path string `mapstructure:"path"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (q *QueueState) InitDefaults() error {
+	if q.Path == "" {
+		q.Path = "/.rr/queue"
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (q *QueueState) Valid() error {
+	const op = errors.Op("queue_state_validation")
+	if !strings.HasPrefix(q.Path, "/") {
+		return errors.E(op, errors.Str("path should start with /"))
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// ReadinessProbe configures the request each worker must answer with the Status before the pool receives the
+// traffic: on start, after the reset and when the blue/green pool is prepared. The workers failing the probe are
+// killed and their replacements are probed again. The workers respawned by the pool later (max_jobs, TTL, crash or
+// kill) are probed once before they are returned to the pool, the pool retries the allocation of the failed ones
+// until the allocate_timeout. The workers are spawned by the plugin: the pools without the relay declared in the pool
+// section use the own pipes relay instead of the server relay.
+type ReadinessProbe struct {
+	// Path (with the query) of the probe request, required.
+	Path string `mapstructure:"path"`
+	// Method of the probe request, defaults to GET.
+	Method string `mapstructure:"method"`
+	// Status expected in the response, defaults to 200.
+	Status int `mapstructure:"status"`
+	// Timeout of the probe request, the worker is killed after it, defaults to 5s.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Attempts is the number of the probe rounds over the replaced workers, defaults to 3.
+	Attempts int `mapstructure:"attempts"`
+	// Interval between the rounds, defaults to 1s.
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (rp *ReadinessProbe) InitDefaults() error {
+	if rp.Method == "" {
+		rp.Method = http.MethodGet
+	}
+
+	if rp.Status == 0 {
+		rp.Status = http.StatusOK
+	}
+
+	if rp.Timeout == 0 {
+		rp.Timeout = time.Second * 5
+	}
+
+	if rp.Attempts == 0 {
+		rp.Attempts = 3
+	}
+
+	if rp.Interval == 0 {
+		rp.Interval = time.Second
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (rp *ReadinessProbe) Valid() error {
+	const op = errors.Op("readiness_probe_validation")
+	if !strings.HasPrefix(rp.Path, "/") {
+		return errors.E(op, errors.Str("path is required and should start with /"))
+	}
+
+	if rp.Status < 100 || rp.Status > 599 {
+		return errors.E(op, errors.Str("status should be a valid HTTP status"))
+	}
+
+	if rp.Timeout < 0 || rp.Interval < 0 || rp.Attempts < 0 {
+		return errors.E(op, errors.Str("timeout, interval and attempts should be positive"))
+	}
+
+	return nil
+}
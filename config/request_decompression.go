@@ -0,0 +1,63 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// RequestDecompression configures the decoding of the compressed request bodies (Content-Encoding), the workers
+// receive the decoded body. The limits protect against the decompression bombs, the offending requests are rejected
+// with 413.
+type RequestDecompression struct {
+	// Encodings to decode: gzip, deflate, zstd, defaults to all. The bodies with other encodings are passed as is.
+	Encodings []string `mapstructure:"encodings"`
+	// MaxSize is the max decompressed body size in bytes, defaults to 10MB.
+	MaxSize int64 `mapstructure:"max_size"`
+	// MaxRatio is the max decompressed to compressed size ratio, checked after the first 1MB of the decompressed body,
+	// defaults to 100.
+	MaxRatio float64 `mapstructure:"max_ratio"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (rd *RequestDecompression) InitDefaults() error {
+	if len(rd.Encodings) == 0 {
+		rd.Encodings = []string{"gzip", "deflate", "zstd"}
+	}
+
+	for i := 0; i < len(rd.Encodings); i++ {
+		rd.Encodings[i] = strings.ToLower(rd.Encodings[i])
+	}
+
+	if rd.MaxSize == 0 {
+		rd.MaxSize = 10 * 1024 * 1024
+	}
+
+	if rd.MaxRatio == 0 {
+		rd.MaxRatio = 100
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (rd *RequestDecompression) Valid() error {
+	const op = errors.Op("request_decompression_validation")
+	for i := 0; i < len(rd.Encodings); i++ {
+		switch rd.Encodings[i] {
+		case "gzip", "deflate", "zstd":
+		default:
+			return errors.E(op, errors.Errorf("unknown encoding: %s, supported: gzip, deflate, zstd", rd.Encodings[i]))
+		}
+	}
+
+	if rd.MaxSize < 0 {
+		return errors.E(op, errors.Errorf("max_size should be positive, got: %d", rd.MaxSize))
+	}
+
+	if rd.MaxRatio < 1 {
+		return errors.E(op, errors.Errorf("max_ratio should be at least 1, got: %v", rd.MaxRatio))
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package config
+
+// RequestID configures the request ID propagation. The ID is taken from the request header (or generated when
+// missing), passed to the worker in the `request_id` attribute, returned in the response header and attached to the
+// error logs of the request.
+type RequestID struct {
+	// Header with the request ID, defaults to X-Request-Id.
+	Header string `mapstructure:"header"`
+	// WorkerStderr captures the worker stderr written while the worker executes the request: the stderr lines are
+	// logged with the request ID and the captured output is attached to the error log of the request. The stderr is
+	// read by the plugin, the pools without the relay declared in the pool section use the own pipes relay instead of
+	// the server relay.
+	WorkerStderr bool `mapstructure:"worker_stderr"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (r *RequestID) InitDefaults() error {
+	if r.Header == "" {
+		r.Header = "X-Request-Id"
+	}
+
+	return nil
+}
+
+// Stderr returns true if the worker stderr is captured.
+func (r *RequestID) Stderr() bool {
+	return r != nil && r.WorkerStderr
+}
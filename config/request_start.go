@@ -0,0 +1,52 @@
+package config
+
+import (
+	"net/http"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// The request start timestamp units.
+const (
+	// UnitMicroseconds is the t=<microseconds> format, e.g. NewRelic.
+	UnitMicroseconds string = "microseconds"
+	// UnitMilliseconds is the t=<milliseconds> format.
+	UnitMilliseconds string = "milliseconds"
+	// UnitSeconds is the t=<seconds.milliseconds> format.
+	UnitSeconds string = "seconds"
+)
+
+// RequestStart sets the header with the time the request entered the plugin, forwarded to the workers, so the APM
+// agents (NewRelic, Datadog) compute the queue time. The header set by the trusted_subnets, e.g. by the load
+// balancer, is kept to include the time before the plugin, the header of the other peers is replaced.
+type RequestStart struct {
+	// Header name, defaults to X-Request-Start, e.g. X-Queue-Start.
+	Header string `mapstructure:"header"`
+	// Unit of the t=<timestamp> value: microseconds, milliseconds or seconds, defaults to microseconds.
+	Unit string `mapstructure:"unit"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (rs *RequestStart) InitDefaults() error {
+	if rs.Header == "" {
+		rs.Header = "X-Request-Start"
+	}
+
+	rs.Header = http.CanonicalHeaderKey(rs.Header)
+	if rs.Unit == "" {
+		rs.Unit = UnitMicroseconds
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (rs *RequestStart) Valid() error {
+	const op = errors.Op("request_start_validation")
+	switch rs.Unit {
+	case UnitMicroseconds, UnitMilliseconds, UnitSeconds:
+		return nil
+	default:
+		return errors.E(op, errors.Errorf("unknown unit %q, should be microseconds, milliseconds or seconds", rs.Unit))
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+// ResponseBuffer configures the buffered response writer. When enabled, the response body is accumulated in the pooled
+// buffer and flushed to the client once per response (or per stream frame) instead of flushing on every write.
+type ResponseBuffer struct {
+	// Size of the buffer in bytes, defaults to 4KB.
+	Size int `mapstructure:"size"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (rb *ResponseBuffer) InitDefaults() error {
+	if rb.Size <= 0 {
+		rb.Size = 4 * 1024
+	}
+
+	return nil
+}
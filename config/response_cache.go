@@ -0,0 +1,52 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// ResponseCache configures the shared in-memory cache of the worker responses. Only the successful GET responses
+// with the explicit freshness (Cache-Control s-maxage/max-age, Expires) or the DefaultTTL are cached, responses with
+// the Set-Cookie header, the `Vary: *` header or the no-store, no-cache and private directives are never cached. The
+// requests with the Cookie header bypass the cache unless the cache key includes it: the Vary lists the Cookie or the
+// response varies on it (`Vary: Cookie`), so the personalized responses, the DefaultTTL ones as well, are not shared.
+type ResponseCache struct {
+	// MaxSize is the max total size of the cached bodies in bytes, defaults to 64MB. The least recently used responses
+	// are evicted first.
+	MaxSize int64 `mapstructure:"max_size"`
+	// MaxEntrySize is the max size of the cached body in bytes, defaults to 1MB.
+	MaxEntrySize int64 `mapstructure:"max_entry_size"`
+	// Vary is the list of the request headers included in the cache keys in addition to the ones listed in the Vary
+	// response header, e.g. the Accept-Language when the worker does not send the Vary header.
+	Vary []string `mapstructure:"vary"`
+	// DefaultTTL is the freshness of the responses without the explicit one, 0 - such responses are not cached.
+	DefaultTTL time.Duration `mapstructure:"default_ttl"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (c *ResponseCache) InitDefaults() error {
+	if c.MaxSize == 0 {
+		c.MaxSize = 64 * 1024 * 1024
+	}
+
+	if c.MaxEntrySize == 0 {
+		c.MaxEntrySize = 1024 * 1024
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (c *ResponseCache) Valid() error {
+	const op = errors.Op("response_cache_validation")
+	if c.MaxEntrySize <= 0 || c.MaxSize < c.MaxEntrySize {
+		return errors.E(op, errors.Str("response cache max_size should be greater or equal to max_entry_size"))
+	}
+
+	if c.DefaultTTL < 0 {
+		return errors.E(op, errors.Str("response cache default_ttl should not be negative"))
+	}
+
+	return nil
+}
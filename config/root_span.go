@@ -0,0 +1,59 @@
+package config
+
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// The root span samplers.
+const (
+	// SamplerParentBased leaves the root spans to the sampler of the otel plugin.
+	SamplerParentBased string = "parent_based"
+	// SamplerRatio samples the ratio of the root spans.
+	SamplerRatio string = "ratio"
+	// SamplerRateLimited samples up to the rate of the root spans per second.
+	SamplerRateLimited string = "rate_limited"
+	// SamplerAlwaysOff drops the root spans.
+	SamplerAlwaysOff string = "always_off"
+)
+
+// RootSpan samples the requests without the incoming trace context before the otel middleware starts the root
+// span, e.g. to keep the high-volume health checks out of the collector. The dropped requests get the not sampled
+// trace context, so the otel middleware, the plugin and the workers skip them with the parent-based samplers (the
+// otel SDK default). The requests with the incoming trace context follow the parent decision.
+type RootSpan struct {
+	// Sampler is one of parent_based, ratio, rate_limited or always_off. Defaults to parent_based.
+	Sampler string `mapstructure:"sampler"`
+	// Ratio (0-1] of the sampled root spans, the ratio sampler.
+	Ratio float64 `mapstructure:"ratio"`
+	// Rate is the max number of the sampled root spans per second, the rate_limited sampler.
+	Rate float64 `mapstructure:"rate"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (rs *RootSpan) InitDefaults() error {
+	if rs.Sampler == "" {
+		rs.Sampler = SamplerParentBased
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (rs *RootSpan) Valid() error {
+	const op = errors.Op("root_span_validation")
+	switch rs.Sampler {
+	case SamplerParentBased, SamplerAlwaysOff:
+	case SamplerRatio:
+		if rs.Ratio <= 0 || rs.Ratio > 1 {
+			return errors.E(op, errors.Errorf("ratio should be in the (0,1] range, got: %v", rs.Ratio))
+		}
+	case SamplerRateLimited:
+		if rs.Rate <= 0 {
+			return errors.E(op, errors.Errorf("rate should be positive, got: %v", rs.Rate))
+		}
+	default:
+		return errors.E(op, errors.Errorf("unknown sampler %q, should be parent_based, ratio, rate_limited or always_off", rs.Sampler))
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/pool/pool"
+)
+
+// RoutePool serves the requests under the path prefixes with the own worker pool and transport, e.g. the remote
+// workers connected over the TCP relay serve /reports/ while the local pipes serve the rest. The longest matching
+// prefix wins, the other requests are served by the main pool.
+type RoutePool struct {
+	// Name of the pool, used in the logs, required.
+	Name string `mapstructure:"name"`
+	// Prefixes of the request paths served by the pool, required.
+	Prefixes []string `mapstructure:"prefixes"`
+	// Relay is the worker transport: pipes, unix:///path/rr.sock or tcp://address:port, empty - the server relay.
+	Relay string `mapstructure:"relay"`
+	// Pool configures the workers, the command defaults to the server command.
+	Pool *pool.Config `mapstructure:"pool"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (rp *RoutePool) InitDefaults() error {
+	if rp.Pool == nil {
+		rp.Pool = &pool.Config{}
+	}
+
+	rp.Pool.InitDefaults()
+	return nil
+}
+
+// Valid validates the configuration.
+func (rp *RoutePool) Valid() error {
+	const op = errors.Op("route_pool_validation")
+	if rp.Name == "" {
+		return errors.E(op, errors.Str("route pool name is required"))
+	}
+
+	if len(rp.Prefixes) == 0 {
+		return errors.E(op, errors.Errorf("route pool %s: prefixes are required", rp.Name))
+	}
+
+	for i := 0; i < len(rp.Prefixes); i++ {
+		if !strings.HasPrefix(rp.Prefixes[i], "/") {
+			return errors.E(op, errors.Errorf("route pool %s: prefix %s should start with /", rp.Name, rp.Prefixes[i]))
+		}
+	}
+
+	if rp.Relay != "" {
+		err := (&PoolRelay{Relay: rp.Relay}).Valid()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// SelfTest configures the internal endpoint which sends a synthetic request through the full middleware chain to a
+// worker and reports the per-stage timings. The synthetic request carries a random nonce (32 hex characters) in the
+// Header, the worker answers it with the nonce as the plain text body before the application routing, e.g.:
+//
+//	$nonce = $request->getHeaderLine('X-RR-Self-Test');
+//	if (strlen($nonce) === 32 && ctype_xdigit($nonce)) {
+//	    $worker->respond(new Response(200, ['Content-Type' => 'text/plain'], $nonce));
+//	    continue;
+//	}
+//
+// The Header is removed from the client requests, so it reaches the worker only with the synthetic request. The
+// endpoint is served only to the trusted_subnets.
+type SelfTest struct {
+	// Path of the endpoint, defaults to /.rr/self-test.
+	Path string `mapstructure:"path"`
+	// Header with the nonce, defaults to X-RR-Self-Test.
+	Header string `mapstructure:"header"`
+	// Timeout of the synthetic request, defaults to 5s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (s *SelfTest) InitDefaults() error {
+	if s.Path == "" {
+		s.Path = "/.rr/self-test"
+	}
+
+	if s.Header == "" {
+		s.Header = "X-RR-Self-Test"
+	}
+
+	if s.Timeout == 0 {
+		s.Timeout = time.Second * 5
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (s *SelfTest) Valid() error {
+	const op = errors.Op("self_test_validation")
+	if !strings.HasPrefix(s.Path, "/") {
+		return errors.E(op, errors.Str("path should start with /"))
+	}
+
+	if s.Timeout < 0 {
+		return errors.E(op, errors.Str("timeout should be positive"))
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// ShutdownDrain delays the shutdown, so the load balancers stop sending the new traffic at the start of the drain
+// window: when the plugin is stopped, the drain status endpoint and the readiness flip to 503 at once, the keep-alive
+// connections are closed after the responses, the requests are served as usual until the delay elapses, then the
+// in-flight requests are completed and the servers are stopped.
+type ShutdownDrain struct {
+	// Path of the drain status endpoint, 200 while serving and 503 while draining. Defaults to /drain-status.
+	Path string `mapstructure:"path"`
+	// Delay between the status flip and the servers stop, should be longer than the health check interval of the
+	// load balancer and shorter than the graceful shutdown timeout. Defaults to 5s.
+	Delay time.Duration `mapstructure:"delay"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (sd *ShutdownDrain) InitDefaults() error {
+	if sd.Path == "" {
+		sd.Path = "/drain-status"
+	}
+
+	if sd.Delay == 0 {
+		sd.Delay = 5 * time.Second
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (sd *ShutdownDrain) Valid() error {
+	const op = errors.Op("shutdown_drain_validation")
+	if !strings.HasPrefix(sd.Path, "/") {
+		return errors.E(op, errors.Errorf("path should be absolute, got: %s", sd.Path))
+	}
+
+	if sd.Delay < 0 {
+		return errors.E(op, errors.Str("delay should be positive"))
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"path"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// SignedURLs configures the validation of the HMAC-signed URLs. Requests to the protected paths are rejected with 403
+// unless the URL contains the not expired expiration time (unix seconds) and the hex HMAC-SHA256 signature of the
+// `path + "\n" + expires` string, both for the static files and the worker responses (X-Sendfile).
+type SignedURLs struct {
+	// Key is the HMAC secret.
+	Key string `mapstructure:"key"`
+	// Paths is the list of the protected path globs (path.Match syntax), the `/**` suffix matches everything under
+	// the prefix.
+	Paths []string `mapstructure:"paths"`
+	// ExpiresParam is the query parameter with the expiration time, defaults to expires.
+	ExpiresParam string `mapstructure:"expires_param"`
+	// SignatureParam is the query parameter with the signature, defaults to signature.
+	SignatureParam string `mapstructure:"signature_param"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (s *SignedURLs) InitDefaults() error {
+	if s.ExpiresParam == "" {
+		s.ExpiresParam = "expires"
+	}
+
+	if s.SignatureParam == "" {
+		s.SignatureParam = "signature"
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (s *SignedURLs) Valid() error {
+	const op = errors.Op("signed_urls_validation")
+	if s.Key == "" {
+		return errors.E(op, errors.Str("signed_urls key should be set"))
+	}
+
+	if len(s.Paths) == 0 {
+		return errors.E(op, errors.Str("signed_urls paths should be set"))
+	}
+
+	for i := 0; i < len(s.Paths); i++ {
+		_, err := path.Match(strings.TrimSuffix(s.Paths[i], "/**"), "")
+		if err != nil {
+			return errors.E(op, errors.Errorf("bad path %s: %v", s.Paths[i], err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// ETagMode defines how the ETag of the static files is calculated.
+type ETagMode string
+
+const (
+	// ETagMtime calculates the ETag from the file size and modification time.
+	ETagMtime ETagMode = "mtime"
+	// ETagHash calculates the ETag from the file content.
+	ETagHash ETagMode = "hash"
+	// ETagOff disables the ETag.
+	ETagOff ETagMode = "off"
+)
+
+// SymlinkPolicy defines which symlinks inside the static root are followed.
+type SymlinkPolicy string
+
+const (
+	// SymlinksNever serves no files with the symlinks in the path.
+	SymlinksNever SymlinkPolicy = "never"
+	// SymlinksSameRoot follows the symlinks resolved inside the static root.
+	SymlinksSameRoot SymlinkPolicy = "same_root"
+	// SymlinksAlways follows all the symlinks.
+	SymlinksAlways SymlinkPolicy = "always"
+)
+
+// Static configures the static files serving. Files from the Dir are served before the request is sent to the
+// workers, requests for the missing or not allowed files are passed to the workers.
+type Static struct {
+	// Dir is the document root.
+	Dir string `mapstructure:"dir"`
+
+	// Archive is the zip archive used as the document root instead of the Dir, read into the memory on start.
+	Archive string `mapstructure:"archive"`
+
+	// FS is the name of the plugin providing the file system (e.g. embed.FS) used as the document root instead of
+	// the Dir.
+	FS string `mapstructure:"fs"`
+
+	// Forbid specifies list of file extensions which are never served, defaults to .php and .htaccess.
+	Forbid []string `mapstructure:"forbid"`
+
+	// Allow specifies list of file extensions which are served, all (except forbidden) if both Allow and AllowPatterns
+	// are empty.
+	Allow []string `mapstructure:"allow"`
+
+	// ForbidPatterns specifies list of the glob or regexp (`re:` prefix) patterns of the paths relative to the Dir
+	// which are never served, e.g. `**/.git/**`.
+	ForbidPatterns []string `mapstructure:"forbid_patterns"`
+
+	// AllowPatterns specifies list of the glob or regexp (`re:` prefix) patterns of the served paths relative to the
+	// Dir, e.g. `media/**.pdf`.
+	AllowPatterns []string `mapstructure:"allow_patterns"`
+
+	// ETag is the strong ETag calculation: mtime (size and modification time), hash (content hash) or off,
+	// defaults to mtime.
+	ETag ETagMode `mapstructure:"etag"`
+
+	// Symlinks is the symlinks policy: never, same_root (the real path should be inside the Dir) or always, defaults
+	// to same_root.
+	Symlinks SymlinkPolicy `mapstructure:"symlinks"`
+
+	// Precompressed serves the .br, .zst and .gz files next to the requested one, if accepted by the client.
+	Precompressed bool `mapstructure:"precompressed"`
+
+	// Listings renders the HTML or JSON (Accept: application/json) directory listings. Listing of the directory is
+	// disabled by the .nolisting marker file inside it.
+	Listings bool `mapstructure:"listings"`
+
+	// SPAIndex is the file (relative to the Dir) served for the browser GET requests to the missing paths without an
+	// extension, e.g. index.html for the single page applications.
+	SPAIndex string `mapstructure:"spa_index"`
+
+	// SPAExclude is the list of the path prefixes always passed to the workers, e.g. /api.
+	SPAExclude []string `mapstructure:"spa_exclude"`
+
+	// Cache keeps the small files in memory, disabled if not set.
+	Cache *StaticCache `mapstructure:"cache"`
+
+	// internal
+	FileSystem        fs.FS               `mapstructure:"-"`
+	Forbidden         map[string]struct{} `mapstructure:"-"`
+	Allowed           map[string]struct{} `mapstructure:"-"`
+	ForbiddenPatterns Patterns            `mapstructure:"-"`
+	AllowedPatterns   Patterns            `mapstructure:"-"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (s *Static) InitDefaults() error {
+	if len(s.Forbid) == 0 {
+		s.Forbid = []string{".php", ".htaccess"}
+	}
+
+	if s.ETag == "" {
+		s.ETag = ETagMtime
+	}
+
+	if s.Symlinks == "" {
+		s.Symlinks = SymlinksSameRoot
+	}
+
+	if s.Cache != nil {
+		s.Cache.InitDefaults()
+	}
+
+	s.Forbidden = make(map[string]struct{}, len(s.Forbid))
+	s.Allowed = make(map[string]struct{}, len(s.Allow))
+
+	for i := 0; i < len(s.Forbid); i++ {
+		s.Forbidden[strings.ToLower(s.Forbid[i])] = struct{}{}
+	}
+
+	for i := 0; i < len(s.Allow); i++ {
+		s.Allowed[strings.ToLower(s.Allow[i])] = struct{}{}
+	}
+
+	for k := range s.Forbidden {
+		delete(s.Allowed, k)
+	}
+
+	var err error
+	s.ForbiddenPatterns, err = CompilePatterns(s.ForbidPatterns)
+	if err != nil {
+		return err
+	}
+
+	s.AllowedPatterns, err = CompilePatterns(s.AllowPatterns)
+	return err
+}
+
+// Access returns the access checker of the served files.
+func (s *Static) Access() *Access {
+	return NewAccess(s.Forbidden, s.Allowed, s.ForbiddenPatterns, s.AllowedPatterns)
+}
+
+// Valid validates the configuration.
+func (s *Static) Valid() error {
+	const op = errors.Op("static_validation")
+	roots := 0
+	for _, root := range [...]string{s.Dir, s.Archive, s.FS} {
+		if root != "" {
+			roots++
+		}
+	}
+
+	if roots != 1 {
+		return errors.E(op, errors.Str("exactly one of the static dir, archive or fs should be set"))
+	}
+
+	switch s.ETag {
+	case ETagMtime, ETagHash, ETagOff:
+	default:
+		return errors.E(op, errors.Errorf("unknown etag mode: %s", s.ETag))
+	}
+
+	switch s.Symlinks {
+	case SymlinksNever, SymlinksSameRoot, SymlinksAlways:
+	default:
+		return errors.E(op, errors.Errorf("unknown symlinks policy: %s", s.Symlinks))
+	}
+
+	if s.Cache != nil && (s.Cache.MaxFileSize <= 0 || s.Cache.MaxSize < s.Cache.MaxFileSize) {
+		return errors.E(op, errors.Str("static cache max_size should be greater or equal to max_file_size"))
+	}
+
+	if s.Archive != "" {
+		_, err := os.Stat(s.Archive)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if s.Dir == "" {
+		return nil
+	}
+
+	st, err := os.Stat(s.Dir)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	if !st.IsDir() {
+		return errors.E(op, errors.Errorf("static dir is not a directory: %s", s.Dir))
+	}
+
+	return nil
+}
+
+// StaticCache configures the in-memory cache of the static files. Cached files are served without the stat and open
+// syscalls, changes of the files are visible after the TTL or the purge RPC call.
+type StaticCache struct {
+	// MaxFileSize is the max size of the cached file in bytes, defaults to 64KB.
+	MaxFileSize int64 `mapstructure:"max_file_size"`
+
+	// MaxSize is the max total size of the cached files in bytes, defaults to 64MB. The least recently used files are
+	// evicted first.
+	MaxSize int64 `mapstructure:"max_size"`
+
+	// TTL is the time the file is served from the cache, defaults to 1m.
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (c *StaticCache) InitDefaults() {
+	if c.MaxFileSize == 0 {
+		c.MaxFileSize = 64 * 1024
+	}
+
+	if c.MaxSize == 0 {
+		c.MaxSize = 64 * 1024 * 1024
+	}
+
+	if c.TTL == 0 {
+		c.TTL = time.Minute
+	}
+}
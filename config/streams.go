@@ -0,0 +1,64 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// StreamsMode defines what to do with the stream responses above the limit.
+type StreamsMode string
+
+const (
+	// StreamsReject stops the stream and responds with 503.
+	StreamsReject StreamsMode = "reject"
+	// StreamsBuffer collects the whole stream and sends it as a regular response, the streams above the
+	// max_buffer_size are passed through.
+	StreamsBuffer StreamsMode = "buffer"
+)
+
+// Streams limits the number of the stream responses sent at once.
+type Streams struct {
+	// MaxConcurrent is the max number of the stream responses at once, 0 - unlimited.
+	MaxConcurrent int64 `mapstructure:"max_concurrent"`
+	// Mode for the streams above the limit: reject or buffer, defaults to reject.
+	Mode StreamsMode `mapstructure:"mode"`
+	// MaxBufferSize is the max size of the buffered stream in bytes, the stream is passed through to the client when
+	// exceeded, defaults to 1MB.
+	MaxBufferSize int64 `mapstructure:"max_buffer_size"`
+	// FlushInterval coalesces the stream frames: the written frames are sent to the client at most after the
+	// interval instead of after each frame. 0 - each frame is flushed at once.
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (s *Streams) InitDefaults() error {
+	if s.Mode == "" {
+		s.Mode = StreamsReject
+	}
+
+	if s.MaxBufferSize == 0 {
+		s.MaxBufferSize = 1024 * 1024
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (s *Streams) Valid() error {
+	const op = errors.Op("streams_validation")
+	if s.MaxConcurrent < 0 {
+		return errors.E(op, errors.Str("max_concurrent should not be negative"))
+	}
+
+	if s.FlushInterval < 0 || s.MaxBufferSize < 0 {
+		return errors.E(op, errors.Str("flush_interval and max_buffer_size should not be negative"))
+	}
+
+	switch s.Mode {
+	case StreamsReject, StreamsBuffer:
+		return nil
+	default:
+		return errors.E(op, errors.Errorf("unknown streams mode: %s", s.Mode))
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/pool/pool"
+)
+
+// Supervisor describes worker limits which can be set directly in the `http.pool` section, next to the `num_workers` and
+// `max_jobs` options, without the nested `supervisor` block.
+type Supervisor struct {
+	// WatchTick defines how often to check the state of the workers.
+	WatchTick time.Duration `mapstructure:"watch_tick"`
+	// TTL defines the maximum time for the worker is allowed to live.
+	TTL time.Duration `mapstructure:"ttl"`
+	// IdleTTL defines the maximum duration worker can spend in idle mode. Disabled when 0.
+	IdleTTL time.Duration `mapstructure:"idle_ttl"`
+	// ExecTTL defines maximum lifetime per job.
+	ExecTTL time.Duration `mapstructure:"exec_ttl"`
+	// MaxWorkerMemory limits memory per worker, in megabytes.
+	MaxWorkerMemory uint64 `mapstructure:"max_worker_memory"`
+}
+
+// Empty returns true if no limits were specified.
+func (s *Supervisor) Empty() bool {
+	return s == nil || (s.WatchTick == 0 && s.TTL == 0 && s.IdleTTL == 0 && s.ExecTTL == 0 && s.MaxWorkerMemory == 0)
+}
+
+// Valid validates the limits.
+func (s *Supervisor) Valid() error {
+	const op = errors.Op("supervisor_validation")
+	if s == nil {
+		return nil
+	}
+
+	if s.WatchTick < 0 || s.TTL < 0 || s.IdleTTL < 0 || s.ExecTTL < 0 {
+		return errors.E(op, errors.Str("supervisor durations should not be negative"))
+	}
+
+	return nil
+}
+
+// apply merges the limits into the pool supervisor configuration. Values from the nested `supervisor` block take
+// precedence over the flat ones.
+func (s *Supervisor) apply(cfg *pool.Config) {
+	if s.Empty() {
+		return
+	}
+
+	if cfg.Supervisor == nil {
+		cfg.Supervisor = &pool.SupervisorConfig{}
+	}
+
+	if cfg.Supervisor.WatchTick == 0 {
+		cfg.Supervisor.WatchTick = s.WatchTick
+	}
+
+	if cfg.Supervisor.TTL == 0 {
+		cfg.Supervisor.TTL = s.TTL
+	}
+
+	if cfg.Supervisor.IdleTTL == 0 {
+		cfg.Supervisor.IdleTTL = s.IdleTTL
+	}
+
+	if cfg.Supervisor.ExecTTL == 0 {
+		cfg.Supervisor.ExecTTL = s.ExecTTL
+	}
+
+	if cfg.Supervisor.MaxWorkerMemory == 0 {
+		cfg.Supervisor.MaxWorkerMemory = s.MaxWorkerMemory
+	}
+
+	cfg.Supervisor.InitDefaults()
+}
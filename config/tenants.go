@@ -0,0 +1,104 @@
+package config
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Tenants configures the per-tenant limits on the shared cluster. The tenant is identified by the header value or by
+// the host, the profile IDs are matched case-insensitively. The tenants without the profile share the default
+// profile limits.
+type Tenants struct {
+	// Header contains the tenant ID, the request host (without the port) is used if empty. The header is honored only
+	// for the peers from the trusted_subnets, the requests of the other peers get the default profile.
+	Header string `mapstructure:"header"`
+	// Profiles by the tenant ID.
+	Profiles map[string]*TenantLimits `mapstructure:"profiles"`
+	// Default profile for the unknown tenants, not limited if not set.
+	Default *TenantLimits `mapstructure:"default"`
+}
+
+// TenantLimits is the tenant limits profile, 0 - not limited.
+type TenantLimits struct {
+	// MaxRequestSize is the max request body size in MB, as the max_request_size.
+	MaxRequestSize uint64 `mapstructure:"max_request_size"`
+	// RateLimit is the max number of requests per second.
+	RateLimit float64 `mapstructure:"rate_limit"`
+	// Burst is the max number of requests over the rate limit sent at once, defaults to the rate limit rounded up.
+	Burst int `mapstructure:"burst"`
+	// MaxConcurrency is the max number of the requests handled at once.
+	MaxConcurrency int64 `mapstructure:"max_concurrency"`
+	// UploadQuota is the max size of the uploaded files in bytes per the upload window.
+	UploadQuota int64 `mapstructure:"upload_quota"`
+	// UploadWindow is the upload quota window, defaults to 1h.
+	UploadWindow time.Duration `mapstructure:"upload_window"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (t *Tenants) InitDefaults() error {
+	profiles := make(map[string]*TenantLimits, len(t.Profiles))
+	for id, p := range t.Profiles {
+		if p == nil {
+			p = &TenantLimits{}
+		}
+
+		p.initDefaults()
+		profiles[strings.ToLower(id)] = p
+	}
+	t.Profiles = profiles
+
+	if t.Default != nil {
+		t.Default.initDefaults()
+	}
+
+	return nil
+}
+
+func (l *TenantLimits) initDefaults() {
+	if l.RateLimit > 0 && l.Burst == 0 {
+		l.Burst = int(math.Ceil(l.RateLimit))
+	}
+
+	if l.UploadWindow == 0 {
+		l.UploadWindow = time.Hour
+	}
+}
+
+// Valid validates the configuration.
+func (t *Tenants) Valid() error {
+	const op = errors.Op("tenants_validation")
+	if len(t.Profiles) == 0 && t.Default == nil {
+		return errors.E(op, errors.Str("at least one tenant profile or the default profile should be set"))
+	}
+
+	for id, p := range t.Profiles {
+		err := p.valid()
+		if err != nil {
+			return errors.E(op, errors.Errorf("tenant %s: %v", id, err))
+		}
+	}
+
+	if t.Default != nil {
+		err := t.Default.valid()
+		if err != nil {
+			return errors.E(op, errors.Errorf("default tenant: %v", err))
+		}
+	}
+
+	return nil
+}
+
+func (l *TenantLimits) valid() error {
+	if l.RateLimit < 0 || l.Burst < 0 || l.MaxConcurrency < 0 || l.UploadQuota < 0 {
+		return errors.Str("limits should not be negative")
+	}
+
+	if l.UploadWindow < 0 {
+		return errors.Str("upload_window should be positive")
+	}
+
+	return nil
+}
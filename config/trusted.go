@@ -0,0 +1,56 @@
+package config
+
+import (
+	"net"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// defaultTrustedSubnets are loopback and private networks, every peer of the private network is trusted by default.
+func defaultTrustedSubnets() []string {
+	return []string{
+		"10.0.0.0/8",
+		"127.0.0.0/8",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
+	}
+}
+
+// parseSubnets parses the list of CIDRs.
+func parseSubnets(subnets []string) ([]*net.IPNet, error) {
+	const op = errors.Op("parse_subnets")
+	nets := make([]*net.IPNet, 0, len(subnets))
+	for i := 0; i < len(subnets); i++ {
+		_, n, err := net.ParseCIDR(subnets[i])
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// IsTrusted returns true if the ip belongs to the trusted subnets.
+func (c *Config) IsTrusted(ip string) bool {
+	if len(c.TrustedNets) == 0 {
+		return false
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for i := 0; i < len(c.TrustedNets); i++ {
+		if c.TrustedNets[i].Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 )
 
 // Uploads describes file location and controls access to them.
@@ -16,9 +17,23 @@ type Uploads struct {
 	// Allowed files
 	Allow []string `mapstructure:"allow"`
 
+	// ForbidPatterns specifies list of the glob or regexp (`re:` prefix) patterns of the forbidden file names.
+	// Example: `*.phar`, `re:^\.`.
+	ForbidPatterns []string `mapstructure:"forbid_patterns"`
+
+	// AllowPatterns specifies list of the glob or regexp (`re:` prefix) patterns of the allowed file names.
+	AllowPatterns []string `mapstructure:"allow_patterns"`
+
+	// Lazy writes the uploaded files to the Dir once while the request body is read, instead of parsing them to the
+	// multipart temp files and copying to the Dir. The files are passed to the workers by reference (tmpName) as
+	// usual and read by the worker on demand.
+	Lazy bool `mapstructure:"lazy"`
+
 	// internal
-	Forbidden map[string]struct{} `mapstructure:"-"`
-	Allowed   map[string]struct{} `mapstructure:"-"`
+	Forbidden         map[string]struct{} `mapstructure:"-"`
+	Allowed           map[string]struct{} `mapstructure:"-"`
+	ForbiddenPatterns Patterns            `mapstructure:"-"`
+	AllowedPatterns   Patterns            `mapstructure:"-"`
 }
 
 // InitDefaults sets missing values to their default values.
@@ -35,11 +50,11 @@ func (cfg *Uploads) InitDefaults() error {
 	cfg.Allowed = make(map[string]struct{})
 
 	for i := 0; i < len(cfg.Forbid); i++ {
-		cfg.Forbidden[cfg.Forbid[i]] = struct{}{}
+		cfg.Forbidden[strings.ToLower(cfg.Forbid[i])] = struct{}{}
 	}
 
 	for i := 0; i < len(cfg.Allow); i++ {
-		cfg.Allowed[cfg.Allow[i]] = struct{}{}
+		cfg.Allowed[strings.ToLower(cfg.Allow[i])] = struct{}{}
 	}
 
 	for k := range cfg.Forbidden {
@@ -49,5 +64,17 @@ func (cfg *Uploads) InitDefaults() error {
 	cfg.Forbid = nil
 	cfg.Allow = nil
 
-	return nil
+	var err error
+	cfg.ForbiddenPatterns, err = CompilePatterns(cfg.ForbidPatterns)
+	if err != nil {
+		return err
+	}
+
+	cfg.AllowedPatterns, err = CompilePatterns(cfg.AllowPatterns)
+	return err
+}
+
+// Access returns the access checker of the uploaded file names.
+func (cfg *Uploads) Access() *Access {
+	return NewAccess(cfg.Forbidden, cfg.Allowed, cfg.ForbiddenPatterns, cfg.AllowedPatterns)
 }
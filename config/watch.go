@@ -0,0 +1,62 @@
+package config
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// Watch configures the development watch mode: the worker pool is reset gracefully when the matching files under the
+// Dir change (created, modified or deleted). The files are polled, so the mode works on the mounted volumes as well.
+type Watch struct {
+	// Dir to watch, defaults to the working directory.
+	Dir string `mapstructure:"dir"`
+	// Patterns of the watched files relative to the Dir, defaults to **/*.php (CompilePatterns syntax).
+	Patterns []string `mapstructure:"patterns"`
+	// Ignore patterns, defaults to vendor/** and **/.git/**.
+	Ignore []string `mapstructure:"ignore"`
+	// Interval between the polls, defaults to 1s.
+	Interval time.Duration `mapstructure:"interval"`
+
+	// internal
+	WatchedPatterns Patterns `mapstructure:"-"`
+	IgnoredPatterns Patterns `mapstructure:"-"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (w *Watch) InitDefaults() error {
+	if w.Dir == "" {
+		w.Dir = "."
+	}
+
+	if len(w.Patterns) == 0 {
+		w.Patterns = []string{"**/*.php"}
+	}
+
+	if w.Ignore == nil {
+		w.Ignore = []string{"vendor/**", "**/.git/**"}
+	}
+
+	if w.Interval == 0 {
+		w.Interval = time.Second
+	}
+
+	var err error
+	w.WatchedPatterns, err = CompilePatterns(w.Patterns)
+	if err != nil {
+		return err
+	}
+
+	w.IgnoredPatterns, err = CompilePatterns(w.Ignore)
+	return err
+}
+
+// Valid validates the configuration.
+func (w *Watch) Valid() error {
+	const op = errors.Op("watch_validation")
+	if w.Interval < 0 {
+		return errors.E(op, errors.Str("interval should be positive"))
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// XSendfile enables the X-Sendfile worker responses: the file from the header path is sent instead of the response
+// body. Only the files under the Root are sent, the relative paths are resolved against it. Without the section the
+// header is passed to the middleware (e.g. the sendfile plugin).
+type XSendfile struct {
+	// Root is the directory with the files the workers are allowed to send, required.
+	Root string `mapstructure:"root"`
+}
+
+// InitDefaults sets missing values to their default values.
+func (x *XSendfile) InitDefaults() error {
+	if x.Root != "" {
+		x.Root = filepath.Clean(x.Root)
+	}
+
+	return nil
+}
+
+// Valid validates the configuration.
+func (x *XSendfile) Valid() error {
+	const op = errors.Op("x_sendfile_validation")
+	if x.Root == "" {
+		return errors.E(op, errors.Str("x_sendfile root should be set"))
+	}
+
+	st, err := os.Stat(x.Root)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	if !st.IsDir() {
+		return errors.E(op, errors.Errorf("x_sendfile root %s is not a directory", x.Root))
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDrainTimeoutKill(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	cfg := &config.Config{
+		Uploads:            &config.Uploads{},
+		InternalErrorCode:  500,
+		CancelOnDisconnect: true,
+		DrainTimeout:       100 * time.Millisecond,
+		Relay:              &config.PoolRelay{Relay: config.RelayPipes},
+		Pool:               &pool.Config{NumWorkers: 1, AllocateTimeout: time.Second, DestroyTimeout: time.Second},
+	}
+	p := &Plugin{log: zap.New(core), cfg: cfg, server: &testServer{t: t}, stderr: handler.NewStderr()}
+
+	require.NoError(t, p.initRelay())
+	pl, err := p.newPool(cfg.Pool)
+	require.NoError(t, err)
+	defer pl.Destroy(context.Background())
+
+	h, err := handler.NewHandler(cfg, pl, p.log)
+	require.NoError(t, err)
+	h.CaptureStderr(p.stderr)
+
+	pid := pl.Workers()[0].Pid()
+
+	// the client is gone after the first frame, the worker ignores the stop
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start := time.Now()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream-hang", nil).WithContext(ctx))
+	assert.Less(t, time.Since(start), time.Second)
+
+	entries := logs.FilterMessage("stream drain timeout, the rest of the stream is discarded").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, pid, entries[0].ContextMap()["killed"])
+
+	// the killed worker is replaced
+	require.Eventually(t, func() bool {
+		workers := pl.Workers()
+		return len(workers) == 1 && workers[0].Pid() != pid
+	}, 5*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+const (
+	// number of the samples averaged before the long-term latency is smoothed
+	gradientWarmup = 10
+	// long-term latency window, in samples
+	gradientWindow = 600
+)
+
+// concurrencyLimiter computes the dispatch concurrency limit from the dispatch latency samples.
+type concurrencyLimiter struct {
+	mu        sync.Mutex
+	algorithm config.ConcurrencyAlgorithm
+	limit     float64
+	min       float64
+	max       float64
+
+	// gradient
+	tolerance float64
+	smoothing float64
+	longRTT   float64
+	samples   int
+
+	// aimd
+	threshold time.Duration
+	backoff   float64
+}
+
+func newConcurrencyLimiter(cfg *config.AdaptiveConcurrency) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		algorithm: cfg.Algorithm,
+		limit:     float64(cfg.InitialLimit),
+		min:       float64(cfg.MinLimit),
+		max:       float64(cfg.MaxLimit),
+		tolerance: cfg.Tolerance,
+		smoothing: cfg.Smoothing,
+		threshold: cfg.LatencyThreshold,
+		backoff:   cfg.BackoffRatio,
+	}
+}
+
+// sample updates the limit with the dispatch latency, inFlight is the number of the dispatched requests including
+// the sampled one, dropped is true when the pool had no free worker in time. Returns the new limit.
+func (l *concurrencyLimiter) sample(rtt time.Duration, inFlight int64, dropped bool) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch l.algorithm {
+	case config.ConcurrencyAIMD:
+		l.aimd(rtt, inFlight, dropped)
+	default:
+		l.gradient(rtt, inFlight, dropped)
+	}
+
+	return int64(l.limit)
+}
+
+func (l *concurrencyLimiter) aimd(rtt time.Duration, inFlight int64, dropped bool) {
+	switch {
+	case dropped || rtt > l.threshold:
+		l.limit = math.Max(l.min, math.Floor(l.limit*l.backoff))
+	// the limit is increased only when it's used
+	case float64(inFlight)*2 >= l.limit:
+		l.limit = math.Min(l.max, l.limit+1)
+	}
+}
+
+func (l *concurrencyLimiter) gradient(rtt time.Duration, inFlight int64, dropped bool) {
+	if dropped {
+		l.limit = math.Max(l.min, l.limit/2)
+		return
+	}
+
+	short := float64(rtt)
+	if short <= 0 {
+		return
+	}
+
+	if l.samples < gradientWarmup {
+		l.samples++
+		l.longRTT += (short - l.longRTT) / float64(l.samples)
+	} else {
+		l.longRTT += (short - l.longRTT) / gradientWindow
+	}
+
+	// the long-term latency follows the recovery faster, the limit would not grow for the whole window otherwise
+	if l.longRTT > short*2 {
+		l.longRTT *= 0.95
+	}
+
+	// the app-limited samples do not show where the knee is
+	if float64(inFlight) < l.limit/2 {
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1, l.tolerance*l.longRTT/short))
+	// the square root of the limit is the allowed queue, so the limit grows while the latency is stable
+	next := l.limit*gradient + math.Sqrt(l.limit)
+	next = l.limit*(1-l.smoothing) + next*l.smoothing
+	l.limit = math.Max(l.min, math.Min(l.max, next))
+}
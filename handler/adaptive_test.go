@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiterAIMD(t *testing.T) {
+	l := newConcurrencyLimiter(&config.AdaptiveConcurrency{
+		Algorithm:        config.ConcurrencyAIMD,
+		InitialLimit:     10,
+		MinLimit:         1,
+		MaxLimit:         12,
+		LatencyThreshold: time.Millisecond * 100,
+		BackoffRatio:     0.5,
+	})
+
+	// not used enough
+	assert.Equal(t, int64(10), l.sample(time.Millisecond, 2, false))
+	assert.Equal(t, int64(11), l.sample(time.Millisecond, 5, false))
+	assert.Equal(t, int64(12), l.sample(time.Millisecond, 11, false))
+	assert.Equal(t, int64(12), l.sample(time.Millisecond, 12, false))
+
+	assert.Equal(t, int64(6), l.sample(time.Second, 12, false))
+	assert.Equal(t, int64(3), l.sample(time.Millisecond, 6, true))
+	assert.Equal(t, int64(1), l.sample(time.Second, 1, false))
+	assert.Equal(t, int64(1), l.sample(time.Second, 1, false))
+}
+
+func TestConcurrencyLimiterGradient(t *testing.T) {
+	l := newConcurrencyLimiter(&config.AdaptiveConcurrency{
+		Algorithm:    config.ConcurrencyGradient,
+		InitialLimit: 10,
+		MinLimit:     1,
+		MaxLimit:     100,
+		Tolerance:    1.5,
+		Smoothing:    0.2,
+	})
+
+	var limit int64
+	for range 50 {
+		limit = l.sample(time.Millisecond*10, int64(l.limit), false)
+	}
+	assert.Greater(t, limit, int64(10))
+
+	grown := limit
+	for range 50 {
+		limit = l.sample(time.Millisecond*100, int64(l.limit), false)
+	}
+	assert.Less(t, limit, grown)
+
+	assert.Equal(t, limit/2, l.sample(time.Millisecond, limit, true))
+}
+
+func TestPriorityGateResize(t *testing.T) {
+	g := newPriorityGate(1)
+	require.NoError(t, g.acquire(context.Background(), 0))
+
+	acquired := make(chan struct{}, 2)
+	for range 2 {
+		go func() {
+			assert.NoError(t, g.acquire(context.Background(), 0))
+			acquired <- struct{}{}
+		}()
+	}
+	time.Sleep(time.Millisecond * 20)
+
+	g.resize(3)
+	<-acquired
+	<-acquired
+	assert.Equal(t, int64(3), g.inFlight())
+
+	// the slots above the capacity are not passed to the waiters
+	g.resize(1)
+	g.release()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.Error(t, g.acquire(ctx, 0))
+}
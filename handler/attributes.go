@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+// attributePatterns matches the attribute names exactly or by the prefix
+type attributePatterns struct {
+	names    map[string]struct{}
+	prefixes []string
+}
+
+func newAttributePatterns(list []string) *attributePatterns {
+	if len(list) == 0 {
+		return nil
+	}
+
+	p := &attributePatterns{names: make(map[string]struct{}, len(list))}
+	for i := 0; i < len(list); i++ {
+		if prefix, ok := strings.CutSuffix(list[i], "*"); ok {
+			p.prefixes = append(p.prefixes, prefix)
+			continue
+		}
+
+		p.names[list[i]] = struct{}{}
+	}
+
+	return p
+}
+
+func (p *attributePatterns) match(name string) bool {
+	if _, ok := p.names[name]; ok {
+		return true
+	}
+
+	for i := 0; i < len(p.prefixes); i++ {
+		if strings.HasPrefix(name, p.prefixes[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// attributePolicy filters the middleware attributes sent to the workers.
+type attributePolicy struct {
+	allow *attributePatterns
+	deny  *attributePatterns
+}
+
+func newAttributePolicy(cfg *config.Attributes) *attributePolicy {
+	return &attributePolicy{
+		allow: newAttributePatterns(cfg.Allow),
+		deny:  newAttributePatterns(cfg.Deny),
+	}
+}
+
+func (a *attributePolicy) forwarded(name string) bool {
+	if a.allow != nil && !a.allow.match(name) {
+		return false
+	}
+
+	return a.deny == nil || !a.deny.match(name)
+}
+
+// filter returns the forwarded attributes. The attributes map belongs to the request context, so a new map is
+// returned instead of deleting the keys.
+func (a *attributePolicy) filter(attrs map[string][]string) map[string][]string {
+	if len(attrs) == 0 {
+		return attrs
+	}
+
+	forwarded := make(map[string][]string, len(attrs))
+	for k, v := range attrs {
+		if a.forwarded(k) {
+			forwarded[k] = v
+		}
+	}
+
+	return forwarded
+}
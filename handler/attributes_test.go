@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributePolicy(t *testing.T) {
+	attrs := map[string][]string{
+		"tls_version":    {"TLS 1.3"},
+		"tls_cipher":     {"TLS_AES_128_GCM_SHA256"},
+		"ja3":            {"hash"},
+		"internal_token": {"secret"},
+		"user_id":        {"42"},
+	}
+
+	p := newAttributePolicy(&config.Attributes{Allow: []string{"tls_*", "user_id", "internal_token"}, Deny: []string{"internal_*", "tls_cipher"}})
+	assert.Equal(t, map[string][]string{
+		"tls_version": {"TLS 1.3"},
+		"user_id":     {"42"},
+	}, p.filter(attrs))
+	// the context attributes are not modified
+	assert.Len(t, attrs, 5)
+
+	p = newAttributePolicy(&config.Attributes{Deny: []string{"ja3"}})
+	assert.Len(t, p.filter(attrs), 4)
+	assert.Nil(t, p.filter(nil))
+}
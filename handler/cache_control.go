@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+const (
+	cacheControlHeader = "Cache-Control"
+	expiresHeader      = "Expires"
+)
+
+// cacheControl applies the configured cache headers.
+type cacheControl struct {
+	rules   []*config.CacheControlRule
+	workers bool
+}
+
+// match returns the first rule matching the request path, nil if none.
+func (c *cacheControl) match(uri string) *config.CacheControlRule {
+	fp := path.Clean("/" + uri)
+	ext := strings.ToLower(path.Ext(fp))
+	for i := 0; i < len(c.rules); i++ {
+		for j := 0; j < len(c.rules[i].Extensions); j++ {
+			if ext != "" && c.rules[i].Extensions[j] == ext {
+				return c.rules[i]
+			}
+		}
+
+		for j := 0; j < len(c.rules[i].Paths); j++ {
+			if matchPath(c.rules[i].Paths[j], fp) {
+				return c.rules[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchPath matches the path against the glob, the `/**` suffix matches everything under the prefix.
+func matchPath(pattern, fp string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		// match the prefix against the same number of the path segments
+		n := strings.Count(prefix, "/")
+		parts := strings.SplitAfterN(fp, "/", n+2)
+		if len(parts) <= n+1 {
+			return false
+		}
+
+		ok, _ = path.Match(prefix, strings.TrimSuffix(strings.Join(parts[:n+1], ""), "/"))
+		return ok
+	}
+
+	ok, _ := path.Match(pattern, fp)
+	return ok
+}
+
+// setCacheHeaders sets the cache headers of the rule.
+func setCacheHeaders(h http.Header, rule *config.CacheControlRule) {
+	if rule.Value != "" {
+		h.Set(cacheControlHeader, rule.Value)
+	}
+
+	if rule.Expires > 0 {
+		h.Set(expiresHeader, time.Now().Add(rule.Expires).UTC().Format(http.TimeFormat))
+	}
+}
+
+// delCacheHeaders removes the headers set by the rule.
+func delCacheHeaders(h http.Header) {
+	h.Del(cacheControlHeader)
+	h.Del(expiresHeader)
+}
+
+// hasCacheHeaders returns true if the worker set the cache headers itself.
+func hasCacheHeaders(h http.Header) bool {
+	return h.Get(cacheControlHeader) != "" || h.Get(expiresHeader) != ""
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheControlMatch(t *testing.T) {
+	assets := &config.CacheControlRule{Paths: []string{"/assets/**"}, Value: "public, max-age=31536000, immutable"}
+	css := &config.CacheControlRule{Extensions: []string{".css"}, Expires: time.Hour}
+	docs := &config.CacheControlRule{Paths: []string{"/docs/*.html"}, Value: "no-cache"}
+	cc := &cacheControl{rules: []*config.CacheControlRule{assets, css, docs}}
+
+	tests := map[string]*config.CacheControlRule{
+		"/assets/app.js":       assets,
+		"/assets/img/logo.png": assets,
+		"/assets/../app.CSS":   css,
+		"/docs/index.html":     docs,
+		"/docs/api/index.html": nil,
+		"/assets":              nil,
+		"/assetsx/app.js":      nil,
+		"/index.php":           nil,
+	}
+
+	for uri, rule := range tests {
+		assert.Same(t, rule, cc.match(uri), uri)
+	}
+
+	h := http.Header{}
+	setCacheHeaders(h, css)
+	assert.Empty(t, h.Get(cacheControlHeader))
+	exp, err := http.ParseTime(h.Get(expiresHeader))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), exp, time.Minute)
+	assert.True(t, hasCacheHeaders(h))
+
+	delCacheHeaders(h)
+	assert.False(t, hasCacheHeaders(h))
+}
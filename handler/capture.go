@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	"go.uber.org/zap"
+)
+
+// redacted is the value of the redacted header in the captured request
+const redacted = "[redacted]"
+
+// capturedRequest is the disk format of the captured request.
+type capturedRequest struct {
+	Time       time.Time     `json:"time"`
+	Duration   time.Duration `json:"duration"`
+	Status     int           `json:"status"`
+	Method     string        `json:"method"`
+	URI        string        `json:"uri"`
+	Host       string        `json:"host"`
+	Proto      string        `json:"proto"`
+	TLS        bool          `json:"tls"`
+	RemoteAddr string        `json:"remote_addr"`
+	Header     http.Header   `json:"header"`
+	Body       []byte        `json:"body,omitempty"`
+}
+
+// request builds the request to be replayed.
+func (c *capturedRequest) request(ctx context.Context) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, c.Method, c.URI, bytes.NewReader(c.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	r.RequestURI = c.URI
+	r.Host = c.Host
+	r.RemoteAddr = c.RemoteAddr
+	if c.Header != nil {
+		r.Header = c.Header
+		// the redacted values are not sent to the worker
+		for name, values := range r.Header {
+			if len(values) == 1 && values[0] == redacted {
+				delete(r.Header, name)
+			}
+		}
+	}
+
+	if major, minor, ok := http.ParseHTTPVersion(c.Proto); ok {
+		r.Proto, r.ProtoMajor, r.ProtoMinor = c.Proto, major, minor
+	}
+
+	if c.TLS {
+		r.TLS = &tls.ConnectionState{}
+	}
+
+	return r, nil
+}
+
+// captureBody records the request body while it is read by the handler.
+type captureBody struct {
+	io.ReadCloser
+	buf      bytes.Buffer
+	limit    int64
+	overflow bool
+	eof      bool
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.overflow {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.overflow = true
+			b.buf.Reset()
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+
+	if err == io.EOF {
+		b.eof = true
+	}
+
+	return n, err
+}
+
+// capture records the matching requests to the disk.
+type capture struct {
+	dir      string
+	methods  []string
+	paths    []string
+	statuses []int
+	maxBody  int64
+	maxFiles int64
+	redact   []string
+	log      *zap.Logger
+
+	files atomic.Int64
+	seq   atomic.Uint64
+}
+
+func newCapture(cfg *config.Capture, log *zap.Logger) *capture {
+	return &capture{
+		dir:      cfg.Dir,
+		methods:  cfg.Methods,
+		paths:    cfg.Paths,
+		statuses: cfg.Statuses,
+		maxBody:  cfg.MaxBodySize,
+		maxFiles: cfg.MaxFiles,
+		redact:   cfg.Redact,
+		log:      log,
+	}
+}
+
+// match checks the request filters, the response status is checked after the request is handled.
+func (c *capture) match(r *http.Request) bool {
+	if c.maxFiles > 0 && c.files.Load() >= c.maxFiles {
+		return false
+	}
+
+	if len(c.methods) > 0 && !slices.Contains(c.methods, r.Method) {
+		return false
+	}
+
+	if len(c.paths) == 0 {
+		return true
+	}
+
+	fp := path.Clean("/" + r.URL.Path)
+	for i := 0; i < len(c.paths); i++ {
+		if matchPath(c.paths[i], fp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wrap starts the capture of the request, the returned function writes the captured request after the response is
+// sent.
+func (c *capture) wrap(w http.ResponseWriter, r *http.Request, start time.Time) (http.ResponseWriter, func()) {
+	rec := &capturedRequest{
+		Time:       start,
+		Method:     r.Method,
+		URI:        r.RequestURI,
+		Host:       r.Host,
+		Proto:      r.Proto,
+		TLS:        r.TLS != nil,
+		RemoteAddr: r.RemoteAddr,
+		// the handler might modify the headers
+		Header: r.Header.Clone(),
+	}
+
+	for i := 0; i < len(c.redact); i++ {
+		if _, ok := rec.Header[c.redact[i]]; ok {
+			rec.Header[c.redact[i]] = []string{redacted}
+		}
+	}
+
+	body := &captureBody{ReadCloser: r.Body, limit: c.maxBody}
+	r.Body = body
+
+	// only the status is recorded
+	cw := newCaptureWriter(w, 0)
+
+	return cw, func() {
+		// read the rest of the body not needed by the handler, e.g. without the content type
+		if !body.eof && !body.overflow && int64(body.buf.Len()) != r.ContentLength {
+			_, _ = io.Copy(io.Discard, io.LimitReader(body, c.maxBody+1))
+		}
+		r.Body = body.ReadCloser
+
+		// the body is too large or was not read completely, the request can't be replayed
+		if body.overflow || (!body.eof && int64(body.buf.Len()) != r.ContentLength) {
+			return
+		}
+
+		rec.Status = cw.status
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+
+		if len(c.statuses) > 0 && !slices.Contains(c.statuses, rec.Status) {
+			return
+		}
+
+		if c.maxFiles > 0 && c.files.Add(1) > c.maxFiles {
+			return
+		}
+
+		rec.Duration = time.Since(start)
+		rec.Body = body.buf.Bytes()
+		c.write(rec)
+	}
+}
+
+func (c *capture) write(rec *capturedRequest) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		c.log.Warn("failed to encode the captured request", zap.Error(err))
+		return
+	}
+
+	// the names are sorted in the capture order
+	name := fmt.Sprintf("%019d-%d.json", rec.Time.UnixNano(), c.seq.Add(1))
+	err = os.WriteFile(filepath.Join(c.dir, name), data, 0o600)
+	if err != nil {
+		c.log.Warn("failed to write the captured request", zap.String("file", name), zap.Error(err))
+		return
+	}
+
+	c.log.Debug("request captured", zap.String("file", name), zap.String("uri", rec.URI), zap.Int("status", rec.Status))
+}
+
+// names returns the captured files in the capture order.
+func (c *capture) names() ([]string, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for i := 0; i < len(entries); i++ {
+		if entries[i].Type().IsRegular() && strings.HasSuffix(entries[i].Name(), ".json") {
+			names = append(names, entries[i].Name())
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// read loads the captured request, the name is resolved inside the capture directory.
+func (c *capture) read(name string) (*capturedRequest, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, filepath.Base(name)))
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &capturedRequest{}
+	err = json.Unmarshal(data, rec)
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// Replay sends the captured requests to the workers, all the captured requests are replayed when no names are
+// provided. Returns the response statuses by the file names.
+func (h *Handler) Replay(names []string) (map[string]int, error) {
+	const op = errors.Op("http_replay")
+	if h.capture == nil {
+		return nil, errors.E(op, errors.Str("capture is not enabled"))
+	}
+
+	if len(names) == 0 {
+		var err error
+		names, err = h.capture.names()
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+
+	statuses := make(map[string]int, len(names))
+	for i := 0; i < len(names); i++ {
+		rec, err := h.capture.read(names[i])
+		if err != nil {
+			return statuses, errors.E(op, err)
+		}
+
+		r, err := rec.request(h.internalCtx)
+		if err != nil {
+			return statuses, errors.E(op, err)
+		}
+
+		// the response is discarded, the replayed requests are not captured again
+		dw := &discardWriter{header: http.Header{}}
+		h.dispatch(dw, r, nil, time.Now())
+		if dw.status == 0 {
+			dw.status = http.StatusOK
+		}
+
+		statuses[names[i]] = dw.status
+	}
+
+	return statuses, nil
+}
+
+// discardWriter records the status of the replayed response and discards the body.
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (d *discardWriter) Header() http.Header {
+	return d.header
+}
+
+func (d *discardWriter) WriteHeader(code int) {
+	if code >= 200 && d.status == 0 {
+		d.status = code
+	}
+}
+
+func (d *discardWriter) Write(p []byte) (int, error) {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/payload"
+	staticPool "github.com/roadrunner-server/pool/pool/static_pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type replayPool struct {
+	common.Pool
+	bodies []string
+}
+
+func (p *replayPool) Exec(_ context.Context, pld *payload.Payload, _ chan struct{}) (chan *staticPool.PExec, error) {
+	p.bodies = append(p.bodies, string(pld.Body))
+	return nil, errors.E(errors.NoFreeWorkers)
+}
+
+func TestCapture(t *testing.T) {
+	cfg := &config.Capture{Dir: t.TempDir(), Methods: []string{"post"}, Paths: []string{"/api/**"}, MaxBodySize: 8, MaxFiles: 2}
+	require.NoError(t, cfg.InitDefaults())
+	require.NoError(t, cfg.Valid())
+
+	pool := &replayPool{}
+	h, err := NewHandler(&config.Config{Uploads: &config.Uploads{}, Capture: cfg, InternalErrorCode: 500, RawBody: true}, pool, zap.NewNop())
+	require.NoError(t, err)
+
+	serve := func(method, uri, body string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, uri, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Header.Set("Cookie", "session=secret")
+		r.Header.Set("Accept", "text/plain")
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, 500, serve(http.MethodPost, "/api/users?id=1", "payload"))
+	// not matching the filters or the body is too large
+	serve(http.MethodGet, "/api/users", "")
+	serve(http.MethodPost, "/users", "payload")
+	serve(http.MethodPost, "/api/users", "too large payload")
+
+	names, err := h.capture.names()
+	require.NoError(t, err)
+	require.Len(t, names, 1)
+
+	rec, err := h.capture.read(names[0])
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, rec.Method)
+	assert.Equal(t, "/api/users?id=1", rec.URI)
+	assert.Equal(t, "payload", string(rec.Body))
+	assert.Equal(t, 500, rec.Status)
+	assert.Equal(t, []string{"[redacted]"}, rec.Header["Authorization"])
+	assert.Equal(t, []string{"[redacted]"}, rec.Header["Cookie"])
+	assert.Equal(t, "text/plain", rec.Header.Get("Accept"))
+
+	// the files limit
+	serve(http.MethodPost, "/api/a", "")
+	serve(http.MethodPost, "/api/b", "")
+	names, err = h.capture.names()
+	require.NoError(t, err)
+	assert.Len(t, names, 2)
+
+	pool.bodies = nil
+	statuses, err := h.Replay([]string{names[0]})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{names[0]: 500}, statuses)
+	assert.Equal(t, []string{"payload"}, pool.bodies)
+
+	// the replayed requests are not captured again
+	names, err = h.capture.names()
+	require.NoError(t, err)
+	assert.Len(t, names, 2)
+
+	_, err = h.Replay([]string{"missing.json"})
+	assert.Error(t, err)
+
+	r, err := rec.request(context.Background())
+	require.NoError(t, err)
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(body))
+	assert.Equal(t, "example.com", r.Host)
+	// the redacted headers are not replayed
+	assert.Empty(t, r.Header.Get("Authorization"))
+	assert.Empty(t, r.Header.Get("Cookie"))
+	assert.Equal(t, "text/plain", r.Header.Get("Accept"))
+
+	require.NoError(t, os.RemoveAll(cfg.Dir))
+	_, err = h.Replay(nil)
+	assert.Error(t, err)
+}
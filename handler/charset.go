@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+const contentTypeHeader string = "Content-Type"
+
+// setCharset appends the charset to the text Content-Type header without one. Existing charset parameters are kept
+// as is.
+func setCharset(header http.Header, charset string) {
+	ct := header.Get(contentTypeHeader)
+	if ct == "" || !textContent(ct) || strings.Contains(strings.ToLower(ct), "charset=") {
+		return
+	}
+
+	header.Set(contentTypeHeader, ct+"; charset="+charset)
+}
+
+// textContent returns true for the media types which could have a charset parameter.
+func textContent(ct string) bool {
+	mt, _, _ := strings.Cut(ct, ";")
+	mt = strings.ToLower(strings.TrimSpace(mt))
+
+	switch {
+	case strings.HasPrefix(mt, "text/"):
+		return true
+	case mt == "application/json", mt == "application/javascript", mt == "application/xml", strings.HasSuffix(mt, "+xml"):
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetCharset(t *testing.T) {
+	tests := map[string]string{
+		"text/html":                       "text/html; charset=ISO-8859-1",
+		"text/html; charset=windows-1251": "text/html; charset=windows-1251",
+		"text/plain;Charset=utf-8":        "text/plain;Charset=utf-8",
+		"application/atom+xml":            "application/atom+xml; charset=ISO-8859-1",
+		"image/png":                       "image/png",
+		"":                                "",
+	}
+
+	for ct, want := range tests {
+		header := http.Header{}
+		if ct != "" {
+			header.Set(contentTypeHeader, ct)
+		}
+
+		setCharset(header, "ISO-8859-1")
+		assert.Equal(t, want, header.Get(contentTypeHeader), ct)
+	}
+}
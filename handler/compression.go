@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/payload"
+)
+
+const (
+	// BodyZstd is the frame flag of the request payload with the compressible body, set by the handler. The worker
+	// relay compresses the body if the worker negotiated the zstd encoding and clears the flag otherwise, the worker
+	// receives the flag with the zstd compressed body only. The flag bit is not used by the goridge frames.
+	BodyZstd byte = 0x02
+	// BodyEncodingHeader is set by the worker when the response body is compressed, it is not sent to the client.
+	BodyEncodingHeader string = "X-Rr-Body-Encoding"
+	// EncodingZstd is the encoding negotiated with the workers.
+	EncodingZstd string = "zstd"
+)
+
+// payloadCodec selects the request bodies compressed for the workers and decompresses the response bodies.
+type payloadCodec struct {
+	minSize      int
+	contentTypes *attributePatterns
+	paths        *attributePatterns
+	dec          *zstd.Decoder
+}
+
+func newPayloadCodec(cfg *config.PayloadCompression) (*payloadCodec, error) {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payloadCodec{
+		minSize:      cfg.MinSize,
+		contentTypes: newAttributePatterns(cfg.ExcludeContentTypes),
+		paths:        newAttributePatterns(cfg.ExcludePaths),
+		dec:          dec,
+	}, nil
+}
+
+// compress marks the payload body to be compressed by the worker relay, the body is compressed for the workers which
+// negotiated the encoding only.
+func (c *payloadCodec) compress(p *payload.Payload, r *http.Request) {
+	if len(p.Body) < c.minSize || c.excluded(r) {
+		return
+	}
+
+	p.Codec |= BodyZstd
+}
+
+// excluded reports whether the request body is sent as is by the content type or the path
+func (c *payloadCodec) excluded(r *http.Request) bool {
+	if c.paths != nil && c.paths.match(r.URL.Path) {
+		return true
+	}
+
+	if c.contentTypes == nil {
+		return false
+	}
+
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return c.contentTypes.match(strings.ToLower(mt))
+}
+
+// decompress returns the decompressed response body if the response is marked as compressed.
+func (c *payloadCodec) decompress(headers map[string]*httpV1proto.HeaderValue, body []byte) ([]byte, error) {
+	enc := headers[BodyEncodingHeader]
+	if enc == nil {
+		return body, nil
+	}
+
+	delete(headers, BodyEncodingHeader)
+	if len(enc.GetValue()) == 0 || enc.GetValue()[0] != EncodingZstd || len(body) == 0 {
+		return body, nil
+	}
+
+	return c.dec.DecodeAll(body, nil)
+}
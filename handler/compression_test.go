@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadCodecExclusions(t *testing.T) {
+	cfg := &config.PayloadCompression{ExcludePaths: []string{"/upload/*", "/raw"}}
+	require.NoError(t, cfg.InitDefaults())
+	require.NoError(t, cfg.Valid())
+
+	c, err := newPayloadCodec(cfg)
+	require.NoError(t, err)
+
+	body := bytes.Repeat([]byte("payload"), 1024)
+	compressed := func(path, contentType string, size int) bool {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.Header.Set("Content-Type", contentType)
+		p := &payload.Payload{Codec: frame.CodecProto, Body: body[:size]}
+		c.compress(p, r)
+
+		return p.Codec&BodyZstd != 0
+	}
+
+	assert.True(t, compressed("/api", "application/json", len(body)))
+	assert.False(t, compressed("/api", "application/json", 100))
+	assert.False(t, compressed("/api", "image/PNG", len(body)))
+	assert.False(t, compressed("/api", "video/mp4; codecs=avc1", len(body)))
+	assert.True(t, compressed("/api", "image/svg+xml", len(body)))
+	assert.False(t, compressed("/upload/avatar", "application/json", len(body)))
+	assert.False(t, compressed("/raw", "application/json", len(body)))
+	assert.True(t, compressed("/raw/data", "application/json", len(body)))
+
+	cfg = &config.PayloadCompression{ExcludePaths: []string{"upload"}}
+	require.NoError(t, cfg.InitDefaults())
+	assert.Error(t, cfg.Valid())
+}
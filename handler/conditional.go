@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// notModified returns true if the response validators (ETag, Last-Modified) match the conditional request headers
+// (If-None-Match, If-Modified-Since) of the GET or HEAD request, RFC 9110 13.1.2 and 13.1.3.
+func notModified(r *http.Request, h http.Header) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	// If-Modified-Since is ignored when If-None-Match is present
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := h.Get("ETag")
+		return etag != "" && etagMatch(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	lm := h.Get("Last-Modified")
+	if ims == "" || lm == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	modified, err := http.ParseTime(lm)
+	if err != nil {
+		return false
+	}
+
+	return !modified.After(since)
+}
+
+// etagMatch performs the weak comparison of the ETag with the If-None-Match list.
+func etagMatch(inm, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(inm, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// toNotModified removes the representation headers which are not sent with the 304 response.
+func toNotModified(h http.Header) {
+	h.Del("Content-Type")
+	h.Del(contentLength)
+	h.Del("Content-Encoding")
+	if h.Get("ETag") != "" {
+		h.Del("Last-Modified")
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotModified(t *testing.T) {
+	const lm = "Wed, 21 Oct 2015 07:28:00 GMT"
+
+	tests := []struct {
+		name    string
+		method  string
+		request map[string]string
+		resp    map[string]string
+		want    bool
+	}{
+		{"etag", http.MethodGet, map[string]string{"If-None-Match": `"a", "b"`}, map[string]string{"ETag": `"b"`}, true},
+		{"weak etag", http.MethodHead, map[string]string{"If-None-Match": `W/"b"`}, map[string]string{"ETag": `"b"`}, true},
+		{"any", http.MethodGet, map[string]string{"If-None-Match": "*"}, map[string]string{"ETag": `"b"`}, true},
+		{"etag mismatch", http.MethodGet, map[string]string{"If-None-Match": `"a"`}, map[string]string{"ETag": `"b"`}, false},
+		{"no etag", http.MethodGet, map[string]string{"If-None-Match": `"a"`}, map[string]string{"Last-Modified": lm}, false},
+		{"if-none-match wins", http.MethodGet, map[string]string{"If-None-Match": `"a"`, "If-Modified-Since": lm}, map[string]string{"ETag": `"b"`, "Last-Modified": lm}, false},
+		{"not modified since", http.MethodGet, map[string]string{"If-Modified-Since": lm}, map[string]string{"Last-Modified": lm}, true},
+		{"modified since", http.MethodGet, map[string]string{"If-Modified-Since": "Tue, 20 Oct 2015 07:28:00 GMT"}, map[string]string{"Last-Modified": lm}, false},
+		{"post", http.MethodPost, map[string]string{"If-None-Match": `"b"`}, map[string]string{"ETag": `"b"`}, false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/", nil)
+		for k, v := range tt.request {
+			r.Header.Set(k, v)
+		}
+
+		h := http.Header{}
+		for k, v := range tt.resp {
+			h.Set(k, v)
+		}
+
+		assert.Equal(t, tt.want, notModified(r, h), tt.name)
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/roadrunner-server/http/v5/middleware"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// connectionField is the number of the connection field of the http.v1.Request message declared in the
+// protofiles/http.proto. The number is in the range owned by the plugin, not by the api schema, the field is encoded
+// as an unknown field of the upstream message.
+const connectionField protowire.Number = 1000
+
+// connectionInfo is the connection-info submessage of the request context.
+type connectionInfo struct {
+	LocalAddr   string   `msgpack:"local_addr"`
+	RemoteAddr  string   `msgpack:"remote_addr"`
+	Listener    string   `msgpack:"listener"`
+	HTTPVersion string   `msgpack:"http_version"`
+	TLS         *tlsInfo `msgpack:"tls,omitempty"`
+}
+
+type tlsInfo struct {
+	Version        string `msgpack:"version"`
+	CipherSuite    string `msgpack:"cipher_suite"`
+	ALPN           string `msgpack:"alpn"`
+	ServerName     string `msgpack:"server_name"`
+	Resumed        bool   `msgpack:"resumed"`
+	ClientSubject  string `msgpack:"client_subject"`
+	ClientVerified bool   `msgpack:"client_verified"`
+}
+
+func newConnectionInfo(r *http.Request) *connectionInfo {
+	c := &connectionInfo{
+		RemoteAddr:  r.RemoteAddr,
+		Listener:    middleware.ListenerName(r.Context()),
+		HTTPVersion: httpVersion(r),
+	}
+
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		c.LocalAddr = addr.String()
+	}
+
+	if r.TLS != nil {
+		c.TLS = &tlsInfo{
+			Version:        tls.VersionName(r.TLS.Version),
+			CipherSuite:    tls.CipherSuiteName(r.TLS.CipherSuite),
+			ALPN:           r.TLS.NegotiatedProtocol,
+			ServerName:     r.TLS.ServerName,
+			Resumed:        r.TLS.DidResume,
+			ClientVerified: len(r.TLS.VerifiedChains) > 0,
+		}
+
+		if len(r.TLS.PeerCertificates) > 0 {
+			c.TLS.ClientSubject = r.TLS.PeerCertificates[0].Subject.String()
+		}
+	}
+
+	return c
+}
+
+// httpVersion returns the ALPN identifier of the request protocol.
+func httpVersion(r *http.Request) string {
+	switch r.ProtoMajor {
+	case 3:
+		return "h3"
+	case 2:
+		if r.TLS == nil {
+			return "h2c"
+		}
+		return "h2"
+	default:
+		return strings.ToLower(r.Proto)
+	}
+}
+
+// setUnknown puts the connection info into the unknown fields of the proto request, reusing their buffer.
+func (c *connectionInfo) setUnknown(m protoreflect.Message) {
+	m.SetUnknown(c.appendProto(m.GetUnknown()[:0]))
+}
+
+// appendProto appends the connection info as the field of the http.v1.Request message.
+func (c *connectionInfo) appendProto(b []byte) []byte {
+	b = protowire.AppendTag(b, connectionField, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(c.size())) //nolint:gosec
+	b = appendString(b, 1, c.LocalAddr)
+	b = appendString(b, 2, c.RemoteAddr)
+	b = appendString(b, 3, c.Listener)
+	b = appendString(b, 4, c.HTTPVersion)
+	if c.TLS == nil {
+		return b
+	}
+
+	b = protowire.AppendTag(b, 5, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(c.TLS.size())) //nolint:gosec
+	b = appendString(b, 1, c.TLS.Version)
+	b = appendString(b, 2, c.TLS.CipherSuite)
+	b = appendString(b, 3, c.TLS.ALPN)
+	b = appendString(b, 4, c.TLS.ServerName)
+	b = appendBool(b, 5, c.TLS.Resumed)
+	b = appendString(b, 6, c.TLS.ClientSubject)
+	return appendBool(b, 7, c.TLS.ClientVerified)
+}
+
+func (c *connectionInfo) size() int {
+	n := sizeString(1, c.LocalAddr) + sizeString(2, c.RemoteAddr) + sizeString(3, c.Listener) + sizeString(4, c.HTTPVersion)
+	if c.TLS != nil {
+		n += protowire.SizeTag(5) + protowire.SizeBytes(c.TLS.size())
+	}
+
+	return n
+}
+
+func (t *tlsInfo) size() int {
+	return sizeString(1, t.Version) + sizeString(2, t.CipherSuite) + sizeString(3, t.ALPN) + sizeString(4, t.ServerName) +
+		sizeBool(5, t.Resumed) + sizeString(6, t.ClientSubject) + sizeBool(7, t.ClientVerified)
+}
+
+// the proto3 default values are not encoded
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, 1)
+}
+
+func sizeString(num protowire.Number, v string) int {
+	if v == "" {
+		return 0
+	}
+
+	return protowire.SizeTag(num) + protowire.SizeBytes(len(v))
+}
+
+func sizeBool(num protowire.Number, v bool) int {
+	if !v {
+		return 0
+	}
+
+	return protowire.SizeTag(num) + 1
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+
+	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestConnectionInfo(t *testing.T) {
+	var conn *connectionInfo
+	srv := httptest.NewTLSServer(middleware.Listener(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		conn = newConnectionInfo(r)
+	}), config.ListenerHTTPS))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	require.NotNil(t, conn)
+	assert.Equal(t, srv.Listener.Addr().String(), conn.LocalAddr)
+	_, _, err = net.SplitHostPort(conn.RemoteAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, config.ListenerHTTPS, conn.Listener)
+	assert.Equal(t, "http/1.1", conn.HTTPVersion)
+	require.NotNil(t, conn.TLS)
+	assert.Equal(t, tls.VersionName(tls.VersionTLS13), conn.TLS.Version)
+	assert.NotEmpty(t, conn.TLS.CipherSuite)
+	assert.Empty(t, conn.TLS.ClientSubject)
+
+	// the field follows the known fields of the request, the old schema keeps it as unknown
+	req := &httpV1proto.Request{Method: http.MethodGet}
+	conn.setUnknown(req.ProtoReflect())
+	data, err := proto.Marshal(req)
+	require.NoError(t, err)
+
+	decoded := &httpV1proto.Request{}
+	require.NoError(t, proto.Unmarshal(data, decoded))
+	assert.Equal(t, http.MethodGet, decoded.GetMethod())
+
+	fields := consumeFields(t, decoded.ProtoReflect().GetUnknown())
+	require.Contains(t, fields, connectionField)
+	fields = consumeFields(t, fields[connectionField])
+	assert.Equal(t, conn.LocalAddr, string(fields[1]))
+	assert.Equal(t, conn.RemoteAddr, string(fields[2]))
+	assert.Equal(t, config.ListenerHTTPS, string(fields[3]))
+	assert.Equal(t, "http/1.1", string(fields[4]))
+	fields = consumeFields(t, fields[5])
+	assert.Equal(t, conn.TLS.Version, string(fields[1]))
+	assert.Equal(t, conn.TLS.CipherSuite, string(fields[2]))
+	assert.NotContains(t, fields, protowire.Number(6))
+
+	// the msgpack codec sends it as the connection map
+	h, err := NewHandler(&config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500, Codec: config.CodecMsgpack}, &replayPool{}, zap.NewNop())
+	require.NoError(t, err)
+	pld := h.getPld()
+	assert.Equal(t, frame.CodecMsgpack, pld.Codec)
+	require.NoError(t, (&Request{conn: conn}).PayloadContext(pld, &httpV1proto.Request{}))
+
+	var m map[string]any
+	require.NoError(t, msgpack.Unmarshal(pld.Context, &m))
+	c := m["connection"].(map[string]any)
+	assert.Equal(t, "http/1.1", c["http_version"])
+	assert.Equal(t, conn.TLS.Version, c["tls"].(map[string]any)["version"])
+}
+
+func TestConnectionFieldOwned(t *testing.T) {
+	// the upstream schema must not define the plugin-owned field, the api upgrade would send two different messages
+	// with the same number
+	fields := (&httpV1proto.Request{}).ProtoReflect().Descriptor().Fields()
+	assert.Nil(t, fields.ByNumber(connectionField))
+	for i := 0; i < fields.Len(); i++ {
+		assert.Less(t, fields.Get(i).Number(), connectionField)
+	}
+}
+
+func TestConnectionFieldDeclared(t *testing.T) {
+	// the shipped schema is the upstream one with the plugin-owned field
+	data, err := os.ReadFile("../protofiles/http.proto")
+	require.NoError(t, err)
+	body := regexp.MustCompile(`(?s)message Request \{(.*?)\n\}`).FindSubmatch(data)
+	require.Len(t, body, 2)
+
+	declared := make(map[string]protowire.Number)
+	for _, m := range regexp.MustCompile(`(\w+) = (\d+);`).FindAllSubmatch(body[1], -1) {
+		n, errA := strconv.Atoi(string(m[2]))
+		require.NoError(t, errA)
+		declared[string(m[1])] = protowire.Number(n)
+	}
+
+	expected := map[string]protowire.Number{"connection": connectionField}
+	fields := (&httpV1proto.Request{}).ProtoReflect().Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		expected[string(fields.Get(i).Name())] = fields.Get(i).Number()
+	}
+
+	assert.Equal(t, expected, declared)
+}
+
+func TestConnectionInfoPlain(t *testing.T) {
+	h, err := NewHandler(&config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500, ConnectionInfo: true}, &replayPool{}, zap.NewNop())
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Proto, r.ProtoMajor, r.ProtoMinor = "HTTP/2.0", 2, 0
+	req := h.getReq(r)
+	require.NotNil(t, req.conn)
+	assert.Equal(t, "h2c", req.conn.HTTPVersion)
+	assert.Nil(t, req.conn.TLS)
+
+	reqproto := h.getProtoReq(req)
+	assert.NotEmpty(t, reqproto.ProtoReflect().GetUnknown())
+	h.putProtoReq(reqproto)
+	h.putReq(req)
+
+	// the pooled request is sent without the field when disabled
+	reqproto = h.getProtoReq(&Request{})
+	assert.Empty(t, reqproto.ProtoReflect().GetUnknown())
+}
+
+// consumeFields returns the last value of the bytes and varint fields by the number.
+func consumeFields(t *testing.T, b []byte) map[protowire.Number][]byte {
+	fields := make(map[protowire.Number][]byte)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			fields[num], b = v, b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0)
+			fields[num], b = protowire.AppendVarint(nil, v), b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+
+	return fields
+}
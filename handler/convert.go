@@ -1,42 +1,82 @@
 package handler
 
 import (
-	"net/http"
-
 	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
 )
 
-func convert(headers http.Header) map[string]*httpV1proto.HeaderValue {
+// convert converts the headers into the dst map. The dst map and its HeaderValue structures are reused between the
+// requests (dst comes from the pooled proto request), keys which are not present in the headers are removed.
+func convert(dst map[string]*httpV1proto.HeaderValue, headers map[string][]string) map[string]*httpV1proto.HeaderValue {
 	if len(headers) == 0 {
-		return nil
+		clear(dst)
+		return dst
+	}
+
+	if dst == nil {
+		dst = make(map[string]*httpV1proto.HeaderValue, len(headers))
 	}
 
-	resp := make(map[string]*httpV1proto.HeaderValue, len(headers))
+	for k := range dst {
+		if _, ok := headers[k]; !ok {
+			delete(dst, k)
+		}
+	}
 
 	for k, v := range headers {
-		if resp[k] == nil {
-			resp[k] = &httpV1proto.HeaderValue{}
+		hv := dst[k]
+		if hv == nil {
+			hv = &httpV1proto.HeaderValue{}
+			dst[k] = hv
 		}
-		resp[k].Value = append(resp[k].Value, v...)
+
+		// fast path, most of the headers have a single value
+		if len(v) == 1 {
+			hv.Value = setSingle(hv.Value, v[0])
+			continue
+		}
+
+		hv.Value = append(hv.Value[:0], v...)
 	}
 
-	return resp
+	return dst
 }
 
-func convertCookies(headers map[string]string) map[string]*httpV1proto.HeaderValue {
-	if len(headers) == 0 {
-		return nil
+// convertCookies converts the cookies into the dst map, see convert.
+func convertCookies(dst map[string]*httpV1proto.HeaderValue, cookies map[string]string) map[string]*httpV1proto.HeaderValue {
+	if len(cookies) == 0 {
+		clear(dst)
+		return dst
 	}
 
-	resp := make(map[string]*httpV1proto.HeaderValue, len(headers))
+	if dst == nil {
+		dst = make(map[string]*httpV1proto.HeaderValue, len(cookies))
+	}
 
-	for k, v := range headers {
-		if resp[k] == nil {
-			resp[k] = &httpV1proto.HeaderValue{}
+	for k := range dst {
+		if _, ok := cookies[k]; !ok {
+			delete(dst, k)
+		}
+	}
+
+	for k, v := range cookies {
+		hv := dst[k]
+		if hv == nil {
+			hv = &httpV1proto.HeaderValue{}
+			dst[k] = hv
 		}
 
-		resp[k].Value = append(resp[k].Value, v)
+		hv.Value = setSingle(hv.Value, v)
+	}
+
+	return dst
+}
+
+func setSingle(values []string, v string) []string {
+	if cap(values) == 0 {
+		return []string{v}
 	}
 
-	return resp
+	values = values[:1]
+	values[0] = v
+	return values
 }
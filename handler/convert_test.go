@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertReuse(t *testing.T) {
+	dst := convert(nil, map[string][]string{
+		"Accept": {"text/html"},
+		"X-Foo":  {"a", "b"},
+	})
+	require.Len(t, dst, 2)
+	accept := dst["Accept"]
+
+	dst = convert(dst, map[string][]string{
+		"Accept": {"application/json"},
+	})
+	require.Len(t, dst, 1)
+	// the same structure is reused
+	assert.Same(t, accept, dst["Accept"])
+	assert.Equal(t, []string{"application/json"}, dst["Accept"].GetValue())
+
+	dst = convert(dst, nil)
+	assert.Len(t, dst, 0)
+}
+
+func TestConvertCookies(t *testing.T) {
+	dst := convertCookies(nil, map[string]string{"a": "1", "b": "2"})
+	require.Len(t, dst, 2)
+
+	dst = convertCookies(dst, map[string]string{"b": "3"})
+	require.Len(t, dst, 1)
+	assert.Equal(t, []string{"3"}, dst["b"].GetValue())
+}
+
+func BenchmarkConvert(b *testing.B) {
+	headers := map[string][]string{
+		"Accept":          {"text/html"},
+		"Accept-Encoding": {"gzip, deflate"},
+		"User-Agent":      {"Mozilla/5.0"},
+		"X-Forwarded-For": {"127.0.0.1", "10.0.0.1"},
+	}
+
+	b.ReportAllocs()
+	dst := convert(nil, headers)
+	for n := 0; n < b.N; n++ {
+		dst = convert(dst, headers)
+	}
+}
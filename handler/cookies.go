@@ -0,0 +1,43 @@
+package handler
+
+import (
+	stderr "errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+const cookieHeader string = "Cookie"
+
+// errCookiesLimit is returned when the request cookies exceed the configured limits
+var errCookiesLimit = stderr.New("request cookies exceed the limits") //nolint:gochecknoglobals
+
+// cookies checks the cookies limits and parses the cookies into the request, unless the raw mode is used.
+func (h *Handler) cookies(r *http.Request, req *Request) error {
+	if h.cookiesCfg != nil && (h.cookiesCfg.MaxSize > 0 || h.cookiesCfg.MaxCount > 0) {
+		size, count := 0, 0
+		for _, value := range r.Header.Values(cookieHeader) {
+			size += len(value)
+			count += strings.Count(value, ";") + 1
+		}
+
+		if (h.cookiesCfg.MaxSize > 0 && size > h.cookiesCfg.MaxSize) || (h.cookiesCfg.MaxCount > 0 && count > h.cookiesCfg.MaxCount) {
+			return errCookiesLimit
+		}
+	}
+
+	// cookies are available in the Cookie header only
+	if h.cookiesCfg != nil && h.cookiesCfg.Mode == config.CookiesRaw {
+		return nil
+	}
+
+	for _, c := range r.Cookies() {
+		if v, err := url.QueryUnescape(c.Value); err == nil {
+			req.Cookies[c.Name] = v
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/middleware"
+)
+
+// DeadlineAttr is the attribute with the request deadline in unix milliseconds.
+const DeadlineAttr string = "deadline"
+
+// deadline computes the request time budget.
+type deadline struct {
+	header  string
+	timeout time.Duration
+	routes  []*config.RouteTimeout
+	// the pool exec_ttl, 0 if not set
+	execTTL time.Duration
+}
+
+func newDeadline(cfg *config.Deadline, execTTL time.Duration) *deadline {
+	return &deadline{
+		header:  cfg.Header,
+		timeout: cfg.Timeout,
+		routes:  cfg.Routes,
+		execTTL: execTTL,
+	}
+}
+
+// budget returns the request deadline, the request time is counted from the arrival if stamped by the middleware.
+// Returns false if the request is not limited.
+func (d *deadline) budget(r *http.Request, start time.Time) (time.Time, bool) {
+	if arrival, ok := middleware.ArrivalTime(r.Context()); ok {
+		start = arrival
+	}
+
+	timeout := d.timeout
+	fp := path.Clean("/" + r.URL.Path)
+routes:
+	for i := 0; i < len(d.routes); i++ {
+		for j := 0; j < len(d.routes[i].Paths); j++ {
+			if matchPath(d.routes[i].Paths[j], fp) {
+				timeout = d.routes[i].Timeout
+				break routes
+			}
+		}
+	}
+
+	// the worker is stopped after the exec_ttl anyway
+	if d.execTTL > 0 && (timeout == 0 || d.execTTL < timeout) {
+		timeout = d.execTTL
+	}
+
+	var dl time.Time
+	if timeout > 0 {
+		dl = start.Add(timeout)
+	}
+
+	if ctxDl, ok := r.Context().Deadline(); ok && (dl.IsZero() || ctxDl.Before(dl)) {
+		dl = ctxDl
+	}
+
+	return dl, !dl.IsZero()
+}
+
+// set passes the remaining budget in the header and the deadline in the attribute to the worker.
+func (d *deadline) set(r *http.Request, req *Request, start time.Time) {
+	dl, ok := d.budget(r, start)
+	if !ok {
+		return
+	}
+
+	remaining := max(time.Until(dl).Milliseconds(), 0)
+	// the client value is replaced
+	req.Header.Set(d.header, strconv.FormatInt(remaining, 10))
+	req.setAttr(DeadlineAttr, strconv.FormatInt(dl.UnixMilli(), 10))
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadline(t *testing.T) {
+	cfg := &config.Deadline{
+		Timeout: 30 * time.Second,
+		Routes: []*config.RouteTimeout{
+			{Paths: []string{"/reports/**"}, Timeout: 2 * time.Minute},
+			{Paths: []string{"/health"}, Timeout: time.Second},
+		},
+	}
+	require.NoError(t, cfg.InitDefaults())
+	require.NoError(t, cfg.Valid())
+
+	start := time.Now()
+	d := newDeadline(cfg, time.Minute)
+	for uri, budget := range map[string]time.Duration{
+		"/":                30 * time.Second,
+		"/health":          time.Second,
+		"/reports/a/b":     time.Minute,
+		"/other/../health": time.Second,
+	} {
+		dl, ok := d.budget(httptest.NewRequest(http.MethodGet, uri, nil), start)
+		require.True(t, ok, uri)
+		assert.Equal(t, start.Add(budget), dl, uri)
+	}
+
+	// the context deadline is earlier
+	ctx, cancel := context.WithDeadline(context.Background(), start.Add(500*time.Millisecond))
+	defer cancel()
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	dl, _ := d.budget(r, start)
+	assert.Equal(t, start.Add(500*time.Millisecond), dl)
+
+	// not limited
+	_, ok := newDeadline(&config.Deadline{}, 0).budget(httptest.NewRequest(http.MethodGet, "/", nil), start)
+	assert.False(t, ok)
+
+	r = httptest.NewRequest(http.MethodGet, "/health", nil)
+	r.Header.Set(cfg.Header, "999999")
+	req := &Request{Header: r.Header}
+	d.set(r, req, start)
+
+	remaining, err := strconv.ParseInt(req.Header.Get(cfg.Header), 10, 64)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, remaining, int64(1000))
+	assert.Equal(t, []string{strconv.FormatInt(start.Add(time.Second).UnixMilli(), 10)}, req.Attributes[DeadlineAttr])
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDebugHeader(t *testing.T) {
+	cfg := &config.Config{
+		Address:           "127.0.0.1:0",
+		Uploads:           &config.Uploads{},
+		InternalErrorCode: 500,
+		Pool:              &pool.Config{Debug: true},
+		Debug:             &config.Debug{Workers: 1, TTL: time.Second, Header: "X-Debug"},
+	}
+	require.NoError(t, cfg.InitDefaults())
+
+	h, err := NewHandler(cfg, &contextPool{}, zap.NewNop())
+	require.NoError(t, err)
+
+	serve := func(debug bool) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if debug {
+			r.Header.Set("X-Debug", "1")
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	assert.Empty(t, serve(false))
+	assert.Contains(t, serve(true), "NoFreeWorkers")
+}
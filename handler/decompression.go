@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+const (
+	contentEncoding string = "Content-Encoding"
+	// the ratio is checked after the first 1MB, the small bodies are compressed with the high ratios too
+	ratioThreshold int64 = 1024 * 1024
+)
+
+// decompressionError is returned when the request body can't be decoded or exceeds the decompression limits.
+type decompressionError struct {
+	encoding string
+	// the limit exceeded, empty for the malformed bodies
+	limit string
+	// compressed bytes read
+	read int64
+	err  error
+}
+
+func (e *decompressionError) Error() string {
+	if e.limit != "" {
+		return "request body decompression: " + e.limit + " exceeded"
+	}
+
+	return "request body decompression: " + e.err.Error()
+}
+
+func (e *decompressionError) Unwrap() error {
+	return e.err
+}
+
+// decompression decodes the compressed request bodies.
+type decompression struct {
+	encodings []string
+	maxSize   int64
+	maxRatio  float64
+}
+
+func newDecompression(cfg *config.RequestDecompression) *decompression {
+	return &decompression{
+		encodings: cfg.Encodings,
+		maxSize:   cfg.MaxSize,
+		maxRatio:  cfg.MaxRatio,
+	}
+}
+
+// wrap replaces the request body with the decoding reader, the body is decoded on read. Only the single encodings are
+// decoded, the bodies with the stacked or unknown encodings are passed as is.
+func (d *decompression) wrap(r *http.Request) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get(contentEncoding)))
+	if encoding == "" || !slices.Contains(d.encodings, encoding) || r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+
+	src := &countingReader{r: r.Body}
+	r.Body = &decompressReader{d: d, encoding: encoding, body: r.Body, src: src}
+	r.Header.Del(contentEncoding)
+	r.Header.Del(contentLength)
+	r.ContentLength = -1
+}
+
+// countingReader counts the compressed bytes consumed by the decoder.
+type countingReader struct {
+	r io.Reader
+	n int64
+	// the body read error, e.g. the read timeout or the max request size
+	err error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if err != nil && err != io.EOF {
+		c.err = err
+	}
+
+	return n, err
+}
+
+// decompressReader decodes the body and enforces the size and the ratio limits.
+type decompressReader struct {
+	d        *decompression
+	encoding string
+	body     io.Closer
+	src      *countingReader
+	// created on the first read, the gzip reader reads the header in the constructor
+	dec   io.Reader
+	close func()
+	out   int64
+	err   error
+}
+
+func (dr *decompressReader) Read(p []byte) (int, error) {
+	if dr.err != nil {
+		return 0, dr.err
+	}
+
+	if dr.dec == nil {
+		err := dr.init()
+		if err != nil {
+			dr.err = dr.fail(err)
+			return 0, dr.err
+		}
+	}
+
+	n, err := dr.dec.Read(p)
+	dr.out += int64(n)
+
+	switch {
+	case dr.out > dr.d.maxSize:
+		dr.err = &decompressionError{encoding: dr.encoding, limit: "max_size", read: dr.src.n}
+		return 0, dr.err
+	case dr.out > ratioThreshold && float64(dr.out) > float64(dr.src.n)*dr.d.maxRatio:
+		dr.err = &decompressionError{encoding: dr.encoding, limit: "max_ratio", read: dr.src.n}
+		return 0, dr.err
+	}
+
+	if err != nil && err != io.EOF {
+		dr.err = dr.fail(err)
+		return n, dr.err
+	}
+
+	return n, err
+}
+
+// fail returns the body read errors as is, so they are handled as for the plain bodies, the decoding errors otherwise.
+func (dr *decompressReader) fail(err error) error {
+	if dr.src.err != nil {
+		return dr.src.err
+	}
+
+	return &decompressionError{encoding: dr.encoding, read: dr.src.n, err: err}
+}
+
+func (dr *decompressReader) init() error {
+	switch dr.encoding {
+	case "gzip":
+		zr, err := gzip.NewReader(dr.src)
+		if err != nil {
+			return err
+		}
+		dr.dec, dr.close = zr, func() { _ = zr.Close() }
+	case "deflate":
+		// the HTTP deflate is the zlib format (RFC 9110)
+		zr, err := zlib.NewReader(dr.src)
+		if err != nil {
+			return err
+		}
+		dr.dec, dr.close = zr, func() { _ = zr.Close() }
+	case "zstd":
+		zr, err := zstd.NewReader(dr.src, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(dr.d.maxSize))) //nolint:gosec
+		if err != nil {
+			return err
+		}
+		dr.dec, dr.close = zr, zr.Close
+	}
+
+	return nil
+}
+
+func (dr *decompressReader) Close() error {
+	if dr.close != nil {
+		dr.close()
+	}
+
+	return dr.body.Close()
+}
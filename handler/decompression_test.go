@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func compressBody(t *testing.T, encoding string, data []byte) []byte {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(buf)
+	case "deflate":
+		w = zlib.NewWriter(buf)
+	case "zstd":
+		var err error
+		w, err = zstd.NewWriter(buf)
+		require.NoError(t, err)
+	}
+
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestDecompression(t *testing.T) {
+	cfg := &config.RequestDecompression{MaxSize: 4 * 1024 * 1024}
+	require.NoError(t, cfg.InitDefaults())
+	d := newDecompression(cfg)
+
+	for _, encoding := range []string{"gzip", "deflate", "zstd"} {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(compressBody(t, encoding, []byte("payload"))))
+		r.Header.Set(contentEncoding, strings.ToUpper(encoding))
+		d.wrap(r)
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err, encoding)
+		assert.Equal(t, "payload", string(body))
+		assert.Empty(t, r.Header.Get(contentEncoding))
+		assert.NoError(t, r.Body.Close())
+	}
+
+	// the unknown encodings are passed as is
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("raw"))
+	r.Header.Set(contentEncoding, "br")
+	d.wrap(r)
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "raw", string(body))
+
+	// 8MB of zeros compress to ~8KB
+	bomb := compressBody(t, "gzip", make([]byte, 8*1024*1024))
+	for limit, cfg := range map[string]*decompression{
+		"max_size":  {encodings: cfg.Encodings, maxSize: 4 * 1024 * 1024, maxRatio: 10000},
+		"max_ratio": {encodings: cfg.Encodings, maxSize: 16 * 1024 * 1024, maxRatio: 100},
+	} {
+		r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bomb))
+		r.Header.Set(contentEncoding, "gzip")
+		cfg.wrap(r)
+
+		_, err = io.ReadAll(r.Body)
+		var derr *decompressionError
+		require.ErrorAs(t, err, &derr)
+		assert.Equal(t, limit, derr.limit)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not gzip"))
+	r.Header.Set(contentEncoding, "gzip")
+	d.wrap(r)
+	_, err = io.ReadAll(r.Body)
+	var derr *decompressionError
+	require.ErrorAs(t, err, &derr)
+	assert.Empty(t, derr.limit)
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	cfg := &config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500, RequestDecompression: &config.RequestDecompression{}}
+	require.NoError(t, cfg.RequestDecompression.InitDefaults())
+	h, err := NewHandler(cfg, &replayPool{}, zap.NewNop())
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(compressBody(t, "gzip", make([]byte, 16*1024*1024))))
+	r.Header.Set(contentEncoding, "gzip")
+	r, failure := WithFailure(r)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.ErrorIs(t, failure.Err(), ErrSizeExceeded)
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/http/v5/common"
+	staticPool "github.com/roadrunner-server/pool/pool/static_pool"
+)
+
+// drain tracks the stream stopped by the handler. After the stop signal the worker should finish the stream, the
+// handler waits for it at most the drain timeout.
+type drain struct {
+	stopCh  chan struct{}
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+// stop sends the stop signal to the pool and starts the drain timer.
+func (d *drain) stop() {
+	select {
+	case d.stopCh <- struct{}{}:
+	default:
+	}
+
+	if d.timer == nil && d.timeout > 0 {
+		d.timer = time.NewTimer(d.timeout)
+	}
+}
+
+// expired returns the drain timer channel, nil (blocks forever) until the stream is stopped.
+func (d *drain) expired() <-chan time.Time {
+	if d.timer == nil {
+		return nil
+	}
+
+	return d.timer.C
+}
+
+func (d *drain) close() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+// discard reads the rest of the stream in the background, so the pool is not blocked on the full channel and
+// releases the worker once the stream is stopped.
+func discard(wResp chan *staticPool.PExec) {
+	go func() {
+		for range wResp { //nolint:revive
+		}
+	}()
+}
+
+// killWorker kills the worker of the stream not finished in time, the pool ends the stream and replaces the worker.
+// Returns the pid of the killed worker, 0 if the worker is unknown (the stderr correlation is off) or already gone.
+func killWorker(pool common.Pool, pid int64) int64 {
+	if pid == 0 {
+		return 0
+	}
+
+	workers := pool.Workers()
+	for i := 0; i < len(workers); i++ {
+		if workers[i].Pid() == pid {
+			_ = workers[i].Kill()
+			return pid
+		}
+	}
+
+	return 0
+}
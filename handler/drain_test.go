@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/payload"
+	staticPool "github.com/roadrunner-server/pool/pool/static_pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stuckPool has a single worker which ignores the stop signal, the stream is ended by the exec_ttl like in the
+// supervised static pool: the rest of the frames are sent, the channel is closed and the worker is released.
+type stuckPool struct {
+	common.Pool
+	execTTL time.Duration
+	slot    chan struct{}
+}
+
+func (p *stuckPool) Exec(_ context.Context, _ *payload.Payload, _ chan struct{}) (chan *staticPool.PExec, error) {
+	select {
+	case p.slot <- struct{}{}:
+	default:
+		return nil, errors.E(errors.NoFreeWorkers)
+	}
+
+	resp := make(chan *staticPool.PExec, 5)
+	go func() {
+		time.Sleep(p.execTTL)
+		// more frames than the channel buffer, blocks if nobody reads the stream
+		for i := 0; i < 10; i++ {
+			resp <- &staticPool.PExec{}
+		}
+		close(resp)
+		<-p.slot
+	}()
+
+	return resp, nil
+}
+
+func TestDrainTimeout(t *testing.T) {
+	pool := &stuckPool{execTTL: 100 * time.Millisecond, slot: make(chan struct{}, 1)}
+	h, err := NewHandler(&config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500, CancelOnDisconnect: true, DrainTimeout: 20 * time.Millisecond}, pool, zap.NewNop())
+	require.NoError(t, err)
+
+	// the client is gone before the first frame
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	assert.Less(t, time.Since(start), pool.execTTL)
+	assert.Equal(t, uint64(1), h.stats.ForcedDrains.Load())
+	assert.Len(t, pool.slot, 1)
+
+	// the discarded stream does not block the pool, the worker is released after the exec_ttl
+	require.Eventually(t, func() bool { return len(pool.slot) == 0 }, time.Second, 10*time.Millisecond)
+}
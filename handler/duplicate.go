@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+// duplicates applies the policy to the repeated request headers.
+type duplicates struct {
+	policy  config.DuplicatePolicy
+	headers []string
+}
+
+func newDuplicates(cfg *config.DuplicateHeaders) *duplicates {
+	d := &duplicates{policy: cfg.Policy, headers: make([]string, 0, len(cfg.Headers))}
+	if d.policy == "" {
+		d.policy = config.DuplicateReject
+	}
+
+	for i := 0; i < len(cfg.Headers); i++ {
+		d.headers = append(d.headers, http.CanonicalHeaderKey(cfg.Headers[i]))
+	}
+
+	return d
+}
+
+// apply normalizes the headers in place, returns an error if the request should be rejected.
+func (d *duplicates) apply(header http.Header) error {
+	const op = errors.Op("duplicate_headers")
+	for i := 0; i < len(d.headers); i++ {
+		values := header[d.headers[i]]
+		if len(values) < 2 {
+			continue
+		}
+
+		switch d.policy {
+		case config.DuplicateFirst:
+			header[d.headers[i]] = values[:1]
+		case config.DuplicateJoin:
+			header[d.headers[i]] = []string{strings.Join(values, ", ")}
+		default:
+			return errors.E(op, errors.Errorf("repeated header: %s", d.headers[i]))
+		}
+	}
+
+	return nil
+}
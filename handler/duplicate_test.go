@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicates(t *testing.T) {
+	header := func() http.Header {
+		return http.Header{"X-Forwarded-For": {"10.0.0.1", "10.0.0.2"}, "Accept": {"a", "b"}}
+	}
+
+	cfg := &config.DuplicateHeaders{Headers: []string{"x-forwarded-for"}}
+	assert.Error(t, newDuplicates(cfg).apply(header()))
+
+	cfg.Policy = config.DuplicateFirst
+	h := header()
+	assert.NoError(t, newDuplicates(cfg).apply(h))
+	assert.Equal(t, []string{"10.0.0.1"}, h["X-Forwarded-For"])
+	assert.Len(t, h["Accept"], 2)
+
+	cfg.Policy = config.DuplicateJoin
+	h = header()
+	assert.NoError(t, newDuplicates(cfg).apply(h))
+	assert.Equal(t, []string{"10.0.0.1, 10.0.0.2"}, h["X-Forwarded-For"])
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	"go.uber.org/zap"
+)
+
+// Options configures the handler used without the plugin and the endure container, e.g. embedded into a Go service.
+type Options struct {
+	// Config is the http section, the server addresses are not required. The defaults are set by New. Required.
+	Config *config.Config
+	// Pool executes the requests, e.g. the static pool created with the pipes factory. Required.
+	Pool common.Pool
+	// Log defaults to the no-op logger.
+	Log *zap.Logger
+	// Middleware is applied in order, the last one is the outermost, like the middleware list of the plugin config.
+	Middleware []common.Middleware
+}
+
+// New creates the handler with the middleware as a plain http.Handler. NewHandler should be used for the access to
+// the handler counters.
+func New(opts Options) (http.Handler, error) {
+	const op = errors.Op("http_handler_new")
+	if opts.Config == nil || opts.Pool == nil {
+		return nil, errors.E(op, errors.Str("config and pool are required"))
+	}
+
+	if opts.Log == nil {
+		opts.Log = zap.NewNop()
+	}
+
+	opts.Config.Embedded = true
+	err := opts.Config.InitDefaults()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	h, err := NewHandler(opts.Config, opts.Pool, opts.Log)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	var next http.Handler = h
+	for i := 0; i < len(opts.Middleware); i++ {
+		next = opts.Middleware[i].Middleware(next)
+	}
+
+	return next, nil
+}
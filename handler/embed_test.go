@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type headerMiddleware string
+
+func (m headerMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Middleware", string(m))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m headerMiddleware) Name() string {
+	return string(m)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Options{Config: &config.Config{}})
+	assert.Error(t, err)
+
+	pool := &replayPool{}
+	h, err := New(Options{
+		Config:     &config.Config{},
+		Pool:       pool,
+		Middleware: []common.Middleware{headerMiddleware("inner"), headerMiddleware("outer")},
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, []string{"outer", "inner"}, w.Header().Values("X-Middleware"))
+	assert.Len(t, pool.bodies, 1)
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+// defaultErrorPage is used for the HTML error responses when no template is configured.
+const defaultErrorPage string = `<!DOCTYPE html>
+<html>
+<head><title>{{.Code}} {{.Status}}</title></head>
+<body>
+<h1>{{.Code}} {{.Status}}</h1>
+{{if .RequestID}}<p>Request ID: {{.RequestID}}</p>{{end}}
+<p>{{.Timestamp}}</p>
+{{if .Message}}<pre>{{.Message}}</pre>{{end}}
+</body>
+</html>
+`
+
+// errorBody is the body of the internal error response.
+type errorBody struct {
+	Code      int    `json:"code"`
+	Status    string `json:"status"`
+	RequestID string `json:"request_id,omitempty"`
+	Timestamp string `json:"timestamp"`
+	Message   string `json:"message,omitempty"`
+}
+
+// errorPages writes the structured internal error responses.
+type errorPages struct {
+	format config.ErrorFormat
+	tmpl   *template.Template
+	// pre-rendered pages by the status, served when no workers are available
+	fallback map[int][]byte
+}
+
+func newErrorPages(cfg *config.ErrorPages) (*errorPages, error) {
+	ep := &errorPages{format: cfg.Format}
+	if ep.format == "" {
+		ep.format = config.ErrorFormatAuto
+	}
+
+	var err error
+	if cfg.Template != "" {
+		ep.tmpl, err = template.ParseFiles(cfg.Template)
+	} else {
+		ep.tmpl, err = template.New("error").Parse(defaultErrorPage)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for code, file := range cfg.Fallback {
+		status, err := strconv.Atoi(code)
+		if err != nil {
+			return nil, err
+		}
+
+		page, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if ep.fallback == nil {
+			ep.fallback = make(map[int][]byte, len(cfg.Fallback))
+		}
+
+		ep.fallback[status] = page
+	}
+
+	return ep, nil
+}
+
+// writeFallback writes the pre-rendered page of the status if the error means no workers are available and the
+// client accepts HTML. Returns false if the page is not sent.
+func (ep *errorPages) writeFallback(w http.ResponseWriter, r *http.Request, status int, err error) bool {
+	page, ok := ep.fallback[status]
+	if !ok || !unavailable(err) || !ep.html(r) {
+		return false
+	}
+
+	w.Header().Set(contentTypeHeader, "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, _ = w.Write(page)
+	return true
+}
+
+// unavailable returns true if the request failed because no workers are available.
+func unavailable(err error) bool {
+	return errors.Is(errors.NoFreeWorkers, err) || errors.Is(errors.WorkerAllocate, err) || errors.Is(errors.QueueSize, err)
+}
+
+// write writes the error response, the message is included only in the debug mode.
+func (ep *errorPages) write(w http.ResponseWriter, r *http.Request, status int, requestID, message string) {
+	body := &errorBody{
+		Code:      status,
+		Status:    http.StatusText(status),
+		RequestID: requestID,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Message:   message,
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if ep.html(r) {
+		w.Header().Set(contentTypeHeader, "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_ = ep.tmpl.Execute(w, body)
+		return
+	}
+
+	w.Header().Set(contentTypeHeader, "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// html returns true if the HTML response should be sent.
+func (ep *errorPages) html(r *http.Request) bool {
+	switch ep.format {
+	case config.ErrorFormatHTML:
+		return true
+	case config.ErrorFormatJSON:
+		return false
+	default:
+		// browsers prefer HTML, API clients - JSON
+		accept := r.Header.Get("Accept")
+		return strings.Contains(accept, "text/html") && !strings.Contains(accept, "application/json")
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorPages(t *testing.T) {
+	ep, err := newErrorPages(&config.ErrorPages{})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	ep.write(w, r, http.StatusBadGateway, "id", "")
+
+	body := &errorBody{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), body))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, http.StatusBadGateway, body.Code)
+	assert.Equal(t, "id", body.RequestID)
+
+	r.Header.Set("Accept", "text/html,application/xhtml+xml")
+	w = httptest.NewRecorder()
+	ep.write(w, r, http.StatusBadGateway, "id", "<script>")
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get(contentTypeHeader))
+	assert.Contains(t, w.Body.String(), "Request ID: id")
+	assert.Contains(t, w.Body.String(), "&lt;script&gt;")
+}
+
+func TestErrorStatus(t *testing.T) {
+	h := &Handler{internalHTTPCode: 500}
+	assert.Equal(t, 500, h.errorStatus(errors.E(errors.ExecTTL)))
+
+	h.errorStatuses = &config.ErrorStatuses{}
+	require.NoError(t, h.errorStatuses.InitDefaults())
+	assert.Equal(t, 504, h.errorStatus(errors.E(errors.Op("exec"), errors.ExecTTL)))
+	assert.Equal(t, 503, h.errorStatus(errors.E(errors.NoFreeWorkers)))
+	assert.Equal(t, 503, h.errorStatus(errors.E(errors.WorkerAllocate)))
+	assert.Equal(t, 500, h.errorStatus(errors.E(errors.SoftJob)))
+	assert.Equal(t, 500, h.errorStatus(errors.Str("unknown")))
+}
+
+func TestErrorPagesFallback(t *testing.T) {
+	page := filepath.Join(t.TempDir(), "maintenance.html")
+	require.NoError(t, os.WriteFile(page, []byte("<h1>We'll be right back</h1>"), 0o600))
+
+	ep, err := newErrorPages(&config.ErrorPages{Fallback: map[string]string{"503": page}})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html")
+
+	w := httptest.NewRecorder()
+	assert.True(t, ep.writeFallback(w, r, http.StatusServiceUnavailable, errors.E(errors.WorkerAllocate)))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "<h1>We'll be right back</h1>", w.Body.String())
+
+	// not a worker availability error
+	assert.False(t, ep.writeFallback(httptest.NewRecorder(), r, http.StatusServiceUnavailable, errors.E(errors.SoftJob)))
+	// no page for the status
+	assert.False(t, ep.writeFallback(httptest.NewRecorder(), r, http.StatusBadGateway, errors.E(errors.NoFreeWorkers)))
+
+	// API clients get the JSON error
+	r.Header.Set("Accept", "application/json")
+	assert.False(t, ep.writeFallback(httptest.NewRecorder(), r, http.StatusServiceUnavailable, errors.E(errors.NoFreeWorkers)))
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Code is the handler failure mode, the values are stable and might be used in the logs and metrics labels.
+type Code string
+
+const (
+	// CodeSizeExceeded - the request body or cookies exceeded the configured limits.
+	CodeSizeExceeded Code = "size_exceeded"
+	// CodeUploadForbidden - an uploaded file was rejected by the uploads access patterns, the request is still sent
+	// to the worker with the file error.
+	CodeUploadForbidden Code = "upload_forbidden"
+	// CodeWorkerTimeout - the worker did not respond in time (exec TTL).
+	CodeWorkerTimeout Code = "worker_timeout"
+	// CodeNoWorkers - no free workers, the pool queue is full or the queue wait timeout exceeded.
+	CodeNoWorkers Code = "no_workers"
+	// CodePayloadDecode - the worker response can't be decoded.
+	CodePayloadDecode Code = "payload_decode"
+)
+
+// Error is the handler failure. Errors with the same code match each other with errors.Is, so the middleware might
+// branch on the sentinel values, e.g. errors.Is(err, handler.ErrNoWorkers).
+type Error struct {
+	Code Code
+	// Err is the underlying error, nil for the sentinel values.
+	Err error
+}
+
+var (
+	// ErrSizeExceeded matches the CodeSizeExceeded failures.
+	ErrSizeExceeded = &Error{Code: CodeSizeExceeded} //nolint:gochecknoglobals
+	// ErrUploadForbidden matches the CodeUploadForbidden failures.
+	ErrUploadForbidden = &Error{Code: CodeUploadForbidden} //nolint:gochecknoglobals
+	// ErrWorkerTimeout matches the CodeWorkerTimeout failures.
+	ErrWorkerTimeout = &Error{Code: CodeWorkerTimeout} //nolint:gochecknoglobals
+	// ErrNoWorkers matches the CodeNoWorkers failures.
+	ErrNoWorkers = &Error{Code: CodeNoWorkers} //nolint:gochecknoglobals
+	// ErrPayloadDecode matches the CodePayloadDecode failures.
+	ErrPayloadDecode = &Error{Code: CodePayloadDecode} //nolint:gochecknoglobals
+)
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return "http handler: " + string(e.Code)
+	}
+
+	return "http handler: " + string(e.Code) + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the errors with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+type failureKey struct{}
+
+// Failure holds the first handler failure of the request.
+type Failure struct {
+	mu  sync.Mutex
+	err *Error
+}
+
+// Err returns the handler failure, nil if the request was served without the handler failures.
+func (f *Failure) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err == nil {
+		return nil
+	}
+
+	return f.err
+}
+
+// WithFailure returns the request with the failure recorder, the middleware checks the recorder after the request
+// is served:
+//
+//	r, failure := handler.WithFailure(r)
+//	next.ServeHTTP(w, r)
+//	if errors.Is(failure.Err(), handler.ErrNoWorkers) { ... }
+func WithFailure(r *http.Request) (*http.Request, *Failure) {
+	f := &Failure{}
+	return r.WithContext(context.WithValue(r.Context(), failureKey{}, f)), f
+}
+
+// fail records the failure if the request has the recorder.
+func fail(r *http.Request, code Code, err error) {
+	f, ok := r.Context().Value(failureKey{}).(*Failure)
+	if !ok {
+		return
+	}
+
+	f.mu.Lock()
+	if f.err == nil {
+		f.err = &Error{Code: code, Err: err}
+	}
+	f.mu.Unlock()
+}
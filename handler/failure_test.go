@@ -0,0 +1,47 @@
+package handler
+
+import (
+	stderr "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFailure(t *testing.T) {
+	timeout := &Error{Code: CodeWorkerTimeout, Err: stderr.New("exec ttl")}
+	assert.ErrorIs(t, timeout, ErrWorkerTimeout)
+	assert.NotErrorIs(t, timeout, ErrNoWorkers)
+	assert.Equal(t, "http handler: worker_timeout: exec ttl", timeout.Error())
+
+	h, err := NewHandler(&config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500}, &replayPool{}, zap.NewNop())
+	require.NoError(t, err)
+
+	r, failure := WithFailure(httptest.NewRequest(http.MethodGet, "/", nil))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.ErrorIs(t, failure.Err(), ErrNoWorkers)
+
+	// the body limit is set by the middleware
+	r, failure = WithFailure(httptest.NewRequest(http.MethodPost, "/", strings.NewReader("body")))
+	w := httptest.NewRecorder()
+	r.Body = http.MaxBytesReader(w, r.Body, 2)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.ErrorIs(t, failure.Err(), ErrSizeExceeded)
+
+	// no recorder
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	err = h.Write(&payload.Payload{Codec: frame.CodecProto, Context: []byte("malformed")}, httptest.NewRecorder())
+	assert.ErrorIs(t, err, ErrPayloadDecode)
+
+	_, failure = WithFailure(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NoError(t, failure.Err())
+}
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+const (
+	forwardedHeader       string = "Forwarded"
+	xForwardedForHeader   string = "X-Forwarded-For"
+	xForwardedProtoHeader string = "X-Forwarded-Proto"
+	xForwardedHostHeader  string = "X-Forwarded-Host"
+)
+
+// forwardedHop is a single element of the Forwarded header (RFC 7239).
+type forwardedHop struct {
+	For   string
+	By    string
+	Proto string
+	Host  string
+}
+
+// forward returns the request headers with the forwarding chain in the configured format. The incoming chain is
+// accepted only from the trusted peers, the current hop is appended to it.
+func (h *Handler) forward(r *http.Request, remoteAddr string) http.Header {
+	var hops []forwardedHop
+	if h.isTrusted(remoteAddr) {
+		hops = incomingHops(r.Header)
+	}
+
+	current := forwardedHop{For: remoteAddr, Proto: "http", Host: r.Host}
+	if r.TLS != nil {
+		current.Proto = "https"
+	}
+
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		current.By = FetchIP(addr.String(), h.log)
+	}
+
+	hops = append(hops, current)
+
+	header := r.Header.Clone()
+	header.Del(forwardedHeader)
+	header.Del(xForwardedForHeader)
+	header.Del(xForwardedProtoHeader)
+	header.Del(xForwardedHostHeader)
+
+	if h.forwardedFormat != config.ForwardedX {
+		header.Set(forwardedHeader, formatForwarded(hops))
+	}
+
+	if h.forwardedFormat != config.ForwardedRFC7239 {
+		ips := make([]string, 0, len(hops))
+		for i := 0; i < len(hops); i++ {
+			if hops[i].For != "" {
+				ips = append(ips, hops[i].For)
+			}
+		}
+
+		header.Set(xForwardedForHeader, strings.Join(ips, ", "))
+		// the original client request
+		header.Set(xForwardedProtoHeader, hops[0].Proto)
+		header.Set(xForwardedHostHeader, hops[0].Host)
+	}
+
+	return header
+}
+
+// incomingHops parses the Forwarded header, or the X-Forwarded-* headers if there is no Forwarded header.
+func incomingHops(header http.Header) []forwardedHop {
+	if values := header.Values(forwardedHeader); len(values) > 0 {
+		return parseForwarded(values)
+	}
+
+	var hops []forwardedHop
+	for _, value := range header.Values(xForwardedForHeader) {
+		for _, ip := range strings.Split(value, ",") {
+			ip = strings.TrimSpace(ip)
+			if ip != "" {
+				hops = append(hops, forwardedHop{For: ip})
+			}
+		}
+	}
+
+	if len(hops) > 0 {
+		hops[0].Proto = strings.TrimSpace(header.Get(xForwardedProtoHeader))
+		hops[0].Host = strings.TrimSpace(header.Get(xForwardedHostHeader))
+	}
+
+	return hops
+}
+
+// parseForwarded parses the Forwarded header values: `for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8::1]"`.
+func parseForwarded(values []string) []forwardedHop {
+	var hops []forwardedHop
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			var hop forwardedHop
+			for _, pair := range splitQuoted(element, ';') {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+
+				v = strings.Trim(strings.TrimSpace(v), `"`)
+				switch strings.ToLower(k) {
+				case "for":
+					hop.For = unbracket(v)
+				case "by":
+					hop.By = unbracket(v)
+				case "proto":
+					hop.Proto = strings.ToLower(v)
+				case "host":
+					hop.Host = v
+				}
+			}
+
+			if hop != (forwardedHop{}) {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	return hops
+}
+
+// formatForwarded builds the Forwarded header value.
+func formatForwarded(hops []forwardedHop) string {
+	var sb strings.Builder
+	for i := 0; i < len(hops); i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		pairs := 0
+		add := func(k, v string) {
+			if v == "" {
+				return
+			}
+
+			if pairs > 0 {
+				sb.WriteByte(';')
+			}
+
+			pairs++
+			sb.WriteString(k)
+			sb.WriteByte('=')
+			sb.WriteString(quoteForwarded(v))
+		}
+
+		add("for", bracket(hops[i].For))
+		add("by", bracket(hops[i].By))
+		add("proto", hops[i].Proto)
+		add("host", hops[i].Host)
+	}
+
+	return sb.String()
+}
+
+// splitQuoted splits the string by the separator outside the quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// bracket wraps the IPv6 address into the square brackets.
+func bracket(ip string) string {
+	if strings.Contains(ip, ":") && !strings.HasPrefix(ip, "[") {
+		return "[" + ip + "]"
+	}
+
+	return ip
+}
+
+// unbracket removes the square brackets (and the port) from the IPv6 address.
+func unbracket(v string) string {
+	if strings.HasPrefix(v, "[") {
+		if end := strings.IndexByte(v, ']'); end > 0 {
+			return v[1:end]
+		}
+	}
+
+	return v
+}
+
+// quoteForwarded quotes the value if it is not a token.
+func quoteForwarded(v string) string {
+	if strings.ContainsAny(v, `:[]"; ,=`) {
+		return `"` + strings.ReplaceAll(v, `"`, `\"`) + `"`
+	}
+
+	return v
+}
+
+// proxyScheme returns the scheme of the original client request from the Forwarded or X-Forwarded-Proto headers,
+// empty if the headers are missing or invalid.
+func proxyScheme(header http.Header) string {
+	hops := incomingHops(header)
+	if len(hops) == 0 {
+		// X-Forwarded-Proto without X-Forwarded-For
+		hops = []forwardedHop{{Proto: strings.TrimSpace(header.Get(xForwardedProtoHeader))}}
+	}
+
+	switch proto := strings.ToLower(hops[0].Proto); proto {
+	case "http", "https":
+		return proto
+	default:
+		return ""
+	}
+}
+
+// schemeURI replaces the scheme of the request URI.
+func schemeURI(r *http.Request, scheme string) string {
+	uri := URI(r)
+	if r.URL.Host != "" {
+		return uri
+	}
+
+	_, rest, _ := strings.Cut(uri, "://")
+	return scheme + "://" + rest
+}
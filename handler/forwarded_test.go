@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/payload"
+	staticPool "github.com/roadrunner-server/pool/pool/static_pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseForwarded(t *testing.T) {
+	hops := parseForwarded([]string{`for=192.0.2.60;proto=https;host="example.com", For="[2001:db8:cafe::17]:4711"`})
+	assert.Equal(t, []forwardedHop{
+		{For: "192.0.2.60", Proto: "https", Host: "example.com"},
+		{For: "2001:db8:cafe::17"},
+	}, hops)
+
+	assert.Equal(t, `for=192.0.2.60;proto=https;host=example.com, for="[2001:db8:cafe::17]"`, formatForwarded(hops))
+}
+
+func TestIncomingXForwarded(t *testing.T) {
+	header := http.Header{}
+	header.Set(xForwardedForHeader, "10.0.0.1, 10.0.0.2")
+	header.Set(xForwardedProtoHeader, "https")
+
+	assert.Equal(t, []forwardedHop{{For: "10.0.0.1", Proto: "https"}, {For: "10.0.0.2"}}, incomingHops(header))
+}
+
+func TestSchemeURI(t *testing.T) {
+	header := http.Header{}
+	header.Set(xForwardedProtoHeader, "HTTPS")
+	assert.Equal(t, "https", proxyScheme(header))
+
+	header.Set(forwardedHeader, "for=10.0.0.1;proto=http")
+	assert.Equal(t, "http", proxyScheme(header))
+
+	r := httptest.NewRequest(http.MethodGet, "/path?q=1", nil)
+	assert.Equal(t, "https://example.com/path?q=1", schemeURI(r, "https"))
+}
+
+// contextPool records the request contexts sent to the workers.
+type contextPool struct {
+	common.Pool
+	reqs []*httpV1proto.Request
+}
+
+func (p *contextPool) Exec(_ context.Context, pld *payload.Payload, _ chan struct{}) (chan *staticPool.PExec, error) {
+	req := &httpV1proto.Request{}
+	if err := proto.Unmarshal(pld.Context, req); err != nil {
+		return nil, err
+	}
+
+	p.reqs = append(p.reqs, req)
+	return nil, errors.E(errors.NoFreeWorkers)
+}
+
+func TestTrustedHeaders(t *testing.T) {
+	cfg := &config.Config{
+		Address:           "127.0.0.1:0",
+		Uploads:           &config.Uploads{},
+		InternalErrorCode: 500,
+		TrustedSubnets:    []string{"127.0.0.0/8"},
+		ProxyScheme:       true,
+		Priority:          &config.Priority{MaxConcurrency: 1},
+		Forwarded:         &config.Forwarded{},
+	}
+	require.NoError(t, cfg.InitDefaults())
+
+	pool := &contextPool{}
+	h, err := NewHandler(cfg, pool, zap.NewNop())
+	require.NoError(t, err)
+
+	serve := func(remoteAddr string) *httpV1proto.Request {
+		r := httptest.NewRequest(http.MethodGet, "/path", nil)
+		r.Host = "example.com"
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-RR-Priority", "5")
+		r.Header.Set(xForwardedForHeader, "10.0.0.1")
+		r.Header.Set(xForwardedProtoHeader, "https")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		require.NotEmpty(t, pool.reqs)
+		return pool.reqs[len(pool.reqs)-1]
+	}
+
+	req := serve("127.0.0.1:4000")
+	assert.Equal(t, "https://example.com/path", req.GetUri())
+	assert.Equal(t, []string{"10.0.0.1, 127.0.0.1"}, req.GetHeader()[xForwardedForHeader].GetValue())
+
+	// the headers of the untrusted peers are replaced
+	req = serve("192.0.2.1:4000")
+	assert.Equal(t, "http://example.com/path", req.GetUri())
+	assert.Equal(t, []string{"192.0.2.1"}, req.GetHeader()[xForwardedForHeader].GetValue())
+}
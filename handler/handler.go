@@ -1,11 +1,18 @@
 package handler
 
 import (
+	"bufio"
+	"container/list"
 	"context"
 	stderr "errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/roadrunner-server/http/v5/common"
@@ -14,61 +21,162 @@ import (
 	"github.com/roadrunner-server/errors"
 	"github.com/roadrunner-server/goridge/v3/pkg/frame"
 	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/static"
 	"github.com/roadrunner-server/pool/payload"
+	staticPool "github.com/roadrunner-server/pool/pool/static_pool"
 	"go.uber.org/zap"
 )
 
 const (
-	noWorkers string = "No-Workers"
-	trueStr   string = "true"
+	noWorkers  string = "No-Workers"
+	trueStr    string = "true"
+	retryAfter string = "Retry-After"
 )
 
 var _ http.Handler = (*Handler)(nil)
 
 type uploads struct {
 	dir    string
-	allow  map[string]struct{}
-	forbid map[string]struct{}
+	access *config.Access
+	// the files are written to the dir while the request body is read
+	lazy bool
 }
 
 // Handler serves http connections to underlying PHP application using PSR-7 protocol. Context will include request headers,
 // parsed files and query, payload will include parsed form dataTree (if any).
 type Handler struct {
-	uploads     *uploads
-	log         *zap.Logger
-	pool        common.Pool
+	uploads *uploads
+	log     *zap.Logger
+	// pool is replaced by the blue/green swap while the requests are served
+	pool        atomic.Pointer[common.Pool]
 	internalCtx context.Context
 
 	internalHTTPCode uint64
 	sendRawBody      bool
 	debugMode        bool
+	debugHeader      string
+	largeBody        *config.LargeBody
+	bufferResponse   bool
+	cookiesCfg       *config.Cookies
+	strictValidation bool
+	formLimits       *config.FormLimits
+	queryLimits      *config.QueryLimits
+	// pool errors to HTTP statuses mapping, nil if disabled
+	errorStatuses   *config.ErrorStatuses
+	errorRetryAfter string
+	// structured internal error responses, nil if disabled
+	errorPages *errorPages
+	// charset appended to the text responses without one
+	defaultCharset string
+	// form bodies on these path prefixes are not parsed
+	rawBodyRoutes []string
+	// repeated headers policy, nil if disabled
+	duplicates *duplicates
+	// static files served before the workers, nil if disabled
+	static *static.Static
+	// cache headers rules, nil if disabled
+	cacheControl *cacheControl
+	// convert the worker responses to 304 for the matching conditional requests
+	conditionalResponses bool
+	// send the Server-Timing header with the worker responses
+	serverTiming bool
+	// allow-listed headers passed as the attributes, nil if disabled
+	headerEnv headerEnv
+	// middleware attributes filter, nil if all attributes are sent
+	attributes *attributePolicy
+	// dispatch time of the pending requests, nil if the queue state is not exposed
+	pending *pendingList
+	// shared cache of the worker responses, nil if disabled
+	responseCache *responseCache
+	// signed URLs validation, nil if disabled
+	signedURLs *signedURLs
+	// the directory of the X-Sendfile files with the symlinks resolved, empty if the header is passed as is
+	sendfileRoot string
+	// requests recording, nil if disabled
+	capture *capture
+	// load shedding, the state is kept in the stats
+	shedPriority   int
+	shedRetryAfter string
+	// stop the worker when the client disconnects
+	cancelOnDisconnect bool
+	// max time to wait for the stopped stream to finish, 0 - unlimited
+	drainTimeout time.Duration
+	// Link preload translation, nil if disabled
+	preload *preload
+	// take the URI scheme from the trusted proxies
+	proxyScheme bool
+	// forwarding headers format, empty if disabled
+	forwardedFormat config.ForwardedFormat
+	// streams limit
+	maxStreams       int64
+	streamsMode      config.StreamsMode
+	streamBufferSize int64
+	// stream frames flush interval, 0 - each frame is flushed
+	flushInterval time.Duration
+	// payload context codec, frame.CodecProto or frame.CodecMsgpack
+	payloadCodec byte
+	// send the connection info with the request context
+	connectionInfo bool
+	// payload bodies compression
+	codec *payloadCodec
+	// request bodies decompression
+	decompression *decompression
+	// per-tenant limits
+	tenants *tenants
+	// request time budget
+	deadline *deadline
+	// idempotency keys deduplication
+	idempotency *idempotency
+	// backpressure
+	maxPending    int64
+	pendingStatus int
+	// prioritization
+	gate           *priorityGate
+	priorityHeader string
+	allocTimeout   time.Duration
+	queueWait      time.Duration
+	retryAfter     string
+	// adaptive concurrency limit of the gate, nil if disabled
+	limiter *concurrencyLimiter
+	// signals to the clients of the long requests, nil if disabled
+	keepalive *keepalive
+	// request ID header, empty if disabled
+	requestIDHeader string
+	// stderr correlates the worker stderr with the requests, nil - the stderr is not captured
+	stderr *Stderr
+	// the trusted subnets of the priority, forwarded and proxy scheme headers
+	cfg *config.Config
 
 	// permissions
 	uid int
 	gid int
 
 	// internal
+	stats         *Stats
 	reqPool       sync.Pool
 	protoRespPool sync.Pool
 	protoReqPool  sync.Pool
 	pldPool       sync.Pool
 	stopChPool    sync.Pool
+	bufWriterPool sync.Pool
 }
 
 // NewHandler return handle interface implementation
 func NewHandler(cfg *config.Config, pool common.Pool, log *zap.Logger) (*Handler, error) {
-	return &Handler{
+	h := &Handler{
 		uploads: &uploads{
 			dir:    cfg.Uploads.Dir,
-			allow:  cfg.Uploads.Allowed,
-			forbid: cfg.Uploads.Forbidden,
+			access: cfg.Uploads.Access(),
+			lazy:   cfg.Uploads.Lazy,
 		},
-		pool:             pool,
 		debugMode:        checkDebug(cfg),
 		log:              log,
 		internalHTTPCode: cfg.InternalErrorCode,
 		sendRawBody:      cfg.RawBody,
+		largeBody:        cfg.LargeBody,
 		internalCtx:      context.Background(),
+		stats:            &Stats{},
+		cfg:              cfg,
 
 		// permissions
 		uid: cfg.UID,
@@ -110,23 +218,365 @@ func NewHandler(cfg *config.Config, pool common.Pool, log *zap.Logger) (*Handler
 				}
 			},
 		},
-	}, nil
+		payloadCodec:   frame.CodecProto,
+		connectionInfo: cfg.ConnectionInfo,
+	}
+
+	if cfg.Codec == config.CodecMsgpack {
+		h.payloadCodec = frame.CodecMsgpack
+	}
+
+	if cfg.Backpressure != nil {
+		h.maxPending = cfg.Backpressure.MaxPending
+		h.pendingStatus = cfg.Backpressure.Status
+	}
+
+	if cfg.Priority != nil && cfg.Priority.MaxConcurrency > 0 {
+		h.gate = newPriorityGate(cfg.Priority.MaxConcurrency)
+		h.priorityHeader = cfg.Priority.Header
+	}
+
+	if cfg.PayloadCompression != nil {
+		var err error
+		h.codec, err = newPayloadCodec(cfg.PayloadCompression)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.RequestDecompression != nil {
+		h.decompression = newDecompression(cfg.RequestDecompression)
+	}
+
+	if cfg.LongRequests != nil {
+		h.keepalive = newKeepalive(cfg.LongRequests)
+	}
+
+	if cfg.ErrorStatuses != nil {
+		h.errorStatuses = cfg.ErrorStatuses
+		h.errorRetryAfter = strconv.Itoa(int(math.Ceil(cfg.ErrorStatuses.RetryAfter.Seconds())))
+	}
+
+	if cfg.ErrorPages != nil {
+		var err error
+		h.errorPages, err = newErrorPages(cfg.ErrorPages)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	h.cookiesCfg = cfg.Cookies
+	h.drainTimeout = cfg.DrainTimeout
+	h.cancelOnDisconnect = cfg.CancelOnDisconnect
+
+	if cfg.Static != nil {
+		h.static = static.NewStatic(cfg.Static, log)
+		h.stats.StaticCache = h.static.CacheStats()
+	}
+	h.proxyScheme = cfg.ProxyScheme
+	h.conditionalResponses = cfg.ConditionalResponses
+	h.serverTiming = cfg.ServerTiming
+	if len(cfg.HeaderEnv) > 0 {
+		h.headerEnv = newHeaderEnv(cfg.HeaderEnv)
+	}
+
+	if cfg.Attributes != nil {
+		h.attributes = newAttributePolicy(cfg.Attributes)
+	}
+
+	if cfg.QueueState != nil {
+		h.pending = &pendingList{}
+	}
+
+	if cfg.Capture != nil {
+		h.capture = newCapture(cfg.Capture, log)
+	}
+
+	if cfg.LoadShedding != nil {
+		h.stats.Shedding = &Shedding{}
+		h.shedPriority = cfg.LoadShedding.Priority
+		h.shedRetryAfter = strconv.Itoa(int(math.Ceil(cfg.LoadShedding.RetryAfter.Seconds())))
+	}
+
+	if cfg.SignedURLs != nil {
+		h.signedURLs = newSignedURLs(cfg.SignedURLs)
+	}
+
+	if cfg.XSendfile != nil {
+		var err error
+		h.sendfileRoot, err = filepath.EvalSymlinks(cfg.XSendfile.Root)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Tenants != nil {
+		h.tenants = newTenants(cfg.Tenants)
+		h.stats.Tenants = h.tenants.stats()
+	}
+
+	if cfg.Idempotency != nil {
+		h.idempotency = newIdempotency(cfg.Idempotency, h.stats, log)
+	}
+
+	if cfg.Deadline != nil {
+		var execTTL time.Duration
+		if cfg.Pool != nil && cfg.Pool.Supervisor != nil {
+			execTTL = cfg.Pool.Supervisor.ExecTTL
+		}
+		h.deadline = newDeadline(cfg.Deadline, execTTL)
+	}
+
+	if cfg.CacheControl != nil {
+		h.cacheControl = &cacheControl{rules: cfg.CacheControl.Rules, workers: cfg.CacheControl.Workers}
+	}
+
+	if cfg.Preload != nil {
+		h.preload = newPreload(cfg.Preload)
+	}
+	h.strictValidation = cfg.StrictValidation
+	h.formLimits = cfg.FormLimits
+	h.queryLimits = cfg.QueryLimits
+	h.rawBodyRoutes = cfg.RawBodyRoutes
+	h.defaultCharset = cfg.DefaultCharset
+
+	if cfg.DuplicateHeaders != nil {
+		h.duplicates = newDuplicates(cfg.DuplicateHeaders)
+	}
+
+	if cfg.Forwarded != nil {
+		h.forwardedFormat = cfg.Forwarded.Format
+		if h.forwardedFormat == "" {
+			h.forwardedFormat = config.ForwardedBoth
+		}
+	}
+
+	if cfg.Streams != nil {
+		h.maxStreams = cfg.Streams.MaxConcurrent
+		h.streamsMode = cfg.Streams.Mode
+		h.streamBufferSize = cfg.Streams.MaxBufferSize
+		h.flushInterval = cfg.Streams.FlushInterval
+	}
+
+	if cfg.Debug != nil {
+		h.debugHeader = cfg.Debug.Header
+	}
+
+	if cfg.RequestID != nil {
+		h.requestIDHeader = cfg.RequestID.Header
+	}
+
+	if cfg.Queue != nil && cfg.Queue.MaxWait > 0 {
+		h.queueWait = cfg.Queue.MaxWait
+		h.retryAfter = strconv.Itoa(int(math.Ceil(cfg.Queue.RetryAfter.Seconds())))
+		// requests wait for the workers in the gate, where the wait time can be limited
+		if h.gate == nil {
+			h.gate = newPriorityGate(int64(cfg.NumWorkers())) //nolint:gosec
+		}
+	}
+
+	if cfg.AdaptiveConcurrency != nil {
+		h.limiter = newConcurrencyLimiter(cfg.AdaptiveConcurrency)
+		h.stats.ConcurrencyLimit.Store(cfg.AdaptiveConcurrency.InitialLimit)
+		// the adaptive limit replaces the priority max_concurrency
+		if h.gate == nil {
+			h.gate = newPriorityGate(cfg.AdaptiveConcurrency.InitialLimit)
+		} else {
+			h.gate.resize(cfg.AdaptiveConcurrency.InitialLimit)
+		}
+	}
+
+	if h.gate != nil && cfg.Pool != nil {
+		h.allocTimeout = cfg.Pool.AllocateTimeout
+	}
+
+	if cfg.ResponseBuffer != nil && cfg.ResponseBuffer.Size > 0 {
+		size := cfg.ResponseBuffer.Size
+		h.bufferResponse = true
+		h.bufWriterPool = sync.Pool{
+			New: func() any {
+				return &bufferedWriter{
+					buf: bufio.NewWriterSize(nil, size),
+				}
+			},
+		}
+	}
+
+	if cfg.ResponseCache != nil {
+		h.responseCache = newResponseCache(cfg.ResponseCache, h.stats, h.requestIDHeader, log)
+	}
+
+	h.SetPool(pool)
+	return h, nil
+}
+
+// CaptureStderr attaches the worker stderr correlation, the stderr of the workers executing the requests is attached
+// to the error logs of the requests. Must be called before the handler serves the requests.
+func (h *Handler) CaptureStderr(s *Stderr) {
+	h.stderr = s
+}
+
+// SetPool replaces the pool used for the new requests, the requests dispatched before are completed by the previous
+// pool.
+func (h *Handler) SetPool(pool common.Pool) {
+	h.pool.Store(&pool)
 }
 
 // ServeHTTP transform original request to the PSR-7 passed then to the underlying application. Attempts to serve static files first if enabled.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	const op = errors.Op("serve_http")
 	start := time.Now()
 
+	if h.bufferResponse {
+		bw := h.getBufWriter(w)
+		defer h.putBufWriter(bw)
+		w = bw
+	}
+
+	if h.duplicates != nil {
+		err := h.duplicates.apply(r.Header)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			h.log.Warn("request rejected", zap.Error(err))
+			return
+		}
+	}
+
+	if h.strictValidation {
+		verr := validateRequest(r)
+		if verr != nil {
+			h.badRequest(w, verr)
+			return
+		}
+	}
+
+	if h.queryLimits != nil {
+		verr := checkQuery(h.queryLimits, r)
+		if verr != nil {
+			h.badRequest(w, verr)
+			return
+		}
+	}
+
+	// the protected files are served only by the signed links, both from the static root and by the workers
+	if h.signedURLs != nil && h.signedURLs.protected(r.URL.Path) {
+		err := h.signedURLs.verify(r, time.Now())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			h.log.Debug("signed url rejected", zap.String("path", r.URL.Path), zap.Error(err))
+			return
+		}
+	}
+
+	var cacheRule *config.CacheControlRule
+	if h.cacheControl != nil {
+		cacheRule = h.cacheControl.match(r.URL.Path)
+	}
+
+	if h.static != nil {
+		if cacheRule != nil {
+			setCacheHeaders(w.Header(), cacheRule)
+		}
+
+		if h.static.Serve(w, r) {
+			h.stats.StaticServed.Add(1)
+			return
+		}
+
+		if cacheRule != nil {
+			delCacheHeaders(w.Header())
+		}
+	}
+
+	// the rules are applied to the worker responses only if enabled
+	if cacheRule != nil && !h.cacheControl.workers {
+		cacheRule = nil
+	}
+
+	if h.capture != nil && h.capture.match(r) {
+		var record func()
+		w, record = h.capture.wrap(w, r, start)
+		defer record()
+	}
+
+	if h.idempotency != nil && h.idempotency.applies(r) {
+		h.idempotency.serve(w, r, func(w http.ResponseWriter, r *http.Request) {
+			h.dispatch(w, r, cacheRule, start)
+		})
+		return
+	}
+
+	if h.responseCache != nil && h.responseCache.cacheable(r) {
+		h.responseCache.serve(w, r, func(w http.ResponseWriter, r *http.Request) {
+			h.dispatch(w, r, cacheRule, start)
+		})
+		return
+	}
+
+	h.dispatch(w, r, cacheRule, start)
+}
+
+// dispatch sends the request to the worker and writes the response.
+func (h *Handler) dispatch(w http.ResponseWriter, r *http.Request, cacheRule *config.CacheControlRule, start time.Time) {
+	const op = errors.Op("serve_http")
+	if h.stats.Shedding != nil && h.shed(r) {
+		w.Header().Set(retryAfter, h.shedRetryAfter)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	var tn *tenant
+	if h.tenants != nil {
+		tn = h.tenants.resolve(r, func() bool { return h.isTrusted(FetchIP(r.RemoteAddr, h.log)) })
+	}
+
+	if tn != nil {
+		if !tn.enter() {
+			w.Header().Set(retryAfter, "1")
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			h.log.Debug("request rejected by the tenant limits", zap.String("tenant", tn.stats.ID))
+			return
+		}
+		defer tn.leave()
+
+		if !tn.limitBody(w, r) {
+			fail(r, CodeSizeExceeded, nil)
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			h.log.Debug("request rejected by the tenant max request size", zap.String("tenant", tn.stats.ID))
+			return
+		}
+	}
+
 	req := h.getReq(r)
-	err := request(r, req, h.uid, h.gid, h.sendRawBody)
+
+	log := h.log
+	var id string
+	if h.requestIDHeader != "" {
+		id = h.requestID(r)
+		req.setAttr(RequestIDAttr, id)
+		w.Header().Set(h.requestIDHeader, id)
+		log = h.log.With(zap.String(RequestIDAttr, id))
+	}
+
+	if h.deadline != nil {
+		h.deadline.set(r, req, start)
+	}
+
+	if h.headerEnv != nil {
+		h.headerEnv.set(r, req)
+	}
+
+	if h.decompression != nil {
+		h.decompression.wrap(r)
+	}
+
+	err := h.request(r, req)
 	if err != nil {
 		// if the pipe is broken, there is no sense to write the header
 		// in this case, we just report about error
 		if stderr.Is(err, errEPIPE) {
 			req.Close(h.log, r)
 			h.putReq(req)
-			h.log.Error(
+			log.Error(
 				"write response error",
 				zap.Time("start", start),
 				zap.Int64("elapsed", time.Since(start).Milliseconds()),
@@ -135,10 +585,79 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// the client did not send the body in time
+		var ne net.Error
+		if stderr.As(err, &ne) && ne.Timeout() {
+			req.Close(h.log, r)
+			h.putReq(req)
+			w.Header().Set(connectionHeader, "close")
+			http.Error(w, http.StatusText(http.StatusRequestTimeout), http.StatusRequestTimeout)
+			log.Warn("request body read timeout",
+				zap.String("method", r.Method),
+				zap.String("uri", req.URI),
+				zap.String("remote_address", req.RemoteAddr),
+				zap.Int64("content_length", r.ContentLength),
+				zap.Error(err))
+			return
+		}
+
+		// the body exceeded the max_request_size while reading
+		var mbe *http.MaxBytesError
+		if stderr.As(err, &mbe) {
+			if tn != nil && mbe.Limit == tn.maxBody {
+				tn.stats.SizeExceeded.Add(1)
+			}
+			fail(r, CodeSizeExceeded, err)
+			req.Close(h.log, r)
+			h.putReq(req)
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			log.Warn("request rejected, body is too large", zap.Int64("limit", mbe.Limit))
+			return
+		}
+
+		var derr *decompressionError
+		if stderr.As(err, &derr) {
+			req.Close(h.log, r)
+			h.putReq(req)
+			if derr.limit == "" {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				log.Warn("request rejected, malformed compressed body", zap.String("encoding", derr.encoding), zap.Error(derr.err))
+				return
+			}
+
+			fail(r, CodeSizeExceeded, err)
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			log.Warn("request rejected, decompression limit exceeded",
+				zap.String("limit", derr.limit),
+				zap.String("encoding", derr.encoding),
+				zap.String("method", r.Method),
+				zap.String("uri", req.URI),
+				zap.String("remote_address", req.RemoteAddr),
+				zap.Int64("compressed_bytes", derr.read))
+			return
+		}
+
+		var verr *validationError
+		if stderr.As(err, &verr) {
+			req.Close(h.log, r)
+			h.putReq(req)
+			h.badRequest(w, verr)
+			return
+		}
+
+		if stderr.Is(err, errCookiesLimit) {
+			fail(r, CodeSizeExceeded, err)
+			req.Close(h.log, r)
+			h.putReq(req)
+			http.Error(w, err.Error(), http.StatusRequestHeaderFieldsTooLarge)
+			log.Warn("request rejected", zap.Error(err))
+			return
+		}
+
 		req.Close(h.log, r)
 		h.putReq(req)
 		http.Error(w, errors.E(op, err).Error(), 500)
-		h.log.Error(
+		log.Error(
 			"request forming error",
 			zap.Time("start", start),
 			zap.Int64("elapsed", time.Since(start).Milliseconds()),
@@ -147,19 +666,37 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req.Open(h.log, h.uploads.dir, h.uploads.forbid, h.uploads.allow)
+	if tn != nil && req.Uploads != nil && !tn.chargeUploads(req.Uploads.size()) {
+		fail(r, CodeSizeExceeded, nil)
+		req.Close(h.log, r)
+		h.putReq(req)
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		log.Warn("request rejected, tenant upload quota exceeded", zap.String("tenant", tn.stats.ID))
+		return
+	}
+
+	req.Open(h.log, h.uploads.dir, h.uploads.access)
+	if req.Uploads != nil && req.Uploads.forbidden() {
+		fail(r, CodeUploadForbidden, nil)
+	}
 	// get payload from the pool
 	pld := h.getPld()
 	// get proto request from the pool
 	reqproto := h.getProtoReq(req)
-	err = req.Payload(pld, h.sendRawBody, reqproto)
+	err = req.PayloadBody(pld, h.sendRawBody)
+	if err == nil {
+		err = req.PayloadContext(pld, reqproto)
+	}
+	if err == nil && h.codec != nil {
+		h.codec.compress(pld, r)
+	}
 	h.putProtoReq(reqproto)
 	if err != nil {
 		req.Close(h.log, r)
 		h.putReq(req)
 		h.putPld(pld)
-		h.handleError(w, err)
-		h.log.Error(
+		h.handleError(w, r, err)
+		log.Error(
 			"payload forming error",
 			zap.Time("start", start),
 			zap.Int64("elapsed", time.Since(start).Milliseconds()),
@@ -168,58 +705,314 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the stderr of the worker is correlated by the payload context with the request ID, the worker of the stream is
+	// known for the drain timeout
+	var ew *execWindow
+	if h.stderr != nil && (id != "" || h.drainTimeout > 0) {
+		ew = h.stderr.start(pld.Context, id)
+		defer h.stderr.finish(ew)
+	}
+
+	// reject the request if too many requests are waiting for the workers
+	if n := h.stats.Pending.Add(1); h.maxPending > 0 && n > h.maxPending {
+		h.stats.Pending.Add(-1)
+		h.stats.Rejected.Add(1)
+		req.Close(h.log, r)
+		h.putReq(req)
+		h.putPld(pld)
+		w.WriteHeader(h.pendingStatus)
+		log.Debug("request rejected, too many pending requests", zap.Int64("pending", n))
+		return
+	}
+
+	var pe *list.Element
+	if h.pending != nil {
+		pe = h.pending.add()
+	}
+
+	tm := timings{start: start}
+	if h.gate != nil {
+		queued := time.Now()
+		err = h.acquire(r, req.RemoteAddr)
+		tm.queue = time.Since(queued)
+		if err != nil {
+			h.stats.Pending.Add(-1)
+			if pe != nil {
+				h.pending.remove(pe)
+			}
+			req.Close(h.log, r)
+			h.putReq(req)
+			h.putPld(pld)
+			if stderr.Is(err, errQueueTimeout) {
+				fail(r, CodeNoWorkers, err)
+				h.stats.QueueTimeouts.Add(1)
+				w.Header().Set(retryAfter, h.retryAfter)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			} else {
+				h.handleError(w, r, err)
+			}
+			log.Error("dispatch", zap.Time("start", start), zap.Int64("elapsed", time.Since(start).Milliseconds()), zap.Error(err))
+			return
+		}
+	}
+
+	// early hints for the resources preloaded by the previous responses
+	if h.preload != nil {
+		h.preload.hints(w, r)
+	}
+
 	stopCh := h.getCh()
-	wResp, err := h.pool.Exec(h.internalCtx, pld, stopCh)
+	execCtx := h.internalCtx
+	if h.cancelOnDisconnect {
+		// stops waiting for a free worker, the worker is recycled if the exec_ttl is set
+		execCtx = r.Context()
+	}
+
+	dispatched := time.Now()
+	var stopKeepalive func() bool
+	if h.keepalive != nil {
+		stopKeepalive = h.keepalive.start(w, r)
+	}
+	pool := *h.pool.Load()
+	wResp, err := pool.Exec(execCtx, pld, stopCh)
+	if stopKeepalive != nil && stopKeepalive() {
+		// the status and the headers are sent with the whitespace
+		w = &committedWriter{ResponseWriter: w}
+	}
+	h.stats.Pending.Add(-1)
+	if pe != nil {
+		h.pending.remove(pe)
+	}
+	if h.gate != nil {
+		// the failed requests are not sampled, except for the pool overload
+		if h.limiter != nil && (err == nil || unavailable(err)) {
+			limit := h.limiter.sample(time.Since(dispatched), h.gate.inFlight(), err != nil)
+			h.gate.resize(limit)
+			h.stats.ConcurrencyLimit.Store(limit)
+		}
+
+		// NOTE: stream responses release the slot after the first frame
+		h.gate.release()
+	}
 	if err != nil {
 		req.Close(h.log, r)
 		h.putReq(req)
 		h.putPld(pld)
 		h.putCh(stopCh)
-		h.handleError(w, err)
-		h.log.Error("execute", zap.Time("start", start), zap.Int64("elapsed", time.Since(start).Milliseconds()), zap.Error(err))
+		// nobody is waiting for the response
+		if h.cancelOnDisconnect && r.Context().Err() != nil {
+			h.stats.Canceled.Add(1)
+			log.Debug("request canceled, client disconnected", zap.Error(err))
+			return
+		}
+
+		h.handleError(w, r, err)
+		log.Error("execute", zap.Time("start", start), zap.Int64("elapsed", time.Since(start).Milliseconds()), zap.Error(err), h.stderr.finish(ew))
 		return
 	}
 	// return payload to the pool
 	h.putPld(pld)
 
-	for recv := range wResp {
+	var streaming, dropped bool
+	var lw *latencyWriter
+	out := w
+	dr := &drain{stopCh: stopCh, timeout: h.drainTimeout}
+	defer dr.close()
+	var disconnected <-chan struct{}
+	if h.cancelOnDisconnect {
+		disconnected = r.Context().Done()
+	}
+
+	for {
+		var recv *staticPool.PExec
+		select {
+		case <-disconnected:
+			// stop the stream, nobody is waiting for the response
+			disconnected = nil
+			h.stats.Canceled.Add(1)
+			dr.stop()
+			log.Debug("stream canceled, client disconnected")
+			continue
+		case recv = <-wResp:
+		case <-dr.expired():
+			// the worker did not finish the stopped stream in time
+			h.stats.ForcedDrains.Add(1)
+			discard(wResp)
+			req.Close(h.log, r)
+			h.putReq(req)
+			// the stop channel is still used by the pool
+			pid := killWorker(pool, h.stderr.worker(ew))
+			log.Warn("stream drain timeout, the rest of the stream is discarded", zap.Duration("timeout", h.drainTimeout), zap.Int64("killed", pid))
+			return
+		}
+
+		// the stream is completed
+		if recv == nil {
+			break
+		}
+
 		if recv.Error() != nil {
 			req.Close(h.log, r)
 			h.putReq(req)
 			h.putCh(stopCh)
-			w.WriteHeader(int(h.internalHTTPCode))
-			h.log.Error("read stream",
+			h.handleError(w, r, recv.Error())
+			log.Error("read stream",
 				zap.Time("start", start),
 				zap.Int64("elapsed", time.Since(start).Milliseconds()),
-				zap.Error(recv.Error()))
+				zap.Error(recv.Error()),
+				h.stderr.finish(ew))
 			return
 		}
 
-		err = h.Write(recv.Payload(), w)
-		if err != nil {
-			// send stop signal to the worker pool
-			select {
-			case stopCh <- struct{}{}:
+		// limit the number of the concurrent streams
+		if !streaming && h.maxStreams > 0 && recv.Payload().Flags&frame.STREAM != 0 {
+			streaming = true
+			n := h.stats.Streams.Add(1)
+			switch {
+			case n > h.maxStreams && h.streamsMode == config.StreamsReject:
+				h.stats.Streams.Add(-1)
+				dropped = true
+				dr.stop()
+				w.WriteHeader(http.StatusServiceUnavailable)
+				log.Warn("stream rejected, too many concurrent streams", zap.Int64("streams", n))
+			case n > h.maxStreams:
+				defer h.stats.Streams.Add(-1)
+				sb := &streamBuffer{w: w, limit: h.streamBufferSize}
+				defer sb.flush()
+				out = sb
 			default:
+				defer h.stats.Streams.Add(-1)
 			}
+		}
+
+		// wait for the worker to stop the rejected stream
+		if dropped {
+			continue
+		}
+
+		// the buffered streams are sent at once
+		if h.flushInterval > 0 && lw == nil && out == w && recv.Payload().Flags&frame.STREAM != 0 {
+			lw = newLatencyWriter(w, h.flushInterval)
+			defer lw.stop()
+			out = lw
+		}
+
+		// the headers are sent with the first frame
+		if tm.exec == 0 && h.withServerTiming(r) {
+			tm.exec = time.Since(dispatched)
+			setServerTiming(out.Header(), r, &tm)
+		}
+
+		err = h.write(recv.Payload(), out, r, cacheRule)
+		if err != nil {
+			var herr *Error
+			if stderr.As(err, &herr) {
+				fail(r, herr.Code, herr.Err)
+			}
+
+			// send stop signal to the worker pool
+			dr.stop()
 
 			// we should not exit from the loop here, since after sending close signal, it should be closed from the SDK side
-			h.log.Error("write response (chunk) error",
+			log.Error("write response (chunk) error",
 				zap.Time("start", start),
 				zap.Int64("elapsed", time.Since(start).Milliseconds()),
 				zap.Error(err))
 		}
 	}
 
+	if h.preload != nil {
+		h.preload.record(r, w.Header())
+	}
+
 	req.Close(h.log, r)
 	h.putReq(req)
 	h.putCh(stopCh)
 }
 
+// acquire waits for the dispatch slot according to the request priority.
+func (h *Handler) acquire(r *http.Request, remoteAddr string) error {
+	const op = errors.Op("dispatch_acquire")
+	timeout := h.allocTimeout
+	if h.queueWait > 0 {
+		timeout = h.queueWait
+	}
+
+	ctx := r.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := h.gate.acquire(ctx, h.priority(r, remoteAddr))
+	if err != nil {
+		if h.queueWait > 0 && stderr.Is(err, context.DeadlineExceeded) {
+			return errQueueTimeout
+		}
+
+		return errors.E(op, errors.NoFreeWorkers, err)
+	}
+
+	return nil
+}
+
+// isTrusted returns true if the peer belongs to the trusted subnets.
+func (h *Handler) isTrusted(ip string) bool {
+	return h.cfg.IsTrusted(ip)
+}
+
+// badRequest rejects the request which failed the validation.
+func (h *Handler) badRequest(w http.ResponseWriter, err *validationError) {
+	http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	h.log.Warn("request rejected", zap.String("reason", err.reason), zap.Error(err))
+}
+
+// errorStatus returns the HTTP status for the pool error.
+func (h *Handler) errorStatus(err error) int {
+	if h.errorStatuses == nil {
+		return int(h.internalHTTPCode)
+	}
+
+	status := 0
+	switch {
+	case errors.Is(errors.ExecTTL, err):
+		status = h.errorStatuses.ExecTTL
+	case errors.Is(errors.NoFreeWorkers, err):
+		status = h.errorStatuses.NoFreeWorkers
+	case errors.Is(errors.WorkerAllocate, err):
+		status = h.errorStatuses.WorkerAllocate
+	case errors.Is(errors.SoftJob, err):
+		status = h.errorStatuses.SoftJob
+	}
+
+	if status == 0 {
+		return int(h.internalHTTPCode)
+	}
+
+	return status
+}
+
 // handleError will handle internal RR errors and return 500
-func (h *Handler) handleError(w http.ResponseWriter, err error) {
-	// write an internal server error
-	w.WriteHeader(int(h.internalHTTPCode))
+func (h *Handler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(errors.ExecTTL, err):
+		fail(r, CodeWorkerTimeout, err)
+	case errors.Is(errors.NoFreeWorkers, err), errors.Is(errors.QueueSize, err):
+		fail(r, CodeNoWorkers, err)
+	}
+
+	status := h.errorStatus(err)
+	// the pool queue is full
+	queueFull := h.pendingStatus != 0 && errors.Is(errors.QueueSize, err)
+	if queueFull {
+		h.stats.Rejected.Add(1)
+		status = h.pendingStatus
+	}
+
+	if status == http.StatusServiceUnavailable && h.errorStatuses != nil {
+		w.Header().Set(retryAfter, h.errorRetryAfter)
+	}
 
 	// if there are no free workers -> write a special header
 	if errors.Is(errors.NoFreeWorkers, err) {
@@ -228,7 +1021,30 @@ func (h *Handler) handleError(w http.ResponseWriter, err error) {
 	}
 
 	// in debug mode, write all output into the browser/curl/any_tool
-	if h.debugMode {
+	debug := !queueFull && h.debugMode && (h.debugHeader == "" || r.Header.Get(h.debugHeader) != "")
+
+	if h.errorPages != nil {
+		if h.errorPages.writeFallback(w, r, status, err) {
+			return
+		}
+
+		message := ""
+		if debug {
+			message = err.Error()
+		}
+
+		var requestID string
+		if h.requestIDHeader != "" {
+			requestID = w.Header().Get(h.requestIDHeader)
+		}
+
+		h.errorPages.write(w, r, status, requestID, message)
+		return
+	}
+
+	// write an internal server error
+	w.WriteHeader(status)
+	if debug {
 		_, _ = fmt.Fprintln(w, err)
 	}
 }
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// headerEnv maps the allow-listed request headers to the worker attributes.
+type headerEnv map[string]string
+
+func newHeaderEnv(mapping map[string]string) headerEnv {
+	// the config keys are lower-cased
+	he := make(headerEnv, len(mapping))
+	for header, name := range mapping {
+		he[http.CanonicalHeaderKey(header)] = name
+	}
+
+	return he
+}
+
+// set copies the header values to the attributes, the missing headers are skipped.
+func (he headerEnv) set(r *http.Request, req *Request) {
+	for header, name := range he {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		if req.Attributes == nil {
+			req.Attributes = make(map[string][]string, len(he))
+		}
+
+		req.Attributes[name] = append([]string(nil), values...)
+	}
+}
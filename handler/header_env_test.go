@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderEnv(t *testing.T) {
+	he := newHeaderEnv(map[string]string{"x-tenant": "TENANT", "x-region": "REGION"})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Tenant", "acme")
+	r.Header.Set("X-Other", "ignored")
+
+	req := &Request{}
+	he.set(r, req)
+	assert.Equal(t, map[string][]string{"TENANT": {"acme"}}, req.Attributes)
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
+)
+
+const connectionHeader string = "Connection"
+
+// hopHeaders are meaningful only for a single transport-level connection and must not be forwarded (RFC 7230, 6.1).
+var hopHeaders = [...]string{ //nolint:gochecknoglobals
+	connectionHeader,
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Te",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// stripHopByHop removes the hop-by-hop headers and the headers listed in the Connection header.
+func stripHopByHop(headers map[string]*httpV1proto.HeaderValue) {
+	if len(headers) == 0 {
+		return
+	}
+
+	for k, v := range headers {
+		if http.CanonicalHeaderKey(k) != connectionHeader {
+			continue
+		}
+
+		for _, value := range v.GetValue() {
+			for _, name := range strings.Split(value, ",") {
+				name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+				if name != "" {
+					deleteHeader(headers, name)
+				}
+			}
+		}
+	}
+
+	for i := 0; i < len(hopHeaders); i++ {
+		deleteHeader(headers, hopHeaders[i])
+	}
+}
+
+// deleteHeader removes the header in any case, worker responses might use not canonical keys.
+func deleteHeader(headers map[string]*httpV1proto.HeaderValue, name string) {
+	if _, ok := headers[name]; ok {
+		delete(headers, name)
+		return
+	}
+
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			delete(headers, k)
+		}
+	}
+}
+
+// connectionClose returns true if the Connection header contains the close option.
+func connectionClose(headers map[string]*httpV1proto.HeaderValue) bool {
+	for k, v := range headers {
+		if !strings.EqualFold(k, connectionHeader) {
+			continue
+		}
+
+		for _, value := range v.GetValue() {
+			for _, option := range strings.Split(value, ",") {
+				if strings.EqualFold(strings.TrimSpace(option), "close") {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
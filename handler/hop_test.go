@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"testing"
+
+	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripHopByHop(t *testing.T) {
+	headers := map[string]*httpV1proto.HeaderValue{
+		"Connection":   {Value: []string{"keep-alive, X-Hop"}},
+		"Keep-Alive":   {Value: []string{"timeout=5"}},
+		"x-hop":        {Value: []string{"1"}},
+		"upgrade":      {Value: []string{"websocket"}},
+		"Content-Type": {Value: []string{"text/plain"}},
+	}
+
+	assert.False(t, connectionClose(headers))
+	stripHopByHop(headers)
+	assert.Len(t, headers, 1)
+	assert.Contains(t, headers, "Content-Type")
+}
+
+func TestConnectionClose(t *testing.T) {
+	assert.True(t, connectionClose(map[string]*httpV1proto.HeaderValue{"connection": {Value: []string{"Upgrade, Close"}}}))
+}
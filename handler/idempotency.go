@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"go.uber.org/zap"
+)
+
+const (
+	// idempotentReplayedHeader marks the stored responses sent to the retries.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLen is the max length of the idempotency key accepted from the client
+	maxIdempotencyKeyLen int = 255
+)
+
+// idempotentResponse is the stored response of the request with the idempotency key.
+type idempotentResponse struct {
+	key string
+	// hash of the method, the URI and the body
+	fingerprint [sha256.Size]byte
+	// the request is being handled
+	pending bool
+	// the position in the expiration order, nil while pending
+	el      *list.Element
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// idempotency deduplicates the retries of the requests with the idempotency key.
+type idempotency struct {
+	mu      sync.Mutex
+	entries map[string]*idempotentResponse
+	// the completed responses in the expiration order, the TTL is the same for all of them
+	order *list.List
+	size  int64
+
+	header       string
+	methods      []string
+	required     []string
+	scopes       []string
+	ttl          time.Duration
+	maxBody      int64
+	maxSize      int64
+	maxEntrySize int64
+	stats        *Stats
+	log          *zap.Logger
+}
+
+func newIdempotency(cfg *config.Idempotency, stats *Stats, log *zap.Logger) *idempotency {
+	return &idempotency{
+		entries:      make(map[string]*idempotentResponse),
+		order:        list.New(),
+		header:       cfg.Header,
+		methods:      cfg.Methods,
+		required:     cfg.Required,
+		scopes:       cfg.Scope,
+		ttl:          cfg.TTL,
+		maxBody:      cfg.MaxBodySize,
+		maxSize:      cfg.MaxSize,
+		maxEntrySize: cfg.MaxEntrySize,
+		stats:        stats,
+		log:          log,
+	}
+}
+
+// applies returns true if the request method is handled with the idempotency key.
+func (i *idempotency) applies(r *http.Request) bool {
+	return slices.Contains(i.methods, r.Method)
+}
+
+// serve writes the stored response for the retry or the response of the next handler, which is stored.
+func (i *idempotency) serve(w http.ResponseWriter, r *http.Request, next func(w http.ResponseWriter, r *http.Request)) {
+	// the key is a structured field string, the quotes are optional for the compatibility
+	key := strings.Trim(strings.TrimSpace(r.Header.Get(i.header)), `"`)
+	if key == "" {
+		if i.isRequired(r) {
+			http.Error(w, i.header+" header is required", http.StatusBadRequest)
+			return
+		}
+
+		next(w, r)
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLen {
+		http.Error(w, i.header+" header is too long", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, i.maxBody+1))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if int64(len(body)) > i.maxBody {
+		fail(r, CodeSizeExceeded, nil)
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	scoped := i.scope(r, key)
+	fp := fingerprint(r, body)
+
+	i.mu.Lock()
+	if e, ok := i.entries[scoped]; ok {
+		switch {
+		case !e.pending && time.Now().After(e.expires):
+			i.remove(e)
+		case e.fingerprint != fp:
+			i.mu.Unlock()
+			http.Error(w, i.header+" is already used for a different request", http.StatusUnprocessableEntity)
+			return
+		case e.pending:
+			i.mu.Unlock()
+			http.Error(w, "the request with the same "+i.header+" is being processed", http.StatusConflict)
+			return
+		default:
+			i.mu.Unlock()
+			i.stats.IdempotentReplays.Add(1)
+			e.write(w, r)
+			return
+		}
+	}
+
+	e := &idempotentResponse{key: scoped, fingerprint: fp, pending: true}
+	i.entries[scoped] = e
+	i.mu.Unlock()
+
+	// the pending entry would block the retries forever if the next handler panics
+	defer func() {
+		if rec := recover(); rec != nil {
+			i.mu.Lock()
+			delete(i.entries, scoped)
+			i.mu.Unlock()
+			panic(rec)
+		}
+	}()
+
+	cw := newCaptureWriter(w, i.maxEntrySize)
+	next(cw, r)
+	i.complete(e, cw)
+}
+
+// complete stores the response, the failed requests are removed to be retried.
+func (i *idempotency) complete(e *idempotentResponse, cw *captureWriter) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if cw.status == 0 || cw.status >= http.StatusInternalServerError || cw.overflow || cw.partial {
+		delete(i.entries, e.key)
+		return
+	}
+
+	e.pending = false
+	e.status = cw.status
+	e.header = cw.snapshot.Clone()
+	e.body = append([]byte(nil), cw.body.Bytes()...)
+	e.expires = time.Now().Add(i.ttl)
+
+	e.el = i.order.PushBack(e)
+	i.size += int64(len(e.body))
+
+	// evict the expired and the oldest responses
+	now := time.Now()
+	for el := i.order.Front(); el != nil; el = i.order.Front() {
+		oldest := el.Value.(*idempotentResponse)
+		if i.size <= i.maxSize && now.Before(oldest.expires) {
+			break
+		}
+
+		i.remove(oldest)
+	}
+}
+
+// remove deletes the completed response, should be called under the lock.
+func (i *idempotency) remove(e *idempotentResponse) {
+	i.order.Remove(e.el)
+	delete(i.entries, e.key)
+	i.size -= int64(len(e.body))
+}
+
+func (i *idempotency) isRequired(r *http.Request) bool {
+	fp := path.Clean("/" + r.URL.Path)
+	for j := 0; j < len(i.required); j++ {
+		if matchPath(i.required[j], fp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scope returns the key scoped by the host and the scope headers (credentials, session), so the clients can't get the
+// responses of the others.
+func (i *idempotency) scope(r *http.Request, key string) string {
+	h := sha256.New()
+	for j := 0; j < len(i.scopes); j++ {
+		for _, v := range r.Header.Values(i.scopes[j]) {
+			h.Write([]byte(v))
+			h.Write([]byte{'\n'})
+		}
+		h.Write([]byte{0})
+	}
+
+	return r.Host + "\n" + string(h.Sum(nil)) + "\n" + key
+}
+
+// fingerprint hashes the request method, URI and body.
+func fingerprint(r *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{' '})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// write sends the stored response.
+func (e *idempotentResponse) write(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	for k, v := range e.header {
+		h[k] = v
+	}
+
+	h.Set(idempotentReplayedHeader, "true")
+	h.Set(contentLength, strconv.Itoa(len(e.body)))
+	w.WriteHeader(e.status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(e.body)
+	}
+}
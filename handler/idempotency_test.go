@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestIdempotency(t *testing.T) {
+	cfg := &config.Idempotency{Required: []string{"/payments/**"}}
+	require.NoError(t, cfg.InitDefaults())
+	require.NoError(t, cfg.Valid())
+	stats := &Stats{}
+	idem := newIdempotency(cfg, stats, zap.NewNop())
+
+	calls := 0
+	status := http.StatusCreated
+	var pending func()
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if pending != nil {
+			pending()
+		}
+		w.Header().Set("X-Charge", strings.Repeat("x", calls))
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}
+
+	send := func(key, uri, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, uri, strings.NewReader(body))
+		if key != "" {
+			r.Header.Set(cfg.Header, key)
+		}
+		w := httptest.NewRecorder()
+		idem.serve(w, r, next)
+		return w
+	}
+
+	w := send(`"key-1"`, "/payments/1", "amount=10")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "amount=10", w.Body.String())
+
+	// the retry gets the stored response
+	w = send("key-1", "/payments/1", "amount=10")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "amount=10", w.Body.String())
+	assert.Equal(t, "x", w.Header().Get("X-Charge"))
+	assert.Equal(t, "true", w.Header().Get(idempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, uint64(1), stats.IdempotentReplays.Load())
+
+	// the key is reused for a different payload
+	w = send("key-1", "/payments/1", "amount=20")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// the key is required
+	w = send("", "/payments/2", "amount=10")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = send("", "/other", "amount=10")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 2, calls)
+
+	// the concurrent retry
+	pending = func() {
+		pending = nil
+		assert.Equal(t, http.StatusConflict, send("key-2", "/payments/2", "amount=10").Code)
+	}
+	send("key-2", "/payments/2", "amount=10")
+	assert.Equal(t, 3, calls)
+
+	// the failed requests are retried
+	status = http.StatusBadGateway
+	send("key-3", "/payments/3", "amount=10")
+	status = http.StatusCreated
+	w = send("key-3", "/payments/3", "amount=10")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(idempotentReplayedHeader))
+	assert.Equal(t, 5, calls)
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	cfg := &config.Idempotency{Scope: []string{"cookie", "x-session"}}
+	require.NoError(t, cfg.InitDefaults())
+	idem := newIdempotency(cfg, &Stats{}, zap.NewNop())
+
+	calls := 0
+	next := func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	send := func(header, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader("amount=10"))
+		r.Header.Set(cfg.Header, "key-1")
+		r.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		idem.serve(w, r, next)
+		return w
+	}
+
+	send("Cookie", "session=a")
+	assert.Equal(t, "true", send("Cookie", "session=a").Header().Get(idempotentReplayedHeader))
+	// the same key of the other sessions is not replayed
+	assert.Empty(t, send("Cookie", "session=b").Header().Get(idempotentReplayedHeader))
+	assert.Empty(t, send("X-Session", "a").Header().Get(idempotentReplayedHeader))
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotencyPanic(t *testing.T) {
+	cfg := &config.Idempotency{}
+	require.NoError(t, cfg.InitDefaults())
+	idem := newIdempotency(cfg, &Stats{}, zap.NewNop())
+
+	send := func(next func(w http.ResponseWriter, r *http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader("amount=10"))
+		r.Header.Set(cfg.Header, "key-1")
+		w := httptest.NewRecorder()
+		idem.serve(w, r, next)
+		return w
+	}
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		send(func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) })
+	})
+
+	// the retry is not rejected as the pending one
+	w := send(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusCreated) })
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
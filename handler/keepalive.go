@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+// whitespace is flushed to the body in the whitespace mode
+var whitespace = []byte{' '} //nolint:gochecknoglobals
+
+// keepalive sends the periodic signals to the client while the worker is executing the long request.
+type keepalive struct {
+	after       time.Duration
+	interval    time.Duration
+	whitespace  bool
+	contentType string
+	paths       *attributePatterns
+}
+
+func newKeepalive(cfg *config.LongRequests) *keepalive {
+	return &keepalive{
+		after:       cfg.After,
+		interval:    cfg.Interval,
+		whitespace:  cfg.Mode == config.KeepaliveWhitespace,
+		contentType: cfg.ContentType,
+		paths:       newAttributePatterns(cfg.Paths),
+	}
+}
+
+// start sends the signals until the returned function is called. The writer must not be used until then, the
+// returned function reports whether the response is started by the whitespace. The whitespace is not sent to the
+// buffered captures (not sent to the client until the worker responds), the passed through captures are not stored:
+// the recorded 200 status is not the worker status.
+func (k *keepalive) start(w http.ResponseWriter, r *http.Request) func() bool {
+	cw, captured := w.(*captureWriter)
+	if (k.paths != nil && !k.paths.match(r.URL.Path)) || (!k.whitespace && !r.ProtoAtLeast(1, 1)) ||
+		(k.whitespace && captured && cw.w == nil) {
+		return func() bool { return false }
+	}
+
+	stopCh := make(chan struct{})
+	doneCh := make(chan bool, 1)
+	go func() {
+		var started bool
+		defer func() {
+			doneCh <- started
+		}()
+
+		timer := time.NewTimer(k.after)
+		defer timer.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-r.Context().Done():
+				return
+			case <-timer.C:
+			}
+
+			if !k.whitespace {
+				// the interim responses are sent at once, the flush would send the final status
+				w.WriteHeader(http.StatusProcessing)
+				timer.Reset(k.interval)
+				continue
+			}
+
+			if !started {
+				started = true
+				if captured {
+					cw.partial = true
+				}
+				w.Header().Set("Content-Type", k.contentType)
+				w.WriteHeader(http.StatusOK)
+			}
+
+			_, err := w.Write(whitespace)
+			if err == nil {
+				err = http.NewResponseController(w).Flush() //nolint:bodyclose
+			}
+
+			// the client is gone
+			if err != nil {
+				return
+			}
+
+			timer.Reset(k.interval)
+		}
+	}()
+
+	return func() bool {
+		close(stopCh)
+		return <-doneCh
+	}
+}
+
+var _ http.ResponseWriter = (*committedWriter)(nil)
+
+// committedWriter writes the worker response after the whitespace, the status and the headers are already sent.
+type committedWriter struct {
+	http.ResponseWriter
+}
+
+func (c *committedWriter) WriteHeader(int) {}
+
+// ReadFrom passes the reader to the underlying writer (sendfile).
+func (c *committedWriter) ReadFrom(src io.Reader) (int64, error) {
+	if rf, ok := c.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+
+	return io.Copy(c.ResponseWriter, src)
+}
+
+// Unwrap is used by the http.ResponseController.
+func (c *committedWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestKeepaliveProcessing(t *testing.T) {
+	cfg := &config.LongRequests{After: 20 * time.Millisecond, Interval: 20 * time.Millisecond, Paths: []string{"/reports/*"}}
+	require.NoError(t, cfg.InitDefaults())
+	require.NoError(t, cfg.Valid())
+	k := newKeepalive(cfg)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stop := k.start(w, r)
+		time.Sleep(110 * time.Millisecond)
+		assert.False(t, stop())
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("report"))
+	}))
+	defer srv.Close()
+
+	var interim atomic.Int32
+	get := func(path string) *http.Response {
+		trace := &httptrace.ClientTrace{
+			Got1xxResponse: func(code int, _ textproto.MIMEHeader) error {
+				if code == http.StatusProcessing {
+					interim.Add(1)
+				}
+				return nil
+			},
+		}
+
+		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := get("/reports/monthly")
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "report", string(body))
+	assert.GreaterOrEqual(t, interim.Load(), int32(3))
+
+	// the other paths get no signals
+	interim.Store(0)
+	resp = get("/users")
+	_ = resp.Body.Close()
+	assert.Equal(t, int32(0), interim.Load())
+}
+
+func TestKeepaliveWhitespace(t *testing.T) {
+	cfg := &config.LongRequests{After: 20 * time.Millisecond, Interval: 20 * time.Millisecond, Mode: config.KeepaliveWhitespace}
+	require.NoError(t, cfg.InitDefaults())
+	k := newKeepalive(cfg)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	stop := k.start(w, r)
+	time.Sleep(70 * time.Millisecond)
+	require.True(t, stop())
+
+	// the worker response is appended, the status and the headers are already sent
+	out := &committedWriter{ResponseWriter: w}
+	out.Header().Set("Content-Type", "application/json")
+	out.WriteHeader(http.StatusCreated)
+	_, _ = out.Write([]byte(`{"ok":true}`))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Result().Header.Get("Content-Type")) //nolint:bodyclose
+	assert.Regexp(t, `^ {2,}\{"ok":true\}$`, w.Body.String())
+
+	// the short requests are not signaled
+	w = httptest.NewRecorder()
+	assert.False(t, k.start(w, r)())
+	assert.Empty(t, w.Body.String())
+}
+
+func TestKeepaliveIdempotency(t *testing.T) {
+	cfg := &config.LongRequests{After: 20 * time.Millisecond, Interval: 20 * time.Millisecond, Mode: config.KeepaliveWhitespace}
+	require.NoError(t, cfg.InitDefaults())
+	k := newKeepalive(cfg)
+
+	icfg := &config.Idempotency{}
+	require.NoError(t, icfg.InitDefaults())
+	idem := newIdempotency(icfg, &Stats{}, zap.NewNop())
+
+	calls := 0
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		stop := k.start(w, r)
+		time.Sleep(70 * time.Millisecond)
+		if stop() {
+			w = &committedWriter{ResponseWriter: w}
+		}
+
+		// the worker failed after the whitespace was sent
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	send := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/payments/1", nil)
+		r.Header.Set(icfg.Header, "key-1")
+		w := httptest.NewRecorder()
+		idem.serve(w, r, next)
+		return w
+	}
+
+	// the whitespace status is not stored, the retry is executed again
+	assert.Equal(t, http.StatusOK, send().Code)
+	assert.Equal(t, http.StatusOK, send().Code)
+	assert.Equal(t, 2, calls)
+
+	// the buffered captures get no whitespace
+	cw := newCaptureWriter(nil, 1024)
+	stop := k.start(cw, httptest.NewRequest(http.MethodGet, "/", nil))
+	time.Sleep(70 * time.Millisecond)
+	assert.False(t, stop())
+	assert.Zero(t, cw.status)
+}
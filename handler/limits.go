@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+// limits failure reasons, reported in the logs
+const (
+	reasonTooDeep       string = "fields_too_deep"
+	reasonTooManyFields string = "too_many_fields"
+	reasonQueryParams   string = "too_many_query_params"
+	reasonQueryKey      string = "query_key_too_long"
+	reasonQueryValue    string = "query_value_too_long"
+	reasonQueryDepth    string = "query_too_deep"
+)
+
+// checkFields checks the nesting depth of the field names and the number of the fields, count is shared between
+// the values and the files of the same form.
+func checkFields[V any](limits *config.FormLimits, fields map[string]V, count *int) error {
+	if limits == nil {
+		return nil
+	}
+
+	*count += len(fields)
+	if limits.MaxKeys > 0 && *count > limits.MaxKeys {
+		return &validationError{reason: reasonTooManyFields}
+	}
+
+	if limits.MaxDepth <= 0 {
+		return nil
+	}
+
+	keys := make([]string, 1)
+	for k := range fields {
+		keys = keys[:1]
+		keys[0] = ""
+		fetchIndexes(k, &keys)
+		if len(keys) > limits.MaxDepth {
+			return &validationError{reason: reasonTooDeep, name: k}
+		}
+	}
+
+	return nil
+}
+
+// checkQuery checks the raw query against the limits, unless the path matches one of the raw routes.
+func checkQuery(limits *config.QueryLimits, r *http.Request) *validationError {
+	if r.URL.RawQuery == "" {
+		return nil
+	}
+
+	for i := 0; i < len(limits.RawRoutes); i++ {
+		if strings.HasPrefix(r.URL.Path, limits.RawRoutes[i]) {
+			return nil
+		}
+	}
+
+	count := 0
+	keys := make([]string, 1)
+	for query := r.URL.RawQuery; query != ""; {
+		var param string
+		param, query, _ = strings.Cut(query, "&")
+		if param == "" {
+			continue
+		}
+
+		count++
+		if limits.MaxParams > 0 && count > limits.MaxParams {
+			return &validationError{reason: reasonQueryParams}
+		}
+
+		k, v, _ := strings.Cut(param, "=")
+		if key, err := url.QueryUnescape(k); err == nil {
+			k = key
+		}
+
+		if limits.MaxKeyLength > 0 && len(k) > limits.MaxKeyLength {
+			return &validationError{reason: reasonQueryKey}
+		}
+
+		if limits.MaxValueLength > 0 {
+			if value, err := url.QueryUnescape(v); err == nil {
+				v = value
+			}
+
+			if len(v) > limits.MaxValueLength {
+				return &validationError{reason: reasonQueryValue, name: k}
+			}
+		}
+
+		if limits.MaxDepth > 0 {
+			keys = keys[:1]
+			keys[0] = ""
+			fetchIndexes(k, &keys)
+			if len(keys) > limits.MaxDepth {
+				return &validationError{reason: reasonQueryDepth, name: k}
+			}
+		}
+	}
+
+	return nil
+}
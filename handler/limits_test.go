@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckFields(t *testing.T) {
+	limits := &config.FormLimits{MaxDepth: 3, MaxKeys: 3}
+	fields := map[string][]string{"a[b][c]": {"1"}, "d": {"2"}}
+
+	count := 0
+	assert.NoError(t, checkFields(limits, fields, &count))
+	// the count is shared between the values and the files
+	assert.Error(t, checkFields(limits, fields, &count))
+
+	assert.Error(t, checkFields(limits, map[string][]string{"a[b][c][d]": {"1"}}, new(int)))
+}
+
+func TestCheckQuery(t *testing.T) {
+	limits := &config.QueryLimits{MaxParams: 2, MaxKeyLength: 8, MaxValueLength: 4, MaxDepth: 2, RawRoutes: []string{"/raw"}}
+
+	tests := map[string]bool{
+		"/?a=1&b=2":          true,
+		"/?a=1&b=2&c=3":      false,
+		"/raw?a=1&b=2&c=3":   true,
+		"/?verylongkey=1":    false,
+		"/?a=%41%41%41%41":   true,
+		"/?a=12345":          false,
+		"/?a[b]=1":           true,
+		"/?a%5Bb%5D%5Bc%5D=": false,
+	}
+
+	for uri, ok := range tests {
+		r := httptest.NewRequest(http.MethodGet, uri, nil)
+		assert.Equal(t, ok, checkQuery(limits, r) == nil, uri)
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"bytes"
+
+	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// msgpackRequest is the msgpack format of the request context, the fields follow the http.v1 Request message.
+type msgpackRequest struct {
+	RemoteAddr string              `msgpack:"remote_addr"`
+	Protocol   string              `msgpack:"protocol"`
+	Method     string              `msgpack:"method"`
+	URI        string              `msgpack:"uri"`
+	Header     map[string][]string `msgpack:"header"`
+	Cookies    map[string][]string `msgpack:"cookies"`
+	RawQuery   string              `msgpack:"raw_query"`
+	Parsed     bool                `msgpack:"parsed"`
+	// the JSON tree of the uploaded files, empty without the uploads
+	Uploads    string              `msgpack:"uploads"`
+	Attributes map[string][]string `msgpack:"attributes"`
+	// the connection info, sent only if enabled
+	Connection *connectionInfo `msgpack:"connection,omitempty"`
+}
+
+// msgpackResponse is the msgpack format of the response context, the fields follow the http.v1 Response message.
+type msgpackResponse struct {
+	Status  int64               `msgpack:"status"`
+	Headers map[string][]string `msgpack:"headers"`
+}
+
+// marshalMsgpack appends the msgpack encoded request context to the buffer.
+func marshalMsgpack(buf []byte, req *httpV1proto.Request, conn *connectionInfo) ([]byte, error) {
+	b := bytes.NewBuffer(buf)
+	err := msgpack.NewEncoder(b).Encode(&msgpackRequest{
+		RemoteAddr: req.GetRemoteAddr(),
+		Protocol:   req.GetProtocol(),
+		Method:     req.GetMethod(),
+		URI:        req.GetUri(),
+		Header:     fromHeaderValues(req.GetHeader()),
+		Cookies:    fromHeaderValues(req.GetCookies()),
+		RawQuery:   req.GetRawQuery(),
+		Parsed:     req.GetParsed(),
+		Uploads:    string(req.GetUploads()),
+		Attributes: fromHeaderValues(req.GetAttributes()),
+		Connection: conn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// unmarshalMsgpack decodes the msgpack response context into the proto response.
+func unmarshalMsgpack(data []byte, rsp *httpV1proto.Response) error {
+	mr := &msgpackResponse{}
+	err := msgpack.Unmarshal(data, mr)
+	if err != nil {
+		return err
+	}
+
+	rsp.Status = mr.Status
+	if len(mr.Headers) > 0 {
+		rsp.Headers = make(map[string]*httpV1proto.HeaderValue, len(mr.Headers))
+		for k, v := range mr.Headers {
+			rsp.Headers[k] = &httpV1proto.HeaderValue{Value: v}
+		}
+	}
+
+	return nil
+}
+
+func fromHeaderValues(hv map[string]*httpV1proto.HeaderValue) map[string][]string {
+	if len(hv) == 0 {
+		return nil
+	}
+
+	m := make(map[string][]string, len(hv))
+	for k, v := range hv {
+		m[k] = v.GetValue()
+	}
+
+	return m
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+)
+
+func TestMsgpackCodec(t *testing.T) {
+	h, err := NewHandler(&config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500, Codec: config.CodecMsgpack}, &replayPool{}, zap.NewNop())
+	require.NoError(t, err)
+
+	pld := h.getPld()
+	assert.Equal(t, frame.CodecMsgpack, pld.Codec)
+
+	req := &Request{}
+	err = req.PayloadContext(pld, &httpV1proto.Request{
+		Method:     http.MethodPost,
+		Uri:        "http://example.com/a?b=c",
+		RawQuery:   "b=c",
+		Header:     map[string]*httpV1proto.HeaderValue{"Accept": {Value: []string{"a", "b"}}},
+		Attributes: map[string]*httpV1proto.HeaderValue{"tenant": {Value: []string{"t1"}}},
+	})
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, msgpack.Unmarshal(pld.Context, &decoded))
+	assert.Equal(t, http.MethodPost, decoded["method"])
+	assert.Equal(t, "b=c", decoded["raw_query"])
+	assert.Equal(t, map[string]any{"Accept": []any{"a", "b"}}, decoded["header"])
+	assert.Equal(t, map[string]any{"tenant": []any{"t1"}}, decoded["attributes"])
+	assert.Equal(t, "", decoded["uploads"])
+	h.putPld(pld)
+
+	// the worker response
+	ctx, err := msgpack.Marshal(map[string]any{
+		"status":  201,
+		"headers": map[string][]string{"Content-Type": {"text/plain"}},
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	err = h.Write(&payload.Payload{Codec: frame.CodecMsgpack, Context: ctx, Body: []byte("created")}, w)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "created", w.Body.String())
+
+	err = h.Write(&payload.Payload{Codec: frame.CodecMsgpack, Context: []byte{0xc1}}, httptest.NewRecorder())
+	assert.Error(t, err)
+}
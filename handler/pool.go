@@ -5,7 +5,6 @@ import (
 	"strings"
 
 	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
-	"github.com/roadrunner-server/goridge/v3/pkg/frame"
 	"github.com/roadrunner-server/http/v5/attributes"
 	"github.com/roadrunner-server/http/v5/config"
 	"github.com/roadrunner-server/pool/payload"
@@ -18,11 +17,25 @@ func (h *Handler) getProtoReq(r *Request) *httpV1proto.Request {
 	req.Protocol = r.Protocol
 	req.Method = r.Method
 	req.Uri = r.URI
-	req.Header = convert(r.Header)
-	req.Cookies = convertCookies(r.Cookies)
+	// maps are reused from the previous request
+	req.Header = convert(req.Header, r.Header)
+	stripHopByHop(req.Header)
+	req.Cookies = convertCookies(req.Cookies, r.Cookies)
 	req.RawQuery = r.RawQuery
 	req.Parsed = r.Parsed
-	req.Attributes = convert(r.Attributes)
+	req.Attributes = convert(req.Attributes, r.Attributes)
+
+	if r.bodyFile != "" {
+		if req.Attributes == nil {
+			req.Attributes = make(map[string]*httpV1proto.HeaderValue, 1)
+		}
+
+		req.Attributes[BodyFileAttr] = &httpV1proto.HeaderValue{Value: []string{r.bodyFile}}
+	}
+
+	if r.conn != nil {
+		r.conn.setUnknown(req.ProtoReflect())
+	}
 
 	return req
 }
@@ -32,12 +45,15 @@ func (h *Handler) putProtoReq(req *httpV1proto.Request) {
 	req.Protocol = ""
 	req.Method = ""
 	req.Uri = ""
-	req.Header = nil
-	req.Cookies = nil
+	// header, cookies and attributes maps are kept to be reused
 	req.RawQuery = ""
 	req.Parsed = false
-	req.Uploads = nil
-	req.Attributes = nil
+	// keep the uploads buffer, unless it's too big
+	req.Uploads = reuse(req.Uploads)
+	// keep the connection info buffer
+	if m := req.ProtoReflect(); len(m.GetUnknown()) > 0 {
+		m.SetUnknown(m.GetUnknown()[:0])
+	}
 
 	h.protoReqPool.Put(req)
 }
@@ -54,9 +70,27 @@ func (h *Handler) getReq(r *http.Request) *Request {
 	req.Protocol = r.Proto
 	req.Method = r.Method
 	req.URI = URI(r)
+	if h.proxyScheme && h.isTrusted(req.RemoteAddr) {
+		if scheme := proxyScheme(r.Header); scheme != "" {
+			req.URI = schemeURI(r, scheme)
+		}
+	}
 	req.Header = r.Header
-	req.Cookies = make(map[string]string)
+	if h.forwardedFormat != "" {
+		req.Header = h.forward(r, req.RemoteAddr)
+	}
+
+	if req.Cookies == nil {
+		req.Cookies = make(map[string]string)
+	}
 	req.Attributes = attributes.All(r)
+	if h.attributes != nil {
+		req.Attributes = h.attributes.filter(req.Attributes)
+	}
+
+	if h.connectionInfo {
+		req.conn = newConnectionInfo(r)
+	}
 
 	req.Parsed = false
 	req.body = nil
@@ -69,12 +103,14 @@ func (h *Handler) putReq(req *Request) {
 	req.Method = ""
 	req.URI = ""
 	req.Header = nil
-	req.Cookies = nil
+	clear(req.Cookies)
 	req.RawQuery = ""
 	req.Parsed = false
 	req.Uploads = nil
 	req.Attributes = nil
 	req.body = nil
+	req.bodyFile = ""
+	req.conn = nil
 
 	h.reqPool.Put(req)
 }
@@ -91,13 +127,14 @@ func (h *Handler) getProtoRsp() *httpV1proto.Response {
 
 func (h *Handler) putPld(pld *payload.Payload) {
 	pld.Body = nil
-	pld.Context = nil
+	// the context is always marshaled into the pooled buffer
+	pld.Context = reuse(pld.Context)
 	h.pldPool.Put(pld)
 }
 
 func (h *Handler) getPld() *payload.Payload {
 	pld := h.pldPool.Get().(*payload.Payload)
-	pld.Codec = frame.CodecProto
+	pld.Codec = h.payloadCodec
 	return pld
 }
 
@@ -115,9 +152,27 @@ func (h *Handler) putCh(ch chan struct{}) {
 }
 
 func checkDebug(cfg *config.Config) bool {
-	if cfg != nil && cfg.Pool != nil {
+	if cfg == nil {
+		return false
+	}
+
+	// the pool debug mode might be replaced with the on-demand workers
+	if cfg.Debug != nil && cfg.Debug.Active {
+		return true
+	}
+
+	if cfg.Pool != nil {
 		return cfg.Pool.Debug
 	}
 
 	return false
 }
+
+// reuse truncates the buffer to be reused, big buffers are released to the GC
+func reuse(buf []byte) []byte {
+	if cap(buf) > maxPooledBuffer {
+		return nil
+	}
+
+	return buf[:0]
+}
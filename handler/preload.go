@@ -0,0 +1,160 @@
+package handler
+
+import (
+	stderr "errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+const linkHeader string = "Link"
+
+// preload translates the `Link: rel=preload` headers from the worker responses into the HTTP/2 push or the
+// 103 Early Hints for the next requests to the same path.
+type preload struct {
+	mode     config.PreloadMode
+	maxPaths int
+
+	mu    sync.RWMutex
+	links map[string][]string
+}
+
+func newPreload(cfg *config.Preload) *preload {
+	p := &preload{
+		mode:     cfg.Mode,
+		maxPaths: cfg.MaxPaths,
+		links:    make(map[string][]string),
+	}
+
+	if p.mode == "" {
+		p.mode = config.PreloadHints
+	}
+
+	if p.maxPaths <= 0 {
+		p.maxPaths = 1000
+	}
+
+	return p
+}
+
+// hints sends the 103 Early Hints with the links remembered for the request path.
+func (p *preload) hints(w http.ResponseWriter, r *http.Request) {
+	if p.mode != config.PreloadHints || r.Method != http.MethodGet {
+		return
+	}
+
+	p.mu.RLock()
+	links := p.links[r.URL.Path]
+	p.mu.RUnlock()
+
+	if len(links) == 0 {
+		return
+	}
+
+	for i := 0; i < len(links); i++ {
+		w.Header().Add(linkHeader, links[i])
+	}
+
+	w.WriteHeader(http.StatusEarlyHints)
+	// the final response sets its own links
+	w.Header().Del(linkHeader)
+}
+
+// record remembers the preload links from the response headers.
+func (p *preload) record(r *http.Request, header http.Header) {
+	if p.mode != config.PreloadHints || r.Method != http.MethodGet {
+		return
+	}
+
+	links := preloadLinks(header.Values(linkHeader))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(links) == 0 {
+		delete(p.links, r.URL.Path)
+		return
+	}
+
+	if _, ok := p.links[r.URL.Path]; !ok && len(p.links) >= p.maxPaths {
+		return
+	}
+
+	p.links[r.URL.Path] = links
+}
+
+// push pushes the preloaded resources, if supported by the connection.
+func (p *preload) push(w http.ResponseWriter, headers map[string]*httpV1proto.HeaderValue) error {
+	if p.mode != config.PreloadPush {
+		return nil
+	}
+
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return nil
+	}
+
+	var values []string
+	for k, v := range headers {
+		if strings.EqualFold(k, linkHeader) {
+			values = append(values, v.GetValue()...)
+		}
+	}
+
+	links := preloadLinks(values)
+	for i := 0; i < len(links); i++ {
+		target, _, _ := strings.Cut(links[i], ";")
+		target = strings.Trim(strings.TrimSpace(target), "<>")
+		// only the same origin resources could be pushed
+		if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+			continue
+		}
+
+		err := pusher.Push(target, nil)
+		if err != nil {
+			if stderr.Is(err, http.ErrNotSupported) {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// preloadLinks returns the links with the rel=preload parameter.
+func preloadLinks(values []string) []string {
+	var links []string
+	for _, value := range values {
+		for _, link := range splitQuoted(value, ',') {
+			link = strings.TrimSpace(link)
+			if isPreload(link) {
+				links = append(links, link)
+			}
+		}
+	}
+
+	return links
+}
+
+func isPreload(link string) bool {
+	params := splitQuoted(link, ';')
+	for i := 1; i < len(params); i++ {
+		k, v, ok := strings.Cut(strings.TrimSpace(params[i]), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(k), "rel") {
+			continue
+		}
+
+		for _, rel := range strings.Fields(strings.Trim(v, `"`)) {
+			if strings.EqualFold(rel, "preload") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreloadLinks(t *testing.T) {
+	links := preloadLinks([]string{`</app.css>; rel=preload; as=style, </next>; rel=next`, `</app.js>; rel="preload modulepreload"`})
+	assert.Equal(t, []string{"</app.css>; rel=preload; as=style", `</app.js>; rel="preload modulepreload"`}, links)
+}
+
+func TestPreloadHints(t *testing.T) {
+	p := newPreload(&config.Preload{})
+	r := httptest.NewRequest(http.MethodGet, "/page", nil)
+
+	header := http.Header{}
+	header.Add(linkHeader, "</app.css>; rel=preload; as=style")
+	p.record(r, header)
+
+	w := httptest.NewRecorder()
+	p.hints(w, r)
+	// the recorder keeps the first status
+	assert.Equal(t, http.StatusEarlyHints, w.Code)
+	assert.Empty(t, w.Header().Get(linkHeader))
+}
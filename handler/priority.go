@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"container/heap"
+	"context"
+	stderr "errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errQueueTimeout is returned when the request waited for a free worker longer than allowed
+var errQueueTimeout = stderr.New("queue wait timeout exceeded") //nolint:gochecknoglobals
+
+// waiter is the request waiting for the dispatch slot.
+type waiter struct {
+	priority int
+	// seq preserves FIFO order for the requests with the same priority
+	seq   uint64
+	ready chan struct{}
+	index int
+	// since is the enqueue time
+	since time.Time
+}
+
+type waiters []*waiter
+
+func (w waiters) Len() int { return len(w) }
+
+func (w waiters) Less(i, j int) bool {
+	if w[i].priority == w[j].priority {
+		return w[i].seq < w[j].seq
+	}
+
+	return w[i].priority > w[j].priority
+}
+
+func (w waiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *waiters) Push(x any) {
+	wt := x.(*waiter)
+	wt.index = len(*w)
+	*w = append(*w, wt)
+}
+
+func (w *waiters) Pop() any {
+	old := *w
+	n := len(old)
+	wt := old[n-1]
+	old[n-1] = nil
+	wt.index = -1
+	*w = old[:n-1]
+	return wt
+}
+
+// priorityGate limits the number of requests dispatched to the pool at once. When all slots are busy, the requests
+// wait in the priority queue, so the high-priority requests are dispatched first.
+type priorityGate struct {
+	mu       sync.Mutex
+	capacity int64
+	inUse    int64
+	seq      uint64
+	queue    waiters
+}
+
+func newPriorityGate(capacity int64) *priorityGate {
+	return &priorityGate{
+		capacity: capacity,
+		queue:    make(waiters, 0, capacity),
+	}
+}
+
+// acquire waits for the free slot or for the context to be done.
+func (g *priorityGate) acquire(ctx context.Context, priority int) error {
+	g.mu.Lock()
+	if g.inUse < g.capacity && g.queue.Len() == 0 {
+		g.inUse++
+		g.mu.Unlock()
+		return nil
+	}
+
+	g.seq++
+	wt := &waiter{
+		priority: priority,
+		seq:      g.seq,
+		ready:    make(chan struct{}),
+		since:    time.Now(),
+	}
+	heap.Push(&g.queue, wt)
+	g.mu.Unlock()
+
+	select {
+	case <-wt.ready:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		// the slot might be handed to us concurrently
+		select {
+		case <-wt.ready:
+			g.releaseLocked()
+		default:
+			heap.Remove(&g.queue, wt.index)
+		}
+
+		return ctx.Err()
+	}
+}
+
+// tryAcquire takes the free slot without waiting, the waiting requests are served first.
+func (g *priorityGate) tryAcquire() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.inUse < g.capacity && g.queue.Len() == 0 {
+		g.inUse++
+		return true
+	}
+
+	return false
+}
+
+// waiting returns the number of the waiting requests and the enqueue time of the oldest one.
+func (g *priorityGate) waiting() (int, time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var oldest time.Time
+	for i := 0; i < len(g.queue); i++ {
+		if oldest.IsZero() || g.queue[i].since.Before(oldest) {
+			oldest = g.queue[i].since
+		}
+	}
+
+	return len(g.queue), oldest
+}
+
+// release frees the slot and hands it to the waiter with the highest priority.
+func (g *priorityGate) release() {
+	g.mu.Lock()
+	g.releaseLocked()
+	g.mu.Unlock()
+}
+
+func (g *priorityGate) releaseLocked() {
+	// the slot is not passed when the capacity was decreased
+	if g.queue.Len() > 0 && g.inUse <= g.capacity {
+		// the slot is passed to the waiter as is
+		wt := heap.Pop(&g.queue).(*waiter)
+		close(wt.ready)
+		return
+	}
+
+	g.inUse--
+}
+
+// resize changes the number of the slots, the waiters are dispatched when the capacity grows. The slots above the
+// decreased capacity are taken back on release.
+func (g *priorityGate) resize(capacity int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.capacity = capacity
+	for g.inUse < g.capacity && g.queue.Len() > 0 {
+		g.inUse++
+		wt := heap.Pop(&g.queue).(*waiter)
+		close(wt.ready)
+	}
+}
+
+// inFlight returns the number of the taken slots.
+func (g *priorityGate) inFlight() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.inUse
+}
+
+// priority returns the request priority, the header is honored only for the trusted peers.
+func (h *Handler) priority(r *http.Request, remoteAddr string) int {
+	val := r.Header.Get(h.priorityHeader)
+	if val == "" || !h.isTrusted(remoteAddr) {
+		return 0
+	}
+
+	p, err := strconv.Atoi(val)
+	if err != nil {
+		return 0
+	}
+
+	return p
+}
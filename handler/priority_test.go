@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPriorityGateOrder(t *testing.T) {
+	g := newPriorityGate(1)
+	require.NoError(t, g.acquire(context.Background(), 0))
+
+	order := make(chan int, 3)
+	for _, p := range []int{1, 10, 5} {
+		go func(p int) {
+			assert.NoError(t, g.acquire(context.Background(), p))
+			order <- p
+			g.release()
+		}(p)
+		// make sure that all waiters are in the queue
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	g.release()
+	assert.Equal(t, 10, <-order)
+	assert.Equal(t, 5, <-order)
+	assert.Equal(t, 1, <-order)
+}
+
+func TestPriorityGateCancel(t *testing.T) {
+	g := newPriorityGate(1)
+	require.NoError(t, g.acquire(context.Background(), 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.Error(t, g.acquire(ctx, 0))
+
+	g.release()
+	require.NoError(t, g.acquire(context.Background(), 0))
+}
+
+func TestQueueMaxWaitDefaultWorkers(t *testing.T) {
+	cfg := &config.Config{
+		Address:           "127.0.0.1:0",
+		Uploads:           &config.Uploads{},
+		InternalErrorCode: 500,
+		// num_workers is not set, the pool starts runtime.NumCPU() workers
+		Pool:  &pool.Config{},
+		Queue: &config.Queue{MaxWait: 20 * time.Millisecond},
+	}
+	require.NoError(t, cfg.InitDefaults())
+
+	wp := &contextPool{}
+	h, err := NewHandler(cfg, wp, zap.NewNop())
+	require.NoError(t, err)
+	require.NotNil(t, h.gate)
+	assert.Equal(t, int64(runtime.NumCPU()), h.gate.capacity)
+
+	// all workers are busy
+	for i := 0; i < runtime.NumCPU(); i++ {
+		require.NoError(t, h.gate.acquire(context.Background(), 0))
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get(retryAfter))
+	assert.Equal(t, uint64(1), h.stats.QueueTimeouts.Load())
+	assert.Empty(t, wp.reqs)
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/pool/fsm"
+	"github.com/roadrunner-server/pool/payload"
+	"github.com/roadrunner-server/pool/worker"
+)
+
+// Probe sends the request to the worker directly, bypassing the pool, and returns the response status. The worker
+// must not be taken by the pool during the probe, e.g. the pool doesn't receive the traffic yet. The worker is
+// killed by the timeout of the context.
+func (h *Handler) Probe(ctx context.Context, wrk *worker.Process, r *http.Request) (int, error) {
+	const op = errors.Op("http_probe")
+	req := h.getReq(r)
+	defer h.putReq(req)
+
+	pld := h.getPld()
+	defer h.putPld(pld)
+
+	err := h.probePayload(r, req, pld)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+
+	rsp, err := wrk.Exec(ctx, pld)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+
+	// the stream is not read, the worker should be replaced
+	if rsp.Flags&frame.STREAM != 0 {
+		return 0, errors.E(op, errors.Str("stream response to the probe"))
+	}
+
+	if wrk.State().Compare(fsm.StateWorking) {
+		wrk.State().Transition(fsm.StateReady)
+	}
+
+	return h.probeStatus(rsp, r)
+}
+
+// Ping sends the request to a free worker through the pool and returns the response status, the pool takes the
+// worker exclusively. The ping yields to the requests: it is not sent while the requests wait for the workers and it
+// takes the concurrency slot without waiting, otherwise the NoFreeWorkers error is returned. The context should have
+// no deadline, it bounds the wait for the worker and the exec at once: the worker not responding is killed by the pool
+// exec_ttl counted from the moment the worker is taken. The stream responses are stopped.
+func (h *Handler) Ping(ctx context.Context, r *http.Request) (int, error) {
+	const op = errors.Op("http_ping")
+	if h.stats.Pending.Load() > 0 {
+		return 0, errors.E(op, errors.NoFreeWorkers, errors.Str("the requests wait for the workers"))
+	}
+
+	if h.gate != nil {
+		if !h.gate.tryAcquire() {
+			return 0, errors.E(op, errors.NoFreeWorkers, errors.Str("the concurrency limit is reached"))
+		}
+		defer h.gate.release()
+	}
+
+	req := h.getReq(r)
+	defer h.putReq(req)
+
+	pld := h.getPld()
+	defer h.putPld(pld)
+
+	err := h.probePayload(r, req, pld)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+
+	stopCh := make(chan struct{}, 1)
+	wResp, err := (*h.pool.Load()).Exec(ctx, pld, stopCh)
+	if err != nil {
+		return 0, err
+	}
+
+	recv := <-wResp
+	if recv == nil {
+		return 0, errors.E(op, errors.Str("empty response to the ping"))
+	}
+
+	if recv.Error() != nil {
+		return 0, recv.Error()
+	}
+
+	if recv.Payload().Flags&frame.STREAM != 0 {
+		stopCh <- struct{}{}
+		discard(wResp)
+	}
+
+	return h.probeStatus(recv.Payload(), r)
+}
+
+// probePayload forms the payload of the probe request.
+func (h *Handler) probePayload(r *http.Request, req *Request, pld *payload.Payload) error {
+	// the client requests have no body
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+
+	err := h.request(r, req)
+	if err != nil {
+		return err
+	}
+
+	reqproto := h.getProtoReq(req)
+	defer h.putProtoReq(reqproto)
+
+	err = req.PayloadBody(pld, h.sendRawBody)
+	if err != nil {
+		return err
+	}
+
+	err = req.PayloadContext(pld, reqproto)
+	if err != nil {
+		return err
+	}
+
+	if h.codec != nil {
+		h.codec.compress(pld, r)
+	}
+
+	return nil
+}
+
+// probeStatus returns the status of the probe response, the body is discarded.
+func (h *Handler) probeStatus(rsp *payload.Payload, r *http.Request) (int, error) {
+	const op = errors.Op("http_probe_status")
+	dw := &discardWriter{header: http.Header{}}
+	err := h.write(rsp, dw, r, nil)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+
+	if dw.status == 0 {
+		dw.status = http.StatusOK
+	}
+
+	return dw.status, nil
+}
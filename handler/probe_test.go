@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPingYields(t *testing.T) {
+	cfg := &config.Config{
+		Address:           "127.0.0.1:0",
+		Uploads:           &config.Uploads{},
+		InternalErrorCode: 500,
+		Priority:          &config.Priority{MaxConcurrency: 1},
+	}
+	require.NoError(t, cfg.InitDefaults())
+
+	pool := &contextPool{}
+	h, err := NewHandler(cfg, pool, zap.NewNop())
+	require.NoError(t, err)
+
+	ping := func() error {
+		r := httptest.NewRequest(http.MethodGet, "/.rr/liveness", nil)
+		_, err := h.Ping(context.Background(), r)
+		return err
+	}
+
+	// the ping goes through the pool and releases the slot
+	assert.True(t, errors.Is(errors.NoFreeWorkers, ping()))
+	assert.Len(t, pool.reqs, 1)
+
+	// the ping doesn't wait for the concurrency slot
+	require.True(t, h.gate.tryAcquire())
+	assert.True(t, errors.Is(errors.NoFreeWorkers, ping()))
+	h.gate.release()
+
+	// the ping is not sent while the requests wait for the workers
+	h.stats.Pending.Add(1)
+	assert.True(t, errors.Is(errors.NoFreeWorkers, ping()))
+	h.stats.Pending.Add(-1)
+	assert.Len(t, pool.reqs, 1)
+
+	assert.True(t, errors.Is(errors.NoFreeWorkers, ping()))
+	assert.Len(t, pool.reqs, 2)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// QueueState is the snapshot of the requests waiting for the workers.
+type QueueState struct {
+	// Pending is the number of requests dispatched to the pool and waiting for the response.
+	Pending int64
+	// OldestPending is the age of the oldest pending request, 0 if not tracked.
+	OldestPending time.Duration
+	// Queued is the number of requests waiting for the dispatch slot (priority, queue wait limit).
+	Queued int
+	// OldestQueued is the age of the oldest queued request.
+	OldestQueued time.Duration
+}
+
+// pendingList tracks the dispatch time of the pending requests, the oldest request is at the front.
+type pendingList struct {
+	mu sync.Mutex
+	l  list.List
+}
+
+func (p *pendingList) add() *list.Element {
+	p.mu.Lock()
+	// the time is taken under the lock, so the list is ordered
+	e := p.l.PushBack(time.Now())
+	p.mu.Unlock()
+	return e
+}
+
+func (p *pendingList) remove(e *list.Element) {
+	p.mu.Lock()
+	p.l.Remove(e)
+	p.mu.Unlock()
+}
+
+func (p *pendingList) oldest() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e := p.l.Front(); e != nil {
+		return e.Value.(time.Time)
+	}
+
+	return time.Time{}
+}
+
+// QueueState returns the current queue state, the pending requests age is tracked only when enabled.
+func (h *Handler) QueueState() *QueueState {
+	now := time.Now()
+	st := &QueueState{Pending: h.stats.Pending.Load()}
+
+	if h.pending != nil {
+		if oldest := h.pending.oldest(); !oldest.IsZero() {
+			st.OldestPending = now.Sub(oldest)
+		}
+	}
+
+	if h.gate != nil {
+		var oldest time.Time
+		st.Queued, oldest = h.gate.waiting()
+		if !oldest.IsZero() {
+			st.OldestQueued = now.Sub(oldest)
+		}
+	}
+
+	return st
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueState(t *testing.T) {
+	h := &Handler{stats: &Stats{}, pending: &pendingList{}, gate: newPriorityGate(1)}
+
+	st := h.QueueState()
+	assert.Zero(t, st.OldestPending)
+	assert.Zero(t, st.Queued)
+
+	first := h.pending.add()
+	time.Sleep(5 * time.Millisecond)
+	second := h.pending.add()
+	h.stats.Pending.Add(2)
+
+	require.NoError(t, h.gate.acquire(context.Background(), 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- h.gate.acquire(ctx, 0)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return h.QueueState().Queued == 1
+	}, time.Second, time.Millisecond)
+
+	st = h.QueueState()
+	assert.Equal(t, int64(2), st.Pending)
+	assert.GreaterOrEqual(t, st.OldestPending, 5*time.Millisecond)
+	assert.Positive(t, st.OldestQueued)
+
+	h.pending.remove(first)
+	assert.Less(t, h.QueueState().OldestPending, st.OldestPending)
+	h.pending.remove(second)
+	assert.Zero(t, h.QueueState().OldestPending)
+
+	cancel()
+	assert.Error(t, <-done)
+	assert.Zero(t, h.QueueState().Queued)
+}
@@ -1,16 +1,19 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
-	"net/url"
+	"os"
 	"strings"
 
 	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
 	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/http/v5/config"
 	"github.com/roadrunner-server/pool/payload"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
@@ -22,8 +25,19 @@ const (
 	contentStream
 	contentMultipart
 	contentURLEncoded
+
+	bodyPattern = "body"
+	// maxPooledBuffer is the max capacity of the buffer which can be returned to the pool
+	maxPooledBuffer = 64 * 1024
+	// maxPreallocBody is the max body size to preallocate based on the Content-Length header
+	maxPreallocBody = 1024 * 1024
+	// BodyFileAttr is the attribute which holds the path to the request body file (large body mode)
+	BodyFileAttr = "rr_body_file"
 )
 
+// newLine is appended by the json encoder
+var newLine = []byte{'\n'} //nolint:gochecknoglobals
+
 // Request maps net/http requests to PSR7 compatible structure and managed state of temporary uploaded files.
 type Request struct {
 	// RemoteAddr contains ip address of a client, make sure to check X-Real-Ip and X-Forwarded-For for real client address.
@@ -48,6 +62,10 @@ type Request struct {
 	Attributes map[string][]string `json:"attributes"`
 	// request body can be parsedData or []byte
 	body any
+	// bodyFile is the path to the file with the request body (large body mode)
+	bodyFile string
+	// conn is the connection info, nil if disabled
+	conn *connectionInfo
 }
 
 func FetchIP(pair string, log *zap.Logger) string {
@@ -69,30 +87,64 @@ func FetchIP(pair string, log *zap.Logger) string {
 	return ip.String()
 }
 
-func request(r *http.Request, req *Request, uid, gid int, sendRawBody bool) error {
-	for _, c := range r.Cookies() {
-		if v, err := url.QueryUnescape(c.Value); err == nil {
-			req.Cookies[c.Name] = v
-		}
+func (h *Handler) request(r *http.Request, req *Request) error {
+	err := h.cookies(r, req)
+	if err != nil {
+		return err
 	}
 
-	switch req.contentType() {
+	ct := req.contentType()
+	// forms on the raw body routes are sent as is, like any other stream
+	if (ct == contentMultipart || ct == contentURLEncoded) && h.rawBodyRoute(r) {
+		ct = contentStream
+	}
+
+	switch ct {
 	case contentNone:
 		return nil
 
 	case contentStream:
-		var err error
-		req.body, err = io.ReadAll(r.Body)
+		var head []byte
+		if h.largeBody != nil && r.ContentLength < 0 {
+			// the body of the unknown length (chunked) is sent in the payload if it ends below the threshold
+			var err error
+			head, err = io.ReadAll(io.LimitReader(r.Body, h.largeBody.Threshold))
+			if err != nil {
+				return err
+			}
+
+			if int64(len(head)) < h.largeBody.Threshold {
+				req.body = head
+				return nil
+			}
+		}
+
+		if h.largeBody != nil && (head != nil || r.ContentLength >= h.largeBody.Threshold) {
+			f, err := h.bodyFile()
+			if err == nil {
+				return req.storeBody(io.MultiReader(bytes.NewReader(head), r.Body), f)
+			}
+
+			// fallback to the payload framing
+			h.log.Warn("unable to create the body file, sending the body in the payload", zap.Error(err))
+		}
+
+		body, err := readBody(r)
 		if err != nil {
 			return err
 		}
 
+		if head != nil {
+			body = append(head, body...)
+		}
+
+		req.body = body
 		return nil
 
 	case contentMultipart:
-		if sendRawBody {
+		if h.sendRawBody {
 			var err error
-			req.body, err = io.ReadAll(r.Body)
+			req.body, err = readBody(r)
 			if err != nil {
 				return err
 			}
@@ -100,12 +152,39 @@ func request(r *http.Request, req *Request, uid, gid int, sendRawBody bool) erro
 			return nil
 		}
 
+		if h.uploads.lazy {
+			err := h.storeUploads(r, req)
+			if err != nil {
+				return err
+			}
+
+			break
+		}
+
 		err := r.ParseMultipartForm(defaultMaxMemory)
 		if err != nil {
 			return err
 		}
 
-		req.Uploads, err = parseUploads(r, uid, gid)
+		if h.strictValidation {
+			err = validateFields(r.MultipartForm)
+			if err != nil {
+				return err
+			}
+		}
+
+		count := 0
+		err = checkFields(h.formLimits, r.MultipartForm.Value, &count)
+		if err != nil {
+			return err
+		}
+
+		err = checkFields(h.formLimits, r.MultipartForm.File, &count)
+		if err != nil {
+			return err
+		}
+
+		req.Uploads, err = parseUploads(r, h.uid, h.gid)
 		if err != nil {
 			return err
 		}
@@ -117,9 +196,9 @@ func request(r *http.Request, req *Request, uid, gid int, sendRawBody bool) erro
 
 		req.Parsed = true
 	case contentURLEncoded:
-		if sendRawBody {
+		if h.sendRawBody {
 			var err error
-			req.body, err = io.ReadAll(r.Body)
+			req.body, err = readBody(r)
 			if err != nil {
 				return err
 			}
@@ -132,6 +211,11 @@ func request(r *http.Request, req *Request, uid, gid int, sendRawBody bool) erro
 			return err
 		}
 
+		err = checkFields(h.formLimits, r.PostForm, new(int))
+		if err != nil {
+			return err
+		}
+
 		req.body, err = parsePostForm(r)
 		if err != nil {
 			return err
@@ -143,17 +227,84 @@ func request(r *http.Request, req *Request, uid, gid int, sendRawBody bool) erro
 	return nil
 }
 
+// rawBodyRoute returns true if the request path matches one of the raw body routes.
+func (h *Handler) rawBodyRoute(r *http.Request) bool {
+	for i := 0; i < len(h.rawBodyRoutes); i++ {
+		if strings.HasPrefix(r.URL.Path, h.rawBodyRoutes[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readBody reads the whole body, the buffer is preallocated if the content length is known.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.ContentLength > 0 && r.ContentLength <= maxPreallocBody {
+		buf := make([]byte, r.ContentLength)
+		_, err := io.ReadFull(r.Body, buf)
+		if err != nil {
+			return nil, err
+		}
+
+		return buf, nil
+	}
+
+	return io.ReadAll(r.Body)
+}
+
 // Open moves all uploaded files to temporary directory so it can be given to php later.
-func (r *Request) Open(log *zap.Logger, dir string, forbid, allow map[string]struct{}) {
+func (r *Request) Open(log *zap.Logger, dir string, access *config.Access) {
 	if r.Uploads == nil {
 		return
 	}
 
-	r.Uploads.Open(log, dir, forbid, allow)
+	r.Uploads.Open(log, dir, access)
+}
+
+// bodyFile creates the large body file owned by the worker user.
+func (h *Handler) bodyFile() (*os.File, error) {
+	f, err := os.CreateTemp(h.largeBody.Dir, bodyPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// set permissions, 0 means root or error
+	if h.uid != 0 && h.gid != 0 {
+		err = f.Chown(h.uid, h.gid)
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// storeBody streams the request body into the file, the file is removed on Close.
+func (r *Request) storeBody(body io.Reader, f *os.File) error {
+	r.bodyFile = f.Name()
+	_, err := io.Copy(f, body)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
 }
 
 // Close clears all temp file uploads
 func (r *Request) Close(log *zap.Logger, hr *http.Request) {
+	if r.bodyFile != "" {
+		err := os.Remove(r.bodyFile)
+		if err != nil && log != nil {
+			log.Error("error removing the body file", zap.Error(err))
+		}
+
+		r.bodyFile = ""
+	}
+
 	if r.Uploads == nil {
 		return
 	}
@@ -167,23 +318,47 @@ func (r *Request) Close(log *zap.Logger, hr *http.Request) {
 // Payload request marshaled RoadRunner payload based on PSR7 data. values encode method is JSON. Make sure to open
 // files prior to calling this method.
 func (r *Request) Payload(p *payload.Payload, sendRawBody bool, req *httpV1proto.Request) error {
-	const op = errors.Op("marshal_payload")
+	err := r.PayloadBody(p, sendRawBody)
+	if err != nil {
+		return err
+	}
+
+	return r.PayloadContext(p, req)
+}
+
+// PayloadContext marshals the request context (headers, uploads, etc.) into the payload with the payload codec.
+func (r *Request) PayloadContext(p *payload.Payload, req *httpV1proto.Request) error {
+	const op = errors.Op("marshal_payload_context")
 
 	if r.Uploads != nil {
-		data, err := json.Marshal(r.Uploads)
+		// reuse the uploads buffer of the pooled proto request
+		buf := bytes.NewBuffer(req.Uploads[:0])
+		err := json.NewEncoder(buf).Encode(r.Uploads)
 		if err != nil {
 			return errors.E(op, err)
 		}
 
-		req.Uploads = data
+		req.Uploads = bytes.TrimSuffix(buf.Bytes(), newLine)
 	}
 
 	var err error
-	p.Context, err = proto.Marshal(req)
+	// reuse the context buffer of the pooled payload
+	if p.Codec == frame.CodecMsgpack {
+		p.Context, err = marshalMsgpack(p.Context[:0], req, r.conn)
+	} else {
+		p.Context, err = proto.MarshalOptions{}.MarshalAppend(p.Context[:0], req)
+	}
 	if err != nil {
 		return errors.E(op, err)
 	}
 
+	return nil
+}
+
+// PayloadBody puts the request body (raw or parsed) into the payload.
+func (r *Request) PayloadBody(p *payload.Payload, sendRawBody bool) error {
+	const op = errors.Op("marshal_payload_body")
+
 	// if user wanted to get a raw body, just send it
 	if sendRawBody {
 		if r.body == nil {
@@ -211,7 +386,7 @@ func (r *Request) Payload(p *payload.Payload, sendRawBody bool, req *httpV1proto
 
 			return nil
 		case dataTree:
-			err = packDataTree(bdy, p)
+			err := packDataTree(bdy, p)
 			if err != nil {
 				return errors.E(op, err)
 			}
@@ -228,7 +403,7 @@ func (r *Request) Payload(p *payload.Payload, sendRawBody bool, req *httpV1proto
 		case []byte:
 			p.Body = t
 		case dataTree:
-			err = packDataTree(t, p)
+			err := packDataTree(t, p)
 			if err != nil {
 				return errors.E(op, err)
 			}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	// RequestIDAttr is the attribute with the request ID.
+	RequestIDAttr string = "request_id"
+	// maxRequestIDLen is the max length of the request ID accepted from the client
+	maxRequestIDLen int = 128
+)
+
+// requestID returns the request ID from the header or generates a new one.
+func (h *Handler) requestID(r *http.Request) string {
+	if id := r.Header.Get(h.requestIDHeader); validRequestID(id) {
+		return id
+	}
+
+	var buf [16]byte
+	_, _ = rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// validRequestID accepts only printable ASCII IDs of the limited length.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setAttr sets the attribute which is passed to the worker.
+func (r *Request) setAttr(key, value string) {
+	if r.Attributes == nil {
+		r.Attributes = make(map[string][]string, 1)
+	}
+
+	r.Attributes[key] = []string{value}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	pool := &contextPool{}
+	h, err := NewHandler(&config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500, RequestID: &config.RequestID{Header: "X-Request-Id"}}, pool, zap.New(core))
+	require.NoError(t, err)
+
+	serve := func(id string) (string, string) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			r.Header.Set("X-Request-Id", id)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		require.NotEmpty(t, pool.reqs)
+		attr := pool.reqs[len(pool.reqs)-1].GetAttributes()[RequestIDAttr].GetValue()
+		require.Len(t, attr, 1)
+		return w.Header().Get("X-Request-Id"), attr[0]
+	}
+
+	// the client ID is propagated to the worker and the response
+	header, attr := serve("req-1")
+	assert.Equal(t, "req-1", header)
+	assert.Equal(t, "req-1", attr)
+
+	// the error log of the request is tagged
+	require.NotZero(t, logs.Len())
+	assert.Equal(t, "req-1", logs.All()[logs.Len()-1].ContextMap()[RequestIDAttr])
+
+	// the missing and the invalid IDs are generated
+	for _, id := range []string{"", "with space", strings.Repeat("a", maxRequestIDLen+1)} {
+		header, attr = serve(id)
+		assert.Regexp(t, `^[0-9a-f]{32}$`, header)
+		assert.Equal(t, header, attr)
+	}
+
+	first, _ := serve("")
+	second, _ := serve("")
+	assert.NotEqual(t, first, second)
+}
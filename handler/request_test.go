@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRequestBody(t *testing.T) {
+	uploads := &config.Uploads{Dir: t.TempDir()}
+	require.NoError(t, uploads.InitDefaults())
+	h, err := NewHandler(&config.Config{
+		Uploads:           uploads,
+		InternalErrorCode: 500,
+		LargeBody:         &config.LargeBody{Dir: t.TempDir(), Threshold: 8},
+	}, &replayPool{}, zap.NewNop())
+	require.NoError(t, err)
+
+	send := func(r *http.Request) *Request {
+		req := h.getReq(r)
+		require.NoError(t, h.request(r, req))
+		t.Cleanup(func() { req.Close(zap.NewNop(), r) })
+		return req
+	}
+
+	// the forms are parsed regardless of the large body threshold
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("title=report&tags[]=a"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req := send(r)
+	assert.True(t, req.Parsed)
+	assert.Equal(t, dataTree{"title": "report", "tags": []string{"a"}}, req.body)
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	require.NoError(t, mw.WriteField("title", "report"))
+	fw, err := mw.CreateFormFile("doc", "a.txt")
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte("first"))
+	require.NoError(t, mw.Close())
+
+	r = httptest.NewRequest(http.MethodPost, "/", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	req = send(r)
+	assert.True(t, req.Parsed)
+	assert.Equal(t, dataTree{"title": "report"}, req.body)
+	require.NotNil(t, req.Uploads)
+	require.Len(t, req.Uploads.list, 1)
+	assert.Equal(t, "a.txt", req.Uploads.list[0].Name)
+
+	// the other bodies are sent in the payload below the large body threshold
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	req = send(r)
+	assert.False(t, req.Parsed)
+	assert.Empty(t, req.bodyFile)
+	assert.Equal(t, []byte(`{}`), req.body)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"title":"report"}`))
+	r.Header.Set("Content-Type", "application/json")
+	req = send(r)
+	assert.NotEmpty(t, req.bodyFile)
+	assert.Empty(t, req.body)
+
+	// the chunked bodies are read up to the threshold
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.ContentLength = -1
+	req = send(r)
+	assert.Empty(t, req.bodyFile)
+	assert.Equal(t, []byte(`{}`), req.body)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"title":"report"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.ContentLength = -1
+	req = send(r)
+	require.NotEmpty(t, req.bodyFile)
+	assert.Empty(t, req.body)
+	data, err := os.ReadFile(req.bodyFile)
+	require.NoError(t, err)
+	assert.Equal(t, `{"title":"report"}`, string(data))
+}
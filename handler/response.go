@@ -9,6 +9,7 @@ import (
 	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
 	"github.com/roadrunner-server/errors"
 	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/http/v5/config"
 	"github.com/roadrunner-server/pool/payload"
 	"google.golang.org/protobuf/proto"
 )
@@ -28,9 +29,14 @@ type Response struct {
 
 // Write writes response headers, status and body into ResponseWriter.
 func (h *Handler) Write(pld *payload.Payload, w http.ResponseWriter) error {
+	return h.write(pld, w, nil, nil)
+}
+
+// write writes the response of the request, the cache rule is applied if the worker did not set the cache headers.
+func (h *Handler) write(pld *payload.Payload, w http.ResponseWriter, r *http.Request, cacheRule *config.CacheControlRule) error {
 	switch pld.Codec {
-	case frame.CodecProto:
-		return h.handlePROTOresponse(pld, w)
+	case frame.CodecProto, frame.CodecMsgpack:
+		return h.handlePROTOresponse(pld, w, r, cacheRule)
 	case frame.CodecJSON:
 		return errors.Str("JSON codec is not supported")
 	default:
@@ -38,15 +44,29 @@ func (h *Handler) Write(pld *payload.Payload, w http.ResponseWriter) error {
 	}
 }
 
-func (h *Handler) handlePROTOresponse(pld *payload.Payload, w http.ResponseWriter) error {
+func (h *Handler) handlePROTOresponse(pld *payload.Payload, w http.ResponseWriter, r *http.Request, cacheRule *config.CacheControlRule) error {
 	rsp := h.getProtoRsp()
 	defer h.putProtoRsp(rsp)
 
+	body := pld.Body
 	if len(pld.Context) != 0 {
 		// unmarshal context into response
-		err := proto.Unmarshal(pld.Context, rsp)
+		var err error
+		if pld.Codec == frame.CodecMsgpack {
+			err = unmarshalMsgpack(pld.Context, rsp)
+		} else {
+			err = proto.Unmarshal(pld.Context, rsp)
+		}
 		if err != nil {
-			return err
+			return &Error{Code: CodePayloadDecode, Err: err}
+		}
+
+		// the body might be compressed by the worker
+		if h.codec != nil {
+			body, err = h.codec.decompress(rsp.GetHeaders(), body)
+			if err != nil {
+				return &Error{Code: CodePayloadDecode, Err: err}
+			}
 		}
 
 		// handle push headers
@@ -57,16 +77,39 @@ func (h *Handler) handlePROTOresponse(pld *payload.Payload, w http.ResponseWrite
 				for i := 0; i < len(push); i++ {
 					err = pusher.Push(rsp.GetHeaders()[HTTP2Push].GetValue()[i], nil)
 					if err != nil {
+						// the buffered writer over HTTP/1
+						if stderr.Is(err, http.ErrNotSupported) {
+							break
+						}
+
 						return err
 					}
 				}
 			}
 		}
 
+		if h.preload != nil {
+			err = h.preload.push(w, rsp.GetHeaders())
+			if err != nil {
+				return err
+			}
+		}
+
 		if rsp.GetHeaders() != nil && rsp.GetHeaders()[Trailer] != nil {
 			handleProtoTrailers(rsp.GetHeaders())
 		}
 
+		// the file would be sent instead of the body
+		var sendFile string
+		if xsf := rsp.GetHeaders()[XSendFile]; h.sendfileRoot != "" && xsf != nil && len(xsf.GetValue()) > 0 {
+			sendFile = xsf.GetValue()[0]
+			delete(rsp.GetHeaders(), XSendFile)
+		}
+
+		// the worker asks to close the client connection
+		closeConn := connectionClose(rsp.GetHeaders())
+		stripHopByHop(rsp.GetHeaders())
+
 		// write all headers from the response to the writer
 		for k := range rsp.GetHeaders() {
 			for kk := range rsp.GetHeaders()[k].GetValue() {
@@ -74,25 +117,73 @@ func (h *Handler) handlePROTOresponse(pld *payload.Payload, w http.ResponseWrite
 			}
 		}
 
+		if closeConn {
+			// the server closes the connection after the response
+			w.Header().Set(connectionHeader, "close")
+		}
+
+		if h.defaultCharset != "" {
+			setCharset(w.Header(), h.defaultCharset)
+		}
+
 		// The provided code must be a valid HTTP 1xx-5xx status code.
 		if rsp.Status < 100 || rsp.Status >= 600 {
 			http.Error(w, fmt.Sprintf("unknown status code from worker: %d", rsp.Status), 500)
 			return errors.Errorf("unknown status code from worker: %d", rsp.Status)
 		}
 
+		if cacheRule != nil && rsp.Status < 400 && !hasCacheHeaders(w.Header()) {
+			setCacheHeaders(w.Header(), cacheRule)
+		}
+
+		// the client already has the response
+		if h.conditionalResponses && r != nil && rsp.Status == http.StatusOK && notModified(r, w.Header()) {
+			toNotModified(w.Header())
+			rsp.Status = http.StatusNotModified
+		}
+
+		// 204 and 304 responses never have a body
+		if noBody(int(rsp.Status)) {
+			if rsp.Status == http.StatusNoContent {
+				w.Header().Del(contentLength)
+			}
+
+			w.WriteHeader(int(rsp.Status))
+			return nil
+		}
+
+		if sendFile != "" {
+			return h.sendFile(sendFile, int(rsp.Status), w, r)
+		}
+
 		w.WriteHeader(int(rsp.Status))
 	}
 
 	// do not write body if it is empty
-	if len(pld.Body) == 0 {
+	if len(body) == 0 {
 		return nil
 	}
 
-	_, err := w.Write(pld.Body)
+	_, err := w.Write(body)
 	if err != nil {
+		// the stream frames after the 204 or 304 response
+		if stderr.Is(err, http.ErrBodyNotAllowed) {
+			return nil
+		}
+
 		return err
 	}
 
+	// buffered responses are flushed once at the end, except for the stream frames
+	if h.bufferResponse && pld.Flags&frame.STREAM == 0 {
+		return nil
+	}
+
+	// the stream frames are flushed by the latency writer
+	if _, ok := w.(*latencyWriter); ok && pld.Flags&frame.STREAM != 0 {
+		return nil
+	}
+
 	rw := http.NewResponseController(w) //nolint:bodyclose
 	err = rw.Flush()
 	if stderr.Is(err, http.ErrNotSupported) {
@@ -116,3 +207,8 @@ func handleProtoTrailers(h map[string]*httpV1proto.HeaderValue) {
 
 	delete(h, Trailer)
 }
+
+// noBody returns true for the statuses which must not have a body.
+func noBody(status int) bool {
+	return status == http.StatusNoContent || status == http.StatusNotModified
+}
@@ -0,0 +1,505 @@
+package handler
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"go.uber.org/zap"
+)
+
+// surrogateKeyHeader contains the space-separated keys used to purge the cached responses, not sent to the clients.
+const surrogateKeyHeader = "Surrogate-Key"
+
+// cachedResponse is the cached worker response.
+type cachedResponse struct {
+	key    string
+	status int
+	header http.Header
+	body   []byte
+	// surrogate keys of the response
+	tags    []string
+	stored  time.Time
+	expires time.Time
+	// stale-while-revalidate and stale-if-error windows after the expiration
+	swr time.Duration
+	sie time.Duration
+}
+
+// responseCache is the shared LRU cache of the worker responses, RFC 9111 and RFC 5861 subset.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+	// keys refreshed in the background
+	refreshing map[string]struct{}
+	// request headers the responses vary on, per the request URL
+	varyIndex map[string][]string
+	// request headers always included in the keys
+	vary []string
+	// cache keys per surrogate key
+	tagIndex map[string]map[string]struct{}
+
+	maxSize         int64
+	maxEntrySize    int64
+	defaultTTL      time.Duration
+	requestIDHeader string
+	stats           *Stats
+	log             *zap.Logger
+}
+
+func newResponseCache(cfg *config.ResponseCache, stats *Stats, requestIDHeader string, log *zap.Logger) *responseCache {
+	return &responseCache{
+		entries:         make(map[string]*list.Element),
+		lru:             list.New(),
+		refreshing:      make(map[string]struct{}),
+		varyIndex:       make(map[string][]string),
+		tagIndex:        make(map[string]map[string]struct{}),
+		vary:            canonicalHeaders(cfg.Vary),
+		maxSize:         cfg.MaxSize,
+		maxEntrySize:    cfg.MaxEntrySize,
+		defaultTTL:      cfg.DefaultTTL,
+		requestIDHeader: requestIDHeader,
+		stats:           stats,
+		log:             log,
+	}
+}
+
+// cacheable returns true if the request might be served from the cache.
+func (c *responseCache) cacheable(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if r.Header.Get("Authorization") != "" {
+		return false
+	}
+
+	// the responses to the requests with the cookies are shared only if the key includes the cookies
+	if r.Header.Get("Cookie") != "" && !c.variesOnCookie(r) {
+		return false
+	}
+
+	_, noStore := parseCacheControl(r.Header.Values(cacheControlHeader))["no-store"]
+	return !noStore
+}
+
+// serve writes the cached response or the response of the next handler, which is cached if allowed.
+func (c *responseCache) serve(w http.ResponseWriter, r *http.Request, next func(w http.ResponseWriter, r *http.Request)) {
+	key := c.key(r)
+	now := time.Now()
+
+	// the stale response might be sent on the worker error even if the client asks for the fresh one
+	e := c.get(key)
+	if e != nil && !requestNoCache(r) {
+		switch {
+		case now.Before(e.expires):
+			c.stats.ResponseCacheHits.Add(1)
+			e.write(w, r, now)
+			return
+		case now.Before(e.expires.Add(e.swr)):
+			// the stale response is sent at once, the single background request refreshes it
+			c.stats.ResponseCacheStale.Add(1)
+			e.write(w, r, now)
+			c.refresh(key, r, next)
+			return
+		}
+	}
+
+	c.stats.ResponseCacheMisses.Add(1)
+
+	// the response is buffered to send the stale one if the worker fails
+	if e != nil && now.Before(e.expires.Add(e.sie)) {
+		cw := c.captureWriter(nil)
+		cw.fallback = w
+		next(cw, r)
+		if cw.status >= http.StatusInternalServerError {
+			c.stats.ResponseCacheStale.Add(1)
+			e.write(w, r, now)
+			return
+		}
+
+		c.store(r, cw)
+		// the response over the limit is already sent
+		if cw.w == nil {
+			cw.replay(w)
+		}
+		return
+	}
+
+	cw := c.captureWriter(w)
+	next(cw, r)
+	c.store(r, cw)
+}
+
+// refresh sends the request to the worker in the background, once per key at a time.
+func (c *responseCache) refresh(key string, r *http.Request, next func(w http.ResponseWriter, r *http.Request)) {
+	c.mu.Lock()
+	if _, ok := c.refreshing[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = struct{}{}
+	c.mu.Unlock()
+
+	// the request should not be used after the ServeHTTP returns
+	rr := r.Clone(context.Background())
+	rr.Method = http.MethodGet
+	rr.Body = http.NoBody
+	rr.Header.Del("If-None-Match")
+	rr.Header.Del("If-Modified-Since")
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+
+		cw := c.captureWriter(nil)
+		next(cw, rr)
+		if cw.status >= http.StatusInternalServerError {
+			// the stale response is kept
+			c.log.Warn("response cache refresh failed", zap.String("key", key), zap.Int("status", cw.status))
+			return
+		}
+
+		c.store(rr, cw)
+	}()
+}
+
+// key returns the cache key of the request, including the headers the last stored response for the URL varies on.
+func (c *responseCache) key(r *http.Request) string {
+	primary := r.Host + r.URL.RequestURI()
+	c.mu.Lock()
+	names, ok := c.varyIndex[primary]
+	c.mu.Unlock()
+	if !ok {
+		names = c.vary
+	}
+
+	return variantKey(primary, names, r)
+}
+
+// variesOnCookie returns true if the cache key of the request includes the Cookie header: configured in the vary or
+// listed in the Vary header of the stored responses for the URL.
+func (c *responseCache) variesOnCookie(r *http.Request) bool {
+	if slices.Contains(c.vary, "Cookie") {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Contains(c.varyIndex[r.Host+r.URL.RequestURI()], "Cookie")
+}
+
+// storeKey returns the cache key of the response and remembers the headers it varies on for the URL.
+func (c *responseCache) storeKey(r *http.Request, h http.Header) string {
+	primary := r.Host + r.URL.RequestURI()
+	names := append(canonicalHeaders(h.Values("Vary")), c.vary...)
+	// the encoded body is never sent to the clients which did not ask for it
+	if h.Get("Content-Encoding") != "" {
+		names = append(names, "Accept-Encoding")
+	}
+
+	// the headers only accumulate, so the lookups never ignore the headers of the stored variants
+	c.mu.Lock()
+	names = append(names, c.varyIndex[primary]...)
+	slices.Sort(names)
+	names = slices.Compact(names)
+	c.varyIndex[primary] = names
+	c.mu.Unlock()
+
+	return variantKey(primary, names, r)
+}
+
+// variantKey appends the normalized values of the request headers to the key.
+func variantKey(primary string, names []string, r *http.Request) string {
+	if len(names) == 0 {
+		return primary
+	}
+
+	var sb strings.Builder
+	sb.WriteString(primary)
+	for i := 0; i < len(names); i++ {
+		sb.WriteByte('\n')
+		sb.WriteString(names[i])
+		sb.WriteByte(':')
+		sb.WriteString(normalizeVary(names[i], r.Header.Values(names[i])))
+	}
+
+	return sb.String()
+}
+
+// normalizeVary normalizes the header value, so the equivalent requests share the cached response.
+func normalizeVary(name string, values []string) string {
+	value := strings.Join(values, ",")
+	switch name {
+	case "Accept-Encoding", "Accept-Language", "Accept":
+		tokens := strings.Split(strings.ToLower(strings.ReplaceAll(value, " ", "")), ",")
+		slices.Sort(tokens)
+		return strings.Join(slices.Compact(tokens), ",")
+	default:
+		return strings.TrimSpace(value)
+	}
+}
+
+// canonicalHeaders returns the canonical names of the comma-separated header lists.
+func canonicalHeaders(values []string) []string {
+	var names []string
+	for i := 0; i < len(values); i++ {
+		for _, name := range strings.Split(values[i], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	return names
+}
+
+// get returns the cached response, fresh or stale.
+func (c *responseCache) get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := el.Value.(*cachedResponse)
+	// useless stale response
+	if time.Now().After(e.expires.Add(max(e.swr, e.sie))) {
+		c.remove(el)
+		return nil
+	}
+
+	c.lru.MoveToFront(el)
+	return e
+}
+
+// store caches the captured response of the GET request if allowed.
+func (c *responseCache) store(r *http.Request, cw *captureWriter) {
+	if r.Method != http.MethodGet || !cacheableStatus(cw.status) || cw.overflow || cw.partial || int64(cw.body.Len()) > c.maxEntrySize {
+		return
+	}
+
+	h := cw.snapshot
+	if h.Get("Set-Cookie") != "" || h.Get("Vary") == "*" {
+		return
+	}
+
+	// the response might be personalized by the cookies, including the one cached with the default_ttl
+	if r.Header.Get("Cookie") != "" && !slices.Contains(append(canonicalHeaders(h.Values("Vary")), c.vary...), "Cookie") {
+		return
+	}
+
+	cc := parseCacheControl(h.Values(cacheControlHeader))
+	for _, directive := range [...]string{"no-store", "no-cache", "private"} {
+		if _, ok := cc[directive]; ok {
+			return
+		}
+	}
+
+	now := time.Now()
+	ttl, ok := freshness(cc, h, now)
+	if !ok {
+		ttl = c.defaultTTL
+	}
+
+	swr := directiveSeconds(cc, "stale-while-revalidate")
+	sie := directiveSeconds(cc, "stale-if-error")
+	if ttl <= 0 && swr <= 0 && sie <= 0 {
+		return
+	}
+
+	key := c.storeKey(r, h)
+	header := h.Clone()
+	header.Del("Age")
+	header.Del(surrogateKeyHeader)
+	if c.requestIDHeader != "" {
+		header.Del(c.requestIDHeader)
+	}
+
+	e := &cachedResponse{
+		key:     key,
+		status:  cw.status,
+		header:  header,
+		body:    append([]byte(nil), cw.body.Bytes()...),
+		tags:    strings.Fields(strings.Join(h.Values(surrogateKeyHeader), " ")),
+		stored:  now,
+		expires: now.Add(ttl),
+		swr:     swr,
+		sie:     sie,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	c.entries[key] = c.lru.PushFront(e)
+	c.size += int64(len(e.body))
+	for i := 0; i < len(e.tags); i++ {
+		if c.tagIndex[e.tags[i]] == nil {
+			c.tagIndex[e.tags[i]] = make(map[string]struct{})
+		}
+
+		c.tagIndex[e.tags[i]][key] = struct{}{}
+	}
+
+	// evict the least recently used responses
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove should be called under the lock.
+func (c *responseCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*cachedResponse)
+	delete(c.entries, e.key)
+	c.size -= int64(len(e.body))
+	for i := 0; i < len(e.tags); i++ {
+		delete(c.tagIndex[e.tags[i]], e.key)
+		if len(c.tagIndex[e.tags[i]]) == 0 {
+			delete(c.tagIndex, e.tags[i])
+		}
+	}
+}
+
+// purge removes the responses with any of the surrogate keys, returns the number of removed responses.
+func (c *responseCache) purge(tags []string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	for i := 0; i < len(tags); i++ {
+		for key := range c.tagIndex[tags[i]] {
+			if el, ok := c.entries[key]; ok {
+				c.remove(el)
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+// captureWriter records the response with the surrogate keys hidden from the client.
+func (c *responseCache) captureWriter(w http.ResponseWriter) *captureWriter {
+	cw := newCaptureWriter(w, c.maxEntrySize)
+	cw.hide = surrogateKeyHeader
+	return cw
+}
+
+// write sends the cached response, the conditional requests are answered with 304.
+func (e *cachedResponse) write(w http.ResponseWriter, r *http.Request, now time.Time) {
+	h := w.Header()
+	for k, v := range e.header {
+		h[k] = v
+	}
+
+	h.Set("Age", strconv.FormatInt(int64(now.Sub(e.stored).Seconds()), 10))
+	if e.status == http.StatusOK && notModified(r, h) {
+		toNotModified(h)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set(contentLength, strconv.Itoa(len(e.body)))
+	w.WriteHeader(e.status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(e.body)
+	}
+}
+
+// cacheableStatus returns true for the statuses cacheable by default, RFC 9110 15.1.
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestNoCache returns true if the client asks to skip the cached response.
+func requestNoCache(r *http.Request) bool {
+	cc := parseCacheControl(r.Header.Values(cacheControlHeader))
+	if _, ok := cc["no-cache"]; ok {
+		return true
+	}
+
+	if v, ok := cc["max-age"]; ok && v == "0" {
+		return true
+	}
+
+	return len(cc) == 0 && strings.EqualFold(r.Header.Get("Pragma"), "no-cache")
+}
+
+// freshness returns the freshness lifetime of the response, false if there is none.
+func freshness(cc map[string]string, h http.Header, now time.Time) (time.Duration, bool) {
+	for _, directive := range [...]string{"s-maxage", "max-age"} {
+		if v, ok := cc[directive]; ok {
+			sec, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || sec < 0 {
+				return 0, true
+			}
+
+			return time.Duration(sec) * time.Second, true
+		}
+	}
+
+	if v := h.Get(expiresHeader); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			// invalid Expires means already expired
+			return 0, true
+		}
+
+		if date, err := http.ParseTime(h.Get("Date")); err == nil {
+			now = date
+		}
+
+		return expires.Sub(now), true
+	}
+
+	return 0, false
+}
+
+// directiveSeconds returns the duration of the directive in seconds, 0 if missing or invalid.
+func directiveSeconds(cc map[string]string, directive string) time.Duration {
+	sec, err := strconv.ParseInt(cc[directive], 10, 64)
+	if err != nil || sec < 0 {
+		return 0
+	}
+
+	return time.Duration(sec) * time.Second
+}
+
+// parseCacheControl returns the lower-cased directives with the unquoted values.
+func parseCacheControl(values []string) map[string]string {
+	cc := make(map[string]string)
+	for i := 0; i < len(values); i++ {
+		for _, part := range strings.Split(values[i], ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+
+	return cc
+}
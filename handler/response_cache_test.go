@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestResponseCacheStale(t *testing.T) {
+	cfg := &config.ResponseCache{}
+	require.NoError(t, cfg.InitDefaults())
+	stats := &Stats{}
+	c := newResponseCache(cfg, stats, "X-Request-Id", zap.NewNop())
+
+	var calls atomic.Int64
+	var status atomic.Int64
+	status.Store(http.StatusOK)
+	next := func(w http.ResponseWriter, _ *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60, stale-if-error=60")
+		w.Header().Set("X-Request-Id", "id")
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte{byte('0' + n)})
+	}
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c.serve(w, httptest.NewRequest(http.MethodGet, "/page", nil), next)
+		return w
+	}
+
+	assert.Equal(t, "1", get().Body.String())
+
+	// the stale response, refreshed in the background
+	w := get()
+	assert.Equal(t, "1", w.Body.String())
+	assert.Empty(t, w.Header().Get("X-Request-Id"))
+	assert.NotEmpty(t, w.Header().Get("Age"))
+	assert.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.refreshing) == 0 && calls.Load() == 2
+	}, time.Second, time.Millisecond*10)
+
+	// the client asks for the fresh response, the stale one is sent on the worker error
+	status.Store(http.StatusInternalServerError)
+	r := httptest.NewRequest(http.MethodGet, "/page", nil)
+	r.Header.Set("Cache-Control", "no-cache")
+	w = httptest.NewRecorder()
+	c.serve(w, r, next)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Body.String())
+	assert.Equal(t, int64(3), calls.Load())
+	assert.Equal(t, uint64(2), stats.ResponseCacheMisses.Load())
+}
+
+func TestResponseCacheStore(t *testing.T) {
+	cfg := &config.ResponseCache{}
+	require.NoError(t, cfg.InitDefaults())
+	stats := &Stats{}
+	c := newResponseCache(cfg, stats, "", zap.NewNop())
+
+	tests := map[string]struct {
+		header map[string]string
+		cached bool
+	}{
+		"/max-age":  {map[string]string{"Cache-Control": "public, max-age=60"}, true},
+		"/s-maxage": {map[string]string{"Cache-Control": "s-maxage=60, max-age=0"}, true},
+		"/expires":  {map[string]string{"Expires": time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}, true},
+		"/no-store": {map[string]string{"Cache-Control": "no-store, max-age=60"}, false},
+		"/private":  {map[string]string{"Cache-Control": "private, max-age=60"}, false},
+		"/cookie":   {map[string]string{"Cache-Control": "max-age=60", "Set-Cookie": "a=b"}, false},
+		"/implicit": {map[string]string{}, false},
+	}
+
+	for uri, tt := range tests {
+		var calls int
+		next := func(w http.ResponseWriter, _ *http.Request) {
+			calls++
+			for k, v := range tt.header {
+				w.Header().Set(k, v)
+			}
+			_, _ = w.Write([]byte("body"))
+		}
+
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			c.serve(w, httptest.NewRequest(http.MethodGet, uri, nil), next)
+			assert.Equal(t, "body", w.Body.String(), uri)
+		}
+
+		if tt.cached {
+			assert.Equal(t, 1, calls, uri)
+		} else {
+			assert.Equal(t, 2, calls, uri)
+		}
+	}
+
+	// the conditional request is answered from the cache
+	c.serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/etag", nil), func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("body"))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/etag", nil)
+	r.Header.Set("If-None-Match", `"v1"`)
+	w := httptest.NewRecorder()
+	c.serve(w, r, nil)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestResponseCacheVary(t *testing.T) {
+	cfg := &config.ResponseCache{Vary: []string{"x-tenant"}}
+	require.NoError(t, cfg.InitDefaults())
+	c := newResponseCache(cfg, &Stats{}, "", zap.NewNop())
+
+	var calls int
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language") + r.Header.Get("Accept-Encoding") + r.Header.Get("X-Tenant")))
+	}
+
+	get := func(uri string, headers ...string) string {
+		r := httptest.NewRequest(http.MethodGet, uri, nil)
+		for i := 0; i < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+
+		w := httptest.NewRecorder()
+		c.serve(w, r, next)
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "en", get("/page", "Accept-Language", "en"))
+	assert.Equal(t, "de", get("/page", "Accept-Language", "de"))
+	assert.Equal(t, "en", get("/page", "Accept-Language", "EN"))
+	assert.Equal(t, "ena", get("/page", "Accept-Language", "en", "X-Tenant", "a"))
+	assert.Equal(t, 3, calls)
+
+	// the gzip response varies on the Accept-Encoding even without the Vary header
+	assert.Equal(t, "gzip, br", get("/gz", "Accept-Encoding", "gzip, br"))
+	assert.Equal(t, "gzip, br", get("/gz", "Accept-Encoding", "br,gzip"))
+	assert.Equal(t, "", get("/gz"))
+	assert.Equal(t, 5, calls)
+}
+
+func TestResponseCachePurge(t *testing.T) {
+	cfg := &config.ResponseCache{}
+	require.NoError(t, cfg.InitDefaults())
+	c := newResponseCache(cfg, &Stats{}, "", zap.NewNop())
+
+	keys := map[string]string{
+		"/post/1": "post-1 posts",
+		"/post/2": "post-2 posts",
+		"/about":  "",
+	}
+
+	var calls int
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set(surrogateKeyHeader, keys[r.URL.Path])
+		_, _ = w.Write([]byte("body"))
+	}
+
+	get := func(uri string) {
+		w := httptest.NewRecorder()
+		c.serve(w, httptest.NewRequest(http.MethodGet, uri, nil), next)
+		assert.Empty(t, w.Header().Get(surrogateKeyHeader))
+	}
+
+	for uri := range keys {
+		get(uri)
+		get(uri)
+	}
+	assert.Equal(t, 3, calls)
+
+	assert.Equal(t, 1, c.purge([]string{"post-1"}))
+	assert.Equal(t, 1, c.purge([]string{"posts", "missing"}))
+	assert.Equal(t, 0, c.purge([]string{"posts"}))
+
+	for uri := range keys {
+		get(uri)
+	}
+	assert.Equal(t, 5, calls)
+}
+
+func TestResponseCacheCookie(t *testing.T) {
+	cfg := &config.ResponseCache{DefaultTTL: time.Minute}
+	require.NoError(t, cfg.InitDefaults())
+	c := newResponseCache(cfg, &Stats{}, "", zap.NewNop())
+
+	cookie := func(path, value string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Cookie", "session="+value)
+		return r
+	}
+
+	// the personalized response cached with the default_ttl is not shared
+	assert.False(t, c.cacheable(cookie("/me", "a")))
+	c.store(cookie("/me", "a"), &captureWriter{status: http.StatusOK, snapshot: http.Header{}})
+	assert.Empty(t, c.entries)
+
+	// the response varies on the cookies
+	cw := &captureWriter{status: http.StatusOK, snapshot: http.Header{"Vary": {"Cookie"}}}
+	cw.body.WriteString("a")
+	c.store(httptest.NewRequest(http.MethodGet, "/me", nil), cw)
+	require.Len(t, c.entries, 1)
+	assert.True(t, c.cacheable(cookie("/me", "a")))
+	assert.Nil(t, c.get(c.key(cookie("/me", "a"))))
+
+	c.store(cookie("/me", "a"), cw)
+	assert.Len(t, c.entries, 2)
+	assert.NotNil(t, c.get(c.key(cookie("/me", "a"))))
+	assert.Nil(t, c.get(c.key(cookie("/me", "b"))))
+}
+
+func TestCaptureWriterLimit(t *testing.T) {
+	// the buffered response is not recorded over the limit
+	cw := newCaptureWriter(nil, 4)
+	_, _ = cw.Write([]byte("abc"))
+	_, _ = cw.Write([]byte("def"))
+	assert.True(t, cw.overflow)
+	assert.Zero(t, cw.body.Len())
+
+	// the fallback receives the whole response
+	w := httptest.NewRecorder()
+	cw = newCaptureWriter(nil, 4)
+	cw.fallback = w
+	cw.Header().Set("X-Test", "1")
+	_, _ = cw.Write([]byte("abc"))
+	assert.Zero(t, w.Body.Len())
+	_, _ = cw.Write([]byte("def"))
+	assert.True(t, cw.overflow)
+	assert.Equal(t, "abcdef", w.Body.String())
+	assert.Equal(t, "1", w.Header().Get("X-Test"))
+
+	// the failed response is not sent
+	w = httptest.NewRecorder()
+	cw = newCaptureWriter(nil, 4)
+	cw.fallback = w
+	cw.WriteHeader(http.StatusBadGateway)
+	_, _ = cw.Write([]byte("abcdef"))
+	assert.True(t, cw.overflow)
+	assert.Zero(t, w.Body.Len())
+}
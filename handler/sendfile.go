@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+)
+
+const (
+	XSendFile     string = "X-Sendfile"
+	contentLength string = "Content-Length"
+)
+
+// sendFile writes the file into the response. When the writer supports io.ReaderFrom, the file is copied by the
+// kernel (sendfile/splice) without the userspace buffer. The successful GET and HEAD responses support the range and
+// conditional requests. Only the files under the x_sendfile root are sent.
+func (h *Handler) sendFile(name string, status int, w http.ResponseWriter, r *http.Request) error {
+	path, err := h.sendfilePath(name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	st, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return err
+	}
+
+	if st.IsDir() {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return nil
+	}
+
+	sw := &sendfileWriter{ResponseWriter: w, h: h, zeroCopy: zeroCopy(r)}
+	if r != nil && status == http.StatusOK && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		// single and multipart ranges, the file parts are still copied via the ReadFrom
+		http.ServeContent(sw, r, st.Name(), st.ModTime(), f)
+		return nil
+	}
+
+	w.Header().Set(contentLength, strconv.FormatInt(st.Size(), 10))
+	w.WriteHeader(status)
+
+	_, err = sw.ReadFrom(f)
+	return err
+}
+
+// sendfilePath cleans the path of the X-Sendfile header and resolves the symlinks, the relative paths are resolved
+// against the root. The paths outside of the root are rejected.
+func (h *Handler) sendfilePath(name string) (string, error) {
+	const op = errors.Op("http_sendfile_path")
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(h.sendfileRoot, name)
+	}
+
+	path, err := filepath.EvalSymlinks(filepath.Clean(name))
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+
+	rel, err := filepath.Rel(h.sendfileRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.E(op, errors.Errorf("%s is outside of the x_sendfile root", name))
+	}
+
+	return path, nil
+}
+
+// zeroCopy returns true if the net/http copies the file by the kernel: the plain HTTP/1 connection. The TLS and
+// HTTP/2 connections copy the file in the userspace.
+func zeroCopy(r *http.Request) bool {
+	return r != nil && r.TLS == nil && r.ProtoMajor == 1
+}
+
+// sendfileWriter counts the bytes copied by the kernel via the io.ReaderFrom of the underlying writer.
+type sendfileWriter struct {
+	http.ResponseWriter
+	h        *Handler
+	zeroCopy bool
+}
+
+func (s *sendfileWriter) ReadFrom(src io.Reader) (int64, error) {
+	rf, ok := s.ResponseWriter.(io.ReaderFrom)
+	if !ok {
+		return io.Copy(s.ResponseWriter, src)
+	}
+
+	n, err := rf.ReadFrom(src)
+	// the other sources (e.g. the multipart ranges) are copied in the userspace
+	if s.zeroCopy && fileSource(src) {
+		s.h.stats.SendfileBytes.Add(uint64(n)) //nolint:gosec
+	}
+
+	return n, err
+}
+
+// fileSource returns true if the reader is the file or the part of it, the sources the kernel can copy.
+func fileSource(src io.Reader) bool {
+	if lr, ok := src.(*io.LimitedReader); ok {
+		src = lr.R
+	}
+
+	_, ok := src.(*os.File)
+	return ok
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendFileRanges(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	fp := filepath.Join(root, "media.bin")
+	require.NoError(t, os.WriteFile(fp, []byte("0123456789"), 0o600))
+	h := &Handler{stats: &Stats{}, sendfileRoot: root}
+
+	send := func(method, ranges string, status int) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/media", nil)
+		if ranges != "" {
+			r.Header.Set("Range", ranges)
+		}
+
+		w := httptest.NewRecorder()
+		require.NoError(t, h.sendFile(fp, status, w, r))
+		return w
+	}
+
+	w := send(http.MethodGet, "", http.StatusOK)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "0123456789", w.Body.String())
+
+	w = send(http.MethodGet, "bytes=2-4", http.StatusOK)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "bytes 2-4/10", w.Header().Get("Content-Range"))
+	assert.Equal(t, "234", w.Body.String())
+
+	w = send(http.MethodGet, "bytes=0-1,8-", http.StatusOK)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	_, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	require.NoError(t, err)
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	var parts []string
+	for {
+		p, errP := mr.NextPart()
+		if errP == io.EOF {
+			break
+		}
+		require.NoError(t, errP)
+		body, errR := io.ReadAll(p)
+		require.NoError(t, errR)
+		parts = append(parts, p.Header.Get("Content-Range")+" "+string(body))
+	}
+	assert.Equal(t, []string{"bytes 0-1/10 01", "bytes 8-9/10 89"}, parts)
+
+	w = send(http.MethodGet, "bytes=20-", http.StatusOK)
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+
+	// the worker status is kept
+	w = send(http.MethodGet, "bytes=2-4", http.StatusNotFound)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "0123456789", w.Body.String())
+}
+
+func TestSendFilePath(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	outside, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("ok"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "link")))
+	h := &Handler{stats: &Stats{}, sendfileRoot: root}
+
+	for _, name := range []string{"file", "./dir/../file", filepath.Join(root, "dir", "..", "file")} {
+		path, errP := h.sendfilePath(name)
+		require.NoError(t, errP, name)
+		assert.Equal(t, filepath.Join(root, "file"), path)
+	}
+
+	// the absolute paths are not resolved against the root, the symlinks are followed
+	for _, name := range []string{"/file", "../secret", filepath.Join(root, "..", filepath.Base(outside), "secret"), filepath.Join(outside, "secret"), "link"} {
+		w := httptest.NewRecorder()
+		assert.Error(t, h.sendFile(name, http.StatusOK, w, httptest.NewRequest(http.MethodGet, "/", nil)), name)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NotContains(t, w.Body.String(), "secret")
+	}
+}
+
+func TestSendFileZeroCopy(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "media.bin"), []byte("0123456789"), 0o600))
+	h := &Handler{stats: &Stats{}, sendfileRoot: root}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = h.sendFile("media.bin", http.StatusOK, w, r)
+	})
+
+	get := func(client *http.Client, url, ranges string) {
+		r, errR := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, errR)
+		if ranges != "" {
+			r.Header.Set("Range", ranges)
+		}
+
+		rsp, errR := client.Do(r)
+		require.NoError(t, errR)
+		_, _ = io.Copy(io.Discard, rsp.Body)
+		_ = rsp.Body.Close()
+	}
+
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	get(plain.Client(), plain.URL, "")
+	get(plain.Client(), plain.URL, "bytes=2-4")
+	assert.Equal(t, uint64(13), h.stats.SendfileBytes.Load())
+
+	// the multipart ranges are copied in the userspace
+	get(plain.Client(), plain.URL, "bytes=0-1,8-")
+	assert.Equal(t, uint64(13), h.stats.SendfileBytes.Load())
+
+	// the TLS connections are copied in the userspace
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+	get(secure.Client(), secure.URL, "")
+	assert.Equal(t, uint64(13), h.stats.SendfileBytes.Load())
+}
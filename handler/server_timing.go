@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/middleware"
+)
+
+const serverTimingHeader string = "Server-Timing"
+
+// timings of the request handling, sent in the Server-Timing header.
+type timings struct {
+	// start of the handler
+	start time.Time
+	// wait for the dispatch slot (prioritization)
+	queue time.Duration
+	// from the dispatch to the first response frame, including the wait for a free worker in the pool
+	exec time.Duration
+}
+
+type serverTimingKey struct{}
+
+// WithServerTiming enables the Server-Timing header for the request regardless of the server_timing option.
+func WithServerTiming(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), serverTimingKey{}, true))
+}
+
+// withServerTiming returns true if the Server-Timing header should be sent.
+func (h *Handler) withServerTiming(r *http.Request) bool {
+	if h.serverTiming {
+		return true
+	}
+
+	forced, _ := r.Context().Value(serverTimingKey{}).(bool)
+	return forced
+}
+
+// setServerTiming sets the Server-Timing header: mw - middleware before the handler (if the arrival is stamped),
+// queue - wait for the dispatch slot, exec - worker execution.
+func setServerTiming(h http.Header, r *http.Request, t *timings) {
+	buf := make([]byte, 0, 64)
+	if arrival, ok := middleware.ArrivalTime(r.Context()); ok {
+		buf = appendTiming(buf, "mw", t.start.Sub(arrival))
+		buf = append(buf, ", "...)
+	}
+
+	buf = appendTiming(buf, "queue", t.queue)
+	buf = append(buf, ", "...)
+	buf = appendTiming(buf, "exec", t.exec)
+
+	h.Add(serverTimingHeader, string(buf))
+}
+
+// appendTiming appends the metric with the duration in milliseconds.
+func appendTiming(buf []byte, name string, d time.Duration) []byte {
+	buf = append(buf, name...)
+	buf = append(buf, ";dur="...)
+	return strconv.AppendFloat(buf, float64(d.Microseconds())/1000, 'f', -1, 64)
+}
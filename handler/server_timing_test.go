@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerTiming(t *testing.T) {
+	start := time.Now()
+	tm := &timings{start: start, queue: 1500 * time.Microsecond, exec: 12 * time.Millisecond}
+
+	hdr := http.Header{}
+	setServerTiming(hdr, httptest.NewRequest(http.MethodGet, "/", nil), tm)
+	assert.Equal(t, "queue;dur=1.5, exec;dur=12", hdr.Get(serverTimingHeader))
+
+	// the arrival is stamped by the middleware
+	var r *http.Request
+	middleware.Arrival(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		r = req
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	arrival, ok := middleware.ArrivalTime(r.Context())
+	assert.True(t, ok)
+
+	tm.start = arrival.Add(250 * time.Microsecond)
+	hdr = http.Header{}
+	setServerTiming(hdr, r, tm)
+	assert.Equal(t, "mw;dur=0.25, queue;dur=1.5, exec;dur=12", hdr.Get(serverTimingHeader))
+}
+
+func TestServerTimingForced(t *testing.T) {
+	h := &Handler{}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.False(t, h.withServerTiming(r))
+	assert.True(t, h.withServerTiming(WithServerTiming(r)))
+
+	// the earlier arrival is kept by the nested middleware
+	var arrival time.Time
+	inner := middleware.Arrival(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		arrival, _ = middleware.ArrivalTime(req.Context())
+	}))
+
+	var outer time.Time
+	middleware.Arrival(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		outer, _ = middleware.ArrivalTime(req.Context())
+		time.Sleep(time.Millisecond)
+		inner.ServeHTTP(w, req)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, outer, arrival)
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+)
+
+// Shedding contains the load shedding state, the fraction is updated by the host resources monitor.
+type Shedding struct {
+	// fraction of the low-priority requests to reject, float64 bits
+	fraction atomic.Uint64
+	// Shed is the number of the rejected requests.
+	Shed atomic.Uint64
+}
+
+// Fraction returns the fraction of the low-priority requests being rejected.
+func (s *Shedding) Fraction() float64 {
+	return math.Float64frombits(s.fraction.Load())
+}
+
+// SetFraction sets the fraction of the low-priority requests to reject, 0 stops the shedding.
+func (s *Shedding) SetFraction(f float64) {
+	s.fraction.Store(math.Float64bits(f))
+}
+
+// shed returns true if the request should be rejected because of the resources pressure.
+func (h *Handler) shed(r *http.Request) bool {
+	fraction := h.stats.Shedding.Fraction()
+	if fraction <= 0 {
+		return false
+	}
+
+	// the priority header is honored only with the prioritization enabled
+	if h.priorityHeader != "" && h.priority(r, FetchIP(r.RemoteAddr, h.log)) > h.shedPriority {
+		return false
+	}
+
+	if rand.Float64() >= fraction { //nolint:gosec
+		return false
+	}
+
+	h.stats.Shedding.Shed.Add(1)
+	return true
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestShed(t *testing.T) {
+	_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+
+	h := &Handler{
+		log:            zap.NewNop(),
+		stats:          &Stats{Shedding: &Shedding{}},
+		priorityHeader: "X-RR-Priority",
+		shedPriority:   1,
+		cfg:            &config.Config{TrustedNets: []*net.IPNet{loopback}},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "127.0.0.1:8080"
+	assert.False(t, h.shed(r))
+
+	h.stats.Shedding.SetFraction(1)
+	assert.True(t, h.shed(r))
+	r.Header.Set("X-RR-Priority", "1")
+	assert.True(t, h.shed(r))
+
+	// the high-priority requests are never rejected
+	r.Header.Set("X-RR-Priority", "2")
+	assert.False(t, h.shed(r))
+	assert.Equal(t, uint64(2), h.stats.Shedding.Shed.Load())
+
+	h.stats.Shedding.SetFraction(0.5)
+	r.Header.Del("X-RR-Priority")
+	shed := 0
+	for i := 0; i < 1000; i++ {
+		if h.shed(r) {
+			shed++
+		}
+	}
+	assert.InDelta(t, 500, shed, 100)
+}
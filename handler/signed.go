@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+// signedURLs validates the signatures of the URLs of the protected paths.
+type signedURLs struct {
+	key            []byte
+	paths          []string
+	expiresParam   string
+	signatureParam string
+}
+
+func newSignedURLs(cfg *config.SignedURLs) *signedURLs {
+	return &signedURLs{
+		key:            []byte(cfg.Key),
+		paths:          cfg.Paths,
+		expiresParam:   cfg.ExpiresParam,
+		signatureParam: cfg.SignatureParam,
+	}
+}
+
+// protected returns true if the path requires the signature.
+func (s *signedURLs) protected(uri string) bool {
+	fp := path.Clean("/" + uri)
+	for i := 0; i < len(s.paths); i++ {
+		if matchPath(s.paths[i], fp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verify checks the expiration time and the signature of the request URL.
+func (s *signedURLs) verify(r *http.Request, now time.Time) error {
+	const op = errors.Op("signed_url_verify")
+	q := r.URL.Query()
+	expires := q.Get(s.expiresParam)
+	signature := q.Get(s.signatureParam)
+	if expires == "" || signature == "" {
+		return errors.E(op, errors.Str("missing signature"))
+	}
+
+	ts, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.E(op, errors.Errorf("bad expiration time: %s", expires))
+	}
+
+	if now.Unix() > ts {
+		return errors.E(op, errors.Str("link expired"))
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.sign(r.URL.Path, expires)) {
+		return errors.E(op, errors.Str("bad signature"))
+	}
+
+	return nil
+}
+
+// sign returns the HMAC-SHA256 of the path and the expiration time.
+func (s *signedURLs) sign(uri, expires string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	_, _ = mac.Write([]byte(uri + "\n" + expires))
+	return mac.Sum(nil)
+}
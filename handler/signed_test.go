@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedURLs(t *testing.T) {
+	cfg := &config.SignedURLs{Key: "secret", Paths: []string{"/downloads/**"}}
+	require.NoError(t, cfg.InitDefaults())
+	s := newSignedURLs(cfg)
+
+	assert.True(t, s.protected("/downloads/report.pdf"))
+	assert.True(t, s.protected("/public/../downloads/report.pdf"))
+	assert.False(t, s.protected("/public/report.pdf"))
+
+	now := time.Now()
+	expires := strconv.FormatInt(now.Add(time.Minute).Unix(), 10)
+	signature := hex.EncodeToString(s.sign("/downloads/report.pdf", expires))
+
+	tests := map[string]bool{
+		"/downloads/report.pdf?expires=" + expires + "&signature=" + signature: true,
+		"/downloads/other.pdf?expires=" + expires + "&signature=" + signature:  false,
+		"/downloads/report.pdf?expires=1&signature=" + signature:               false,
+		"/downloads/report.pdf?expires=" + expires + "&signature=zz":           false,
+		"/downloads/report.pdf?expires=" + expires:                             false,
+		"/downloads/report.pdf": false,
+	}
+
+	for uri, valid := range tests {
+		err := s.verify(httptest.NewRequest(http.MethodGet, uri, nil), now)
+		assert.Equal(t, valid, err == nil, uri)
+	}
+
+	// expired
+	err := s.verify(httptest.NewRequest(http.MethodGet, "/downloads/report.pdf?expires="+expires+"&signature="+signature, nil), now.Add(time.Hour))
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"sync/atomic"
+
+	"github.com/roadrunner-server/http/v5/static"
+)
+
+// Stats contains the handler counters, exported by the plugin's metrics collector.
+type Stats struct {
+	// SendfileBytes is the number of bytes served via the sendfile (io.ReaderFrom) path.
+	SendfileBytes atomic.Uint64
+	// Pending is the number of requests dispatched to the pool and waiting for the response.
+	Pending atomic.Int64
+	// Rejected is the number of requests rejected because of the backpressure.
+	Rejected atomic.Uint64
+	// QueueTimeouts is the number of requests which exceeded the max queue wait time.
+	QueueTimeouts atomic.Uint64
+	// ConcurrencyLimit is the current adaptive concurrency limit, 0 if disabled.
+	ConcurrencyLimit atomic.Int64
+	// Streams is the number of the stream responses being sent.
+	Streams atomic.Int64
+	// ForcedDrains is the number of the stopped streams discarded after the drain timeout.
+	ForcedDrains atomic.Uint64
+	// Canceled is the number of the requests canceled because the client disconnected.
+	Canceled atomic.Uint64
+	// StaticServed is the number of the static files served without the workers.
+	StaticServed atomic.Uint64
+	// ResponseCacheHits is the number of the fresh responses served from the response cache.
+	ResponseCacheHits atomic.Uint64
+	// ResponseCacheStale is the number of the stale responses served from the response cache.
+	ResponseCacheStale atomic.Uint64
+	// ResponseCacheMisses is the number of the cacheable requests sent to the workers.
+	ResponseCacheMisses atomic.Uint64
+	// IdempotentReplays is the number of the stored responses sent to the retries with the idempotency key.
+	IdempotentReplays atomic.Uint64
+	// StaticCache contains the static cache counters, nil if the cache is disabled.
+	StaticCache *static.CacheStats
+	// Shedding contains the load shedding state, nil if the load shedding is disabled.
+	Shedding *Shedding
+	// Tenants contains the per-tenant counters, empty if the tenant limits are disabled.
+	Tenants []*TenantStats
+}
+
+// Stats returns the handler counters.
+func (h *Handler) Stats() *Stats {
+	return h.stats
+}
+
+// PurgeStaticCache removes all the files from the static cache, returns the number of removed files.
+func (h *Handler) PurgeStaticCache() int {
+	if h.static == nil {
+		return 0
+	}
+
+	return h.static.Purge()
+}
+
+// PurgeSurrogateKeys removes the cached responses with any of the surrogate keys, returns the number of removed
+// responses.
+func (h *Handler) PurgeSurrogateKeys(keys []string) int {
+	if h.responseCache == nil {
+		return 0
+	}
+
+	return h.responseCache.purge(keys)
+}
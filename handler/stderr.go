@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// maxStderrSize is the max size of the worker stderr attached to the error log of the request
+const maxStderrSize int = 16 * 1024
+
+// Stderr correlates the worker stderr with the requests by the worker pid and the exec window. The worker executes one
+// request at a time: the window is opened when the request payload is sent to the worker and closed when the next
+// payload is sent or the worker is stopped, the stderr written by the worker in the window belongs to the request. The
+// stderr read after the response (e.g. the output of the crashed worker) is logged with the request ID, but may miss
+// the error log of the request.
+type Stderr struct {
+	mu sync.Mutex
+	// requests waiting for the worker by the payload context, the context contains the request ID
+	pending map[string][]*execWindow
+	// open exec windows by the worker pid
+	open map[int64]*execWindow
+}
+
+// execWindow is the stderr of the worker captured while the worker executes the request
+type execWindow struct {
+	id string
+	// pid of the worker executing the request, 0 while the request waits for the worker
+	pid int64
+	// ctx is the payload context while the request waits for the worker
+	ctx string
+	buf []byte
+	// truncated is set when the stderr exceeds the maxStderrSize
+	truncated bool
+}
+
+// NewStderr creates the worker stderr correlation shared by the handlers and the workers factories.
+func NewStderr() *Stderr {
+	return &Stderr{
+		pending: make(map[string][]*execWindow),
+		open:    make(map[int64]*execWindow),
+	}
+}
+
+// Sent opens the exec window of the worker if the payload context belongs to the request waiting for the worker. Called
+// by the worker relay before the frame is sent.
+func (s *Stderr) Sent(pid int64, ctx []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	windows := s.pending[string(ctx)]
+	if len(windows) == 0 {
+		// probe, ping or the request without the request ID, the previous window is closed
+		delete(s.open, pid)
+		return
+	}
+
+	ew := windows[0]
+	if len(windows) == 1 {
+		delete(s.pending, string(ctx))
+	} else {
+		s.pending[string(ctx)] = windows[1:]
+	}
+
+	ew.ctx = ""
+	ew.pid = pid
+	s.open[pid] = ew
+}
+
+// worker returns the pid of the worker executing the request, 0 if the request was not sent to a worker.
+func (s *Stderr) worker(ew *execWindow) int64 {
+	if ew == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ew.pid
+}
+
+// Closed closes the exec window of the stopped worker.
+func (s *Stderr) Closed(pid int64) {
+	s.mu.Lock()
+	delete(s.open, pid)
+	s.mu.Unlock()
+}
+
+// Write captures the stderr line of the worker, returns the ID of the request executed by the worker or an empty string
+// if the worker is idle.
+func (s *Stderr) Write(pid int64, line string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ew, ok := s.open[pid]
+	if !ok {
+		return ""
+	}
+
+	if len(ew.buf)+len(line) > maxStderrSize {
+		line = line[:max(maxStderrSize-len(ew.buf), 0)]
+		ew.truncated = true
+	}
+
+	ew.buf = append(ew.buf, line...)
+	return ew.id
+}
+
+// start registers the request waiting for the worker
+func (s *Stderr) start(ctx []byte, id string) *execWindow {
+	ew := &execWindow{id: id, ctx: string(ctx)}
+
+	s.mu.Lock()
+	s.pending[ew.ctx] = append(s.pending[ew.ctx], ew)
+	s.mu.Unlock()
+
+	return ew
+}
+
+// finish returns the stderr captured so far as the log field, the request is no longer waiting for the worker
+func (s *Stderr) finish(ew *execWindow) zap.Field {
+	if ew == nil {
+		return zap.Skip()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the request was not sent to the worker
+	if ew.ctx != "" {
+		windows := s.pending[ew.ctx]
+		for i := 0; i < len(windows); i++ {
+			if windows[i] == ew {
+				windows = append(windows[:i:i], windows[i+1:]...)
+				break
+			}
+		}
+
+		if len(windows) == 0 {
+			delete(s.pending, ew.ctx)
+		} else {
+			s.pending[ew.ctx] = windows
+		}
+		ew.ctx = ""
+	}
+
+	if len(ew.buf) == 0 {
+		return zap.Skip()
+	}
+
+	if ew.truncated {
+		return zap.String("stderr", string(ew.buf)+"...")
+	}
+
+	return zap.String("stderr", string(ew.buf))
+}
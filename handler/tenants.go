@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"golang.org/x/time/rate"
+)
+
+// DefaultTenant is the ID of the tenants without the profile in the metrics.
+const DefaultTenant string = "default"
+
+// TenantStats contains the tenant counters, exported by the plugin's metrics collector.
+type TenantStats struct {
+	// ID of the tenant, DefaultTenant for the tenants without the profile.
+	ID string
+	// Requests is the number of the tenant requests.
+	Requests atomic.Uint64
+	// InFlight is the number of the tenant requests being handled.
+	InFlight atomic.Int64
+	// RateLimited is the number of the requests rejected by the rate limit.
+	RateLimited atomic.Uint64
+	// ConcurrencyLimited is the number of the requests rejected by the concurrency limit.
+	ConcurrencyLimited atomic.Uint64
+	// SizeExceeded is the number of the requests rejected by the max request size.
+	SizeExceeded atomic.Uint64
+	// QuotaExceeded is the number of the requests rejected by the upload quota.
+	QuotaExceeded atomic.Uint64
+}
+
+// tenant is the state of the tenant limits.
+type tenant struct {
+	stats          *TenantStats
+	maxBody        int64
+	limiter        *rate.Limiter
+	maxConcurrency int64
+
+	mu          sync.Mutex
+	uploadQuota int64
+	window      time.Duration
+	windowStart time.Time
+	uploaded    int64
+}
+
+func newTenant(id string, limits *config.TenantLimits) *tenant {
+	t := &tenant{
+		stats:          &TenantStats{ID: id},
+		maxBody:        int64(limits.MaxRequestSize) * 1024 * 1024, //nolint:gosec
+		maxConcurrency: limits.MaxConcurrency,
+		uploadQuota:    limits.UploadQuota,
+		window:         limits.UploadWindow,
+	}
+
+	if limits.RateLimit > 0 {
+		t.limiter = rate.NewLimiter(rate.Limit(limits.RateLimit), limits.Burst)
+	}
+
+	return t
+}
+
+// enter applies the rate and the concurrency limits, leave must be called for the admitted requests.
+func (t *tenant) enter() bool {
+	t.stats.Requests.Add(1)
+	if t.limiter != nil && !t.limiter.Allow() {
+		t.stats.RateLimited.Add(1)
+		return false
+	}
+
+	if t.stats.InFlight.Add(1) > t.maxConcurrency && t.maxConcurrency > 0 {
+		t.stats.InFlight.Add(-1)
+		t.stats.ConcurrencyLimited.Add(1)
+		return false
+	}
+
+	return true
+}
+
+func (t *tenant) leave() {
+	t.stats.InFlight.Add(-1)
+}
+
+// limitBody rejects the request if the body is larger than allowed, the chunked bodies are limited while read.
+func (t *tenant) limitBody(w http.ResponseWriter, r *http.Request) bool {
+	if t.maxBody == 0 {
+		return true
+	}
+
+	if r.ContentLength > t.maxBody {
+		t.stats.SizeExceeded.Add(1)
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, t.maxBody)
+	return true
+}
+
+// chargeUploads charges the uploaded files size to the quota, the uploads over the quota are not charged.
+func (t *tenant) chargeUploads(size int64) bool {
+	if t.uploadQuota == 0 || size == 0 {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.windowStart) >= t.window {
+		t.windowStart = now
+		t.uploaded = 0
+	}
+
+	if t.uploaded+size > t.uploadQuota {
+		t.stats.QuotaExceeded.Add(1)
+		return false
+	}
+
+	t.uploaded += size
+	return true
+}
+
+// tenants resolves the tenant of the request.
+type tenants struct {
+	header string
+	byID   map[string]*tenant
+	// nil if the unknown tenants are not limited
+	def *tenant
+}
+
+func newTenants(cfg *config.Tenants) *tenants {
+	t := &tenants{
+		header: cfg.Header,
+		byID:   make(map[string]*tenant, len(cfg.Profiles)),
+	}
+
+	for id, limits := range cfg.Profiles {
+		t.byID[id] = newTenant(id, limits)
+	}
+
+	if cfg.Default != nil {
+		t.def = newTenant(DefaultTenant, cfg.Default)
+	}
+
+	return t
+}
+
+// resolve returns the tenant of the request, nil if the tenant is not limited. The tenant header is honored only for
+// the trusted peers, the requests of the other peers get the default tenant.
+func (t *tenants) resolve(r *http.Request, trusted func() bool) *tenant {
+	var id string
+	if t.header != "" {
+		if !trusted() {
+			return t.def
+		}
+		id = r.Header.Get(t.header)
+	} else {
+		id = r.Host
+		if host, _, err := net.SplitHostPort(id); err == nil {
+			id = host
+		}
+	}
+
+	if tn, ok := t.byID[strings.ToLower(id)]; ok {
+		return tn
+	}
+
+	return t.def
+}
+
+// stats returns the counters of all tenants.
+func (t *tenants) stats() []*TenantStats {
+	st := make([]*TenantStats, 0, len(t.byID)+1)
+	for _, tn := range t.byID {
+		st = append(st, tn.stats)
+	}
+
+	if t.def != nil {
+		st = append(st, t.def.stats)
+	}
+
+	return st
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTenantLimits(t *testing.T) {
+	cfg := &config.Tenants{
+		Header: "X-Tenant",
+		Profiles: map[string]*config.TenantLimits{
+			"Noisy": {RateLimit: 1, MaxConcurrency: 1},
+			"small": {MaxRequestSize: 1, UploadQuota: 10},
+		},
+	}
+	require.NoError(t, cfg.InitDefaults())
+	require.NoError(t, cfg.Valid())
+	tn := newTenants(cfg)
+	trusted := func() bool { return true }
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Nil(t, tn.resolve(r, trusted))
+
+	r.Header.Set("X-Tenant", "noisy")
+	// the header of the untrusted peer is ignored
+	assert.Nil(t, tn.resolve(r, func() bool { return false }))
+	noisy := tn.resolve(r, trusted)
+	require.NotNil(t, noisy)
+
+	assert.True(t, noisy.enter())
+	// the burst is exhausted
+	assert.False(t, noisy.enter())
+	assert.Equal(t, uint64(1), noisy.stats.RateLimited.Load())
+
+	noisy.limiter = nil
+	assert.False(t, noisy.enter())
+	assert.Equal(t, uint64(1), noisy.stats.ConcurrencyLimited.Load())
+	noisy.leave()
+	assert.True(t, noisy.enter())
+	noisy.leave()
+
+	r.Header.Set("X-Tenant", "small")
+	small := tn.resolve(r, trusted)
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 2*1024*1024)))
+	assert.False(t, small.limitBody(httptest.NewRecorder(), r))
+
+	assert.True(t, small.chargeUploads(6))
+	assert.False(t, small.chargeUploads(6))
+	assert.True(t, small.chargeUploads(4))
+	assert.Equal(t, uint64(1), small.stats.QuotaExceeded.Load())
+
+	// the host is used without the header
+	cfg = &config.Tenants{Profiles: map[string]*config.TenantLimits{"example.com": {}}, Default: &config.TenantLimits{}}
+	require.NoError(t, cfg.InitDefaults())
+	tn = newTenants(cfg)
+	r = httptest.NewRequest(http.MethodGet, "http://Example.com:8080/", nil)
+	assert.Equal(t, "example.com", tn.resolve(r, nil).stats.ID)
+	r = httptest.NewRequest(http.MethodGet, "http://other.com/", nil)
+	assert.Equal(t, DefaultTenant, tn.resolve(r, nil).stats.ID)
+}
+
+func TestTenantUploadQuota(t *testing.T) {
+	cfg := &config.Config{
+		Uploads:           &config.Uploads{},
+		InternalErrorCode: 500,
+		Tenants:           &config.Tenants{Default: &config.TenantLimits{UploadQuota: 8}},
+	}
+	require.NoError(t, cfg.Uploads.InitDefaults())
+	require.NoError(t, cfg.Tenants.InitDefaults())
+	h, err := NewHandler(cfg, &replayPool{}, zap.NewNop())
+	require.NoError(t, err)
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	fw, err := mw.CreateFormFile("file", "file.txt")
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte("0123456789"))
+	require.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, uint64(1), h.Stats().Tenants[0].QuotaExceeded.Load())
+	assert.Equal(t, int64(0), h.Stats().Tenants[0].InFlight.Load())
+}
@@ -3,12 +3,12 @@ package handler
 import (
 	"io"
 	"mime/multipart"
+	"net/http"
 	"os"
-	"path"
-	"strings"
 	"sync"
 
 	"github.com/goccy/go-json"
+	"github.com/roadrunner-server/http/v5/config"
 	"go.uber.org/zap"
 )
 
@@ -42,13 +42,13 @@ func (u *Uploads) MarshalJSON() ([]byte, error) {
 
 // Open moves all uploaded files to temp directory, return error in case of issue with temp directory. File errors
 // will be handled individually.
-func (u *Uploads) Open(log *zap.Logger, dir string, forbid, allow map[string]struct{}) {
+func (u *Uploads) Open(log *zap.Logger, dir string, access *config.Access) {
 	var wg sync.WaitGroup
 	for i := 0; i < len(u.list); i++ {
 		wg.Add(1)
 		go func(f *FileUpload) {
 			defer wg.Done()
-			err := f.Open(dir, forbid, allow)
+			err := f.Open(dir, access)
 			if err != nil && log != nil {
 				log.Error("error opening the file", zap.Error(err))
 			}
@@ -58,6 +58,32 @@ func (u *Uploads) Open(log *zap.Logger, dir string, forbid, allow map[string]str
 	wg.Wait()
 }
 
+// size returns the total size of the uploaded files, available before the files are opened.
+func (u *Uploads) size() int64 {
+	var size int64
+	for i := 0; i < len(u.list); i++ {
+		if u.list[i].header != nil {
+			size += u.list[i].header.Size
+		} else {
+			// the file is already stored
+			size += u.list[i].Size
+		}
+	}
+
+	return size
+}
+
+// forbidden returns true if any file was rejected by the access patterns.
+func (u *Uploads) forbidden() bool {
+	for i := 0; i < len(u.list); i++ {
+		if u.list[i].Error == UploadErrorExtension {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Clear deletes all temporary files.
 func (u *Uploads) Clear(log *zap.Logger) {
 	for _, f := range u.list {
@@ -82,8 +108,10 @@ type FileUpload struct {
 	Error int `json:"error"`
 	// TempFilename points to temporary file location.
 	TempFilename string `json:"tmpName"`
-	// associated file header
+	// associated file header, nil for the stored files
 	header *multipart.FileHeader
+	// the file is written to the temp file while the request body is read
+	stored bool
 
 	// private
 	uid int
@@ -109,20 +137,15 @@ func NewUpload(f *multipart.FileHeader, uid, gid int) *FileUpload {
 // STACK
 // DEFER FILE CLOSE (2)
 // DEFER TMP CLOSE  (1)
-func (f *FileUpload) Open(dir string, forbid, allow map[string]struct{}) error {
-	ext := strings.ToLower(path.Ext(f.Name))
-
-	if _, ok := forbid[ext]; ok {
-		f.Error = UploadErrorExtension
+func (f *FileUpload) Open(dir string, access *config.Access) error {
+	if f.stored {
 		return nil
 	}
 
-	// if allow is empty, all extensions (except forbidden) are allowed
-	if len(allow) > 0 {
-		if _, ok := allow[ext]; !ok {
-			f.Error = UploadErrorExtension
-			return nil
-		}
+	// if allow lists are empty, all files (except forbidden) are allowed
+	if !access.Allow(f.Name) {
+		f.Error = UploadErrorExtension
+		return nil
 	}
 
 	file, err := f.header.Open()
@@ -171,3 +194,173 @@ func exists(path string) bool {
 	}
 	return true
 }
+
+// maxParts is the max number of the multipart parts of the lazy uploads, the same as the multipart.Reader default.
+const maxParts = 1000
+
+// storeUploads reads the multipart body part by part: the files are written to the uploads dir once, the values are
+// kept in memory up to the defaultMaxMemory.
+func (h *Handler) storeUploads(r *http.Request, req *Request) error {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+
+	// the stored files are removed by the Request.Close on error
+	u := &Uploads{
+		tree: make(fileTree),
+		list: make([]*FileUpload, 0),
+	}
+	req.Uploads = u
+
+	values := make(map[string][]string)
+	files := make(map[string][]*FileUpload)
+	var size int64
+
+	for parts := 0; ; parts++ {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if parts >= maxParts {
+			return multipart.ErrMessageTooLarge
+		}
+
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+
+		if p.FileName() == "" {
+			data, err := io.ReadAll(io.LimitReader(p, defaultMaxMemory-size+1))
+			if err != nil {
+				return err
+			}
+
+			size += int64(len(data))
+			if size > defaultMaxMemory {
+				return multipart.ErrMessageTooLarge
+			}
+
+			values[name] = append(values[name], string(data))
+			continue
+		}
+
+		f := &FileUpload{
+			Name:   p.FileName(),
+			Mime:   p.Header.Get("Content-Type"),
+			Error:  UploadErrorOK,
+			stored: true,
+			uid:    h.uid,
+			gid:    h.gid,
+		}
+		u.list = append(u.list, f)
+		files[name] = append(files[name], f)
+
+		err = f.store(p, h.uploads.dir, h.uploads.access)
+		if err != nil {
+			return err
+		}
+
+		if f.Error != UploadErrorOK && f.Error != UploadErrorExtension {
+			h.log.Error("error storing the file", zap.String("name", f.Name), zap.Int("error", f.Error))
+		}
+	}
+
+	r.MultipartForm = &multipart.Form{Value: values}
+	if h.strictValidation {
+		err = validateFields(r.MultipartForm)
+		if err != nil {
+			return err
+		}
+
+		for k := range files {
+			if !validString(k, false) {
+				return &validationError{reason: reasonFieldName}
+			}
+		}
+	}
+
+	count := 0
+	err = checkFields(h.formLimits, values, &count)
+	if err != nil {
+		return err
+	}
+
+	err = checkFields(h.formLimits, files, &count)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range files {
+		err = u.tree.push(k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	req.body, err = parseMultipartData(r)
+	return err
+}
+
+// store writes the file part to the temp file in the dir. Only the request body read errors are returned, the file
+// errors are reported in the Error and the rest of the part is discarded.
+func (f *FileUpload) store(part io.Reader, dir string, access *config.Access) error {
+	src := &readTracker{Reader: part}
+	if !access.Allow(f.Name) {
+		f.Error = UploadErrorExtension
+		_, _ = io.Copy(io.Discard, src)
+		return src.err
+	}
+
+	tmp, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		f.Error = UploadErrorNoTmpDir
+		_, _ = io.Copy(io.Discard, src)
+		return src.err
+	}
+
+	f.TempFilename = tmp.Name()
+	// set permissions, 0 means root or error
+	if f.uid != 0 && f.gid != 0 {
+		err = tmp.Chown(f.uid, f.gid)
+		if err != nil {
+			f.Error = UploadErrorCantWrite
+			_ = tmp.Close()
+			_, _ = io.Copy(io.Discard, src)
+			return src.err
+		}
+	}
+
+	f.Size, err = io.Copy(tmp, src)
+	if err != nil && src.err == nil {
+		f.Error = UploadErrorCantWrite
+		_, _ = io.Copy(io.Discard, src)
+	}
+
+	err = tmp.Close()
+	if err != nil && f.Error == UploadErrorOK {
+		f.Error = UploadErrorCantWrite
+	}
+
+	return src.err
+}
+
+// readTracker records the read error to tell it from the write error.
+type readTracker struct {
+	io.Reader
+	err error
+}
+
+func (r *readTracker) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+
+	return n, err
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLazyUploads(t *testing.T) {
+	cfg := &config.Uploads{Dir: t.TempDir(), Lazy: true}
+	require.NoError(t, cfg.InitDefaults())
+	h, err := NewHandler(&config.Config{Uploads: cfg, InternalErrorCode: 500}, &replayPool{}, zap.NewNop())
+	require.NoError(t, err)
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	require.NoError(t, mw.WriteField("title", "report"))
+	fw, err := mw.CreateFormFile("docs[]", "a.txt")
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte("first"))
+	fw, err = mw.CreateFormFile("docs[]", "b.txt")
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte("second file"))
+	fw, err = mw.CreateFormFile("script", "x.php")
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte("<?php"))
+	require.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	req := &Request{}
+	require.NoError(t, h.storeUploads(r, req))
+	require.Len(t, req.Uploads.list, 3)
+	assert.Equal(t, dataTree{"title": "report"}, req.body)
+
+	// the files are not copied again
+	req.Open(zap.NewNop(), cfg.Dir, cfg.Access())
+	first, second, script := req.Uploads.list[0], req.Uploads.list[1], req.Uploads.list[2]
+
+	data, err := os.ReadFile(first.TempFilename)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(data))
+	assert.Equal(t, int64(11), second.Size)
+	assert.Equal(t, int64(16), req.Uploads.size())
+
+	assert.Equal(t, UploadErrorExtension, script.Error)
+	assert.Empty(t, script.TempFilename)
+	assert.True(t, req.Uploads.forbidden())
+
+	tree, err := json.Marshal(req.Uploads)
+	require.NoError(t, err)
+	assert.Contains(t, string(tree), `"tmpName":"`+first.TempFilename+`"`)
+
+	req.Close(zap.NewNop(), r)
+	assert.NoFileExists(t, first.TempFilename)
+	assert.NoFileExists(t, second.TempFilename)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"mime/multipart"
+	"net/http"
+	"unicode/utf8"
+)
+
+// validation failure reasons, reported in the logs
+const (
+	reasonURI         string = "invalid_uri"
+	reasonHeaderName  string = "invalid_header_name"
+	reasonHeaderValue string = "invalid_header_value"
+	reasonFieldName   string = "invalid_field_name"
+)
+
+// validationError is returned by the strict validation, the request is rejected with 400.
+type validationError struct {
+	reason string
+	name   string
+}
+
+func (e *validationError) Error() string {
+	if e.name == "" {
+		return "request validation failed: " + e.reason
+	}
+
+	return "request validation failed: " + e.reason + ": " + e.name
+}
+
+// validateRequest checks the URI and the headers for invalid UTF-8 and control characters.
+func validateRequest(r *http.Request) *validationError {
+	if !validString(r.RequestURI, false) || !validString(r.URL.Path, false) {
+		return &validationError{reason: reasonURI}
+	}
+
+	for k, values := range r.Header {
+		if !validString(k, false) {
+			return &validationError{reason: reasonHeaderName}
+		}
+
+		for i := 0; i < len(values); i++ {
+			// tabs are allowed in the header values
+			if !validString(values[i], true) {
+				return &validationError{reason: reasonHeaderValue, name: k}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateFields checks the multipart field names.
+func validateFields(form *multipart.Form) error {
+	if form == nil {
+		return nil
+	}
+
+	for k := range form.Value {
+		if !validString(k, false) {
+			return &validationError{reason: reasonFieldName}
+		}
+	}
+
+	for k := range form.File {
+		if !validString(k, false) {
+			return &validationError{reason: reasonFieldName}
+		}
+	}
+
+	return nil
+}
+
+// validString returns false if the string is not a valid UTF-8 or contains the control characters.
+func validString(s string, allowTab bool) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\t' && allowTab {
+			continue
+		}
+
+		if c < 0x20 || c == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/path?q=1", nil)
+	r.Header.Set("X-Value", "a\tb")
+	assert.Nil(t, validateRequest(r))
+
+	r.Header.Set("X-Value", "a\x01b")
+	assert.NotNil(t, validateRequest(r))
+
+	r = httptest.NewRequest(http.MethodGet, "/%ff", nil)
+	assert.NotNil(t, validateRequest(r))
+
+	r = httptest.NewRequest(http.MethodGet, "/%00", nil)
+	assert.NotNil(t, validateRequest(r))
+}
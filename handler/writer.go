@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var _ http.ResponseWriter = (*bufferedWriter)(nil)
+
+// bufferedWriter accumulates the response body in the pooled buffer to reduce the number of syscalls.
+type bufferedWriter struct {
+	http.ResponseWriter
+	buf *bufio.Writer
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	return b.buf.Write(p)
+}
+
+// ReadFrom flushes the buffer and passes the reader to the underlying writer (sendfile).
+func (b *bufferedWriter) ReadFrom(src io.Reader) (int64, error) {
+	err := b.buf.Flush()
+	if err != nil {
+		return 0, err
+	}
+
+	if rf, ok := b.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+
+	return io.Copy(b.ResponseWriter, src)
+}
+
+// FlushError flushes the buffer and the underlying writer, used by the http.ResponseController.
+func (b *bufferedWriter) FlushError() error {
+	err := b.buf.Flush()
+	if err != nil {
+		return err
+	}
+
+	return http.NewResponseController(b.ResponseWriter).Flush() //nolint:bodyclose
+}
+
+func (b *bufferedWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := b.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// Unwrap is used by the http.ResponseController.
+func (b *bufferedWriter) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+var _ http.ResponseWriter = (*streamBuffer)(nil)
+
+// streamBuffer collects the stream response to send it at once. The response above the limit is passed through.
+type streamBuffer struct {
+	w     http.ResponseWriter
+	code  int
+	buf   bytes.Buffer
+	limit int64
+	// the buffer is sent, the rest of the stream is passed through
+	passed bool
+}
+
+func (s *streamBuffer) Header() http.Header {
+	return s.w.Header()
+}
+
+func (s *streamBuffer) WriteHeader(code int) {
+	if s.code == 0 {
+		s.code = code
+	}
+}
+
+func (s *streamBuffer) Write(p []byte) (int, error) {
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+
+	if s.passed {
+		return s.w.Write(p)
+	}
+
+	if int64(s.buf.Len()+len(p)) > s.limit {
+		s.flush()
+		return s.w.Write(p)
+	}
+
+	return s.buf.Write(p)
+}
+
+// FlushError is a no-op while buffering, the response is sent at once.
+func (s *streamBuffer) FlushError() error {
+	if !s.passed {
+		return nil
+	}
+
+	return http.NewResponseController(s.w).Flush() //nolint:bodyclose
+}
+
+// flush sends the collected response and switches to the pass-through.
+func (s *streamBuffer) flush() {
+	if s.code == 0 || s.passed {
+		return
+	}
+
+	s.passed = true
+	s.w.WriteHeader(s.code)
+	_, _ = s.w.Write(s.buf.Bytes())
+	s.buf.Reset()
+}
+
+func (h *Handler) getBufWriter(w http.ResponseWriter) *bufferedWriter {
+	bw := h.bufWriterPool.Get().(*bufferedWriter)
+	bw.ResponseWriter = w
+	bw.buf.Reset(w)
+	return bw
+}
+
+func (h *Handler) putBufWriter(bw *bufferedWriter) {
+	err := bw.buf.Flush()
+	if err != nil {
+		h.log.Debug("buffered response flush", zap.Error(err))
+	}
+
+	bw.buf.Reset(nil)
+	bw.ResponseWriter = nil
+	h.bufWriterPool.Put(bw)
+}
+
+var _ http.ResponseWriter = (*captureWriter)(nil)
+
+// captureWriter records the response while sending it to the underlying writer. Without the underlying writer the
+// response is buffered, the body is recorded up to the limit in both cases. The buffered response over the limit is
+// sent to the fallback writer as is, or discarded without the fallback.
+type captureWriter struct {
+	w        http.ResponseWriter
+	fallback http.ResponseWriter
+	header   http.Header
+	status   int
+	snapshot http.Header
+	body     bytes.Buffer
+	limit    int64
+	overflow bool
+	// partial is set when the status was sent before the worker response (the keepalive whitespace), the recorded
+	// response is not stored
+	partial bool
+	// header recorded, but not sent to the client
+	hide string
+}
+
+func newCaptureWriter(w http.ResponseWriter, limit int64) *captureWriter {
+	return &captureWriter{w: w, header: http.Header{}, limit: limit}
+}
+
+func (c *captureWriter) Header() http.Header {
+	if c.w != nil {
+		return c.w.Header()
+	}
+
+	return c.header
+}
+
+func (c *captureWriter) WriteHeader(code int) {
+	// informational responses (103) are not recorded
+	if code >= 200 && c.status == 0 {
+		c.status = code
+		c.snapshot = c.Header().Clone()
+		if c.hide != "" {
+			c.Header().Del(c.hide)
+		}
+	}
+
+	if c.w != nil {
+		c.w.WriteHeader(code)
+	}
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	if !c.overflow && int64(c.body.Len()+len(p)) > c.limit {
+		c.overflow = true
+		// the failed response is not sent, it's replaced anyway
+		if c.w == nil && c.fallback != nil && c.status < http.StatusInternalServerError {
+			c.replay(c.fallback)
+			c.w = c.fallback
+		}
+		c.body.Reset()
+	}
+
+	if !c.overflow {
+		c.body.Write(p)
+	}
+
+	if c.w == nil {
+		return len(p), nil
+	}
+
+	return c.w.Write(p)
+}
+
+// FlushError flushes the underlying writer, used by the http.ResponseController.
+func (c *captureWriter) FlushError() error {
+	if c.w == nil {
+		return nil
+	}
+
+	return http.NewResponseController(c.w).Flush() //nolint:bodyclose
+}
+
+func (c *captureWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := c.w.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// replay sends the buffered response.
+func (c *captureWriter) replay(w http.ResponseWriter) {
+	for k, v := range c.header {
+		w.Header()[k] = v
+	}
+
+	if c.status == 0 {
+		return
+	}
+
+	w.WriteHeader(c.status)
+	_, _ = w.Write(c.body.Bytes())
+}
+
+var _ http.ResponseWriter = (*latencyWriter)(nil)
+
+// latencyWriter flushes the stream frames written since the last flush after the interval.
+type latencyWriter struct {
+	w        http.ResponseWriter
+	interval time.Duration
+
+	mu      sync.Mutex
+	t       *time.Timer
+	pending bool
+}
+
+func newLatencyWriter(w http.ResponseWriter, interval time.Duration) *latencyWriter {
+	return &latencyWriter{w: w, interval: interval}
+}
+
+func (l *latencyWriter) Header() http.Header {
+	return l.w.Header()
+}
+
+func (l *latencyWriter) WriteHeader(code int) {
+	l.mu.Lock()
+	l.w.WriteHeader(code)
+	l.mu.Unlock()
+}
+
+func (l *latencyWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n, err := l.w.Write(p)
+	// the timer is already set or the writer is stopped
+	if l.pending || l.interval == 0 {
+		return n, err
+	}
+
+	l.pending = true
+	if l.t == nil {
+		l.t = time.AfterFunc(l.interval, l.delayedFlush)
+	} else {
+		l.t.Reset(l.interval)
+	}
+
+	return n, err
+}
+
+func (l *latencyWriter) delayedFlush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// flushed in between or stopped
+	if !l.pending {
+		return
+	}
+
+	l.pending = false
+	_ = http.NewResponseController(l.w).Flush() //nolint:bodyclose
+}
+
+// FlushError flushes the frames at once, used by the http.ResponseController.
+func (l *latencyWriter) FlushError() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pending = false
+	return http.NewResponseController(l.w).Flush() //nolint:bodyclose
+}
+
+// stop flushes the rest of the frames, the writer is not used after the handler returns.
+func (l *latencyWriter) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.t != nil {
+		l.t.Stop()
+	}
+
+	l.interval = 0
+	if l.pending {
+		l.pending = false
+		_ = http.NewResponseController(l.w).Flush() //nolint:bodyclose
+	}
+}
+
+// Unwrap is used by the http.ResponseController.
+func (l *latencyWriter) Unwrap() http.ResponseWriter {
+	return l.w
+}
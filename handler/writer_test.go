@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes atomic.Int32
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes.Add(1)
+}
+
+func TestLatencyWriter(t *testing.T) {
+	rec := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	lw := newLatencyWriter(rec, 50*time.Millisecond)
+
+	lw.WriteHeader(http.StatusOK)
+	for i := 0; i < 10; i++ {
+		_, _ = lw.Write([]byte("a"))
+	}
+
+	// the frames are coalesced
+	assert.Equal(t, int32(0), rec.flushes.Load())
+	assert.Eventually(t, func() bool { return rec.flushes.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	// nothing is written since the last flush
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), rec.flushes.Load())
+
+	_, _ = lw.Write([]byte("b"))
+	lw.stop()
+	assert.Equal(t, int32(2), rec.flushes.Load())
+	assert.Equal(t, "aaaaaaaaaab", rec.Body.String())
+
+	// the timer is stopped
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(2), rec.flushes.Load())
+}
+
+func TestStreamBuffer(t *testing.T) {
+	rec := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	sb := &streamBuffer{w: rec, limit: 4}
+
+	sb.WriteHeader(http.StatusAccepted)
+	_, _ = sb.Write([]byte("ab"))
+	_, _ = sb.Write([]byte("cd"))
+	assert.NoError(t, sb.FlushError())
+
+	// the response is collected up to the limit
+	assert.False(t, rec.Flushed)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, int32(0), rec.flushes.Load())
+
+	sb.flush()
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "abcd", rec.Body.String())
+}
+
+func TestStreamBufferOverflow(t *testing.T) {
+	rec := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	sb := &streamBuffer{w: rec, limit: 4}
+
+	_, _ = sb.Write([]byte("abc"))
+	assert.Empty(t, rec.Body.String())
+
+	// the stream above the limit is passed through
+	_, _ = sb.Write([]byte("de"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "abcde", rec.Body.String())
+
+	_, _ = sb.Write([]byte("fgh"))
+	assert.Equal(t, "abcdefgh", rec.Body.String())
+	assert.NoError(t, sb.FlushError())
+	assert.Equal(t, int32(1), rec.flushes.Load())
+
+	// nothing is sent twice
+	sb.flush()
+	assert.Equal(t, "abcdefgh", rec.Body.String())
+}
@@ -2,12 +2,14 @@ package http
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/quic-go/quic-go/http3"
 	"github.com/roadrunner-server/http/v5/acme"
 	"github.com/roadrunner-server/http/v5/common"
 	"github.com/roadrunner-server/http/v5/config"
 	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	"github.com/roadrunner-server/http/v5/servers"
 	"github.com/roadrunner-server/http/v5/servers/fcgi"
 	httpServer "github.com/roadrunner-server/http/v5/servers/http11"
 	http3Server "github.com/roadrunner-server/http/v5/servers/http3"
@@ -32,7 +34,7 @@ func (p *Plugin) initServers() error {
 	}
 
 	if p.cfg.EnableTLS() {
-		https, err := httpsServer.NewHTTPSServer(p, p.cfg.SSLConfig, p.cfg.HTTP2Config, p.stdLog, p.log)
+		https, err := httpsServer.NewHTTPSServer(p, p.cfg.SSLConfig, p.cfg.HTTP2Config, p.cfg.ListenerProtocols[config.ListenerHTTPS], p.stdLog, p.log)
 		if err != nil {
 			return err
 		}
@@ -47,6 +49,44 @@ func (p *Plugin) initServers() error {
 	return nil
 }
 
+// middlewareOrder returns the middleware list of the listener
+func (p *Plugin) middlewareOrder(srv servers.InternalServer[any]) []string {
+	name := listenerName(srv)
+	order, ok := p.cfg.ListenerMiddleware[name]
+	if !ok {
+		order = p.cfg.Middleware
+	}
+
+	// the HTTPS listener sets the TLS headers itself, the other listeners remove the client-provided ones before the
+	// listener middleware
+	if p.tlsHeaders != nil && name != config.ListenerHTTPS {
+		order = append(order[:len(order):len(order)], tlsHeadersName)
+	}
+
+	// the panics of the listener middleware are recovered as well
+	if p.panics != nil {
+		return append(order[:len(order):len(order)], panicReportsName)
+	}
+
+	return order
+}
+
+// listenerName returns the listener name of the server: http, https, fcgi or http3
+func listenerName(srv servers.InternalServer[any]) string {
+	switch srv.(type) {
+	case *httpServer.Server:
+		return config.ListenerHTTP
+	case *httpsServer.Server:
+		return config.ListenerHTTPS
+	case *fcgi.Server:
+		return config.ListenerFCGI
+	case *http3Server.Server:
+		return config.ListenerHTTP3
+	default:
+		return ""
+	}
+}
+
 func nilOr(cfg *config.Config) *acme.Config {
 	if cfg.SSLConfig == nil || cfg.SSLConfig.Acme == nil {
 		return nil
@@ -56,51 +96,228 @@ func nilOr(cfg *config.Config) *acme.Config {
 }
 
 func (p *Plugin) applyBundledMiddleware() {
-	// apply max_req_size and logger middleware
+	// apply max_req_size, allowed_hosts and logger middleware
 	for i := 0; i < len(p.servers); i++ {
 		switch srv := p.servers[i].Server().(type) {
 		case *http.Server:
-			srv.Handler = bundledMw.MaxRequestSize(srv.Handler, p.cfg.MaxRequestSize*MB)
-			srv.Handler = bundledMw.NewLogMiddleware(srv.Handler, p.cfg.AccessLogs, p.log)
+			p.bundledMiddleware(srv)
+			if p.cfg.ConnectionInfo {
+				srv.Handler = bundledMw.Listener(srv.Handler, listenerName(p.servers[i]))
+			}
 		case *http3.Server:
-			srv.Handler = bundledMw.MaxRequestSize(srv.Handler, p.cfg.MaxRequestSize*MB)
-			srv.Handler = bundledMw.NewLogMiddleware(srv.Handler, p.cfg.AccessLogs, p.log)
+			p.bundledHTTP3Middleware(srv)
+			if p.cfg.ConnectionInfo {
+				srv.Handler = bundledMw.Listener(srv.Handler, listenerName(p.servers[i]))
+			}
 		default:
 			p.log.DPanic("unknown server type", zap.Any("server", p.servers[i].Server()))
 		}
 	}
 }
 
+// bundledMiddleware wraps the handler of the HTTP/1 and HTTP/2 server with the bundled middleware
+func (p *Plugin) bundledMiddleware(srv *http.Server) {
+	srv.Handler = p.chaos(srv.Handler)
+	srv.Handler = p.jsonSchema(srv.Handler)
+	srv.Handler = bundledMw.MaxRequestSize(srv.Handler, p.cfg.MaxRequestSize*MB)
+	srv.Handler = bundledMw.AllowedHosts(srv.Handler, p.cfg.AllowedHosts)
+	srv.Handler = p.forwardProxy(srv.Handler)
+	if p.cfg.MaxKeepAliveRequests > 0 || p.cfg.ConnMetadata {
+		srv.ConnContext = bundledMw.ConnContext
+		srv.Handler = bundledMw.MaxKeepAliveRequests(srv.Handler, p.cfg.MaxKeepAliveRequests)
+	}
+	if p.cfg.ConnMetadata {
+		srv.Handler = bundledMw.ConnMetadata(srv.Handler)
+	}
+	srv.Handler = p.logMiddleware(srv.Handler)
+	srv.Handler = p.otelMetrics(srv.Handler)
+	srv.Handler = p.errorRate(srv.Handler)
+	srv.Handler = p.requestStart(srv.Handler)
+	if p.cfg.ServerTiming {
+		srv.Handler = bundledMw.Arrival(srv.Handler)
+	}
+	srv.Handler = p.inspector(srv.Handler)
+	srv.Handler = p.selfTest(srv.Handler, func() http.Handler { return srv.Handler })
+	srv.Handler = p.queueState(srv.Handler)
+	srv.Handler = p.drainStatus(srv.Handler)
+}
+
+// bundledHTTP3Middleware wraps the handler of the HTTP/3 server with the bundled middleware
+func (p *Plugin) bundledHTTP3Middleware(srv *http3.Server) {
+	srv.Handler = p.chaos(srv.Handler)
+	srv.Handler = p.jsonSchema(srv.Handler)
+	srv.Handler = bundledMw.MaxRequestSize(srv.Handler, p.cfg.MaxRequestSize*MB)
+	srv.Handler = bundledMw.AllowedHosts(srv.Handler, p.cfg.AllowedHosts)
+	if p.cfg.HTTP3Config.Allow0RTT {
+		srv.Handler = bundledMw.EarlyData(srv.Handler, p.cfg.HTTP3Config.EarlyData == http3Server.RejectAll)
+	}
+	if p.cfg.ConnMetadata {
+		srv.Handler = bundledMw.ConnMetadata(srv.Handler)
+	}
+	srv.Handler = p.logMiddleware(srv.Handler)
+	srv.Handler = p.otelMetrics(srv.Handler)
+	srv.Handler = p.errorRate(srv.Handler)
+	srv.Handler = p.requestStart(srv.Handler)
+	if p.cfg.ServerTiming {
+		srv.Handler = bundledMw.Arrival(srv.Handler)
+	}
+	srv.Handler = p.inspector(srv.Handler)
+	srv.Handler = p.selfTest(srv.Handler, func() http.Handler { return srv.Handler })
+	srv.Handler = p.queueState(srv.Handler)
+	srv.Handler = p.drainStatus(srv.Handler)
+}
+
+// logMiddleware writes the access log with the template if set
+func (p *Plugin) logMiddleware(next http.Handler) http.Handler {
+	if p.logFormat != nil {
+		return bundledMw.NewFormatLogMiddleware(next, p.logFormat, p.log)
+	}
+
+	return bundledMw.NewLogMiddleware(next, p.cfg.AccessLogs, p.log)
+}
+
+// loadSchemas compiles the request body schemas if the validation is enabled
+func (p *Plugin) loadSchemas() error {
+	if p.cfg.JSONSchema == nil {
+		return nil
+	}
+
+	cfg := p.cfg.JSONSchema
+	routes := make([]bundledMw.SchemaRoute, 0, len(cfg.Routes))
+	for i := 0; i < len(cfg.Routes); i++ {
+		routes = append(routes, bundledMw.SchemaRoute{
+			Method: cfg.Routes[i].Method,
+			Path:   cfg.Routes[i].Path,
+			Schema: cfg.Routes[i].Schema,
+		})
+	}
+
+	var err error
+	p.schemas, err = bundledMw.LoadSchemas(&bundledMw.SchemaOptions{
+		Routes:      routes,
+		OpenAPI:     cfg.OpenAPI,
+		MaxBodySize: cfg.MaxBodySize,
+	})
+
+	return err
+}
+
+// jsonSchema applies the request body validation middleware if enabled
+func (p *Plugin) jsonSchema(next http.Handler) http.Handler {
+	if p.schemas == nil {
+		return next
+	}
+
+	return bundledMw.JSONSchema(next, p.schemas)
+}
+
+// chaos applies the fault injection middleware if configured
+func (p *Plugin) chaos(next http.Handler) http.Handler {
+	if p.cfg.Chaos == nil {
+		return next
+	}
+
+	p.log.Warn("chaos mode is enabled, faults are injected into the requests", zap.String("header", p.cfg.Chaos.Header))
+	return bundledMw.Chaos(next, &bundledMw.Faults{
+		Header:            p.cfg.Chaos.Header,
+		Value:             p.cfg.Chaos.Value,
+		Latency:           p.cfg.Chaos.Latency,
+		LatencyPercentage: p.cfg.Chaos.LatencyPercentage,
+		ErrorStatus:       p.cfg.Chaos.ErrorStatus,
+		ErrorPercentage:   p.cfg.Chaos.ErrorPercentage,
+		DropPercentage:    p.cfg.Chaos.DropPercentage,
+		Trusted:           p.trustedPeer,
+	}, p.log)
+}
+
+// forwardProxy is applied before the allowed_hosts, the proxied requests are checked against the proxy allow-list.
+func (p *Plugin) forwardProxy(next http.Handler) http.Handler {
+	if p.cfg.ForwardProxy == nil {
+		return next
+	}
+
+	return bundledMw.ForwardProxy(next, &bundledMw.ProxyOptions{
+		Hosts:   p.cfg.ForwardProxy.Hosts,
+		Timeout: p.cfg.ForwardProxy.Timeout,
+		Trusted: p.trustedPeer,
+	}, p.log)
+}
+
+// requestStart sets the request start header for the APM agents if configured, the time is taken from the arrival
+func (p *Plugin) requestStart(next http.Handler) http.Handler {
+	if p.cfg.RequestStart == nil {
+		return next
+	}
+
+	unit := time.Microsecond
+	switch p.cfg.RequestStart.Unit {
+	case config.UnitMilliseconds:
+		unit = time.Millisecond
+	case config.UnitSeconds:
+		unit = time.Second
+	}
+
+	return bundledMw.RequestStart(next, &bundledMw.RequestStartOptions{
+		Header:  p.cfg.RequestStart.Header,
+		Unit:    unit,
+		Trusted: p.trustedPeer,
+	})
+}
+
 func (p *Plugin) unmarshal(cfg common.Configurer) error {
+	var err error
+	p.cfg, err = unmarshalConfig(cfg)
+	return err
+}
+
+// unmarshalConfig reads the http section with the nested sections
+func unmarshalConfig(cfg common.Configurer) (*config.Config, error) {
+	var c *config.Config
 	// unmarshal general section
-	err := cfg.UnmarshalKey(PluginName, &p.cfg)
+	err := cfg.UnmarshalKey(PluginName, &c)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if c == nil {
+		c = &config.Config{}
 	}
 
 	// unmarshal HTTPS section
-	err = cfg.UnmarshalKey(sectionHTTPS, &p.cfg.SSLConfig)
+	err = cfg.UnmarshalKey(sectionHTTPS, &c.SSLConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// unmarshal H2C section
-	err = cfg.UnmarshalKey(sectionHTTP2, &p.cfg.HTTP2Config)
+	err = cfg.UnmarshalKey(sectionHTTP2, &c.HTTP2Config)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// unmarshal uploads section
-	err = cfg.UnmarshalKey(sectionUploads, &p.cfg.Uploads)
+	err = cfg.UnmarshalKey(sectionUploads, &c.Uploads)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// unmarshal flat supervisor limits from the pool section
+	err = cfg.UnmarshalKey(sectionPool, &c.Supervisor)
+	if err != nil {
+		return nil, err
+	}
+
+	// unmarshal the pool relay, the workers are prepared by the server plugin
+	err = cfg.UnmarshalKey(sectionPool, &c.Relay)
+	if err != nil {
+		return nil, err
 	}
 
 	// unmarshal fcgi section
-	err = cfg.UnmarshalKey(sectionFCGI, &p.cfg.FCGIConfig)
+	err = cfg.UnmarshalKey(sectionFCGI, &c.FCGIConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return c, nil
 }
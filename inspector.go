@@ -0,0 +1,312 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	stderr "errors"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/handler"
+)
+
+const redacted = "[redacted]"
+
+// inspectedRequest is the request kept by the inspector.
+type inspectedRequest struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	URI        string    `json:"uri"`
+	Host       string    `json:"host"`
+	Proto      string    `json:"proto"`
+	RemoteAddr string    `json:"remote_addr"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	WorkerPID  int64     `json:"worker_pid,omitempty"`
+	// Timings in milliseconds: total, and queue, exec from the Server-Timing header.
+	Timings         map[string]float64 `json:"timings_ms"`
+	RequestHeaders  http.Header        `json:"request_headers"`
+	ResponseHeaders http.Header        `json:"response_headers"`
+}
+
+// inspectorRing keeps the last requests of all the listeners, the oldest one is overwritten.
+type inspectorRing struct {
+	mu      sync.Mutex
+	entries []*inspectedRequest
+	next    int
+	full    bool
+}
+
+func newInspectorRing(size int) *inspectorRing {
+	return &inspectorRing{entries: make([]*inspectedRequest, size)}
+}
+
+func (i *inspectorRing) add(e *inspectedRequest) {
+	i.mu.Lock()
+	i.entries[i.next] = e
+	i.next = (i.next + 1) % len(i.entries)
+	if i.next == 0 {
+		i.full = true
+	}
+	i.mu.Unlock()
+}
+
+// list returns the kept requests, the latest first.
+func (i *inspectorRing) list() []*inspectedRequest {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	n := i.next
+	if i.full {
+		n = len(i.entries)
+	}
+
+	out := make([]*inspectedRequest, 0, n)
+	for j := 1; j <= n; j++ {
+		out = append(out, i.entries[(i.next-j+len(i.entries))%len(i.entries)])
+	}
+
+	return out
+}
+
+// serveInspector starts the inspector listener, the listen errors are returned at once.
+func (p *Plugin) serveInspector(errCh chan error) error {
+	const op = errors.Op("http_serve_inspector")
+	if p.cfg.Inspector == nil {
+		return nil
+	}
+
+	p.inspected = newInspectorRing(p.cfg.Inspector.Size)
+	ln, err := net.Listen("tcp", p.cfg.Inspector.Address)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	p.inspectorSrv = &http.Server{
+		Handler:           inspectorEndpoint(p.cfg.Inspector.Path, p.inspected),
+		ReadHeaderTimeout: time.Minute,
+		ErrorLog:          p.stdLog,
+	}
+
+	go func() {
+		err := p.inspectorSrv.Serve(ln)
+		if err != nil && !stderr.Is(err, http.ErrServerClosed) {
+			errCh <- errors.E(op, err)
+		}
+	}()
+
+	return nil
+}
+
+// inspectorEndpoint lists the kept requests.
+func inspectorEndpoint(path string, ring *inspectorRing) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+
+		writeInspector(w, r, ring.list())
+	})
+}
+
+// inspector records the requests of the listener. The Server-Timing header is requested from the handler for the
+// timings and removed from the response unless the server_timing option is enabled.
+func (p *Plugin) inspector(next http.Handler) http.Handler {
+	if p.inspected == nil {
+		return next
+	}
+
+	return inspect(next, p.inspected, p.cfg.Inspector, !p.cfg.ServerTiming)
+}
+
+func inspect(next http.Handler, ring *inspectorRing, cfg *config.Inspector, strip bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		e := &inspectedRequest{
+			Time:           start,
+			Method:         r.Method,
+			URI:            r.RequestURI,
+			Host:           r.Host,
+			Proto:          r.Proto,
+			RemoteAddr:     r.RemoteAddr,
+			RequestHeaders: redact(r.Header, cfg.Redact),
+			Timings:        make(map[string]float64, 3),
+		}
+
+		iw := &inspectorWriter{w: w, e: e, strip: strip}
+		defer func() {
+			e.Timings["total"] = milliseconds(time.Since(start))
+			if e.Status == 0 {
+				// nothing is written or the connection is hijacked
+				e.Status = iw.status()
+			}
+			ring.add(e)
+		}()
+
+		next.ServeHTTP(iw, handler.WithServerTiming(r))
+
+		if cfg.PIDHeader != "" {
+			e.WorkerPID, _ = strconv.ParseInt(iw.header.Get(cfg.PIDHeader), 10, 64)
+		}
+		e.ResponseHeaders = redact(iw.header, cfg.Redact)
+	})
+}
+
+// redact clones the headers with the hidden values of the listed headers.
+func redact(h http.Header, names []string) http.Header {
+	out := h.Clone()
+	for i := 0; i < len(names); i++ {
+		if _, ok := out[names[i]]; ok {
+			out[names[i]] = []string{redacted}
+		}
+	}
+
+	return out
+}
+
+// inspectorWriter records the status and the response size, the response headers are snapshot when sent.
+type inspectorWriter struct {
+	w      http.ResponseWriter
+	e      *inspectedRequest
+	header http.Header
+	strip  bool
+	hj     bool
+}
+
+func (i *inspectorWriter) Header() http.Header {
+	return i.w.Header()
+}
+
+func (i *inspectorWriter) WriteHeader(code int) {
+	// informational responses are not recorded
+	if code >= 200 && i.e.Status == 0 {
+		i.e.Status = code
+		h := i.w.Header()
+		parseServerTiming(h.Values("Server-Timing"), i.e.Timings)
+		if i.strip {
+			h.Del("Server-Timing")
+		}
+		i.header = h.Clone()
+	}
+
+	i.w.WriteHeader(code)
+}
+
+func (i *inspectorWriter) Write(p []byte) (int, error) {
+	if i.e.Status == 0 {
+		i.WriteHeader(http.StatusOK)
+	}
+
+	n, err := i.w.Write(p)
+	i.e.Bytes += int64(n)
+	return n, err
+}
+
+func (i *inspectorWriter) ReadFrom(src io.Reader) (int64, error) {
+	if i.e.Status == 0 {
+		i.WriteHeader(http.StatusOK)
+	}
+
+	var n int64
+	var err error
+	if rf, ok := i.w.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(i.w, src)
+	}
+
+	i.e.Bytes += n
+	return n, err
+}
+
+func (i *inspectorWriter) Flush() {
+	if fl, ok := i.w.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (i *inspectorWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := i.w.(http.Hijacker); ok {
+		conn, rw, err := hj.Hijack()
+		i.hj = err == nil
+		return conn, rw, err
+	}
+
+	return nil, nil, errors.Str("http.Hijacker interface is not supported")
+}
+
+func (i *inspectorWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := i.w.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// Unwrap is used by the http.ResponseController.
+func (i *inspectorWriter) Unwrap() http.ResponseWriter {
+	return i.w
+}
+
+// status of the response without the headers written by the handler.
+func (i *inspectorWriter) status() int {
+	if i.hj {
+		return http.StatusSwitchingProtocols
+	}
+
+	return http.StatusOK
+}
+
+// writeInspector lists the requests in JSON, or in HTML for the browsers.
+func writeInspector(w http.ResponseWriter, r *http.Request, list []*inspectedRequest) {
+	w.Header().Set("Cache-Control", "no-store")
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = inspectorPage.Execute(w, list)
+}
+
+var inspectorPage = template.Must(template.New("inspector").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>RoadRunner inspector</title>
+<style>
+body { font: 13px monospace; margin: 1em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+details { margin: 0; }
+</style>
+</head>
+<body>
+<table>
+<tr><th>time</th><th>request</th><th>status</th><th>bytes</th><th>pid</th><th>timings, ms</th><th>headers</th></tr>
+{{- range .}}
+<tr>
+<td>{{.Time.Format "15:04:05.000"}}</td>
+<td>{{.Method}} {{.URI}}<br>{{.Host}} {{.Proto}} {{.RemoteAddr}}</td>
+<td>{{.Status}}</td>
+<td>{{.Bytes}}</td>
+<td>{{if .WorkerPID}}{{.WorkerPID}}{{end}}</td>
+<td>{{range $k, $v := .Timings}}{{$k}}={{$v}} {{end}}</td>
+<td><details><summary>request</summary>{{range $k, $v := .RequestHeaders}}{{$k}}: {{range $v}}{{.}} {{end}}<br>{{end}}</details>
+<details><summary>response</summary>{{range $k, $v := .ResponseHeaders}}{{$k}}: {{range $v}}{{.}} {{end}}<br>{{end}}</details></td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`)) //nolint:gochecknoglobals
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectorRing(t *testing.T) {
+	ring := newInspectorRing(3)
+	assert.Empty(t, ring.list())
+
+	for _, uri := range []string{"/1", "/2", "/3", "/4"} {
+		ring.add(&inspectedRequest{URI: uri})
+	}
+
+	// the latest first, the oldest is overwritten
+	list := ring.list()
+	require.Len(t, list, 3)
+	assert.Equal(t, "/4", list[0].URI)
+	assert.Equal(t, "/3", list[1].URI)
+	assert.Equal(t, "/2", list[2].URI)
+}
+
+func TestInspectorRedact(t *testing.T) {
+	cfg := &config.Inspector{Address: "127.0.0.1:0", Redact: []string{"x-api-key"}}
+	require.NoError(t, cfg.InitDefaults())
+	require.NoError(t, cfg.Valid())
+
+	ring := newInspectorRing(cfg.Size)
+	h := inspect(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Worker-Pid", "42")
+		w.WriteHeader(http.StatusCreated)
+	}), ring, cfg, true)
+
+	r := httptest.NewRequest(http.MethodGet, "/orders?id=1", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Api-Key", "secret")
+	r.Header.Set("Accept", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	list := ring.list()
+	require.Len(t, list, 1)
+	assert.Equal(t, http.StatusCreated, list[0].Status)
+	assert.Equal(t, "/orders?id=1", list[0].URI)
+	assert.Equal(t, redacted, list[0].RequestHeaders.Get("Authorization"))
+	assert.Equal(t, redacted, list[0].RequestHeaders.Get("X-Api-Key"))
+	assert.Equal(t, "application/json", list[0].RequestHeaders.Get("Accept"))
+	assert.Equal(t, redacted, list[0].ResponseHeaders.Get("Set-Cookie"))
+	// the original headers are not changed
+	assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+}
+
+func TestInspectorListener(t *testing.T) {
+	cfg := &config.Inspector{Address: "127.0.0.1:0"}
+	require.NoError(t, cfg.InitDefaults())
+	p := &Plugin{cfg: &config.Config{Inspector: cfg}}
+
+	errCh := make(chan error, 1)
+	require.NoError(t, p.serveInspector(errCh))
+	t.Cleanup(func() { _ = p.inspectorSrv.Close() })
+
+	// the application listeners pass the inspector path to the application
+	app := p.inspector(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, cfg.Path, nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+
+	// the endpoint is served on the inspector listener only
+	w = httptest.NewRecorder()
+	p.inspectorSrv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, cfg.Path, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var list []*inspectedRequest
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	require.Len(t, list, 1)
+	assert.Equal(t, http.StatusTeapot, list[0].Status)
+
+	w = httptest.NewRecorder()
+	p.inspectorSrv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, errCh)
+}
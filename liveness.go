@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/fsm"
+	"github.com/roadrunner-server/pool/worker"
+	"go.uber.org/zap"
+)
+
+// liveness periodically pings the idle workers, so the hung ones are replaced before they receive a request.
+func (p *Plugin) liveness(cfg *config.Liveness, stopCh chan struct{}) {
+	tt := time.NewTicker(cfg.Interval)
+	defer tt.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-tt.C:
+			p.mu.RLock()
+			h, pl := p.handler, p.pool.Load()
+			p.mu.RUnlock()
+
+			// the pings are not sent under the lock, the hung worker would block the reset until the exec_ttl
+			if h != nil && pl != nil {
+				p.pingWorkers(cfg, h, pl.Workers())
+			}
+		}
+	}
+}
+
+// pingWorkers sends the ping requests through the pool at once, one per idle worker, so each idle worker is taken
+// by a ping. The working ones are supervised by the exec_ttl. The pool kills the worker not responding in the
+// exec_ttl and replaces it, the pings not getting a free worker are skipped.
+func (p *Plugin) pingWorkers(cfg *config.Liveness, h *handler.Handler, workers []*worker.Process) int {
+	idle := 0
+	for i := 0; i < len(workers); i++ {
+		if workers[i].State().Compare(fsm.StateReady) {
+			idle++
+		}
+	}
+
+	var hung int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(idle)
+	for i := 0; i < idle; i++ {
+		go func() {
+			defer wg.Done()
+			err := p.ping(cfg, h)
+			switch {
+			case err == nil:
+			case errors.Is(errors.ExecTTL, err):
+				mu.Lock()
+				hung++
+				mu.Unlock()
+				p.log.Warn("worker did not respond to the liveness ping and is replaced", zap.Error(err))
+			case errors.Is(errors.NoFreeWorkers, err):
+				// all the workers are taken by the requests, not a worker failure
+			default:
+				p.log.Warn("liveness ping failed", zap.Error(err))
+			}
+		}()
+	}
+
+	wg.Wait()
+	return hung
+}
+
+// ping sends the ping request to a free worker. The context has no deadline: the wait for the worker is bounded by
+// the pool allocate_timeout and the exec by the exec_ttl, so the ping which waited for the worker has the whole
+// exec_ttl.
+func (p *Plugin) ping(cfg *config.Liveness, h *handler.Handler) error {
+	ctx := context.Background()
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.Path, nil)
+	if err != nil {
+		return err
+	}
+
+	r.RequestURI = cfg.Path
+	r.Host = "localhost"
+	r.RemoteAddr = "127.0.0.1:0"
+	_, err = h.Ping(ctx, r)
+	return err
+}
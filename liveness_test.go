@@ -0,0 +1,53 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/fsm"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLivenessPing(t *testing.T) {
+	pl := newTestPool(t, "ok", 200, &pool.Config{NumWorkers: 2, AllocateTimeout: time.Second, Supervisor: &pool.SupervisorConfig{ExecTTL: 500 * time.Millisecond}})
+	h, err := handler.NewHandler(&config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500}, pl, zap.NewNop())
+	require.NoError(t, err)
+	p := &Plugin{log: zap.NewNop()}
+
+	pids := func() []int64 {
+		workers := pl.Workers()
+		out := make([]int64, 0, len(workers))
+		for i := 0; i < len(workers); i++ {
+			out = append(out, workers[i].Pid())
+		}
+		return out
+	}
+
+	// the responding workers are kept, whatever the status
+	before := pids()
+	require.Len(t, before, 2)
+	assert.Equal(t, 0, p.pingWorkers(&config.Liveness{Path: "/.rr/liveness"}, h, pl.Workers()))
+	assert.ElementsMatch(t, before, pids())
+
+	// each idle worker gets the ping, the hung ones are killed by the exec_ttl and replaced by the pool
+	assert.Equal(t, 2, p.pingWorkers(&config.Liveness{Path: "/hang"}, h, pl.Workers()))
+	require.Eventually(t, func() bool {
+		workers := pl.Workers()
+		for i := 0; i < len(workers); i++ {
+			if !workers[i].State().Compare(fsm.StateReady) {
+				return false
+			}
+		}
+		return len(workers) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+	for _, pid := range pids() {
+		assert.NotContains(t, before, pid)
+	}
+
+	assert.Equal(t, 0, p.pingWorkers(&config.Liveness{Path: "/"}, h, pl.Workers()))
+}
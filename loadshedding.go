@@ -0,0 +1,86 @@
+package http
+
+import (
+	"runtime/metrics"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+	"go.uber.org/zap"
+)
+
+// heapMetric is the size of the live and not yet collected heap objects
+const heapMetric string = "/memory/classes/heap/objects:bytes"
+
+// loadShedding periodically checks the host resources and updates the fraction of the low-priority requests to reject.
+func (p *Plugin) loadShedding(cfg *config.LoadShedding, stopCh chan struct{}) {
+	tt := time.NewTicker(cfg.Interval)
+	defer tt.Stop()
+
+	sample := []metrics.Sample{{Name: heapMetric}}
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-tt.C:
+			p.updateShedding(cfg, sample)
+		}
+	}
+}
+
+func (p *Plugin) updateShedding(cfg *config.LoadShedding, sample []metrics.Sample) {
+	var usage float64
+	if cfg.CPU > 0 {
+		// the usage since the previous call
+		pct, err := cpu.Percent(0, false)
+		if err == nil && len(pct) > 0 {
+			usage = max(usage, pressure(pct[0], cfg.CPU, 100))
+		}
+	}
+
+	if cfg.Memory > 0 {
+		vm, err := mem.VirtualMemory()
+		if err == nil {
+			usage = max(usage, pressure(vm.UsedPercent, cfg.Memory, 100))
+		}
+	}
+
+	if cfg.Heap > 0 {
+		metrics.Read(sample)
+		if sample[0].Value.Kind() == metrics.KindUint64 {
+			usage = max(usage, pressure(float64(sample[0].Value.Uint64()), float64(cfg.Heap), 2*float64(cfg.Heap)))
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.handler == nil {
+		return
+	}
+
+	shedding := p.handler.Stats().Shedding
+	fraction := usage * cfg.MaxFraction
+	prev := shedding.Fraction()
+	shedding.SetFraction(fraction)
+
+	switch {
+	case prev == 0 && fraction > 0:
+		p.log.Warn("host resources pressure, low-priority requests are being rejected", zap.Float64("fraction", fraction))
+	case prev > 0 && fraction == 0:
+		p.log.Info("host resources pressure is gone, load shedding stopped")
+	}
+}
+
+// pressure returns 0 below the threshold, growing linearly to 1 at the limit
+func pressure(value, threshold, limit float64) float64 {
+	switch {
+	case value <= threshold:
+		return 0
+	case value >= limit:
+		return 1
+	default:
+		return (value - threshold) / (limit - threshold)
+	}
+}
@@ -2,32 +2,119 @@ package http
 
 import (
 	"strconv"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/roadrunner-server/http/v5/handler"
 	"github.com/roadrunner-server/pool/fsm"
 	"github.com/roadrunner-server/pool/state/process"
 )
 
+// defaultPoolName is the name of the main HTTP pool in the metrics labels
+const defaultPoolName string = "default"
+
 type Informer interface {
 	Workers() []*process.State
 }
 
+// PoolStats describes the worker pool for the metrics.
+type PoolStats struct {
+	// Name of the pool, used as the metrics label.
+	Name string
+	// Workers is the list of the pool workers states.
+	Workers []*process.State
+	// Queue is the number of requests waiting for the pool workers.
+	Queue uint64
+}
+
+// PoolsInformer provides the states of all pools.
+type PoolsInformer interface {
+	PoolStats() []*PoolStats
+}
+
+// StatsInformer provides the handler counters.
+type StatsInformer interface {
+	Stats() *handler.Stats
+}
+
+// queueSizer is implemented by the static pool
+type queueSizer interface {
+	QueueSize() uint64
+}
+
 func (p *Plugin) MetricsCollector() []prometheus.Collector {
 	return []prometheus.Collector{p.statsExporter}
 }
 
-func newWorkersExporter(stats Informer) *StatsExporter {
+// PoolStats returns the states of all pools
+func (p *Plugin) PoolStats() []*PoolStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	pl := p.pool.Load()
+	if pl == nil {
+		return nil
+	}
+
+	st := &PoolStats{
+		Name:    defaultPoolName,
+		Workers: workersState(pl),
+	}
+
+	if qs, ok := pl.(queueSizer); ok {
+		st.Queue = qs.QueueSize()
+	}
+
+	stats := []*PoolStats{st}
+	if green := p.green.Load(); green != nil {
+		stats = append(stats, &PoolStats{Name: greenPoolName, Workers: workersState(green)})
+	}
+
+	if previous := p.previous.Load(); previous != nil {
+		stats = append(stats, &PoolStats{Name: previousPoolName, Workers: workersState(previous)})
+	}
+
+	return stats
+}
+
+func newWorkersExporter(pools PoolsInformer, counters StatsInformer, panics *atomic.Uint64) *StatsExporter {
 	return &StatsExporter{
-		TotalWorkersDesc: prometheus.NewDesc("rr_http_total_workers", "Total number of workers used by the HTTP plugin", nil, nil),
-		TotalMemoryDesc:  prometheus.NewDesc("rr_http_workers_memory_bytes", "Memory usage by HTTP workers.", nil, nil),
-		StateDesc:        prometheus.NewDesc("rr_http_worker_state", "Worker current state", []string{"state", "pid"}, nil),
-		WorkerMemoryDesc: prometheus.NewDesc("rr_http_worker_memory_bytes", "Worker current memory usage", []string{"pid"}, nil),
+		TotalWorkersDesc: prometheus.NewDesc("rr_http_total_workers", "Total number of workers used by the HTTP plugin", []string{"pool"}, nil),
+		TotalMemoryDesc:  prometheus.NewDesc("rr_http_workers_memory_bytes", "Memory usage by HTTP workers.", []string{"pool"}, nil),
+		StateDesc:        prometheus.NewDesc("rr_http_worker_state", "Worker current state", []string{"state", "pid", "pool"}, nil),
+		WorkerMemoryDesc: prometheus.NewDesc("rr_http_worker_memory_bytes", "Worker current memory usage", []string{"pid", "pool"}, nil),
 
-		WorkersReady:   prometheus.NewDesc("rr_http_workers_ready", "HTTP workers currently in ready state", nil, nil),
-		WorkersWorking: prometheus.NewDesc("rr_http_workers_working", "HTTP workers currently in working state", nil, nil),
-		WorkersInvalid: prometheus.NewDesc("rr_http_workers_invalid", "HTTP workers currently in invalid,killing,destroyed,errored,inactive states", nil, nil),
+		WorkersReady:   prometheus.NewDesc("rr_http_workers_ready", "HTTP workers currently in ready state", []string{"pool"}, nil),
+		WorkersWorking: prometheus.NewDesc("rr_http_workers_working", "HTTP workers currently in working state", []string{"pool"}, nil),
+		WorkersInvalid: prometheus.NewDesc("rr_http_workers_invalid", "HTTP workers currently in invalid,killing,destroyed,errored,inactive states", []string{"pool"}, nil),
+		QueueDesc:      prometheus.NewDesc("rr_http_pool_queue_size", "Requests waiting for the pool workers", []string{"pool"}, nil),
 
-		Workers: stats,
+		SendfileBytes:    prometheus.NewDesc("rr_http_sendfile_bytes_total", "Bytes of the file responses served via sendfile (zero-copy)", nil, nil),
+		RequestsPending:  prometheus.NewDesc("rr_http_requests_pending", "Requests dispatched to the pool and waiting for the response", nil, nil),
+		RequestsRejected: prometheus.NewDesc("rr_http_requests_rejected_total", "Requests rejected because of the backpressure", nil, nil),
+		QueueTimeouts:    prometheus.NewDesc("rr_http_queue_wait_timeouts_total", "Requests which exceeded the max queue wait time", nil, nil),
+		StreamsActive:    prometheus.NewDesc("rr_http_streams_active", "Stream responses being sent", nil, nil),
+		ConcurrencyLimit: prometheus.NewDesc("rr_http_concurrency_limit", "Adaptive limit of the requests dispatched to the pool at once", nil, nil),
+		ForcedDrains:     prometheus.NewDesc("rr_http_stream_forced_drains_total", "Stopped streams discarded after the drain timeout", nil, nil),
+		Canceled:         prometheus.NewDesc("rr_http_requests_canceled_total", "Requests canceled because the client disconnected", nil, nil),
+		StaticServed:     prometheus.NewDesc("rr_http_static_served_total", "Static files served without the workers", nil, nil),
+		StaticCacheHits:  prometheus.NewDesc("rr_http_static_cache_hits_total", "Static files served from the memory cache", nil, nil),
+		StaticCacheMiss:  prometheus.NewDesc("rr_http_static_cache_misses_total", "Static files served from the disk with the cache enabled", nil, nil),
+		StaticCacheBytes: prometheus.NewDesc("rr_http_static_cache_bytes", "Total size of the cached static files", nil, nil),
+		RespCacheHits:    prometheus.NewDesc("rr_http_response_cache_hits_total", "Fresh worker responses served from the response cache", nil, nil),
+		RespCacheStale:   prometheus.NewDesc("rr_http_response_cache_stale_total", "Stale worker responses served from the response cache", nil, nil),
+		RespCacheMisses:  prometheus.NewDesc("rr_http_response_cache_misses_total", "Cacheable requests sent to the workers", nil, nil),
+		Shed:             prometheus.NewDesc("rr_http_load_shed_total", "Low-priority requests rejected because of the host resources pressure", nil, nil),
+		ShedFraction:     prometheus.NewDesc("rr_http_load_shed_fraction", "Fraction of the low-priority requests being rejected", nil, nil),
+		IdempotentReplay: prometheus.NewDesc("rr_http_idempotent_replays_total", "Stored responses sent to the retries with the idempotency key", nil, nil),
+		TenantRequests:   prometheus.NewDesc("rr_http_tenant_requests_total", "Requests by tenant", []string{"tenant"}, nil),
+		TenantInFlight:   prometheus.NewDesc("rr_http_tenant_requests_in_flight", "Tenant requests being handled", []string{"tenant"}, nil),
+		TenantRejected:   prometheus.NewDesc("rr_http_tenant_rejected_total", "Tenant requests rejected by the tenant limits", []string{"tenant", "reason"}, nil),
+		Panics:           prometheus.NewDesc("rr_http_panics_total", "Panics recovered in the handler and the middleware", nil, nil),
+
+		Pools:      pools,
+		Counters:   counters,
+		PanicCount: panics,
 	}
 }
 
@@ -40,8 +127,34 @@ type StatsExporter struct {
 	WorkersReady   *prometheus.Desc
 	WorkersWorking *prometheus.Desc
 	WorkersInvalid *prometheus.Desc
+	QueueDesc      *prometheus.Desc
+
+	SendfileBytes    *prometheus.Desc
+	RequestsPending  *prometheus.Desc
+	RequestsRejected *prometheus.Desc
+	QueueTimeouts    *prometheus.Desc
+	StreamsActive    *prometheus.Desc
+	ConcurrencyLimit *prometheus.Desc
+	ForcedDrains     *prometheus.Desc
+	Canceled         *prometheus.Desc
+	StaticServed     *prometheus.Desc
+	StaticCacheHits  *prometheus.Desc
+	StaticCacheMiss  *prometheus.Desc
+	StaticCacheBytes *prometheus.Desc
+	RespCacheHits    *prometheus.Desc
+	RespCacheStale   *prometheus.Desc
+	RespCacheMisses  *prometheus.Desc
+	Shed             *prometheus.Desc
+	ShedFraction     *prometheus.Desc
+	IdempotentReplay *prometheus.Desc
+	TenantRequests   *prometheus.Desc
+	TenantInFlight   *prometheus.Desc
+	TenantRejected   *prometheus.Desc
+	Panics           *prometheus.Desc
 
-	Workers Informer
+	Pools      PoolsInformer
+	Counters   StatsInformer
+	PanicCount *atomic.Uint64
 }
 
 func (s *StatsExporter) Describe(d chan<- *prometheus.Desc) {
@@ -54,11 +167,85 @@ func (s *StatsExporter) Describe(d chan<- *prometheus.Desc) {
 	d <- s.WorkersReady
 	d <- s.WorkersWorking
 	d <- s.WorkersInvalid
+	d <- s.QueueDesc
+
+	d <- s.SendfileBytes
+	d <- s.RequestsPending
+	d <- s.RequestsRejected
+	d <- s.QueueTimeouts
+	d <- s.StreamsActive
+	d <- s.ConcurrencyLimit
+	d <- s.ForcedDrains
+	d <- s.Canceled
+	d <- s.StaticServed
+	d <- s.StaticCacheHits
+	d <- s.StaticCacheMiss
+	d <- s.StaticCacheBytes
+	d <- s.RespCacheHits
+	d <- s.RespCacheStale
+	d <- s.RespCacheMisses
+	d <- s.Shed
+	d <- s.ShedFraction
+	d <- s.IdempotentReplay
+	d <- s.TenantRequests
+	d <- s.TenantInFlight
+	d <- s.TenantRejected
+	d <- s.Panics
 }
 
 func (s *StatsExporter) Collect(ch chan<- prometheus.Metric) {
-	// get the copy of the processes
-	workerStates := s.Workers.Workers()
+	// get the copy of the pools
+	pools := s.Pools.PoolStats()
+	for i := 0; i < len(pools); i++ {
+		s.collectPool(ch, pools[i])
+	}
+
+	ch <- prometheus.MustNewConstMetric(s.Panics, prometheus.CounterValue, float64(s.PanicCount.Load()))
+
+	// handler counters, not available until the handler is started
+	st := s.Counters.Stats()
+	if st == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(s.SendfileBytes, prometheus.CounterValue, float64(st.SendfileBytes.Load()))
+	ch <- prometheus.MustNewConstMetric(s.RequestsPending, prometheus.GaugeValue, float64(st.Pending.Load()))
+	ch <- prometheus.MustNewConstMetric(s.RequestsRejected, prometheus.CounterValue, float64(st.Rejected.Load()))
+	ch <- prometheus.MustNewConstMetric(s.QueueTimeouts, prometheus.CounterValue, float64(st.QueueTimeouts.Load()))
+	ch <- prometheus.MustNewConstMetric(s.StreamsActive, prometheus.GaugeValue, float64(st.Streams.Load()))
+	ch <- prometheus.MustNewConstMetric(s.ConcurrencyLimit, prometheus.GaugeValue, float64(st.ConcurrencyLimit.Load()))
+	ch <- prometheus.MustNewConstMetric(s.ForcedDrains, prometheus.CounterValue, float64(st.ForcedDrains.Load()))
+	ch <- prometheus.MustNewConstMetric(s.Canceled, prometheus.CounterValue, float64(st.Canceled.Load()))
+	ch <- prometheus.MustNewConstMetric(s.StaticServed, prometheus.CounterValue, float64(st.StaticServed.Load()))
+	ch <- prometheus.MustNewConstMetric(s.RespCacheHits, prometheus.CounterValue, float64(st.ResponseCacheHits.Load()))
+	ch <- prometheus.MustNewConstMetric(s.RespCacheStale, prometheus.CounterValue, float64(st.ResponseCacheStale.Load()))
+	ch <- prometheus.MustNewConstMetric(s.RespCacheMisses, prometheus.CounterValue, float64(st.ResponseCacheMisses.Load()))
+	ch <- prometheus.MustNewConstMetric(s.IdempotentReplay, prometheus.CounterValue, float64(st.IdempotentReplays.Load()))
+
+	if st.StaticCache != nil {
+		ch <- prometheus.MustNewConstMetric(s.StaticCacheHits, prometheus.CounterValue, float64(st.StaticCache.Hits.Load()))
+		ch <- prometheus.MustNewConstMetric(s.StaticCacheMiss, prometheus.CounterValue, float64(st.StaticCache.Misses.Load()))
+		ch <- prometheus.MustNewConstMetric(s.StaticCacheBytes, prometheus.GaugeValue, float64(st.StaticCache.Bytes.Load()))
+	}
+
+	if st.Shedding != nil {
+		ch <- prometheus.MustNewConstMetric(s.Shed, prometheus.CounterValue, float64(st.Shedding.Shed.Load()))
+		ch <- prometheus.MustNewConstMetric(s.ShedFraction, prometheus.GaugeValue, st.Shedding.Fraction())
+	}
+
+	for i := 0; i < len(st.Tenants); i++ {
+		t := st.Tenants[i]
+		ch <- prometheus.MustNewConstMetric(s.TenantRequests, prometheus.CounterValue, float64(t.Requests.Load()), t.ID)
+		ch <- prometheus.MustNewConstMetric(s.TenantInFlight, prometheus.GaugeValue, float64(t.InFlight.Load()), t.ID)
+		ch <- prometheus.MustNewConstMetric(s.TenantRejected, prometheus.CounterValue, float64(t.RateLimited.Load()), t.ID, "rate_limit")
+		ch <- prometheus.MustNewConstMetric(s.TenantRejected, prometheus.CounterValue, float64(t.ConcurrencyLimited.Load()), t.ID, "max_concurrency")
+		ch <- prometheus.MustNewConstMetric(s.TenantRejected, prometheus.CounterValue, float64(t.SizeExceeded.Load()), t.ID, "max_request_size")
+		ch <- prometheus.MustNewConstMetric(s.TenantRejected, prometheus.CounterValue, float64(t.QuotaExceeded.Load()), t.ID, "upload_quota")
+	}
+}
+
+func (s *StatsExporter) collectPool(ch chan<- prometheus.Metric, pool *PoolStats) {
+	workerStates := pool.Workers
 
 	// cumulative RSS memory in bytes
 	var cum float64
@@ -71,8 +258,8 @@ func (s *StatsExporter) Collect(ch chan<- prometheus.Metric) {
 	for i := 0; i < len(workerStates); i++ {
 		cum += float64(workerStates[i].MemoryUsage)
 
-		ch <- prometheus.MustNewConstMetric(s.StateDesc, prometheus.GaugeValue, 0, workerStates[i].StatusStr, strconv.Itoa(int(workerStates[i].Pid)))
-		ch <- prometheus.MustNewConstMetric(s.WorkerMemoryDesc, prometheus.GaugeValue, float64(workerStates[i].MemoryUsage), strconv.Itoa(int(workerStates[i].Pid)))
+		ch <- prometheus.MustNewConstMetric(s.StateDesc, prometheus.GaugeValue, 0, workerStates[i].StatusStr, strconv.Itoa(int(workerStates[i].Pid)), pool.Name)
+		ch <- prometheus.MustNewConstMetric(s.WorkerMemoryDesc, prometheus.GaugeValue, float64(workerStates[i].MemoryUsage), strconv.Itoa(int(workerStates[i].Pid)), pool.Name)
 
 		// sync with sdk/worker/state.go
 		switch workerStates[i].Status {
@@ -85,11 +272,12 @@ func (s *StatsExporter) Collect(ch chan<- prometheus.Metric) {
 		}
 	}
 
-	ch <- prometheus.MustNewConstMetric(s.WorkersReady, prometheus.GaugeValue, ready)
-	ch <- prometheus.MustNewConstMetric(s.WorkersWorking, prometheus.GaugeValue, working)
-	ch <- prometheus.MustNewConstMetric(s.WorkersInvalid, prometheus.GaugeValue, invalid)
+	ch <- prometheus.MustNewConstMetric(s.WorkersReady, prometheus.GaugeValue, ready, pool.Name)
+	ch <- prometheus.MustNewConstMetric(s.WorkersWorking, prometheus.GaugeValue, working, pool.Name)
+	ch <- prometheus.MustNewConstMetric(s.WorkersInvalid, prometheus.GaugeValue, invalid, pool.Name)
+	ch <- prometheus.MustNewConstMetric(s.QueueDesc, prometheus.GaugeValue, float64(pool.Queue), pool.Name)
 
 	// send the values to the prometheus
-	ch <- prometheus.MustNewConstMetric(s.TotalWorkersDesc, prometheus.GaugeValue, float64(len(workerStates)))
-	ch <- prometheus.MustNewConstMetric(s.TotalMemoryDesc, prometheus.GaugeValue, cum)
+	ch <- prometheus.MustNewConstMetric(s.TotalWorkersDesc, prometheus.GaugeValue, float64(len(workerStates)), pool.Name)
+	ch <- prometheus.MustNewConstMetric(s.TotalMemoryDesc, prometheus.GaugeValue, cum, pool.Name)
 }
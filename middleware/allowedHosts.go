@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AllowedHosts rejects the requests with the Host header not matching any of the hosts. Hosts are matched exactly
+// or by the wildcard `*.example.com` (subdomains only), the port is ignored unless the host specifies it. Requests
+// without the Host are rejected with 400, requests for the unknown hosts - with 421.
+func AllowedHosts(next http.Handler, hosts []string) http.Handler {
+	if len(hosts) == 0 {
+		return next
+	}
+
+	allowed := make([]string, 0, len(hosts))
+	for i := 0; i < len(hosts); i++ {
+		allowed = append(allowed, strings.ToLower(strings.TrimSpace(hosts[i])))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "" {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		if !hostAllowed(allowed, strings.ToLower(r.Host)) {
+			http.Error(w, http.StatusText(http.StatusMisdirectedRequest), http.StatusMisdirectedRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hostAllowed(allowed []string, host string) bool {
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+
+	for i := 0; i < len(allowed); i++ {
+		pattern := allowed[i]
+		target := name
+		// match the port as well
+		if _, _, err := net.SplitHostPort(pattern); err == nil {
+			target = host
+		}
+
+		switch {
+		case pattern == "*":
+			return true
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(target, pattern[1:]) && len(target) > len(pattern)-1 {
+				return true
+			}
+		case pattern == target:
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	h := AllowedHosts(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), []string{"example.com", "*.example.org", "localhost:8080"})
+
+	tests := map[string]int{
+		"example.com":      http.StatusOK,
+		"EXAMPLE.com:443":  http.StatusOK,
+		"api.example.org":  http.StatusOK,
+		"example.org":      http.StatusMisdirectedRequest,
+		"evil.com":         http.StatusMisdirectedRequest,
+		"localhost:8080":   http.StatusOK,
+		"localhost:9090":   http.StatusMisdirectedRequest,
+		"":                 http.StatusBadRequest,
+		"example.com.evil": http.StatusMisdirectedRequest,
+	}
+
+	for host, code := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = host
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, code, w.Code, host)
+	}
+}
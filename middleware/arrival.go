@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type arrivalKey struct{}
+
+// Arrival stamps the request arrival time before the rest of the middleware chain, used by the Server-Timing header.
+// The time stamped earlier is kept.
+func Arrival(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ArrivalTime(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), arrivalKey{}, time.Now())))
+	})
+}
+
+// ArrivalTime returns the request arrival time stamped by the Arrival middleware.
+func ArrivalTime(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(arrivalKey{}).(time.Time)
+	return t, ok
+}
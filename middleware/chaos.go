@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Faults injected by the Chaos middleware, the percentages are 0-100.
+type Faults struct {
+	// Header (and Value, if set) of the affected requests.
+	Header string
+	Value  string
+
+	Latency           time.Duration
+	LatencyPercentage float64
+	ErrorStatus       int
+	ErrorPercentage   float64
+	DropPercentage    float64
+
+	// Trusted reports whether the client may trigger the faults.
+	Trusted func(r *http.Request) bool
+}
+
+// Chaos injects the faults into the requests of the trusted clients with the header: the latency is added first, then the request is
+// either rejected with the error status, or dropped without a response, or handled as usual.
+func Chaos(next http.Handler, f *Faults, log *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values := r.Header.Values(f.Header)
+		if len(values) == 0 || (f.Value != "" && values[0] != f.Value) || !f.Trusted(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if f.LatencyPercentage > 0 && rand.Float64()*100 < f.LatencyPercentage { //nolint:gosec
+			t := time.NewTimer(f.Latency)
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			}
+		}
+
+		roll := rand.Float64() * 100 //nolint:gosec
+		switch {
+		case roll < f.ErrorPercentage:
+			log.Debug("chaos: error injected", zap.String("uri", r.RequestURI), zap.Int("status", f.ErrorStatus))
+			http.Error(w, http.StatusText(f.ErrorStatus), f.ErrorStatus)
+		case roll < f.ErrorPercentage+f.DropPercentage:
+			log.Debug("chaos: connection dropped", zap.String("uri", r.RequestURI))
+			// the server closes the connection (HTTP/1) or resets the stream without logging
+			panic(http.ErrAbortHandler)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestChaos(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	trusted := func(r *http.Request) bool { return strings.HasPrefix(r.RemoteAddr, "127.0.0.1:") }
+	request := func(h http.Handler, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "127.0.0.1:1234"
+		if value != "" {
+			r.Header.Set("X-Chaos", value)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	h := Chaos(ok, &Faults{Header: "X-Chaos", Value: "on", ErrorStatus: http.StatusBadGateway, ErrorPercentage: 100, Trusted: trusted}, zap.NewNop())
+	assert.Equal(t, http.StatusOK, request(h, "").Code)
+	assert.Equal(t, http.StatusOK, request(h, "off").Code)
+	assert.Equal(t, http.StatusBadGateway, request(h, "on").Code)
+
+	// the header of the untrusted client is ignored
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Chaos", "on")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	h = Chaos(ok, &Faults{Header: "X-Chaos", DropPercentage: 100, Trusted: trusted}, zap.NewNop())
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { request(h, "1") })
+
+	h = Chaos(ok, &Faults{Header: "X-Chaos", Latency: 20 * time.Millisecond, LatencyPercentage: 100, Trusted: trusted}, zap.NewNop())
+	start := time.Now()
+	assert.Equal(t, http.StatusOK, request(h, "1").Code)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"strings"
+
+	"github.com/roadrunner-server/http/v5/attributes"
+)
+
+const (
+	// ClientCertHeader contains the DER encoded client certificate as the RFC 9440 byte sequence, only when the PEM
+	// is passed.
+	ClientCertHeader string = "Client-Cert"
+	// ClientCertSubjectHeader contains the client certificate subject, e.g. CN=client,O=Example.
+	ClientCertSubjectHeader string = "X-Client-Cert-Subject"
+	// ClientCertSANHeader contains the client certificate subject alternative names, e.g. DNS:client.example.com.
+	ClientCertSANHeader string = "X-Client-Cert-SAN"
+	// ClientCertSerialHeader contains the hex client certificate serial number.
+	ClientCertSerialHeader string = "X-Client-Cert-Serial"
+	// ClientCertFingerprintHeader contains the hex SHA-256 of the DER encoded client certificate.
+	ClientCertFingerprintHeader string = "X-Client-Cert-Fingerprint"
+
+	clientSubjectAttribute     string = "tls_client_subject"
+	clientSANAttribute         string = "tls_client_san"
+	clientSerialAttribute      string = "tls_client_serial"
+	clientFingerprintAttribute string = "tls_client_fingerprint"
+	clientCertAttribute        string = "tls_client_cert"
+)
+
+// ClientCertHeaders are the headers set by the ClientCert, removed from the requests of the other listeners.
+var ClientCertHeaders = []string{
+	ClientCertHeader,
+	ClientCertSubjectHeader,
+	ClientCertSANHeader,
+	ClientCertSerialHeader,
+	ClientCertFingerprintHeader,
+}
+
+// ClientCert passes the verified client certificate details to the next handlers and the workers as the
+// X-Client-Cert-* headers and the tls_client_subject, tls_client_san, tls_client_serial and tls_client_fingerprint
+// attributes. With the pem option, the certificate is passed as the tls_client_cert attribute in PEM and the
+// Client-Cert header (RFC 9440). Only the certificates verified by the server are passed, the client-provided headers
+// are removed, so they can't be spoofed.
+func ClientCert(next http.Handler, pemCert bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < len(ClientCertHeaders); i++ {
+			r.Header.Del(ClientCertHeaders[i])
+		}
+
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+		sum := sha256.Sum256(cert.Raw)
+		subject := cert.Subject.String()
+		serial := strings.ToUpper(cert.SerialNumber.Text(16))
+		fingerprint := hex.EncodeToString(sum[:])
+		sans := subjectAltNames(cert)
+
+		r.Header.Set(ClientCertSubjectHeader, subject)
+		r.Header.Set(ClientCertSerialHeader, serial)
+		r.Header.Set(ClientCertFingerprintHeader, fingerprint)
+		if len(sans) > 0 {
+			r.Header.Set(ClientCertSANHeader, strings.Join(sans, ", "))
+		}
+
+		r = attributes.Init(r)
+		_ = attributes.Set(r, clientSubjectAttribute, subject)
+		_ = attributes.Set(r, clientSerialAttribute, serial)
+		_ = attributes.Set(r, clientFingerprintAttribute, fingerprint)
+		for i := 0; i < len(sans); i++ {
+			_ = attributes.Set(r, clientSANAttribute, sans[i])
+		}
+
+		if pemCert {
+			r.Header.Set(ClientCertHeader, ":"+base64.StdEncoding.EncodeToString(cert.Raw)+":")
+			_ = attributes.Set(r, clientCertAttribute, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// subjectAltNames returns the SANs in the OpenSSL format, e.g. DNS:example.com, IP:10.0.0.1
+func subjectAltNames(cert *x509.Certificate) []string {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.EmailAddresses)+len(cert.IPAddresses)+len(cert.URIs))
+	for i := 0; i < len(cert.DNSNames); i++ {
+		sans = append(sans, "DNS:"+cert.DNSNames[i])
+	}
+
+	for i := 0; i < len(cert.EmailAddresses); i++ {
+		sans = append(sans, "email:"+cert.EmailAddresses[i])
+	}
+
+	for i := 0; i < len(cert.IPAddresses); i++ {
+		sans = append(sans, "IP:"+cert.IPAddresses[i].String())
+	}
+
+	for i := 0; i < len(cert.URIs); i++ {
+		sans = append(sans, "URI:"+cert.URIs[i].String())
+	}
+
+	return sans
+}
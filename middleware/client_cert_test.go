@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/attributes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(0xabc123),
+		Subject:      pkix.Name{CommonName: "client", Organization: []string{"Example"}},
+		DNSNames:     []string{"client.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1")},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	var got *http.Request
+	h := ClientCert(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r
+	}), true)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(ClientCertSubjectHeader, "CN=spoofed")
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "CN=client,O=Example", got.Header.Get(ClientCertSubjectHeader))
+	assert.Equal(t, "ABC123", got.Header.Get(ClientCertSerialHeader))
+	assert.Equal(t, "DNS:client.example.com, IP:10.0.0.1", got.Header.Get(ClientCertSANHeader))
+	assert.Len(t, got.Header.Get(ClientCertFingerprintHeader), 64)
+	assert.Equal(t, ":"+base64.StdEncoding.EncodeToString(der)+":", got.Header.Get(ClientCertHeader))
+
+	attrs := attributes.All(got)
+	assert.Equal(t, []string{"DNS:client.example.com", "IP:10.0.0.1"}, attrs[clientSANAttribute])
+	require.Len(t, attrs[clientCertAttribute], 1)
+	assert.True(t, strings.HasPrefix(attrs[clientCertAttribute][0], "-----BEGIN CERTIFICATE-----"))
+
+	// the unverified certificates are not passed
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(ClientCertSubjectHeader, "CN=spoofed")
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Empty(t, got.Header.Get(ClientCertSubjectHeader))
+	assert.Nil(t, attributes.All(got))
+}
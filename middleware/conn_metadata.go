@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strconv"
+
+	"github.com/roadrunner-server/http/v5/attributes"
+)
+
+const (
+	tlsVersionAttribute string = "tls_version"
+	tlsCipherAttribute  string = "tls_cipher"
+	tlsALPNAttribute    string = "tls_alpn"
+	tlsResumedAttribute string = "tls_resumed"
+	connReusedAttribute string = "conn_reused"
+)
+
+// ConnMetadata passes the connection metadata to the workers as the request attributes: tls_version (e.g. TLS 1.3),
+// tls_cipher, tls_alpn, tls_resumed (TLS session resumption) and conn_reused (the connection served the previous
+// requests). The conn_reused is set only when the server uses ConnContext.
+func ConnMetadata(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = attributes.Init(r)
+
+		if r.TLS != nil {
+			_ = attributes.Set(r, tlsVersionAttribute, tls.VersionName(r.TLS.Version))
+			_ = attributes.Set(r, tlsCipherAttribute, tls.CipherSuiteName(r.TLS.CipherSuite))
+			_ = attributes.Set(r, tlsALPNAttribute, r.TLS.NegotiatedProtocol)
+			_ = attributes.Set(r, tlsResumedAttribute, strconv.FormatBool(r.TLS.DidResume))
+		}
+
+		if st, ok := r.Context().Value(connStateKey{}).(*connState); ok {
+			_ = attributes.Set(r, connReusedAttribute, strconv.FormatBool(st.served.Add(1) > 1))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/attributes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnMetadata(t *testing.T) {
+	var attrs map[string][]string
+	h := ConnMetadata(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		attrs = attributes.All(r)
+	}))
+
+	ctx := ConnContext(context.Background(), nil)
+	for _, reused := range []string{"false", "true"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		r.TLS = &tls.ConnectionState{
+			Version:            tls.VersionTLS13,
+			CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+			NegotiatedProtocol: "h2",
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.Equal(t, map[string][]string{
+			tlsVersionAttribute: {"TLS 1.3"},
+			tlsCipherAttribute:  {"TLS_AES_128_GCM_SHA256"},
+			tlsALPNAttribute:    {"h2"},
+			tlsResumedAttribute: {"false"},
+			connReusedAttribute: {reused},
+		}, attrs)
+	}
+
+	// plain connection without the connection state
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, attrs)
+}
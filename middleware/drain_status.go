@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// DrainStatus serves the drain status endpoint for the load balancers: 200 while serving and 503 as soon as the
+// draining is started, the other requests are passed through and served until the servers are stopped.
+func DrainStatus(next http.Handler, path string, draining *atomic.Bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if draining.Load() {
+			w.Header().Set("Connection", "close")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("draining"))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// EarlyData protects the workers from the replayed 0-RTT requests (RFC 8470). The requests received before the
+// handshake is complete are rejected with 425 Too Early when the method is not idempotent or when rejectAll is set,
+// the client retries them after the handshake. The accepted early data requests are marked with the Early-Data: 1
+// header, the header sent by the client is removed.
+func EarlyData(next http.Handler, rejectAll bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("Early-Data")
+
+		if r.TLS != nil && !r.TLS.HandshakeComplete {
+			if rejectAll || !idempotent(r.Method) {
+				http.Error(w, http.StatusText(http.StatusTooEarly), http.StatusTooEarly)
+				return
+			}
+
+			r.Header.Set("Early-Data", "1")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// idempotent methods, RFC 9110 section 9.2.2
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEarlyData(t *testing.T) {
+	var early string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		early = r.Header.Get("Early-Data")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(h http.Handler, method string, handshake bool) int {
+		early = ""
+		r := httptest.NewRequest(method, "/", nil)
+		r.TLS = &tls.ConnectionState{HandshakeComplete: handshake}
+		// spoofed by the client
+		r.Header.Set("Early-Data", "1")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	h := EarlyData(next, false)
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodGet, false))
+	assert.Equal(t, "1", early)
+	assert.Equal(t, http.StatusTooEarly, serve(h, http.MethodPost, false))
+	assert.Equal(t, http.StatusTooEarly, serve(h, http.MethodPatch, false))
+
+	// after the handshake
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodPost, true))
+	assert.Equal(t, "", early)
+
+	h = EarlyData(next, true)
+	assert.Equal(t, http.StatusTooEarly, serve(h, http.MethodGet, false))
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodGet, true))
+}
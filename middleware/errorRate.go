@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// noWorkersHeader is set by the handler when there were no free workers for the request
+const noWorkersHeader string = "No-Workers"
+
+// ErrorCounters contains the counters of the requests and failed responses.
+type ErrorCounters struct {
+	// Requests is the number of the served requests.
+	Requests atomic.Uint64
+	// ServerErrors is the number of the 5xx responses.
+	ServerErrors atomic.Uint64
+	// NoWorkers is the number of the requests rejected because there were no free workers.
+	NoWorkers atomic.Uint64
+}
+
+// ErrorRate counts the requests, the 5xx responses and the requests rejected because there were no free workers.
+func ErrorRate(next http.Handler, c *ErrorCounters) http.Handler {
+	pool := sync.Pool{
+		New: func() any {
+			return &wrapper{
+				code: http.StatusOK,
+			}
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bw := pool.Get().(*wrapper)
+		bw.w = w
+		defer func() {
+			bw.reset()
+			pool.Put(bw)
+		}()
+
+		next.ServeHTTP(bw, r)
+
+		c.Requests.Add(1)
+		if bw.code >= http.StatusInternalServerError {
+			c.ServerErrors.Add(1)
+		}
+
+		if w.Header().Get(noWorkersHeader) != "" {
+			c.NoWorkers.Add(1)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorRate(t *testing.T) {
+	c := &ErrorCounters{}
+	h := ErrorRate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/busy":
+			w.Header().Set(noWorkersHeader, "true")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}), c)
+
+	for _, path := range []string{"/", "/fail", "/busy", "/missing", "/"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, uint64(5), c.Requests.Load())
+	assert.Equal(t, uint64(2), c.ServerErrors.Load())
+	assert.Equal(t, uint64(1), c.NoWorkers.Load())
+}
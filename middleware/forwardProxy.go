@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ProxyOptions of the ForwardProxy middleware.
+type ProxyOptions struct {
+	// Hosts is the allow-list of the origins, matched as the AllowedHosts.
+	Hosts []string
+	// Timeout to wait for the origin response headers.
+	Timeout time.Duration
+	// Trusted reports whether the client may use the proxy.
+	Trusted func(r *http.Request) bool
+}
+
+// ForwardProxy proxies the requests with the absolute-form target (RFC 9112 section 3.2.2) to the allowed origins,
+// the rest of the requests are passed to the next handler. The untrusted clients and the requests for the origins
+// not in the allow-list are rejected with 403.
+func ForwardProxy(next http.Handler, o *ProxyOptions, log *zap.Logger) http.Handler {
+	allowed := make([]string, 0, len(o.Hosts))
+	for i := 0; i < len(o.Hosts); i++ {
+		allowed = append(allowed, strings.ToLower(strings.TrimSpace(o.Hosts[i])))
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// the target is already absolute, the forwarding headers are not added
+			pr.Out.Host = pr.In.URL.Host
+		},
+		Transport: &http.Transport{
+			// the environment proxy might point back to the server
+			Proxy:                 nil,
+			ResponseHeaderTimeout: o.Timeout,
+			TLSHandshakeTimeout:   o.Timeout,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConnsPerHost:   8,
+			ForceAttemptHTTP2:     true,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Warn("forward proxy: origin request failed", zap.String("url", r.URL.Redacted()), zap.Error(err))
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.IsAbs() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.URL.Scheme != "http" && r.URL.Scheme != "https" {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		if !o.Trusted(r) || r.URL.Host == "" || !hostAllowed(allowed, strings.ToLower(r.URL.Host)) {
+			log.Debug("forward proxy: request rejected", zap.String("remote", r.RemoteAddr), zap.String("host", r.URL.Host))
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		proxy.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestForwardProxy(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Method+" "+r.Host+r.RequestURI+" "+r.Header.Get("Proxy-Authorization"))
+	}))
+	defer origin.Close()
+
+	u, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+
+	h := ForwardProxy(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}), &ProxyOptions{
+		Hosts:   []string{u.Host, "*.example.com"},
+		Timeout: time.Second,
+		Trusted: func(r *http.Request) bool { return strings.HasPrefix(r.RemoteAddr, "127.0.0.1:") },
+	}, zap.NewNop())
+
+	serve := func(target, remote string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader("payload"))
+		r.RemoteAddr = remote
+		r.Header.Set("Proxy-Authorization", "secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// origin-form is not proxied
+	assert.Equal(t, http.StatusTeapot, serve("/hook", "127.0.0.1:1000").Code)
+
+	w := serve(origin.URL+"/hook?a=1", "127.0.0.1:1000")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "POST "+u.Host+"/hook?a=1 ", w.Body.String())
+
+	// untrusted client
+	assert.Equal(t, http.StatusForbidden, serve(origin.URL+"/hook", "10.1.1.1:1000").Code)
+	// origin is not allowed
+	assert.Equal(t, http.StatusForbidden, serve("http://evil.com/hook", "127.0.0.1:1000").Code)
+	assert.Equal(t, http.StatusForbidden, serve("http://example.com/hook", "127.0.0.1:1000").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("ftp://api.example.com/hook", "127.0.0.1:1000").Code)
+}
@@ -0,0 +1,323 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaRoute is the schema file of the request body for the route.
+type SchemaRoute struct {
+	// Method of the requests, empty - any method.
+	Method string
+	// Path of the requests, the {name} segments match any segment.
+	Path string
+	// Schema is the path of the JSON schema file.
+	Schema string
+}
+
+// SchemaOptions of the JSONSchema middleware.
+type SchemaOptions struct {
+	// Routes take precedence over the OpenAPI operations.
+	Routes []SchemaRoute
+	// OpenAPI is the path of the OpenAPI 3 document, JSON or YAML.
+	OpenAPI string
+	// MaxBodySize is the max size of the validated bodies.
+	MaxBodySize int64
+}
+
+// Schemas are the compiled request body schemas by the routes.
+type Schemas struct {
+	routes  []*schemaRoute
+	maxBody int64
+}
+
+type schemaRoute struct {
+	method   string
+	segments []string
+	schema   *gojsonschema.Schema
+	required bool
+}
+
+// schemaError is the item of the 422 response
+type schemaError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// LoadSchemas compiles the schemas of the routes and the OpenAPI operations.
+func LoadSchemas(o *SchemaOptions) (*Schemas, error) {
+	const op = errors.Op("json_schema_load")
+	s := &Schemas{maxBody: o.MaxBodySize}
+
+	for i := 0; i < len(o.Routes); i++ {
+		abs, err := filepath.Abs(o.Routes[i].Schema)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		// the file reference resolves the relative $ref to the neighbour files
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://" + filepath.ToSlash(abs)))
+		if err != nil {
+			return nil, errors.E(op, errors.Errorf("schema %s: %v", o.Routes[i].Schema, err))
+		}
+
+		s.routes = append(s.routes, &schemaRoute{
+			method:   o.Routes[i].Method,
+			segments: splitPath(o.Routes[i].Path),
+			schema:   schema,
+		})
+	}
+
+	if o.OpenAPI != "" {
+		routes, err := loadOpenAPI(o.OpenAPI)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		s.routes = append(s.routes, routes...)
+	}
+
+	return s, nil
+}
+
+// loadOpenAPI compiles the JSON request body schemas of the document operations. Each schema references the operation
+// schema in the whole document, so the #/components references are resolved.
+func loadOpenAPI(path string) ([]*schemaRoute, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON
+	var doc map[string]any
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, errors.Errorf("openapi document %s: %v", path, err)
+	}
+
+	paths, _ := doc["paths"].(map[string]any)
+	// the routes are added in the same order on every start
+	keys := make([]string, 0, len(paths))
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var routes []*schemaRoute
+	for _, p := range keys {
+		item, _ := paths[p].(map[string]any)
+		for _, method := range []string{"post", "put", "patch", "delete"} {
+			operation, _ := item[method].(map[string]any)
+			body, _ := operation["requestBody"].(map[string]any)
+			pointer := "#/paths/" + escapePointer(p) + "/" + method + "/requestBody"
+			if ref, ok := body["$ref"].(string); ok {
+				body, _ = resolvePointer(doc, ref).(map[string]any)
+				pointer = ref
+			}
+
+			content, _ := body["content"].(map[string]any)
+			ct := jsonContentType(content)
+			if ct == "" {
+				continue
+			}
+
+			media, _ := content[ct].(map[string]any)
+			if _, ok := media["schema"]; !ok {
+				continue
+			}
+
+			root := make(map[string]any, len(doc)+1)
+			for k, v := range doc {
+				root[k] = v
+			}
+			root["$ref"] = pointer + "/content/" + escapePointer(ct) + "/schema"
+
+			schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(root))
+			if err != nil {
+				return nil, errors.Errorf("openapi operation %s %s: %v", strings.ToUpper(method), p, err)
+			}
+
+			required, _ := body["required"].(bool)
+			routes = append(routes, &schemaRoute{
+				method:   strings.ToUpper(method),
+				segments: splitPath(p),
+				schema:   schema,
+				required: required,
+			})
+		}
+	}
+
+	return routes, nil
+}
+
+// jsonContentType returns the JSON media type of the request body content
+func jsonContentType(content map[string]any) string {
+	if _, ok := content["application/json"]; ok {
+		return "application/json"
+	}
+
+	for ct := range content {
+		if isJSON(ct) {
+			return ct
+		}
+	}
+
+	return ""
+}
+
+func resolvePointer(doc map[string]any, ref string) any {
+	var v any = doc
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		v = m[strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")]
+	}
+
+	return v
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// match returns the first route matching the request
+func (s *Schemas) match(r *http.Request) *schemaRoute {
+	segments := splitPath(r.URL.Path)
+	for i := 0; i < len(s.routes); i++ {
+		route := s.routes[i]
+		if route.method != "" && route.method != r.Method {
+			continue
+		}
+
+		if matchSegments(route.segments, segments) {
+			return route
+		}
+	}
+
+	return nil
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) != len(segments) {
+		return false
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		p := pattern[i]
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+
+		if p != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// JSONSchema validates the JSON request bodies against the route schemas. The invalid bodies are rejected with 422
+// and the list of the errors, the malformed JSON with 400 and the bodies larger than the limit with 413.
+func JSONSchema(next http.Handler, s *Schemas) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := s.match(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ct := r.Header.Get("Content-Type")
+		if ct != "" && !isJSON(ct) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, s.maxBody+1))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		if int64(len(body)) > s.maxBody {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			if route.required {
+				writeSchemaErrors(w, []schemaError{{Field: "(root)", Message: "request body is required"}})
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !json.Valid(body) {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		result, err := route.schema.Validate(gojsonschema.NewBytesLoader(body))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		if !result.Valid() {
+			errs := make([]schemaError, 0, len(result.Errors()))
+			for _, e := range result.Errors() {
+				errs = append(errs, schemaError{Field: e.Field(), Message: e.Description()})
+			}
+
+			writeSchemaErrors(w, errs)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeSchemaErrors(w http.ResponseWriter, errs []schemaError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error  string        `json:"error"`
+		Errors []schemaError `json:"errors"`
+	}{
+		Error:  "request body does not match the schema",
+		Errors: errs,
+	})
+}
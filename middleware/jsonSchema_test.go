@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["name"],
+	"properties": {
+		"name": {"type": "string"},
+		"age": {"type": "integer", "minimum": 0}
+	}
+}`
+
+const openAPI = `
+openapi: 3.0.3
+paths:
+  /orders/{id}:
+    put:
+      requestBody:
+        $ref: '#/components/requestBodies/Order'
+  /orders:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Order'
+components:
+  requestBodies:
+    Order:
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Order'
+  schemas:
+    Order:
+      type: object
+      required: [sku]
+      properties:
+        sku:
+          type: string
+`
+
+func TestJSONSchema(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user.json"), []byte(userSchema), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "openapi.yaml"), []byte(openAPI), 0o600))
+
+	s, err := LoadSchemas(&SchemaOptions{
+		Routes:      []SchemaRoute{{Method: http.MethodPost, Path: "/users", Schema: filepath.Join(dir, "user.json")}},
+		OpenAPI:     filepath.Join(dir, "openapi.yaml"),
+		MaxBodySize: 64,
+	})
+	require.NoError(t, err)
+
+	h := JSONSchema(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}), s)
+
+	serve := func(method, path, ct, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if ct != "" {
+			r.Header.Set("Content-Type", ct)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/users", "application/json", `{"name":"john","age":3}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"name":"john","age":3}`, w.Body.String())
+
+	w = serve(http.MethodPost, "/users", "application/json; charset=utf-8", `{"age":-1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var rsp struct {
+		Errors []schemaError `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+	assert.Len(t, rsp.Errors, 2)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/users", "", `{"name":`).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(http.MethodPost, "/users", "", `{"name":"`+strings.Repeat("a", 64)+`"}`).Code)
+	// not validated
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/users", "text/plain", `{}`).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/users", "", `{}`).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/users", "", ``).Code)
+
+	// openapi
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/orders", "", `{"sku":"a1"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPost, "/orders", "", `{"sku":1}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPost, "/orders", "", ``).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPut, "/orders/42", "application/json", `{}`).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/orders/42", "application/json", `{"sku":"a1"}`).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/orders/42/items", "application/json", `{}`).Code)
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+type connStateKey struct{}
+
+// connState is the per-connection state of the middleware, each middleware counts the requests on its own, so they
+// can be enabled independently.
+type connState struct {
+	// requests counted by MaxKeepAliveRequests
+	requests atomic.Uint64
+	// requests counted by ConnMetadata
+	served atomic.Uint64
+}
+
+// ConnContext attaches the connection state to the connection context, used with the http.Server ConnContext.
+func ConnContext(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connStateKey{}, &connState{})
+}
+
+// MaxKeepAliveRequests closes the client connection after the max number of the requests served over it. The
+// server must use ConnContext to count the requests.
+func MaxKeepAliveRequests(next http.Handler, maxRequests uint64) http.Handler {
+	if maxRequests == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if st, ok := r.Context().Value(connStateKey{}).(*connState); ok && st.requests.Add(1) >= maxRequests {
+			// the server closes the connection after the response
+			w.Header().Set("Connection", "close")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type listenerKey struct{}
+
+// Listener stamps the name of the listener serving the request: http, https, fcgi or http3.
+func Listener(next http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), listenerKey{}, name)))
+	})
+}
+
+// ListenerName returns the listener name stamped by the Listener middleware.
+func ListenerName(ctx context.Context) string {
+	name, _ := ctx.Value(listenerKey{}).(string)
+	return name
+}
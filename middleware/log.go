@@ -15,6 +15,7 @@ import (
 
 var _ io.ReadCloser = (*wrapper)(nil)
 var _ http.ResponseWriter = (*wrapper)(nil)
+var _ io.ReaderFrom = (*wrapper)(nil)
 
 type wrapper struct {
 	io.ReadCloser
@@ -42,8 +43,8 @@ func (w *wrapper) WriteHeader(code int) {
 		return
 	}
 
-	// do not allow sending 200 twice
-	if code >= 100 && code < 200 {
+	// do not allow sending 200 twice, 1xx (early hints) are followed by the final status
+	if code >= 200 {
 		w.wc = true
 	}
 
@@ -61,9 +62,29 @@ func (w *wrapper) Write(b []byte) (int, error) {
 	return n, err
 }
 
+func (w *wrapper) ReadFrom(src io.Reader) (int64, error) {
+	w.wc = true
+	var n int64
+	var err error
+	if rf, ok := w.w.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(w.w, src)
+	}
+
+	w.write += int(n)
+	return n, err
+}
+
 func (w *wrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if hj, ok := w.w.(http.Hijacker); ok {
-		return hj.Hijack()
+		conn, rw, err := hj.Hijack()
+		if err == nil {
+			// the protocol is switched by the hijacker
+			w.code = http.StatusSwitchingProtocols
+		}
+
+		return conn, rw, err
 	}
 
 	return nil, nil, errors.Str("http.Hijacker interface is not supported")
@@ -92,6 +113,9 @@ func (w *wrapper) reset() {
 type lm struct {
 	pool sync.Pool
 	log  *zap.Logger
+	// access log template, nil - structured log
+	format  *LogFormat
+	bufPool sync.Pool
 }
 
 func NewLogMiddleware(next http.Handler, accessLogs bool, log *zap.Logger) http.Handler {
@@ -109,6 +133,29 @@ func NewLogMiddleware(next http.Handler, accessLogs bool, log *zap.Logger) http.
 	return l.Log(next, accessLogs)
 }
 
+// NewFormatLogMiddleware writes the access log lines formatted with the template.
+func NewFormatLogMiddleware(next http.Handler, format *LogFormat, log *zap.Logger) http.Handler {
+	l := &lm{
+		log:    log,
+		format: format,
+		pool: sync.Pool{
+			New: func() any {
+				return &wrapper{
+					code: http.StatusOK,
+				}
+			},
+		},
+		bufPool: sync.Pool{
+			New: func() any {
+				buf := make([]byte, 0, 256)
+				return &buf
+			},
+		},
+	}
+
+	return l.Log(next, true)
+}
+
 func (l *lm) Log(next http.Handler, accessLogs bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -123,6 +170,11 @@ func (l *lm) Log(next http.Handler, accessLogs bool) http.Handler {
 		}
 
 		next.ServeHTTP(bw, r2)
+		if l.format != nil {
+			l.writeFormatted(r, r2.Header, bw, start)
+			return
+		}
+
 		l.writeLog(accessLogs, r, bw, start)
 	})
 }
@@ -172,6 +224,13 @@ func (l *lm) writeLog(accessLog bool, r *http.Request, bw *wrapper, start time.T
 	}
 }
 
+func (l *lm) writeFormatted(r *http.Request, downstream http.Header, bw *wrapper, start time.Time) {
+	buf := l.bufPool.Get().(*[]byte)
+	*buf = l.format.append((*buf)[:0], &logEntry{r: r, downstream: downstream, w: bw, start: start, elapsed: time.Since(start)})
+	l.log.Info(string(*buf))
+	l.bufPool.Put(buf)
+}
+
 func (l *lm) getW(w http.ResponseWriter) *wrapper {
 	wr := l.pool.Get().(*wrapper)
 	wr.w = w
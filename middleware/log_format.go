@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// logEntry is the served request passed to the access log template.
+type logEntry struct {
+	r *http.Request
+	// downstream request headers, contain the trace context injected toward the worker
+	downstream http.Header
+	w          *wrapper
+	start      time.Time
+	// elapsed is measured once, so %D and %T are consistent
+	elapsed time.Duration
+}
+
+type segment func(buf []byte, e *logEntry) []byte
+
+// LogFormat is the compiled access log template. The placeholders follow the Apache mod_log_config format:
+//
+//	%h - remote address          %v - host                   %m - method
+//	%U - path                    %q - query string (?a=b)    %H - protocol
+//	%r - request line            %s - status                 %t - start time (CLF)
+//	%b - response bytes, - for 0 %B - response bytes         %I - request bytes
+//	%D - duration, microseconds  %T - duration, seconds      %X - W3C trace ID, - if not traced
+//	%{Name}i - request header    %{Name}o - response header  %% - percent sign
+//
+// The worker-provided values, e.g. the route or the worker PID, are logged from the response headers with %{Name}o.
+// Empty values are logged as -.
+type LogFormat struct {
+	segments []segment
+}
+
+// ParseLogFormat compiles the access log template.
+func ParseLogFormat(format string) (*LogFormat, error) {
+	const op = errors.Op("parse_log_format")
+	f := &LogFormat{}
+
+	for len(format) > 0 {
+		i := strings.IndexByte(format, '%')
+		if i < 0 {
+			f.literal(format)
+			break
+		}
+
+		if i > 0 {
+			f.literal(format[:i])
+		}
+
+		format = format[i+1:]
+		if len(format) == 0 {
+			return nil, errors.E(op, errors.Str("trailing % in the log format"))
+		}
+
+		if format[0] == '{' {
+			end := strings.IndexByte(format, '}')
+			if end < 0 || end+1 >= len(format) {
+				return nil, errors.E(op, errors.Str("unterminated %{ in the log format"))
+			}
+
+			name := format[1:end]
+			switch format[end+1] {
+			case 'i':
+				f.segments = append(f.segments, func(buf []byte, e *logEntry) []byte {
+					return appendValue(buf, e.r.Header.Get(name))
+				})
+			case 'o':
+				f.segments = append(f.segments, func(buf []byte, e *logEntry) []byte {
+					return appendValue(buf, e.w.Header().Get(name))
+				})
+			default:
+				return nil, errors.E(op, errors.Errorf("unknown placeholder %%{%s}%c", name, format[end+1]))
+			}
+
+			format = format[end+2:]
+			continue
+		}
+
+		s, ok := directives[format[0]]
+		if !ok {
+			return nil, errors.E(op, errors.Errorf("unknown placeholder %%%c", format[0]))
+		}
+
+		f.segments = append(f.segments, s)
+		format = format[1:]
+	}
+
+	return f, nil
+}
+
+func (f *LogFormat) literal(s string) {
+	f.segments = append(f.segments, func(buf []byte, _ *logEntry) []byte {
+		return append(buf, s...)
+	})
+}
+
+func (f *LogFormat) append(buf []byte, e *logEntry) []byte {
+	for i := 0; i < len(f.segments); i++ {
+		buf = f.segments[i](buf, e)
+	}
+
+	return buf
+}
+
+var directives = map[byte]segment{ //nolint:gochecknoglobals
+	'%': func(buf []byte, _ *logEntry) []byte { return append(buf, '%') },
+	'h': func(buf []byte, e *logEntry) []byte { return appendValue(buf, e.r.RemoteAddr) },
+	'v': func(buf []byte, e *logEntry) []byte { return appendValue(buf, e.r.Host) },
+	'm': func(buf []byte, e *logEntry) []byte { return append(buf, e.r.Method...) },
+	'U': func(buf []byte, e *logEntry) []byte { return appendValue(buf, e.r.URL.Path) },
+	'q': func(buf []byte, e *logEntry) []byte {
+		if e.r.URL.RawQuery == "" {
+			return buf
+		}
+
+		return appendValue(append(buf, '?'), e.r.URL.RawQuery)
+	},
+	'H': func(buf []byte, e *logEntry) []byte { return append(buf, e.r.Proto...) },
+	'r': func(buf []byte, e *logEntry) []byte {
+		buf = append(buf, e.r.Method...)
+		buf = appendValue(append(buf, ' '), e.r.RequestURI)
+		return append(append(buf, ' '), e.r.Proto...)
+	},
+	's': func(buf []byte, e *logEntry) []byte { return strconv.AppendInt(buf, int64(e.w.code), 10) },
+	't': func(buf []byte, e *logEntry) []byte {
+		return append(e.start.AppendFormat(append(buf, '['), "02/Jan/2006:15:04:05 -0700"), ']')
+	},
+	'b': func(buf []byte, e *logEntry) []byte {
+		if e.w.write == 0 {
+			return append(buf, '-')
+		}
+
+		return strconv.AppendInt(buf, int64(e.w.write), 10)
+	},
+	'B': func(buf []byte, e *logEntry) []byte { return strconv.AppendInt(buf, int64(e.w.write), 10) },
+	'I': func(buf []byte, e *logEntry) []byte { return strconv.AppendInt(buf, int64(e.w.read), 10) },
+	'D': func(buf []byte, e *logEntry) []byte { return strconv.AppendInt(buf, e.elapsed.Microseconds(), 10) },
+	'T': func(buf []byte, e *logEntry) []byte { return strconv.AppendFloat(buf, e.elapsed.Seconds(), 'f', 6, 64) },
+	'X': func(buf []byte, e *logEntry) []byte {
+		return appendValue(buf, traceID(e.downstream.Get("traceparent")))
+	},
+}
+
+// appendValue appends the value with the control characters escaped (CWE-117), - for the empty value.
+func appendValue(buf []byte, v string) []byte {
+	if v == "" {
+		return append(buf, '-')
+	}
+
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case c == '\n':
+			buf = append(buf, `\n`...)
+		case c == '\r':
+			buf = append(buf, `\r`...)
+		case c < 0x20 || c == 0x7f:
+			buf = append(buf, `\x`...)
+			buf = append(buf, "0123456789abcdef"[c>>4], "0123456789abcdef"[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+
+	return buf
+}
+
+// traceID returns the trace ID from the W3C traceparent header (version-traceid-parentid-flags).
+func traceID(traceparent string) string {
+	if len(traceparent) < 55 || traceparent[2] != '-' || traceparent[35] != '-' {
+		return ""
+	}
+
+	return traceparent[3:35]
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogFormat(t *testing.T) {
+	for _, bad := range []string{"%", "%z", "%{Host", "%{Host}x"} {
+		_, err := ParseLogFormat(bad)
+		assert.Error(t, err, bad)
+	}
+
+	f, err := ParseLogFormat(`%h "%r" %s %b %{User-Agent}i %{X-Route}o %X 100%% %I`)
+	require.NoError(t, err)
+
+	core, logs := observer.New(zap.InfoLevel)
+	h := NewFormatLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		w.Header().Set("X-Route", "users.show")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not found"))
+	}), f, zap.New(core))
+
+	r := httptest.NewRequest(http.MethodPost, "/users/1?full=1", strings.NewReader("body"))
+	r.Header.Set("User-Agent", "curl\nfake line")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, `192.0.2.1:1234 "POST /users/1?full=1 HTTP/1.1" 404 9 curl\nfake line users.show 4bf92f3577b34da6a3ce929d0e0e4736 100% 0`,
+		logs.All()[0].Message)
+}
@@ -6,7 +6,11 @@ import (
 
 func MaxRequestSize(next http.Handler, maxReqSize uint64) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// validating request size
+		// validating request size by the header, chunked bodies are limited while reading
+		if maxReqSize > 0 && r.ContentLength > int64(maxReqSize) { //nolint:gosec
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
 
 		r2 := r.Clone(r.Context())
 		r2.Body = http.MaxBytesReader(w, r2.Body, int64(maxReqSize))
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName string = "github.com/roadrunner-server/http"
+
+// om records the HTTP server metrics following the OpenTelemetry semantic conventions.
+type om struct {
+	pool sync.Pool
+
+	duration     metric.Float64Histogram
+	active       metric.Int64UpDownCounter
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+}
+
+// NewOtelMetricsMiddleware records the request metrics with the meter provider, the metrics are pushed by the
+// provider's reader, e.g. the OTLP exporter registered by the otel plugin.
+func NewOtelMetricsMiddleware(next http.Handler, mp metric.MeterProvider) (http.Handler, error) {
+	meter := mp.Meter(meterName)
+	m := &om{
+		pool: sync.Pool{
+			New: func() any {
+				return &wrapper{
+					code: http.StatusOK,
+				}
+			},
+		},
+	}
+
+	var err error
+	m.duration, err = meter.Float64Histogram("http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests."),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	m.active, err = meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Number of active HTTP server requests."))
+	if err != nil {
+		return nil, err
+	}
+
+	m.requestSize, err = meter.Int64Histogram("http.server.request.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP server request bodies."))
+	if err != nil {
+		return nil, err
+	}
+
+	m.responseSize, err = meter.Int64Histogram("http.server.response.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP server response bodies."))
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Metrics(next), nil
+}
+
+func (m *om) Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := r.Context()
+
+		attrs := []attribute.KeyValue{
+			attribute.String("http.request.method", method(r.Method)),
+			attribute.String("url.scheme", urlScheme(r)),
+		}
+
+		m.active.Add(ctx, 1, metric.WithAttributes(attrs...))
+
+		bw := m.pool.Get().(*wrapper)
+		bw.w = w
+		defer func() {
+			bw.reset()
+			m.pool.Put(bw)
+		}()
+
+		r2 := r.Clone(ctx)
+		if r2.Body != nil {
+			bw.ReadCloser = r2.Body
+			r2.Body = bw
+		}
+
+		next.ServeHTTP(bw, r2)
+
+		m.active.Add(ctx, -1, metric.WithAttributes(attrs...))
+
+		attrs = append(attrs,
+			attribute.Int("http.response.status_code", bw.code),
+			attribute.String("network.protocol.version", strconv.Itoa(r.ProtoMajor)+"."+strconv.Itoa(r.ProtoMinor)),
+		)
+		set := metric.WithAttributeSet(attribute.NewSet(attrs...))
+
+		m.duration.Record(ctx, time.Since(start).Seconds(), set)
+		m.requestSize.Record(ctx, int64(bw.read), set)
+		m.responseSize.Record(ctx, int64(bw.write), set)
+	})
+}
+
+// method returns the known HTTP methods, others are reported as _OTHER to limit the cardinality
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return m
+	default:
+		return "_OTHER"
+	}
+}
+
+func urlScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+type testMeterProvider struct {
+	noop.MeterProvider
+	meter *testMeter
+}
+
+func (p *testMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return p.meter
+}
+
+type testMeter struct {
+	noop.Meter
+	values map[string][]float64
+	attrs  attribute.Set
+}
+
+type testFloat64Histogram struct {
+	noop.Float64Histogram
+	name string
+	m    *testMeter
+}
+
+func (h *testFloat64Histogram) Record(_ context.Context, v float64, opts ...metric.RecordOption) {
+	h.m.values[h.name] = append(h.m.values[h.name], v)
+	h.m.attrs = metric.NewRecordConfig(opts).Attributes()
+}
+
+type testInt64Histogram struct {
+	noop.Int64Histogram
+	name string
+	m    *testMeter
+}
+
+func (h *testInt64Histogram) Record(_ context.Context, v int64, _ ...metric.RecordOption) {
+	h.m.values[h.name] = append(h.m.values[h.name], float64(v))
+}
+
+type testInt64UpDownCounter struct {
+	noop.Int64UpDownCounter
+	name string
+	m    *testMeter
+}
+
+func (c *testInt64UpDownCounter) Add(_ context.Context, v int64, _ ...metric.AddOption) {
+	c.m.values[c.name] = append(c.m.values[c.name], float64(v))
+}
+
+func (m *testMeter) Float64Histogram(name string, _ ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return &testFloat64Histogram{name: name, m: m}, nil
+}
+
+func (m *testMeter) Int64Histogram(name string, _ ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return &testInt64Histogram{name: name, m: m}, nil
+}
+
+func (m *testMeter) Int64UpDownCounter(name string, _ ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return &testInt64UpDownCounter{name: name, m: m}, nil
+}
+
+func TestOtelMetrics(t *testing.T) {
+	meter := &testMeter{values: make(map[string][]float64)}
+	h, err := NewOtelMetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}), &testMeterProvider{meter: meter})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PROPFIND", "/", strings.NewReader("body")))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "created", w.Body.String())
+
+	assert.Len(t, meter.values["http.server.request.duration"], 1)
+	assert.Equal(t, []float64{1, -1}, meter.values["http.server.active_requests"])
+	assert.Equal(t, []float64{4}, meter.values["http.server.request.body.size"])
+	assert.Equal(t, []float64{7}, meter.values["http.server.response.body.size"])
+
+	status, ok := meter.attrs.Value("http.response.status_code")
+	require.True(t, ok)
+	assert.Equal(t, int64(http.StatusCreated), status.AsInt64())
+	m, _ := meter.attrs.Value("http.request.method")
+	assert.Equal(t, "_OTHER", m.AsString())
+}
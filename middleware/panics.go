@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PanicReport is the structured report of the recovered panic, written as a JSON line.
+type PanicReport struct {
+	Time      time.Time     `json:"time"`
+	Panic     string        `json:"panic"`
+	Stack     string        `json:"stack"`
+	Request   *PanicRequest `json:"request"`
+	WorkerPID int64         `json:"worker_pid,omitempty"`
+	Build     *PanicBuild   `json:"build,omitempty"`
+}
+
+// PanicRequest is the summary of the request, the headers and the body are not reported.
+type PanicRequest struct {
+	Method     string `json:"method"`
+	URI        string `json:"uri"`
+	Host       string `json:"host"`
+	Proto      string `json:"proto"`
+	RemoteAddr string `json:"remote_addr"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+// PanicBuild is the build info of the binary.
+type PanicBuild struct {
+	GoVersion string `json:"go_version"`
+	Path      string `json:"path"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+}
+
+// PanicOptions of the Recover middleware.
+type PanicOptions struct {
+	// Sink receives the reports, the writes are serialized.
+	Sink io.Writer
+	// PIDHeader is the response header with the worker PID.
+	PIDHeader string
+	// RequestIDHeader is the request and response header with the request ID.
+	RequestIDHeader string
+	// Panics is the number of the recovered panics.
+	Panics *atomic.Uint64
+}
+
+// Recover recovers the panics of the next handlers and writes the report to the sink. The request gets 500 if the
+// response is not started, otherwise the connection is aborted. The http.ErrAbortHandler panics are not reported.
+func Recover(next http.Handler, o *PanicOptions, log *zap.Logger) http.Handler {
+	build := buildInfo()
+	var mu sync.Mutex
+
+	pool := sync.Pool{
+		New: func() any {
+			return &wrapper{
+				code: http.StatusOK,
+			}
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bw := pool.Get().(*wrapper)
+		bw.w = w
+		defer func() {
+			v := recover()
+			// the response might be in use by the aborted handlers
+			if v == nil {
+				bw.reset()
+				pool.Put(bw)
+				return
+			}
+
+			if v == http.ErrAbortHandler { //nolint:errorlint
+				panic(v)
+			}
+
+			o.Panics.Add(1)
+			report := &PanicReport{
+				Time:  time.Now().UTC(),
+				Panic: fmt.Sprint(v),
+				Stack: string(debug.Stack()),
+				Request: &PanicRequest{
+					Method:     r.Method,
+					URI:        r.RequestURI,
+					Host:       r.Host,
+					Proto:      r.Proto,
+					RemoteAddr: r.RemoteAddr,
+				},
+				Build: build,
+			}
+
+			if o.PIDHeader != "" {
+				report.WorkerPID, _ = strconv.ParseInt(w.Header().Get(o.PIDHeader), 10, 64)
+			}
+
+			if o.RequestIDHeader != "" {
+				report.Request.RequestID = w.Header().Get(o.RequestIDHeader)
+				if report.Request.RequestID == "" {
+					report.Request.RequestID = r.Header.Get(o.RequestIDHeader)
+				}
+			}
+
+			data, err := json.Marshal(report)
+			if err == nil {
+				mu.Lock()
+				_, err = o.Sink.Write(append(data, '\n'))
+				mu.Unlock()
+			}
+
+			if err != nil {
+				log.Error("failed to write the panic report", zap.Error(err))
+			}
+
+			log.Error("panic recovered", zap.Any("panic", v), zap.String("uri", r.RequestURI))
+
+			if bw.wc || bw.code == http.StatusSwitchingProtocols {
+				// the response is sent partially, the connection is closed without logging the stack
+				panic(http.ErrAbortHandler)
+			}
+
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(bw, r)
+	})
+}
+
+// buildInfo returns the build info of the binary, nil if not available.
+func buildInfo() *PanicBuild {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	b := &PanicBuild{
+		GoVersion: info.GoVersion,
+		Path:      info.Main.Path,
+		Version:   info.Main.Version,
+	}
+
+	for i := 0; i < len(info.Settings); i++ {
+		if info.Settings[i].Key == "vcs.revision" {
+			b.Revision = info.Settings[i].Value
+		}
+	}
+
+	return b
+}
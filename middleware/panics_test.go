@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRecover(t *testing.T) {
+	sink := &bytes.Buffer{}
+	opts := &PanicOptions{Sink: sink, PIDHeader: "X-Worker-PID", RequestIDHeader: "X-Request-Id", Panics: &atomic.Uint64{}}
+
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Worker-PID", "42")
+		panic("boom")
+	}), opts, zap.NewNop())
+
+	r := httptest.NewRequest(http.MethodPost, "/orders?id=1", nil)
+	r.Header.Set("X-Request-Id", "req-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, uint64(1), opts.Panics.Load())
+
+	report := &PanicReport{}
+	require.NoError(t, json.Unmarshal(sink.Bytes(), report))
+	assert.Equal(t, "boom", report.Panic)
+	assert.Contains(t, report.Stack, "TestRecover")
+	assert.Equal(t, http.MethodPost, report.Request.Method)
+	assert.Equal(t, "/orders?id=1", report.Request.URI)
+	assert.Equal(t, "req-1", report.Request.RequestID)
+	assert.Equal(t, int64(42), report.WorkerPID)
+	assert.NotNil(t, report.Build)
+
+	// the started response is aborted
+	h = Recover(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	}), opts, zap.NewNop())
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	assert.Equal(t, uint64(2), opts.Panics.Load())
+
+	// the intended aborts are not reported
+	h = Recover(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), opts, zap.NewNop())
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	assert.Equal(t, uint64(2), opts.Panics.Load())
+}
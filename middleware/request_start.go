@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestStartOptions of the RequestStart middleware.
+type RequestStartOptions struct {
+	// Header with the t=<timestamp> value.
+	Header string
+	// Unit of the timestamp: time.Microsecond, time.Millisecond or time.Second.
+	Unit time.Duration
+	// Trusted peers keep the header set before the plugin.
+	Trusted func(r *http.Request) bool
+}
+
+// RequestStart sets the header with the request arrival time, stamped by the Arrival middleware or the current time.
+func RequestStart(next http.Handler, o *RequestStartOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(o.Header) != "" && o.Trusted(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start, ok := ArrivalTime(r.Context())
+		if !ok {
+			start = time.Now()
+		}
+
+		r.Header.Set(o.Header, "t="+formatTimestamp(start, o.Unit))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func formatTimestamp(t time.Time, unit time.Duration) string {
+	switch unit {
+	case time.Second:
+		return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64)
+	case time.Millisecond:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	default:
+		return strconv.FormatInt(t.UnixMicro(), 10)
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestStart(t *testing.T) {
+	var got string
+	next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Request-Start")
+	})
+
+	trusted := false
+	opts := &RequestStartOptions{Header: "X-Request-Start", Unit: time.Microsecond, Trusted: func(*http.Request) bool { return trusted }}
+	h := RequestStart(next, opts)
+
+	arrival := time.UnixMicro(1700000000123456)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), arrivalKey{}, arrival))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "t=1700000000123456", got)
+
+	// the spoofed header is replaced
+	r.Header.Set("X-Request-Start", "t=1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "t=1700000000123456", got)
+
+	// the load balancer header is kept
+	trusted = true
+	r.Header.Set("X-Request-Start", "t=1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "t=1", got)
+
+	assert.Equal(t, "1700000000123", formatTimestamp(arrival, time.Millisecond))
+	assert.Equal(t, "1700000000.123", formatTimestamp(arrival, time.Second))
+}
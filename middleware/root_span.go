@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"math"
+	randv2 "math/rand/v2"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+)
+
+// Sampler decides whether the new trace is sampled.
+type Sampler func() bool
+
+// RatioSampler samples the ratio (0-1] of the traces.
+func RatioSampler(ratio float64) Sampler {
+	return func() bool {
+		return randv2.Float64() < ratio //nolint:gosec
+	}
+}
+
+// RateLimitedSampler samples up to the number of the traces per second.
+func RateLimitedSampler(perSecond float64) Sampler {
+	return rate.NewLimiter(rate.Limit(perSecond), max(1, int(math.Ceil(perSecond)))).Allow
+}
+
+// AlwaysOffSampler drops all the traces.
+func AlwaysOffSampler() Sampler {
+	return func() bool {
+		return false
+	}
+}
+
+// RootSampler samples the requests without the incoming trace context, it should be placed before the middleware
+// starting the root span. The dropped requests get the not sampled remote trace context in the headers, so the
+// parent-based samplers of the next handlers and of the workers don't record them. The requests with the incoming
+// trace context are passed as is.
+func RootSampler(next http.Handler, prop propagation.TextMapPropagator, sample Sampler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		carrier := propagation.HeaderCarrier(r.Header)
+		if trace.SpanContextFromContext(prop.Extract(r.Context(), carrier)).IsValid() || sample() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var traceID trace.TraceID
+		var spanID trace.SpanID
+		_, _ = rand.Read(traceID[:])
+		_, _ = rand.Read(spanID[:])
+
+		sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, Remote: true})
+		prop.Inject(trace.ContextWithRemoteSpanContext(r.Context(), sc), carrier)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRootSampler(t *testing.T) {
+	prop := propagation.TraceContext{}
+	var sc trace.SpanContext
+	h := RootSampler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		sc = trace.SpanContextFromContext(prop.Extract(r.Context(), propagation.HeaderCarrier(r.Header)))
+	}), prop, AlwaysOffSampler())
+
+	// the root is dropped
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.True(t, sc.IsValid())
+	assert.False(t, sc.IsSampled())
+
+	// the incoming trace context is followed
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.True(t, sc.IsSampled())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+
+	// the sampled root is passed as is
+	h = RootSampler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		sc = trace.SpanContextFromContext(prop.Extract(r.Context(), propagation.HeaderCarrier(r.Header)))
+	}), prop, RatioSampler(1))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, sc.IsValid())
+}
+
+func TestRateLimitedSampler(t *testing.T) {
+	sample := RateLimitedSampler(2)
+	sampled := 0
+	for i := 0; i < 10; i++ {
+		if sample() {
+			sampled++
+		}
+	}
+
+	assert.Equal(t, 2, sampled)
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// StripHeaders removes the headers from the requests, so the client-provided values can't be taken for the values set
+// by the server, e.g. the client certificate details on the listeners without TLS.
+func StripHeaders(next http.Handler, headers []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < len(headers); i++ {
+			r.Header.Del(headers[i])
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/roadrunner-server/http/v5/attributes"
+	"github.com/roadrunner-server/http/v5/tlsfp"
+)
+
+const (
+	// JA3Header contains the MD5 of the client JA3 fingerprint.
+	JA3Header string = "X-TLS-JA3"
+	// JA4Header contains the client JA4 fingerprint.
+	JA4Header string = "X-TLS-JA4"
+
+	ja3Attribute string = "tls_ja3"
+	ja4Attribute string = "tls_ja4"
+)
+
+// TLSFingerprintHeaders are the headers set by the TLSFingerprint, removed from the requests of the other listeners.
+var TLSFingerprintHeaders = []string{JA3Header, JA4Header}
+
+// TLSFingerprint passes the client TLS fingerprint to the next handlers and the workers as the X-TLS-JA3 and
+// X-TLS-JA4 headers and the tls_ja3 and tls_ja4 attributes. The server must use the tlsfp listener and ConnContext.
+// The client-provided headers are removed, so they can't be spoofed.
+func TLSFingerprint(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < len(TLSFingerprintHeaders); i++ {
+			r.Header.Del(TLSFingerprintHeaders[i])
+		}
+
+		fp := tlsfp.FromContext(r.Context())
+		if fp == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Header.Set(JA3Header, fp.JA3Hash)
+		r.Header.Set(JA4Header, fp.JA4)
+
+		r = attributes.Init(r)
+		_ = attributes.Set(r, ja3Attribute, fp.JA3Hash)
+		_ = attributes.Set(r, ja4Attribute, fp.JA4)
+
+		next.ServeHTTP(w, r)
+	})
+}
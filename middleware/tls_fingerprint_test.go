@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSFingerprintSpoofed(t *testing.T) {
+	var ja3, ja4 string
+	h := TLSFingerprint(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ja3, ja4 = r.Header.Get(JA3Header), r.Header.Get(JA4Header)
+	}))
+
+	// no fingerprint recorded for the connection
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(JA3Header, "spoofed")
+	r.Header.Set(JA4Header, "spoofed")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Empty(t, ja3)
+	assert.Empty(t, ja4)
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// tagMiddleware marks the responses passed through it.
+type tagMiddleware struct{}
+
+func (tagMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Tag", "1")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (tagMiddleware) Name() string { return "tag" }
+
+func TestMount(t *testing.T) {
+	cfg := &config.Config{
+		Address:        "127.0.0.1:0",
+		MaxRequestSize: 1,
+		AllowedHosts:   []string{"example.com"},
+		Middleware:     []string{"tag", "missing"},
+		ShutdownDrain:  &config.ShutdownDrain{},
+		Pool:           &pool.Config{NumWorkers: 1, AllocateTimeout: time.Second},
+	}
+	require.NoError(t, cfg.InitDefaults())
+
+	pl := newTestPool(t, "worker", http.StatusOK, cfg.Pool)
+	h, err := handler.NewHandler(cfg, pl, zap.NewNop())
+	require.NoError(t, err)
+
+	p := &Plugin{log: zap.NewNop(), cfg: cfg, handler: h, mdwr: map[string]common.Middleware{"tag": tagMiddleware{}}}
+	p.pool.Store(pl)
+	srv := &http.Server{} //nolint:gosec
+	p.Mount(srv)
+
+	ts := httptest.NewServer(srv.Handler)
+	defer ts.Close()
+
+	send := func(method, host, path, body string) (*http.Response, string) {
+		r, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		r.Host = host
+
+		rsp, err := ts.Client().Do(r)
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		data, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		return rsp, string(data)
+	}
+
+	// the request reaches the worker through the whole chain
+	rsp, body := send(http.MethodGet, "example.com", "/", "")
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, "worker", body)
+	assert.Equal(t, "1", rsp.Header.Get("X-Tag"))
+	assert.NotEmpty(t, rsp.Header.Get("X-Worker-Pid"))
+
+	// the bundled middleware is applied inside the config middleware
+	rsp, _ = send(http.MethodGet, "other.com", "/", "")
+	assert.Equal(t, http.StatusMisdirectedRequest, rsp.StatusCode)
+	assert.Equal(t, "1", rsp.Header.Get("X-Tag"))
+
+	rsp, _ = send(http.MethodPost, "example.com", "/", strings.Repeat("a", 2<<20))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rsp.StatusCode)
+
+	// the drain status is served before the host check
+	rsp, body = send(http.MethodGet, "other.com", cfg.ShutdownDrain.Path, "")
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, "OK", strings.TrimSpace(body))
+}
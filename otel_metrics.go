@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.uber.org/zap"
+)
+
+// newMeterProvider creates the meter provider of the otel_metrics pushing the metrics with the OTLP/HTTP exporter.
+// The exporter connects to the collector on the first export.
+func newMeterProvider(cfg *config.OtelMetricsExporter) (*sdkmetric.MeterProvider, error) {
+	const op = errors.Op("http_otel_meter_provider")
+	opts := make([]otlpmetrichttp.Option, 0, 3)
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+	}
+
+	if cfg.Insecure {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	}
+
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlpmetrichttp.WithHeaders(cfg.Headers))
+	}
+
+	exp, err := otlpmetrichttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp, sdkmetric.WithInterval(cfg.Interval))),
+		sdkmetric.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+	), nil
+}
+
+// otelMetrics applies the OpenTelemetry metrics middleware if enabled, the metrics are recorded by the meter provider
+// of the plugin
+func (p *Plugin) otelMetrics(next http.Handler) http.Handler {
+	if p.meterProvider == nil {
+		return next
+	}
+
+	h, err := bundledMw.NewOtelMetricsMiddleware(next, p.meterProvider)
+	if err != nil {
+		p.log.Error("failed to create the otel metrics instruments, otel metrics are disabled", zap.Error(err))
+		return next
+	}
+
+	return h
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+)
+
+func TestOtelMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	p := &Plugin{log: zap.NewNop(), meterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))}
+
+	h := p.otelMetrics(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	rm := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	var duration *metricdata.Histogram[float64]
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name == "http.server.request.duration" {
+			hist, ok := m.Data.(metricdata.Histogram[float64])
+			require.True(t, ok)
+			duration = &hist
+		}
+	}
+
+	require.NotNil(t, duration)
+	require.Len(t, duration.DataPoints, 1)
+	assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+	status, ok := duration.DataPoints[0].Attributes.Value("http.response.status_code")
+	require.True(t, ok)
+	assert.Equal(t, int64(http.StatusCreated), status.AsInt64())
+}
+
+func TestOtelMetricsExporter(t *testing.T) {
+	cfg := &config.OtelMetricsExporter{Endpoint: "127.0.0.1:1", Insecure: true, Headers: map[string]string{"Authorization": "token"}}
+	require.NoError(t, cfg.InitDefaults())
+
+	mp, err := newMeterProvider(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// the collector is not reachable, the export error is reported
+	assert.Error(t, mp.Shutdown(ctx))
+}
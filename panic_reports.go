@@ -0,0 +1,69 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// panicReportsName is the internal middleware recovering the panics, the outermost one on every listener
+const panicReportsName string = "rr_panic_reports"
+
+// panicReports recovers the panics of the handler and the middleware and writes the reports to the sink
+type panicReports struct {
+	sink io.WriteCloser
+	opts *bundledMw.PanicOptions
+	log  *zap.Logger
+}
+
+func (pr *panicReports) Middleware(next http.Handler) http.Handler {
+	return bundledMw.Recover(next, pr.opts, pr.log)
+}
+
+func (pr *panicReports) Name() string {
+	return panicReportsName
+}
+
+// initPanicReports opens the reports sink and registers the middleware, applied after the middleware of the listener
+func (p *Plugin) initPanicReports(cfg *config.PanicReports, panics *atomic.Uint64) error {
+	const op = errors.Op("http_panic_reports_init")
+	var sink io.WriteCloser = nopCloser{os.Stderr}
+	if cfg.Path != config.PanicReportsStderr {
+		f, err := os.OpenFile(cfg.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+		if err != nil {
+			return errors.E(op, err)
+		}
+
+		sink = f
+	}
+
+	opts := &bundledMw.PanicOptions{
+		Sink:      sink,
+		PIDHeader: cfg.PIDHeader,
+		Panics:    panics,
+	}
+
+	if p.cfg.RequestID != nil {
+		opts.RequestIDHeader = p.cfg.RequestID.Header
+	}
+
+	p.panics = &panicReports{sink: sink, opts: opts, log: p.log}
+	p.mdwr[panicReportsName] = p.panics
+
+	return nil
+}
+
+// nopCloser keeps the standard error open when the plugin is stopped
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
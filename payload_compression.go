@@ -0,0 +1,138 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/goridge/v3/pkg/relay"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/roadrunner-server/pool/worker"
+	"go.uber.org/zap"
+)
+
+// encodingsCommand is the pid handshake offering the encodings, the workers not supporting the encodings reply with
+// the pid only.
+type encodingsCommand struct {
+	Pid       int      `json:"pid"`
+	Encodings []string `json:"encodings,omitempty"`
+}
+
+// codecFactory negotiates the payload compression with the spawned workers: the pid handshake is repeated with the
+// offered encodings, the request bodies are compressed for the workers which replied with the zstd encoding.
+type codecFactory struct {
+	pool.Factory
+	enc *zstd.Encoder
+	log *zap.Logger
+}
+
+// SpawnWorkerWithContext spawns the worker and negotiates the encoding, the worker is killed if the handshake fails.
+func (f *codecFactory) SpawnWorkerWithContext(ctx context.Context, cmd *exec.Cmd, options ...worker.Options) (*worker.Process, error) {
+	const op = errors.Op("http_negotiate_compression")
+	w, err := f.Factory.SpawnWorkerWithContext(ctx, cmd, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		zstd bool
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		ok, err := negotiate(w.Relay())
+		done <- result{zstd: ok, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		err = res.err
+		if err == nil {
+			f.log.Debug("payload compression negotiated", zap.Int64("pid", w.Pid()), zap.Bool("zstd", res.zstd))
+			w.AttachRelay(&codecRelay{Relay: w.Relay(), enc: f.enc, zstd: res.zstd})
+			return w, nil
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	_ = w.Kill()
+	_ = w.Wait()
+	return nil, errors.E(op, err)
+}
+
+// negotiate offers the zstd encoding to the worker, returns true if the worker accepted it.
+func negotiate(rl relay.Relay) (bool, error) {
+	data, err := json.Marshal(&encodingsCommand{Pid: os.Getpid(), Encodings: []string{handler.EncodingZstd}})
+	if err != nil {
+		return false, err
+	}
+
+	fr := frame.NewFrame()
+	fr.WriteVersion(fr.Header(), frame.Version1)
+	fr.WriteFlags(fr.Header(), frame.CONTROL, frame.CodecJSON)
+	fr.WritePayloadLen(fr.Header(), uint32(len(data))) //nolint:gosec
+	fr.WritePayload(data)
+	fr.WriteCRC(fr.Header())
+
+	err = rl.Send(fr)
+	if err != nil {
+		return false, err
+	}
+
+	fr = frame.NewFrame()
+	err = rl.Receive(fr)
+	if err != nil {
+		return false, err
+	}
+
+	if fr.ReadFlags()&frame.CONTROL == 0 {
+		return false, errors.Str("unexpected handshake response, no CONTROL flag")
+	}
+
+	reply := &encodingsCommand{}
+	err = json.Unmarshal(fr.Payload(), reply)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(reply.Encodings, handler.EncodingZstd), nil
+}
+
+// codecRelay compresses the bodies of the request payloads marked by the handler if the worker negotiated the zstd
+// encoding, the mark is cleared for the other workers.
+type codecRelay struct {
+	relay.Relay
+	enc  *zstd.Encoder
+	zstd bool
+}
+
+// Send compresses the body of the marked payload frame, the context is sent as is.
+func (r *codecRelay) Send(fr *frame.Frame) error {
+	flags := fr.ReadFlags()
+	if flags&frame.CONTROL != 0 || flags&handler.BodyZstd == 0 {
+		return r.Relay.Send(fr)
+	}
+
+	options := fr.ReadOptions(fr.Header())
+	if !r.zstd || len(options) == 0 || int(options[0]) > len(fr.Payload()) {
+		fr.Header()[1] &^= handler.BodyZstd
+		fr.WriteCRC(fr.Header())
+		return r.Relay.Send(fr)
+	}
+
+	ctx, body := fr.Payload()[:options[0]], fr.Payload()[options[0]:]
+	data := r.enc.EncodeAll(body, append(make([]byte, 0, len(ctx)+len(body)/2), ctx...))
+	fr.WritePayloadLen(fr.Header(), uint32(len(data))) //nolint:gosec
+	fr.WritePayload(data)
+	fr.WriteCRC(fr.Header())
+
+	return r.Relay.Send(fr)
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// handshakeRelay replies to the pid handshake with the encodings and records the sent frames.
+type handshakeRelay struct {
+	encodings []string
+	sent      []*frame.Frame
+}
+
+func (r *handshakeRelay) Send(fr *frame.Frame) error {
+	r.sent = append(r.sent, frame.From(append([]byte(nil), fr.Header()...), fr.Payload()))
+	return nil
+}
+
+func (r *handshakeRelay) Receive(fr *frame.Frame) error {
+	data, _ := json.Marshal(&encodingsCommand{Pid: 1, Encodings: r.encodings})
+	fr.WriteVersion(fr.Header(), frame.Version1)
+	fr.WriteFlags(fr.Header(), frame.CONTROL, frame.CodecJSON)
+	fr.WritePayloadLen(fr.Header(), uint32(len(data))) //nolint:gosec
+	fr.WritePayload(data)
+	fr.WriteCRC(fr.Header())
+	return nil
+}
+
+func (r *handshakeRelay) Close() error { return nil }
+
+func TestPayloadCompressionNegotiation(t *testing.T) {
+	rl := &handshakeRelay{}
+	ok, err := negotiate(rl)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	offer := &encodingsCommand{}
+	require.NoError(t, json.Unmarshal(rl.sent[0].Payload(), offer))
+	assert.NotZero(t, offer.Pid)
+	assert.Equal(t, []string{handler.EncodingZstd}, offer.Encodings)
+
+	rl = &handshakeRelay{encodings: []string{handler.EncodingZstd}}
+	ok, err = negotiate(rl)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	enc, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	dec, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+
+	ctx, body := []byte("context"), bytes.Repeat([]byte("body"), 1024)
+	request := func(flags byte) *frame.Frame {
+		fr := frame.NewFrame()
+		fr.WriteVersion(fr.Header(), frame.Version1)
+		fr.WriteFlags(fr.Header(), flags)
+		fr.WriteOptions(fr.HeaderPtr(), uint32(len(ctx)))           //nolint:gosec
+		fr.WritePayloadLen(fr.Header(), uint32(len(ctx)+len(body))) //nolint:gosec
+		fr.WritePayload(append(append([]byte(nil), ctx...), body...))
+		fr.WriteCRC(fr.Header())
+		return fr
+	}
+
+	// the worker negotiated the encoding
+	rl = &handshakeRelay{}
+	cr := &codecRelay{Relay: rl, enc: enc, zstd: true}
+	require.NoError(t, cr.Send(request(frame.CodecProto|handler.BodyZstd)))
+	fr := rl.sent[0]
+	assert.True(t, fr.VerifyCRC(fr.Header()))
+	assert.Equal(t, frame.CodecProto|handler.BodyZstd, fr.ReadFlags())
+	assert.Equal(t, ctx, fr.Payload()[:len(ctx)])
+	decoded, err := dec.DecodeAll(fr.Payload()[len(ctx):], nil)
+	require.NoError(t, err)
+	assert.Equal(t, body, decoded)
+
+	// the unmarked body is sent as is
+	require.NoError(t, cr.Send(request(frame.CodecProto)))
+	assert.Equal(t, append(append([]byte(nil), ctx...), body...), rl.sent[1].Payload())
+
+	// the worker without the encoding receives the body as is without the mark
+	cr = &codecRelay{Relay: rl, enc: enc}
+	require.NoError(t, cr.Send(request(frame.CodecProto|handler.BodyZstd)))
+	fr = rl.sent[2]
+	assert.True(t, fr.VerifyCRC(fr.Header()))
+	assert.Equal(t, frame.CodecProto, fr.ReadFlags())
+	assert.Equal(t, append(append([]byte(nil), ctx...), body...), fr.Payload())
+}
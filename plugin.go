@@ -2,9 +2,11 @@ package http
 
 import (
 	"context"
+	"io/fs"
 	stdlog "log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 
 	"github.com/roadrunner-server/endure/v2/dep"
 	"github.com/roadrunner-server/http/v5/common"
@@ -14,10 +16,13 @@ import (
 	"github.com/roadrunner-server/errors"
 	"github.com/roadrunner-server/http/v5/config"
 	"github.com/roadrunner-server/http/v5/handler"
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	"github.com/roadrunner-server/http/v5/propagators"
+	"github.com/roadrunner-server/pool/pool"
 	"github.com/roadrunner-server/pool/state/process"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
-	jprop "go.opentelemetry.io/contrib/propagators/jaeger"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -33,6 +38,7 @@ const (
 	sectionHTTP2   = "http.http2"
 	sectionFCGI    = "http.fcgi"
 	sectionUploads = "http.uploads"
+	sectionPool    = "http.pool"
 
 	// RrMode RR_HTTP env variable key (internal) if the HTTP presents
 	RrMode     = "RR_MODE"
@@ -49,6 +55,8 @@ type Plugin struct {
 	// plugins
 	server common.Server
 	log    *zap.Logger
+	// management operations log
+	auditLog *zap.Logger
 	// stdlog passed to the http/https/fcgi servers to log their internal messages
 	stdLog               *stdlog.Logger
 	experimentalFeatures bool
@@ -58,14 +66,53 @@ type Plugin struct {
 
 	// middlewares to chain
 	mdwr map[string]common.Middleware
-	// Pool which attached to all servers
-	pool common.Pool
+	// compiled access log template, nil if not set
+	logFormat *bundledMw.LogFormat
+	// file systems for the static root, by the plugin name
+	staticFS map[string]fs.FS
+	// plugins taking over the upgraded connections, by the protocol name
+	upgraders map[string]common.Upgrader
+	// Pool which attached to all servers, replaced by the blue/green swap without the handler lock
+	pool poolRef
+	// relay creates the workers when the relay is declared in the pool section, nil - the server plugin creates them
+	relay pool.Factory
+	// deployMu serializes the blue/green deployment operations
+	deployMu sync.Mutex
+	// green is the prepared pool waiting for the swap, previous is the pool kept for the rollback
+	green    poolRef
+	previous poolRef
+	// the pools serving the path prefixes
+	routePools []*routePool
+	// meterProvider records and pushes the otel_metrics, nil if disabled
+	meterProvider *sdkmetric.MeterProvider
+	// stderr correlates the requests with the workers executing them (the stderr, the drain timeout), nil if disabled
+	stderr *handler.Stderr
+	// affinity pins the spawned workers to the CPU sets, nil if disabled
+	affinity *affinity
+	// probeHandler probes the workers respawned by the pools, set after the initial probe
+	probeHandler atomic.Pointer[handler.Handler]
 	// servers RR handler
 	handler *handler.Handler
 	// metrics
 	statsExporter *StatsExporter
+	// responses counters for the alerts, nil if the alerts are disabled
+	errorCounters *bundledMw.ErrorCounters
+	// compiled request body schemas, nil if the validation is disabled
+	schemas *bundledMw.Schemas
+	// panic reports middleware, nil if the reports are disabled
+	panics     *panicReports
+	panicCount atomic.Uint64
+	// the TLS headers removed on the listeners other than HTTPS, nil if the HTTPS listener sets none
+	tlsHeaders *tlsHeaders
+	// draining is set at the start of the shutdown drain window
+	draining atomic.Bool
+	// the requests kept by the inspector and its listener, nil if disabled
+	inspected    *inspectorRing
+	inspectorSrv *http.Server
 	// servers
 	servers []servers.InternalServer[any]
+	// stopCh stops the background jobs
+	stopCh chan struct{}
 }
 
 // Init must return configure svc and return true if svc hasStatus enabled. Must return error in case of
@@ -86,6 +133,13 @@ func (p *Plugin) Init(cfg common.Configurer, log common.Logger, srv common.Serve
 		return errors.E(op, err)
 	}
 
+	if p.cfg.AccessLogFormat != "" {
+		p.logFormat, err = bundledMw.ParseLogFormat(p.cfg.AccessLogFormat)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
 	// check if we have experimental features enabled
 	p.experimentalFeatures = cfg.Experimental()
 
@@ -95,20 +149,51 @@ func (p *Plugin) Init(cfg common.Configurer, log common.Logger, srv common.Serve
 
 	// rr logger (via plugin)
 	p.log = log.NamedLogger(PluginName)
+	p.auditLog = log.NamedLogger(auditChannel)
 
 	// use time and date in UTC format
 	p.stdLog = stdlog.New(NewStdAdapter(p.log), "http_plugin: ", stdlog.Ldate|stdlog.Ltime|stdlog.LUTC)
 	p.mdwr = make(map[string]common.Middleware)
+	p.staticFS = make(map[string]fs.FS)
+	p.upgraders = make(map[string]common.Upgrader)
 
 	if !p.cfg.EnableHTTP() && !p.cfg.EnableTLS() && !p.cfg.EnableFCGI() {
 		return errors.E(op, errors.Disabled)
 	}
 
 	// initialize statsExporter
-	p.statsExporter = newWorkersExporter(p)
+	p.statsExporter = newWorkersExporter(p, p, &p.panicCount)
 	p.server = srv
 	p.servers = make([]servers.InternalServer[any], 0, 4)
-	p.prop = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}, jprop.Jaeger{})
+	p.stopCh = make(chan struct{})
+	p.prop = propagators.New(p.cfg.TracePropagators)
+
+	if p.cfg.Alerts != nil {
+		p.errorCounters = &bundledMw.ErrorCounters{}
+	}
+
+	if p.cfg.Debug != nil && p.cfg.Debug.MaxJobs > 0 {
+		p.log.Info("debug workers are replaced by the ttl, the pool max_jobs is ignored",
+			zap.Uint64("max_jobs", p.cfg.Debug.MaxJobs),
+			zap.Uint64("workers", p.cfg.Debug.Workers),
+			zap.Duration("ttl", p.cfg.Debug.TTL))
+	}
+
+	p.initTLSHeaders(p.cfg.SSLConfig)
+
+	if p.cfg.PanicReports != nil {
+		err = p.initPanicReports(p.cfg.PanicReports, &p.panicCount)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if p.cfg.OtelMetrics {
+		p.meterProvider, err = newMeterProvider(p.cfg.OtelMetricsExporter)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
 
 	return nil
 }
@@ -120,16 +205,45 @@ func (p *Plugin) Serve() chan error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	var err error
-	p.pool, err = p.server.NewPool(context.Background(), p.cfg.Pool, map[string]string{RrMode: RrModeHTTP}, p.log)
+	// the schemas errors are reported before the workers are started
+	err := p.loadSchemas()
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
+	if p.cfg.ExecWindows() && p.stderr == nil {
+		p.stderr = handler.NewStderr()
+	}
+
+	if p.cfg.Affinity != nil && p.affinity == nil {
+		p.affinity = newAffinity(p.cfg.Affinity.Sets, p.log)
+	}
+
+	err = p.initRelay()
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
+	pl, err := p.newPool(p.cfg.Pool)
 	if err != nil {
 		errCh <- err
 		return errCh
 	}
+	p.pool.Store(pl)
+
+	if p.cfg.Static != nil {
+		err = p.staticRoot(p.cfg.Static)
+		if err != nil {
+			errCh <- err
+			return errCh
+		}
+	}
 
 	p.handler, err = handler.NewHandler(
 		p.cfg,
-		p.pool,
+		pl,
 		p.log,
 	)
 	if err != nil {
@@ -137,6 +251,27 @@ func (p *Plugin) Serve() chan error {
 		return errCh
 	}
 
+	if p.stderr != nil {
+		p.handler.CaptureStderr(p.stderr)
+	}
+
+	// the servers are not started yet, the workers can be probed directly
+	err = p.probeWorkers(pl, p.handler)
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
+	err = p.initRoutePools()
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
+	if p.cfg.ReadinessProbe != nil {
+		p.probeHandler.Store(p.handler)
+	}
+
 	// initialize servers based on the configuration
 	err = p.initServers()
 	if err != nil {
@@ -144,13 +279,36 @@ func (p *Plugin) Serve() chan error {
 		return errCh
 	}
 
+	err = p.serveInspector(errCh)
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
 	// apply access_logs, max_request, redirect middleware if specified by user
 	p.applyBundledMiddleware()
 
+	// the debug pool allocates the worker per request, there are no idle workers
+	if p.cfg.Liveness != nil && !p.cfg.Pool.Debug {
+		go p.liveness(p.cfg.Liveness, p.stopCh)
+	}
+
+	if p.cfg.LoadShedding != nil {
+		go p.loadShedding(p.cfg.LoadShedding, p.stopCh)
+	}
+
+	if p.cfg.Watch != nil {
+		go p.watch(p.cfg.Watch, p.stopCh)
+	}
+
+	if p.cfg.Alerts != nil {
+		go p.alerts(p.cfg.Alerts, p.stopCh)
+	}
+
 	// start all servers
 	for i := 0; i < len(p.servers); i++ {
 		go func(idx int) {
-			errSt := p.servers[idx].Serve(p.mdwr, p.cfg.Middleware)
+			errSt := p.servers[idx].Serve(p.mdwr, p.middlewareOrder(p.servers[idx]))
 			if errSt != nil {
 				errCh <- errSt
 				return
@@ -163,17 +321,47 @@ func (p *Plugin) Serve() chan error {
 
 // Stop stops the http.
 func (p *Plugin) Stop(ctx context.Context) error {
+	// the load balancers are notified before the handler lock, the requests are served during the drain window
+	p.drain(ctx)
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	doneCh := make(chan struct{}, 1)
 
+	// stop the background jobs
+	select {
+	case <-p.stopCh:
+	default:
+		close(p.stopCh)
+	}
+
 	go func() {
 		for i := 0; i < len(p.servers); i++ {
 			if p.servers[i] != nil {
 				p.servers[i].Stop()
 			}
 		}
+
+		// the workers started with the own relay are not stopped by the server plugin
+		if p.relay != nil {
+			p.destroyPools(ctx)
+			_ = p.relay.Close()
+		}
+		p.destroyRoutePools(ctx)
+
+		if p.panics != nil {
+			_ = p.panics.sink.Close()
+		}
+
+		if p.inspectorSrv != nil {
+			_ = p.inspectorSrv.Close()
+		}
+
+		// the last metrics are pushed
+		if p.meterProvider != nil {
+			_ = p.meterProvider.Shutdown(ctx)
+		}
 		doneCh <- struct{}{}
 	}()
 
@@ -187,11 +375,22 @@ func (p *Plugin) Stop(ctx context.Context) error {
 
 // ServeHTTP handles connection using set of middleware and pool PSR-7 server.
 func (p *Plugin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the upgraded connection might live for hours, the handler lock is not held
+	if p.upgrade(w, r) {
+		return
+	}
+
 	if val, ok := r.Context().Value(rrcontext.OtelTracerNameKey).(string); ok {
-		tp := trace.SpanFromContext(r.Context()).TracerProvider()
+		ctx := r.Context()
+		tp := trace.SpanFromContext(ctx).TracerProvider()
+		// the upstream context in the formats not extracted by the otel middleware
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			ctx = p.prop.Extract(ctx, propagation.HeaderCarrier(r.Header))
+		}
+
 		ctx, span := tp.Tracer(val, trace.WithSchemaURL(semconv.SchemaURL),
 			trace.WithInstrumentationVersion(otelhttp.Version())).
-			Start(r.Context(), PluginName, trace.WithSpanKind(trace.SpanKindServer))
+			Start(ctx, PluginName, trace.WithSpanKind(trace.SpanKindServer))
 		defer span.End()
 
 		// inject
@@ -201,12 +400,33 @@ func (p *Plugin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// protect the case, when user sends Reset, and we are replacing handler with pool
 	p.mu.RLock()
-	p.handler.ServeHTTP(w, r)
+	p.routeHandler(r).ServeHTTP(w, r)
 	p.mu.RUnlock()
 
 	_ = r.Body.Close()
 }
 
+// Mount sets the production handler chain on the server: the plugin handler wrapped with the bundled middleware and
+// the middleware from the config, in the same order as on the plugin's HTTP servers, so the tests and the embedders
+// can serve the exact chain with their own server. The redirect and the listener options of the plugin's servers are
+// not applied. Must be called after Serve, when the pool and the middleware plugins are ready.
+func (p *Plugin) Mount(srv *http.Server) {
+	srv.Handler = p
+	p.bundledMiddleware(srv)
+
+	for i := 0; i < len(p.cfg.Middleware); i++ {
+		if mdwr, ok := p.mdwr[p.cfg.Middleware[i]]; ok {
+			srv.Handler = mdwr.Middleware(srv.Handler)
+		} else {
+			p.log.Warn("requested middleware does not exist", zap.String("requested", p.cfg.Middleware[i]))
+		}
+	}
+
+	if p.panics != nil {
+		srv.Handler = p.panics.Middleware(srv.Handler)
+	}
+}
+
 func (p *Plugin) RPC() any {
 	return &rpc{srv: p, log: p.log}
 }
@@ -216,11 +436,17 @@ func (p *Plugin) Workers() []*process.State {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	if p.pool == nil {
+	pl := p.pool.Load()
+	if pl == nil {
 		return nil
 	}
 
-	workers := p.pool.Workers()
+	return workersState(pl)
+}
+
+// workersState returns the process states of the pool workers
+func workersState(pool common.Pool) []*process.State {
+	workers := pool.Workers()
 
 	ps := make([]*process.State, 0, len(workers))
 	for i := 0; i < len(workers); i++ {
@@ -234,6 +460,55 @@ func (p *Plugin) Workers() []*process.State {
 	return ps
 }
 
+// Stats returns the handler counters, nil if the handler is not started yet
+func (p *Plugin) Stats() *handler.Stats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.handler == nil {
+		return nil
+	}
+
+	return p.handler.Stats()
+}
+
+// PurgeStaticCache removes all the files from the static cache, returns the number of removed files
+func (p *Plugin) PurgeStaticCache() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.handler == nil {
+		return 0
+	}
+
+	return p.handler.PurgeStaticCache()
+}
+
+// PurgeSurrogateKeys removes the cached responses with any of the surrogate keys, returns the number of removed
+// responses
+func (p *Plugin) PurgeSurrogateKeys(keys []string) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.handler == nil {
+		return 0
+	}
+
+	return p.handler.PurgeSurrogateKeys(keys)
+}
+
+// Replay sends the captured requests to the workers, returns the response statuses by the file names
+func (p *Plugin) Replay(names []string) (map[string]int, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.handler == nil {
+		return nil, errors.Str("http handler is not started")
+	}
+
+	return p.handler.Replay(names)
+}
+
 // Name returns endure.Named interface implementation
 func (p *Plugin) Name() string {
 	return PluginName
@@ -241,6 +516,11 @@ func (p *Plugin) Name() string {
 
 // Reset destroys the old pool and replaces it with new one, waiting for old pool to die
 func (p *Plugin) Reset() error {
+	return p.reset(callerPlugin)
+}
+
+// reset restarts the workers gracefully
+func (p *Plugin) reset(caller string) error {
 	const op = errors.Op("http_plugin_reset")
 
 	p.mu.Lock()
@@ -248,12 +528,21 @@ func (p *Plugin) Reset() error {
 
 	p.log.Info("reset signal was received")
 
-	if p.pool == nil {
+	pl := p.pool.Load()
+	if pl == nil {
 		p.log.Info("pool is nil, nothing to reset")
 		return nil
 	}
 
-	err := p.pool.Reset(context.Background())
+	err := pl.Reset(context.Background())
+	if err == nil {
+		// the requests wait for the lock
+		err = p.probeWorkers(pl, p.handler)
+	}
+	if err == nil {
+		err = p.resetRoutePools()
+	}
+	p.audit("reset", caller, err)
 	if err != nil {
 		return errors.E(op, err)
 	}
@@ -262,15 +551,27 @@ func (p *Plugin) Reset() error {
 	return nil
 }
 
-// Collects collecting http middlewares
+// Collects collecting http middlewares, static file systems and protocol upgraders
 func (p *Plugin) Collects() []*dep.In {
 	return []*dep.In{
 		dep.Fits(func(pp any) {
-			mdw := pp.(common.Middleware)
+			mdw := p.rootSampled(pp.(common.Middleware))
 			// just to be safe
 			p.mu.Lock()
 			p.mdwr[mdw.Name()] = mdw
 			p.mu.Unlock()
 		}, (*common.Middleware)(nil)),
+		dep.Fits(func(pp any) {
+			sfs := pp.(common.StaticFS)
+			p.mu.Lock()
+			p.staticFS[sfs.Name()] = sfs.StaticFS()
+			p.mu.Unlock()
+		}, (*common.StaticFS)(nil)),
+		dep.Fits(func(pp any) {
+			u := pp.(common.Upgrader)
+			p.mu.Lock()
+			p.addUpgrader(u)
+			p.mu.Unlock()
+		}, (*common.Upgrader)(nil)),
 	}
 }
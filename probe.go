@@ -0,0 +1,131 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/fsm"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/roadrunner-server/pool/worker"
+	"go.uber.org/zap"
+)
+
+// probeWorkers runs the readiness probe on the pool workers, the pool must not receive the traffic. The failed
+// workers are killed, so the pool replaces them, and the replacements are probed in the next round.
+func (p *Plugin) probeWorkers(pl common.Pool, h *handler.Handler) error {
+	const op = errors.Op("http_readiness_probe")
+	cfg := p.cfg.ReadinessProbe
+	if cfg == nil {
+		return nil
+	}
+
+	passed := make(map[int64]struct{})
+	total := 0
+	for attempt := 1; ; attempt++ {
+		workers := pl.Workers()
+		if attempt == 1 {
+			total = len(workers)
+		}
+
+		// the killed workers might be removed before the pool adds the replacements
+		failed := max(total-len(workers), 0)
+		for i := 0; i < len(workers); i++ {
+			if _, ok := passed[workers[i].Pid()]; ok {
+				continue
+			}
+
+			// being replaced
+			if !workers[i].State().Compare(fsm.StateReady) {
+				failed++
+				continue
+			}
+
+			status, err := p.probe(h, workers[i])
+			if err == nil && status == cfg.Status {
+				passed[workers[i].Pid()] = struct{}{}
+				continue
+			}
+
+			failed++
+			p.log.Warn("worker failed the readiness probe, killing",
+				zap.Int64("pid", workers[i].Pid()),
+				zap.Int("status", status),
+				zap.Int("attempt", attempt),
+				zap.Error(err))
+			_ = workers[i].Kill()
+		}
+
+		if failed == 0 {
+			p.log.Debug("readiness probe passed", zap.Int("workers", len(workers)))
+			return nil
+		}
+
+		if attempt >= cfg.Attempts {
+			return errors.E(op, errors.Errorf("%d workers failed the readiness probe", failed))
+		}
+
+		time.Sleep(cfg.Interval)
+	}
+}
+
+// probeFactory runs the readiness probe on the workers respawned by the pool (max_jobs, TTL, crash or kill) before
+// they are returned to the pool. The failed workers are killed, the pool retries the allocation.
+type probeFactory struct {
+	pool.Factory
+	p *Plugin
+}
+
+// SpawnWorkerWithContext spawns the worker and probes it, the workers spawned before the initial probe are probed by
+// the probeWorkers.
+func (f *probeFactory) SpawnWorkerWithContext(ctx context.Context, cmd *exec.Cmd, options ...worker.Options) (*worker.Process, error) {
+	const op = errors.Op("http_probe_worker")
+	w, err := f.Factory.SpawnWorkerWithContext(ctx, cmd, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	h := f.p.probeHandler.Load()
+	if h == nil {
+		return w, nil
+	}
+
+	status, err := f.p.probe(h, w)
+	if err == nil && status == f.p.cfg.ReadinessProbe.Status {
+		return w, nil
+	}
+
+	f.p.log.Warn("respawned worker failed the readiness probe, killing",
+		zap.Int64("pid", w.Pid()),
+		zap.Int("status", status),
+		zap.Error(err))
+	_ = w.Kill()
+	_ = w.Wait()
+
+	if err == nil {
+		err = errors.Errorf("readiness probe status %d", status)
+	}
+
+	return nil, errors.E(op, err)
+}
+
+// probe sends the probe request to the worker.
+func (p *Plugin) probe(h *handler.Handler, wrk *worker.Process) (int, error) {
+	cfg := p.cfg.ReadinessProbe
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, cfg.Method, cfg.Path, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	r.RequestURI = cfg.Path
+	r.Host = "localhost"
+	r.RemoteAddr = "127.0.0.1:0"
+	return h.Probe(ctx, wrk, r)
+}
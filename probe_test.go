@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestReadinessProbe(t *testing.T) {
+	probe := &config.ReadinessProbe{Path: "/ready", Attempts: 2, Interval: 50 * time.Millisecond, Timeout: time.Second}
+	require.NoError(t, probe.InitDefaults())
+	require.NoError(t, probe.Valid())
+
+	newPlugin := func(status int) (*Plugin, *handler.Handler, func() []int64) {
+		pl := newTestPool(t, "ok", status, &pool.Config{NumWorkers: 2, AllocateTimeout: time.Second})
+		cfg := &config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500, ReadinessProbe: probe}
+		h, err := handler.NewHandler(cfg, pl, zap.NewNop())
+		require.NoError(t, err)
+
+		p := &Plugin{log: zap.NewNop(), cfg: cfg, handler: h}
+		p.pool.Store(pl)
+		pids := func() []int64 {
+			var out []int64
+			for _, w := range pl.Workers() {
+				out = append(out, w.Pid())
+			}
+			return out
+		}
+
+		return p, h, pids
+	}
+
+	p, h, pids := newPlugin(http.StatusOK)
+	before := pids()
+	require.NoError(t, p.probeWorkers(p.pool.Load(), h))
+	assert.ElementsMatch(t, before, pids())
+
+	// the failed workers are killed, the replacements fail as well
+	p, h, pids = newPlugin(http.StatusServiceUnavailable)
+	before = pids()
+	err := p.probeWorkers(p.pool.Load(), h)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "workers failed the readiness probe")
+	require.Eventually(t, func() bool { return len(pids()) == 2 }, 5*time.Second, 20*time.Millisecond)
+	for _, pid := range pids() {
+		assert.NotContains(t, before, pid)
+	}
+
+	// the reset of the pool failing the probe is reported
+	p, _, _ = newPlugin(http.StatusServiceUnavailable)
+	assert.Error(t, p.reset(callerRPC))
+}
+
+func TestReadinessProbeTimeout(t *testing.T) {
+	probe := &config.ReadinessProbe{Path: "/hang", Attempts: 1, Timeout: 200 * time.Millisecond}
+	require.NoError(t, probe.InitDefaults())
+
+	pl := newTestPool(t, "ok", http.StatusOK, &pool.Config{NumWorkers: 1, AllocateTimeout: time.Second})
+	cfg := &config.Config{Uploads: &config.Uploads{}, InternalErrorCode: 500, ReadinessProbe: probe}
+	h, err := handler.NewHandler(cfg, pl, zap.NewNop())
+	require.NoError(t, err)
+	p := &Plugin{log: zap.NewNop(), cfg: cfg}
+
+	pid := pl.Workers()[0].Pid()
+	start := time.Now()
+	assert.Error(t, p.probeWorkers(pl, h))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the hung worker is killed and replaced
+	require.Eventually(t, func() bool {
+		workers := pl.Workers()
+		return len(workers) == 1 && workers[0].Pid() != pid
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestReadinessProbeRespawned(t *testing.T) {
+	newPlugin := func(path string) (*Plugin, *handler.Handler, *observer.ObservedLogs) {
+		probe := &config.ReadinessProbe{Path: path}
+		require.NoError(t, probe.InitDefaults())
+
+		core, logs := observer.New(zap.WarnLevel)
+		cfg := &config.Config{
+			Uploads:           &config.Uploads{},
+			InternalErrorCode: 500,
+			ReadinessProbe:    probe,
+			Relay:             &config.PoolRelay{Relay: config.RelayPipes},
+			// the pool panics when the last worker can't be allocated
+			Pool: &pool.Config{NumWorkers: 2, AllocateTimeout: 300 * time.Millisecond, DestroyTimeout: time.Second},
+		}
+		p := &Plugin{log: zap.New(core), cfg: cfg, server: &testServer{t: t}}
+
+		require.NoError(t, p.initRelay())
+		pl, err := p.newPool(cfg.Pool)
+		require.NoError(t, err)
+		t.Cleanup(func() { pl.Destroy(context.Background()) })
+		p.pool.Store(pl)
+
+		h, err := handler.NewHandler(cfg, pl, p.log)
+		require.NoError(t, err)
+		// the initial workers are probed by the probeWorkers
+		p.probeHandler.Store(h)
+		return p, h, logs
+	}
+
+	// the respawned worker passing the probe receives the traffic
+	p, _, logs := newPlugin("/")
+	pid := p.pool.Load().Workers()[0].Pid()
+	require.NoError(t, p.pool.Load().Workers()[0].Kill())
+	require.Eventually(t, func() bool {
+		workers := p.pool.Load().Workers()
+		return len(workers) == 2 && workers[0].Pid() != pid && workers[1].Pid() != pid
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Zero(t, logs.FilterMessage("respawned worker failed the readiness probe, killing").Len())
+
+	// the respawned worker failing the probe is killed before it receives the traffic, the pool gives up after the
+	// allocate_timeout
+	p, _, logs = newPlugin("/fail")
+	workers := p.pool.Load().Workers()
+	require.NoError(t, workers[0].Kill())
+	require.Eventually(t, func() bool {
+		return logs.FilterMessage("respawned worker failed the readiness probe, killing").Len() > 0
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Eventually(t, func() bool {
+		left := p.pool.Load().Workers()
+		return len(left) == 1 && left[0].Pid() == workers[1].Pid()
+	}, 5*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,102 @@
+package propagators
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ddTraceID          string = "x-datadog-trace-id"
+	ddParentID         string = "x-datadog-parent-id"
+	ddSamplingPriority string = "x-datadog-sampling-priority"
+	ddTags             string = "x-datadog-tags"
+	// ddTraceIDHigh is the tag with the upper 64 bits of the 128-bit trace ID (hex)
+	ddTraceIDHigh string = "_dd.p.tid"
+)
+
+var _ propagation.TextMapPropagator = Datadog{}
+
+// Datadog propagates the trace context in the Datadog headers. The trace and parent IDs are the decimal 64-bit
+// values, the upper half of the 128-bit trace ID is passed in the _dd.p.tid tag.
+type Datadog struct{}
+
+// Inject sets the Datadog headers from the span context in the ctx.
+func (Datadog) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	tid := sc.TraceID()
+	sid := sc.SpanID()
+
+	carrier.Set(ddTraceID, strconv.FormatUint(binary.BigEndian.Uint64(tid[8:]), 10))
+	carrier.Set(ddParentID, strconv.FormatUint(binary.BigEndian.Uint64(sid[:]), 10))
+
+	if high := binary.BigEndian.Uint64(tid[:8]); high != 0 {
+		carrier.Set(ddTags, ddTraceIDHigh+"="+hex.EncodeToString(tid[:8]))
+	}
+
+	if sc.IsSampled() {
+		carrier.Set(ddSamplingPriority, "1")
+	} else {
+		carrier.Set(ddSamplingPriority, "0")
+	}
+}
+
+// Extract returns the ctx with the remote span context from the Datadog headers, the ctx is returned as is if the
+// headers are missing or malformed.
+func (Datadog) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	low, err := strconv.ParseUint(carrier.Get(ddTraceID), 10, 64)
+	if err != nil || low == 0 {
+		return ctx
+	}
+
+	parent, err := strconv.ParseUint(carrier.Get(ddParentID), 10, 64)
+	if err != nil || parent == 0 {
+		return ctx
+	}
+
+	var tid trace.TraceID
+	binary.BigEndian.PutUint64(tid[8:], low)
+	for _, tag := range strings.Split(carrier.Get(ddTags), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if !ok || k != ddTraceIDHigh || len(v) != 16 {
+			continue
+		}
+
+		// the malformed tag is ignored, the lower 64 bits are still valid
+		high, err := hex.DecodeString(v)
+		if err == nil {
+			copy(tid[:8], high)
+		}
+		break
+	}
+
+	var sid trace.SpanID
+	binary.BigEndian.PutUint64(sid[:], parent)
+
+	cfg := trace.SpanContextConfig{
+		TraceID: tid,
+		SpanID:  sid,
+		Remote:  true,
+	}
+
+	// the user (2) and sampler (1) keep decisions are sampled, the drop ones (<= 0) are not
+	if priority, err := strconv.Atoi(carrier.Get(ddSamplingPriority)); err == nil && priority > 0 {
+		cfg.TraceFlags = trace.FlagsSampled
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(cfg))
+}
+
+// Fields returns the headers used by the propagator.
+func (Datadog) Fields() []string {
+	return []string{ddTraceID, ddParentID, ddSamplingPriority, ddTags}
+}
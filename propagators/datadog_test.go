@@ -0,0 +1,63 @@
+package propagators
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDatadog(t *testing.T) {
+	h := http.Header{}
+	h.Set(ddTraceID, "1234")
+	h.Set(ddParentID, "5678")
+	h.Set(ddSamplingPriority, "2")
+	h.Set(ddTags, "_dd.p.dm=-4,_dd.p.tid=640cfd8d00000000")
+
+	ctx := Datadog{}.Extract(context.Background(), propagation.HeaderCarrier(h))
+	sc := trace.SpanContextFromContext(ctx)
+	require.True(t, sc.IsValid())
+	assert.True(t, sc.IsRemote())
+	assert.True(t, sc.IsSampled())
+	assert.Equal(t, "640cfd8d0000000000000000000004d2", sc.TraceID().String())
+	assert.Equal(t, "000000000000162e", sc.SpanID().String())
+
+	out := http.Header{}
+	Datadog{}.Inject(ctx, propagation.HeaderCarrier(out))
+	assert.Equal(t, h.Get(ddTraceID), out.Get(ddTraceID))
+	assert.Equal(t, h.Get(ddParentID), out.Get(ddParentID))
+	assert.Equal(t, "1", out.Get(ddSamplingPriority))
+	assert.Equal(t, "_dd.p.tid=640cfd8d00000000", out.Get(ddTags))
+
+	// dropped traces are not sampled
+	h.Set(ddSamplingPriority, "-1")
+	sc = trace.SpanContextFromContext(Datadog{}.Extract(context.Background(), propagation.HeaderCarrier(h)))
+	assert.False(t, sc.IsSampled())
+
+	h.Set(ddTraceID, "abc")
+	sc = trace.SpanContextFromContext(Datadog{}.Extract(context.Background(), propagation.HeaderCarrier(h)))
+	assert.False(t, sc.IsValid())
+}
+
+func TestNew(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-B3-TraceId", "463ac35c9f6413ad48485a3953bb6124")
+	h.Set("X-B3-SpanId", "a2fb4a1d1a96d312")
+	h.Set("X-B3-Sampled", "1")
+
+	prop := New([]config.Propagator{config.PropagatorB3Multi, config.PropagatorDatadog})
+	ctx := prop.Extract(context.Background(), propagation.HeaderCarrier(h))
+	require.True(t, trace.SpanContextFromContext(ctx).IsValid())
+
+	out := http.Header{}
+	prop.Inject(ctx, propagation.HeaderCarrier(out))
+	assert.Equal(t, "463ac35c9f6413ad48485a3953bb6124", out.Get("X-B3-TraceId"))
+	assert.Equal(t, "5208512171318403364", out.Get(ddTraceID))
+	assert.NotEmpty(t, out.Get(ddParentID))
+	assert.Empty(t, out.Get("traceparent"))
+}
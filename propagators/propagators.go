@@ -0,0 +1,32 @@
+// Package propagators builds the trace context propagators selected in the configuration.
+package propagators
+
+import (
+	"github.com/roadrunner-server/http/v5/config"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	jprop "go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// New returns the composite propagator, the unknown names are ignored (see the config validation).
+func New(names []config.Propagator) propagation.TextMapPropagator {
+	props := make([]propagation.TextMapPropagator, 0, len(names))
+	for i := 0; i < len(names); i++ {
+		switch names[i] {
+		case config.PropagatorTraceContext:
+			props = append(props, propagation.TraceContext{})
+		case config.PropagatorBaggage:
+			props = append(props, propagation.Baggage{})
+		case config.PropagatorB3:
+			props = append(props, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case config.PropagatorB3Multi:
+			props = append(props, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case config.PropagatorJaeger:
+			props = append(props, jprop.Jaeger{})
+		case config.PropagatorDatadog:
+			props = append(props, Datadog{})
+		}
+	}
+
+	return propagation.NewCompositeTextMapPropagator(props...)
+}
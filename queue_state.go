@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/roadrunner-server/pool/fsm"
+)
+
+// queueStateReport is the response of the queue state endpoint, the ages are in milliseconds.
+type queueStateReport struct {
+	Pending         int64            `json:"pending"`
+	OldestPendingMs float64          `json:"oldest_pending_ms"`
+	Queued          int              `json:"queued"`
+	OldestQueuedMs  float64          `json:"oldest_queued_ms"`
+	Pools           []*poolUsageInfo `json:"pools"`
+}
+
+// poolUsageInfo is the pool utilization, busy workers to all workers.
+type poolUsageInfo struct {
+	Name        string  `json:"name"`
+	Workers     int     `json:"workers"`
+	Busy        int     `json:"busy"`
+	Utilization float64 `json:"utilization"`
+	Queue       uint64  `json:"queue"`
+}
+
+// queueState serves the queue state endpoint.
+func (p *Plugin) queueState(next http.Handler) http.Handler {
+	if p.cfg.QueueState == nil {
+		return next
+	}
+
+	path := p.cfg.QueueState.Path
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !p.trustedPeer(r) {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(p.queueStateReport())
+	})
+}
+
+// queueStateReport collects the queue state of the handler and the pools, the workers states are read without the
+// process stats, so the endpoint is cheap to poll.
+func (p *Plugin) queueStateReport() *queueStateReport {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	report := &queueStateReport{Pools: make([]*poolUsageInfo, 0, 1)}
+	if p.handler != nil {
+		st := p.handler.QueueState()
+		report.Pending = st.Pending
+		report.OldestPendingMs = milliseconds(st.OldestPending)
+		report.Queued = st.Queued
+		report.OldestQueuedMs = milliseconds(st.OldestQueued)
+	}
+
+	pl := p.pool.Load()
+	if pl == nil {
+		return report
+	}
+
+	workers := pl.Workers()
+	usage := &poolUsageInfo{Name: defaultPoolName, Workers: len(workers)}
+	for i := 0; i < len(workers); i++ {
+		if workers[i].State().Compare(fsm.StateWorking) {
+			usage.Busy++
+		}
+	}
+
+	if usage.Workers > 0 {
+		usage.Utilization = float64(usage.Busy) / float64(usage.Workers)
+	}
+
+	if qs, ok := pl.(queueSizer); ok {
+		usage.Queue = qs.QueueSize()
+	}
+
+	report.Pools = append(report.Pools, usage)
+	return report
+}
+
+// milliseconds returns the duration in milliseconds with the microseconds precision.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/ipc/pipe"
+	"github.com/roadrunner-server/pool/ipc/socket"
+	"github.com/roadrunner-server/pool/pool"
+	staticPool "github.com/roadrunner-server/pool/pool/static_pool"
+	"go.uber.org/zap"
+)
+
+// rrRelay is the env variable with the relay address for the workers
+const rrRelay string = "RR_RELAY"
+
+// newPool creates the HTTP pool with the server plugin, or with the relay declared in the pool section.
+func (p *Plugin) newPool(cfg *pool.Config) (*staticPool.Pool, error) {
+	if p.relay == nil {
+		return p.server.NewPool(context.Background(), cfg, map[string]string{RrMode: RrModeHTTP}, p.log)
+	}
+
+	return p.newRelayPool(p.relay, p.cfg.Relay.Relay, cfg)
+}
+
+// newRelayPool creates the pool with the own relay, the workers are prepared by the server plugin.
+func (p *Plugin) newRelayPool(factory pool.Factory, relay string, cfg *pool.Config) (*staticPool.Pool, error) {
+	const op = errors.Op("http_new_relay_pool")
+	cf, ok := p.server.(common.CmdFactory)
+	if !ok {
+		return nil, errors.E(op, errors.Str("the server plugin can't prepare the workers for the own relay"))
+	}
+
+	cmd := workersCommand(cf, map[string]string{RrMode: RrModeHTTP, rrRelay: relay})
+	if network, _ := (&config.PoolRelay{Relay: relay}).Network(); network != "" {
+		cmd = p.logStdout(cmd)
+	}
+
+	return staticPool.NewPool(context.Background(), cmd, factory, cfg, p.log)
+}
+
+// logStdout logs the stdout of the workers, the socket relays leave the stdout unused (discarded otherwise).
+func (p *Plugin) logStdout(command pool.Command) pool.Command {
+	return func(args []string) *exec.Cmd {
+		cmd := command(args)
+		if cmd != nil {
+			cmd.Stdout = &stdoutWriter{cmd: cmd, log: p.log}
+		}
+
+		return cmd
+	}
+}
+
+// stdoutWriter logs the worker stdout with the worker pid.
+type stdoutWriter struct {
+	cmd *exec.Cmd
+	log *zap.Logger
+}
+
+func (s *stdoutWriter) Write(p []byte) (int, error) {
+	// the output is copied after the process is started
+	var pid int
+	if s.cmd.Process != nil {
+		pid = s.cmd.Process.Pid
+	}
+
+	s.log.Info(string(bytes.TrimRight(p, "\n")), zap.Int("pid", pid), zap.String("output", "stdout"))
+	return len(p), nil
+}
+
+// initRelay creates the workers factory for the relay declared in the pool section, the factory is shared by the main
+// and the green pools.
+func (p *Plugin) initRelay() error {
+	const op = errors.Op("http_init_relay")
+	if p.cfg.Relay == nil || p.relay != nil {
+		return nil
+	}
+
+	var err error
+	p.relay, err = p.newRelay(p.cfg.Relay)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// newRelay creates the workers factory listening on the relay address, the payload compression is negotiated with the
+// workers, the worker stderr is correlated with the requests, the workers are pinned to the CPU sets and the respawned
+// workers are probed if enabled.
+func (p *Plugin) newRelay(relay *config.PoolRelay) (pool.Factory, error) {
+	factory, err := p.relayFactory(relay)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.cfg.PayloadCompression != nil {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevel(p.cfg.PayloadCompression.Level)))
+		if err != nil {
+			return nil, err
+		}
+
+		factory = &codecFactory{Factory: factory, enc: enc, log: p.log}
+	}
+
+	if p.stderr != nil {
+		factory = &stderrFactory{Factory: factory, stderr: p.stderr, log: p.log}
+	}
+
+	// the workers are pinned before the probe
+	if p.affinity != nil {
+		factory = &affinityFactory{Factory: factory, affinity: p.affinity}
+	}
+
+	if p.cfg.ReadinessProbe != nil {
+		factory = &probeFactory{Factory: factory, p: p}
+	}
+
+	return factory, nil
+}
+
+// relayFactory creates the pipes or the socket factory of the relay.
+func (p *Plugin) relayFactory(relay *config.PoolRelay) (pool.Factory, error) {
+	network, address := relay.Network()
+	if network == "" {
+		return pipe.NewPipeFactory(p.log), nil
+	}
+
+	if network == "unix" {
+		// the socket file is left after the unclean shutdown
+		_ = os.Remove(address)
+	}
+
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return socket.NewSocketServer(ln, p.log), nil
+}
+
+// workersCommand prepares the workers with the server plugin: the server command, env and user. The pool command
+// (e.g. the blue/green command) replaces the server command, the env and the user are kept.
+func workersCommand(cf common.CmdFactory, env map[string]string) pool.Command {
+	prepare := cf.CmdFactory(env)
+	return func(command []string) *exec.Cmd {
+		cmd := prepare()
+		if cmd == nil || len(command) == 0 {
+			return cmd
+		}
+
+		// the command declared as a string
+		if len(command) == 1 {
+			command = strings.Fields(command[0])
+		}
+
+		custom := exec.Command(command[0], command[1:]...) //nolint:gosec
+		custom.Env = cmd.Env
+		custom.Dir = cmd.Dir
+		custom.SysProcAttr = cmd.SysProcAttr
+		return custom
+	}
+}
+
+// destroyPools stops the workers of all pools, must be called with the plugin lock held.
+func (p *Plugin) destroyPools(ctx context.Context) {
+	for _, pl := range []common.Pool{p.pool.Load(), p.green.Load(), p.previous.Load()} {
+		if pl != nil {
+			pl.Destroy(ctx)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogStdout(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	p := &Plugin{log: zap.New(core)}
+
+	cmd := p.logStdout(func([]string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo debug output")
+	})(nil)
+	require.NoError(t, cmd.Run())
+
+	entries := logs.FilterMessage("debug output").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(cmd.Process.Pid), entries[0].ContextMap()["pid"])
+	assert.Equal(t, "stdout", entries[0].ContextMap()["output"])
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+)
+
+// otelMiddleware is the name of the otel plugin middleware starting the root spans
+const otelMiddleware string = "otel"
+
+// sampledMiddleware places the root span sampler before the otel middleware
+type sampledMiddleware struct {
+	otel   common.Middleware
+	sample func(next http.Handler) http.Handler
+}
+
+func (s *sampledMiddleware) Middleware(next http.Handler) http.Handler {
+	return s.sample(s.otel.Middleware(next))
+}
+
+func (s *sampledMiddleware) Name() string {
+	return s.otel.Name()
+}
+
+// rootSampled wraps the otel middleware with the root span sampler, other middleware is returned as is
+func (p *Plugin) rootSampled(mdw common.Middleware) common.Middleware {
+	if mdw.Name() != otelMiddleware || p.cfg == nil || p.cfg.RootSpan == nil {
+		return mdw
+	}
+
+	var sampler bundledMw.Sampler
+	switch p.cfg.RootSpan.Sampler {
+	case config.SamplerRatio:
+		sampler = bundledMw.RatioSampler(p.cfg.RootSpan.Ratio)
+	case config.SamplerRateLimited:
+		sampler = bundledMw.RateLimitedSampler(p.cfg.RootSpan.Rate)
+	case config.SamplerAlwaysOff:
+		sampler = bundledMw.AlwaysOffSampler()
+	default:
+		return mdw
+	}
+
+	return &sampledMiddleware{
+		otel: mdw,
+		sample: func(next http.Handler) http.Handler {
+			return bundledMw.RootSampler(next, p.prop, sampler)
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/pool"
+	"go.uber.org/zap"
+)
+
+// routePool is the worker pool serving the requests under the path prefixes.
+type routePool struct {
+	cfg *config.RoutePool
+	// relay is the own workers factory, nil - the server plugin creates and stops the workers
+	relay   pool.Factory
+	pool    common.Pool
+	handler *handler.Handler
+}
+
+// initRoutePools starts the route pools, the pools started before the error are stopped by Stop.
+func (p *Plugin) initRoutePools() error {
+	const op = errors.Op("http_init_route_pools")
+	for i := 0; i < len(p.cfg.RoutePools); i++ {
+		cfg := p.cfg.RoutePools[i]
+		rp := &routePool{cfg: cfg}
+
+		var err error
+		if cfg.Relay == "" {
+			rp.pool, err = p.server.NewPool(context.Background(), cfg.Pool, map[string]string{RrMode: RrModeHTTP}, p.log)
+		} else {
+			rp.relay, err = p.newRelay(&config.PoolRelay{Relay: cfg.Relay})
+			if err == nil {
+				rp.pool, err = p.newRelayPool(rp.relay, cfg.Relay, cfg.Pool)
+				if err != nil {
+					_ = rp.relay.Close()
+				}
+			}
+		}
+		if err != nil {
+			return errors.E(op, errors.Errorf("route pool %s: %v", cfg.Name, err))
+		}
+
+		p.routePools = append(p.routePools, rp)
+		rp.handler, err = handler.NewHandler(p.cfg, rp.pool, p.log)
+		if err != nil {
+			return errors.E(op, err)
+		}
+
+		if p.stderr != nil {
+			rp.handler.CaptureStderr(p.stderr)
+		}
+
+		err = p.probeWorkers(rp.pool, rp.handler)
+		if err != nil {
+			return errors.E(op, errors.Errorf("route pool %s: %v", cfg.Name, err))
+		}
+
+		p.log.Info("route pool started", zap.String("name", cfg.Name), zap.Strings("prefixes", cfg.Prefixes), zap.String("relay", cfg.Relay))
+	}
+
+	return nil
+}
+
+// routeHandler returns the handler of the route pool with the longest prefix matching the request path, the main
+// handler otherwise. Must be called with the plugin lock held.
+func (p *Plugin) routeHandler(r *http.Request) *handler.Handler {
+	h, longest := p.handler, 0
+	for i := 0; i < len(p.routePools); i++ {
+		prefixes := p.routePools[i].cfg.Prefixes
+		for j := 0; j < len(prefixes); j++ {
+			if len(prefixes[j]) > longest && strings.HasPrefix(r.URL.Path, prefixes[j]) {
+				h, longest = p.routePools[i].handler, len(prefixes[j])
+			}
+		}
+	}
+
+	return h
+}
+
+// resetRoutePools restarts the workers of the route pools, must be called with the plugin lock held.
+func (p *Plugin) resetRoutePools() error {
+	for i := 0; i < len(p.routePools); i++ {
+		err := p.routePools[i].pool.Reset(context.Background())
+		if err == nil {
+			err = p.probeWorkers(p.routePools[i].pool, p.routePools[i].handler)
+		}
+		if err != nil {
+			return errors.Errorf("route pool %s: %v", p.routePools[i].cfg.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// destroyRoutePools stops the workers started with the own relays, the other route pools are stopped by the server
+// plugin. Must be called with the plugin lock held.
+func (p *Plugin) destroyRoutePools(ctx context.Context) {
+	for i := 0; i < len(p.routePools); i++ {
+		if p.routePools[i].relay == nil {
+			continue
+		}
+
+		p.routePools[i].pool.Destroy(ctx)
+		_ = p.routePools[i].relay.Close()
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutePools(t *testing.T) {
+	p := newDeployPlugin(t, nil)
+	p.cfg.RoutePools = []*config.RoutePool{
+		{Name: "remote", Prefixes: []string{"/reports/"}, Relay: config.RelayPipes, Pool: &pool.Config{NumWorkers: 1, AllocateTimeout: time.Second, DestroyTimeout: time.Second}},
+		{Name: "local", Prefixes: []string{"/reports/local/"}, Pool: &pool.Config{Command: []string{"local"}, NumWorkers: 1, AllocateTimeout: time.Second}},
+	}
+	require.NoError(t, p.initRoutePools())
+	defer p.destroyRoutePools(context.Background())
+
+	serve := func(path string) string {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		body, err := io.ReadAll(w.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	// the longest prefix wins, the rest is served by the main pool
+	assert.Equal(t, "relay", serve("/reports/1"))
+	assert.Equal(t, "local", serve("/reports/local/1"))
+	assert.Equal(t, "blue", serve("/"))
+
+	require.NoError(t, p.resetRoutePools())
+	assert.Equal(t, "relay", serve("/reports/1"))
+}
+
+func TestWorkersCommand(t *testing.T) {
+	cmd := workersCommand(&testServer{t: t}, map[string]string{rrRelay: "tcp://127.0.0.1:6001"})
+
+	// the server command and env
+	c := cmd(nil)
+	assert.Equal(t, []string{os.Args[0]}, c.Args)
+	assert.Contains(t, c.Env, rrRelay+"=tcp://127.0.0.1:6001")
+
+	// the pool command keeps the server env
+	c = cmd([]string{"php worker.php"})
+	assert.Equal(t, []string{"php", "worker.php"}, c.Args)
+	assert.Contains(t, c.Env, rrRelay+"=tcp://127.0.0.1:6001")
+}
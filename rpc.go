@@ -18,6 +18,7 @@ func (rpc *rpc) Release(request *protofiles_v1.ReleaseRequestV1, response *proto
 	var poolObj static_pool.Pool
 
 	err := poolObj.Release(pid)
+	rpc.srv.audit("release", callerRPC, err, zap.Int64("pid", pid))
 	if err != nil {
 		response.Ok = 2
 		return nil
@@ -26,3 +27,90 @@ func (rpc *rpc) Release(request *protofiles_v1.ReleaseRequestV1, response *proto
 	return nil
 
 }
+
+// PurgeStaticCache removes all the files from the static cache, the number of removed files is returned.
+func (rpc *rpc) PurgeStaticCache(_ bool, purged *int64) error {
+	*purged = int64(rpc.srv.PurgeStaticCache())
+	rpc.srv.audit("purge_static_cache", callerRPC, nil, zap.Int64("purged", *purged))
+	rpc.log.Debug("static cache purged", zap.Int64("files", *purged))
+	return nil
+}
+
+// PurgeSurrogateKeys removes the cached responses with any of the surrogate keys, the number of removed responses is
+// returned.
+func (rpc *rpc) PurgeSurrogateKeys(keys []string, purged *int64) error {
+	*purged = int64(rpc.srv.PurgeSurrogateKeys(keys))
+	rpc.srv.audit("purge_surrogate_keys", callerRPC, nil, zap.Strings("keys", keys), zap.Int64("purged", *purged))
+	rpc.log.Debug("response cache purged", zap.Strings("keys", keys), zap.Int64("responses", *purged))
+	return nil
+}
+
+// Replay sends the captured requests to the workers, all the captured requests are replayed when no names are
+// provided. The response statuses are returned by the file names.
+func (rpc *rpc) Replay(names []string, statuses *map[string]int) error {
+	replayed, err := rpc.srv.Replay(names)
+	rpc.srv.audit("replay", callerRPC, err, zap.Strings("names", names), zap.Int("replayed", len(replayed)))
+	if err != nil {
+		return err
+	}
+
+	*statuses = replayed
+	rpc.log.Debug("captured requests replayed", zap.Int("requests", len(replayed)))
+	return nil
+}
+
+// ValidateConfig validates the candidate configuration (YAML or JSON) without applying it, the found problems are
+// returned, empty if the configuration is valid.
+func (rpc *rpc) ValidateConfig(cfg []byte, problems *[]string) error {
+	*problems = rpc.srv.ValidateConfig(cfg)
+	rpc.log.Debug("configuration validated", zap.Strings("problems", *problems))
+	return nil
+}
+
+// PreparePool creates the green pool with the command (the server command if empty) and checks its workers.
+func (rpc *rpc) PreparePool(command []string, ok *bool) error {
+	err := rpc.srv.PreparePool(command)
+	rpc.srv.audit("prepare_pool", callerRPC, err, zap.Strings("command", command))
+	if err != nil {
+		return err
+	}
+
+	*ok = true
+	return nil
+}
+
+// SwapPool switches the traffic to the green pool.
+func (rpc *rpc) SwapPool(_ bool, ok *bool) error {
+	err := rpc.srv.SwapPool()
+	rpc.srv.audit("swap_pool", callerRPC, err)
+	if err != nil {
+		return err
+	}
+
+	*ok = true
+	return nil
+}
+
+// RollbackPool switches the traffic back to the previous pool.
+func (rpc *rpc) RollbackPool(_ bool, ok *bool) error {
+	err := rpc.srv.RollbackPool()
+	rpc.srv.audit("rollback_pool", callerRPC, err)
+	if err != nil {
+		return err
+	}
+
+	*ok = true
+	return nil
+}
+
+// CommitPool destroys the previous pool.
+func (rpc *rpc) CommitPool(_ bool, ok *bool) error {
+	err := rpc.srv.CommitPool()
+	rpc.srv.audit("commit_pool", callerRPC, err)
+	if err != nil {
+		return err
+	}
+
+	*ok = true
+	return nil
+}
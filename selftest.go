@@ -0,0 +1,202 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/handler"
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// selfTestKey marks the synthetic request, it is passed through the endpoint to the rest of the chain.
+type selfTestKey struct{}
+
+// selfTestReport is the response of the self-test endpoint.
+type selfTestReport struct {
+	OK     bool   `json:"ok"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Timings in milliseconds: total, and mw (middleware), queue, exec from the Server-Timing header.
+	Timings map[string]float64 `json:"timings_ms"`
+}
+
+// selfTest serves the self-test endpoint. The chain returns the server handler with the user middleware, it is
+// resolved per request because the user middleware is applied after the bundled one. The nonce header is removed
+// from the client requests, only the synthetic request carries it to the worker.
+func (p *Plugin) selfTest(next http.Handler, chain func() http.Handler) http.Handler {
+	if p.cfg.SelfTest == nil {
+		return next
+	}
+
+	path := p.cfg.SelfTest.Path
+	header := p.cfg.SelfTest.Header
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(selfTestKey{}) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Header.Del(header)
+		if r.URL.Path != path {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !p.trustedPeer(r) {
+			http.NotFound(w, r)
+			return
+		}
+
+		report := p.runSelfTest(r, chain())
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !report.OK {
+			p.log.Warn("self-test failed", zap.Int("status", report.Status), zap.String("error", report.Error))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// trustedPeer returns true if the internal endpoints may be served to the peer.
+func (p *Plugin) trustedPeer(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return p.cfg.IsTrusted(host)
+}
+
+// runSelfTest sends the synthetic request with the nonce through the chain and checks the worker echo.
+func (p *Plugin) runSelfTest(r *http.Request, chain http.Handler) *selfTestReport {
+	cfg := p.cfg.SelfTest
+	report := &selfTestReport{Timings: make(map[string]float64, 4)}
+
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	nonce := hex.EncodeToString(buf)
+
+	// the synthetic request doesn't share the connection state with the original one
+	ctx := context.WithValue(context.Background(), selfTestKey{}, true)
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		ctx = context.WithValue(ctx, http.LocalAddrContextKey, addr)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Path, strings.NewReader(nonce))
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	req.RequestURI = cfg.Path
+	req.Host = r.Host
+	req.RemoteAddr = r.RemoteAddr
+	req.TLS = r.TLS
+	req.Proto, req.ProtoMajor, req.ProtoMinor = r.Proto, r.ProtoMajor, r.ProtoMinor
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set(cfg.Header, nonce)
+	req = handler.WithServerTiming(req)
+
+	sw := &selfTestWriter{header: http.Header{}}
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(done)
+		bundledMw.Arrival(chain).ServeHTTP(sw, req)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// the writer is left to the handler
+		report.Timings["total"] = milliseconds(time.Since(start))
+		report.Error = "timeout waiting for the worker response"
+		return report
+	}
+
+	report.Timings["total"] = milliseconds(time.Since(start))
+	parseServerTiming(sw.header.Values("Server-Timing"), report.Timings)
+
+	report.Status = sw.status
+	if report.Status == 0 {
+		report.Status = http.StatusOK
+	}
+
+	switch {
+	case report.Status != http.StatusOK:
+		report.Error = "unexpected response status"
+	case strings.TrimSpace(sw.body.String()) != nonce:
+		report.Error = "worker did not echo the nonce"
+	default:
+		report.OK = true
+	}
+
+	return report
+}
+
+// parseServerTiming reads the metric durations (name;dur=ms) from the Server-Timing headers.
+func parseServerTiming(values []string, timings map[string]float64) {
+	for i := 0; i < len(values); i++ {
+		for _, metric := range strings.Split(values[i], ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(metric), ";")
+			for _, param := range strings.Split(params, ";") {
+				v, ok := strings.CutPrefix(strings.TrimSpace(param), "dur=")
+				if !ok {
+					continue
+				}
+
+				d, err := strconv.ParseFloat(v, 64)
+				if err == nil {
+					timings[name] = d
+				}
+			}
+		}
+	}
+}
+
+// selfTestMaxBody limits the recorded response body, the echo is only the nonce.
+const selfTestMaxBody = 1024
+
+// selfTestWriter records the synthetic response.
+type selfTestWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (s *selfTestWriter) Header() http.Header {
+	return s.header
+}
+
+func (s *selfTestWriter) WriteHeader(code int) {
+	if code >= 200 && s.status == 0 {
+		s.status = code
+	}
+}
+
+func (s *selfTestWriter) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+
+	if n := selfTestMaxBody - s.body.Len(); n > 0 {
+		s.body.Write(p[:min(n, len(p))])
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSelfTestHeader(t *testing.T) {
+	cfg := &config.Config{Address: "127.0.0.1:0", SelfTest: &config.SelfTest{}}
+	require.NoError(t, cfg.InitDefaults())
+	p := &Plugin{log: zap.NewNop(), cfg: cfg}
+
+	// the worker echoes the nonce header
+	var h http.Handler
+	h = p.selfTest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(cfg.SelfTest.Header)))
+	}), func() http.Handler { return h })
+
+	// the client can't send the nonce header to the worker
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(cfg.SelfTest.Header, "<script>")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Empty(t, w.Body.String())
+
+	// the synthetic request keeps it
+	r = httptest.NewRequest(http.MethodGet, cfg.SelfTest.Path, nil)
+	r.RemoteAddr = "127.0.0.1:4000"
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	report := &selfTestReport{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), report))
+	assert.True(t, report.OK, report.Error)
+
+	// the untrusted peers get 404
+	r = httptest.NewRequest(http.MethodGet, cfg.SelfTest.Path, nil)
+	r.RemoteAddr = "192.0.2.1:4000"
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -14,8 +14,8 @@ import (
 	"github.com/roadrunner-server/errors"
 	"github.com/roadrunner-server/http/v5/config"
 	"github.com/roadrunner-server/http/v5/middleware"
+	"github.com/roadrunner-server/http/v5/servers/https"
 	"go.uber.org/zap"
-	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
 
@@ -25,6 +25,8 @@ type Server struct {
 	address      string
 	redirect     bool
 	redirectPort int
+	// respond with 408 on the request headers read timeout
+	timeoutResponse bool
 }
 
 func NewHTTPServer(handler http.Handler, cfg *config.Config, errLog *log.Logger, log *zap.Logger) servers.InternalServer[any] {
@@ -36,30 +38,34 @@ func NewHTTPServer(handler http.Handler, cfg *config.Config, errLog *log.Logger,
 		redirectPort = cfg.SSLConfig.Port
 	}
 
-	if cfg.HTTP2Config != nil && cfg.HTTP2Config.H2C {
+	proto := cfg.ListenerProtocols[config.ListenerHTTP]
+	handler = proto.Handler(handler)
+	if cfg.HTTP2Config != nil && cfg.HTTP2Config.H2C && proto.Enabled(https.H2C) {
+		srv := &http.Server{
+			Handler:           h2cHandler(handler, cfg.HTTP2Config, proto),
+			ReadTimeout:       time.Minute * 5,
+			WriteTimeout:      time.Minute * 5,
+			IdleTimeout:       time.Hour,
+			ReadHeaderTimeout: time.Minute * 5,
+			ErrorLog:          errLog,
+		}
+		cfg.HTTP2Config.Apply(srv)
+
 		return &Server{
-			log:          log,
-			redirect:     redirect,
-			redirectPort: redirectPort,
-			address:      cfg.Address,
-			http: &http.Server{
-				Handler: h2c.NewHandler(handler, &http2.Server{
-					MaxConcurrentStreams:         cfg.HTTP2Config.MaxConcurrentStreams,
-					PermitProhibitedCipherSuites: false,
-				}),
-				ReadTimeout:       time.Minute * 5,
-				WriteTimeout:      time.Minute * 5,
-				IdleTimeout:       time.Hour,
-				ReadHeaderTimeout: time.Minute * 5,
-				ErrorLog:          errLog,
-			},
+			log:             log,
+			redirect:        redirect,
+			redirectPort:    redirectPort,
+			address:         cfg.Address,
+			timeoutResponse: cfg.RequestTimeoutResponse,
+			http:            srv,
 		}
 	}
 	return &Server{
-		log:          log,
-		redirect:     redirect,
-		redirectPort: redirectPort,
-		address:      cfg.Address,
+		log:             log,
+		redirect:        redirect,
+		redirectPort:    redirectPort,
+		address:         cfg.Address,
+		timeoutResponse: cfg.RequestTimeoutResponse,
 		http: &http.Server{
 			ReadTimeout:       time.Minute * 5,
 			WriteTimeout:      time.Minute * 5,
@@ -71,6 +77,29 @@ func NewHTTPServer(handler http.Handler, cfg *config.Config, errLog *log.Logger,
 	}
 }
 
+// h2cHandler serves h2c to the clients allowed by the protocols policy, the other clients are served over HTTP/1.1
+func h2cHandler(handler http.Handler, cfg *https.HTTP2, proto *https.Protocols) http.Handler {
+	h2 := h2c.NewHandler(handler, cfg.Server())
+	if proto == nil || len(proto.H2Clients) == 0 {
+		return h2
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if proto.H2Client(r.RemoteAddr) {
+			h2.ServeHTTP(w, r)
+			return
+		}
+
+		// the h2c upgrade is ignored, the prior knowledge requests are not accepted
+		if r.Method == "PRI" && r.RequestURI == "*" {
+			http.Error(w, http.StatusText(http.StatusHTTPVersionNotSupported), http.StatusHTTPVersionNotSupported)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
 // Serve is a blocking function
 func (s *Server) Serve(mdwr map[string]common.Middleware, order []string) error {
 	const op = errors.Op("serveHTTP")
@@ -89,6 +118,10 @@ func (s *Server) Serve(mdwr map[string]common.Middleware, order []string) error
 		return errors.E(op, err)
 	}
 
+	if s.timeoutResponse {
+		l = &timeoutListener{Listener: l, log: s.log}
+	}
+
 	s.log.Debug("http server was started", zap.String("address", s.address))
 	err = s.http.Serve(l)
 	if err != nil && !stderr.Is(err, http.ErrServerClosed) {
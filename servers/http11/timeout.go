@@ -0,0 +1,96 @@
+package http
+
+import (
+	"bytes"
+	stderr "errors"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxPartialRequest is the max number of the buffered bytes of the request line reported in the logs
+	maxPartialRequest int    = 256
+	requestTimeout    string = "HTTP/1.1 408 Request Timeout\r\nConnection: close\r\nContent-Length: 0\r\n\r\n"
+)
+
+var headersEnd = []byte("\r\n\r\n") //nolint:gochecknoglobals
+
+// timeoutListener wraps the connections to respond with 408 when the read deadline fires in the middle of the
+// request headers. The http.Server silently closes such connections.
+type timeoutListener struct {
+	net.Listener
+	log *zap.Logger
+}
+
+func (l *timeoutListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &timeoutConn{Conn: c, log: l.log}, nil
+}
+
+// timeoutConn tracks the headers of the current request, the state is reset when the response is written.
+type timeoutConn struct {
+	net.Conn
+	log *zap.Logger
+
+	mu   sync.Mutex
+	read int
+	// the headers are read, the body timeouts are handled by the handler
+	headersDone bool
+	// the last bytes to find the end of the headers between the reads
+	tail    []byte
+	partial []byte
+}
+
+func (c *timeoutConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+
+	c.mu.Lock()
+	c.read += n
+	if len(c.partial) < maxPartialRequest {
+		c.partial = append(c.partial, b[:min(n, maxPartialRequest-len(c.partial))]...)
+	}
+
+	if !c.headersDone && n > 0 {
+		c.tail = append(c.tail, b[:n]...)
+		c.headersDone = bytes.Contains(c.tail, headersEnd)
+		if len(c.tail) > len(headersEnd) {
+			c.tail = append(c.tail[:0], c.tail[len(c.tail)-len(headersEnd)+1:]...)
+		}
+	}
+	read, partial, headersDone := c.read, c.partial, c.headersDone
+	c.mu.Unlock()
+
+	var ne net.Error
+	// idle keep-alive connections are closed silently
+	if err != nil && read > 0 && !headersDone && stderr.As(err, &ne) && ne.Timeout() {
+		line, _, _ := bytes.Cut(partial, []byte("\r\n"))
+		c.log.Warn("request read timeout",
+			zap.String("remote_address", c.RemoteAddr().String()),
+			zap.Int("read_bytes", read),
+			zap.ByteString("partial_request", line))
+
+		// the connection is closed by the server after the error
+		_ = c.SetWriteDeadline(time.Time{})
+		_, _ = c.Conn.Write([]byte(requestTimeout))
+	}
+
+	return n, err
+}
+
+func (c *timeoutConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	c.read = 0
+	c.headersDone = false
+	c.tail = c.tail[:0]
+	c.partial = c.partial[:0]
+	c.mu.Unlock()
+
+	return c.Conn.Write(b)
+}
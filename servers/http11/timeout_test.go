@@ -0,0 +1,38 @@
+package http
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTimeoutConn(t *testing.T) {
+	client, server := net.Pipe()
+	c := &timeoutConn{Conn: server, log: zap.NewNop()}
+
+	go func() {
+		_, _ = client.Write([]byte("GET / HTTP/1.1\r\nHost: exa"))
+	}()
+
+	buf := make([]byte, 64)
+	_, err := c.Read(buf)
+	require.NoError(t, err)
+
+	respCh := make(chan []byte, 1)
+	go func() {
+		resp, _ := io.ReadAll(client)
+		respCh <- resp
+	}()
+
+	require.NoError(t, c.SetReadDeadline(time.Now().Add(time.Millisecond*10)))
+	_, err = c.Read(buf)
+	require.Error(t, err)
+	_ = c.Close()
+
+	assert.Equal(t, requestTimeout, string(<-respCh))
+}
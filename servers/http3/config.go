@@ -1,5 +1,20 @@
 package http3
 
+import (
+	"github.com/roadrunner-server/errors"
+)
+
+// EarlyDataPolicy defines the handling of the requests received in the 0-RTT early data.
+type EarlyDataPolicy string
+
+const (
+	// RejectUnsafe rejects the early data requests with the non-idempotent methods with 425 Too Early, the client
+	// retries them after the handshake.
+	RejectUnsafe EarlyDataPolicy = "reject_unsafe"
+	// RejectAll rejects all the early data requests with 425 Too Early.
+	RejectAll EarlyDataPolicy = "reject_all"
+)
+
 type Config struct {
 	// Address is the address to listen on.
 	Address string `mapstructure:"address"`
@@ -7,4 +22,32 @@ type Config struct {
 	Key string `mapstructure:"key"`
 	// Cert is https certificate.
 	Cert string `mapstructure:"cert"`
+	// Allow0RTT accepts the 0-RTT resumed connections. The early data can be replayed by an attacker, the accepted
+	// early data requests are passed to the workers with the Early-Data: 1 header (RFC 8470).
+	Allow0RTT bool `mapstructure:"allow_0rtt"`
+	// EarlyData is the anti-replay policy of the early data requests, reject_unsafe or reject_all. Defaults to
+	// reject_unsafe.
+	EarlyData EarlyDataPolicy `mapstructure:"early_data"`
+}
+
+func (c *Config) InitDefaults() {
+	if c.Allow0RTT && c.EarlyData == "" {
+		c.EarlyData = RejectUnsafe
+	}
+}
+
+func (c *Config) Valid() error {
+	const op = errors.Op("http3_valid")
+
+	switch c.EarlyData {
+	case "", RejectUnsafe, RejectAll:
+	default:
+		return errors.E(op, errors.Errorf("unknown early_data policy %q, should be reject_unsafe or reject_all", c.EarlyData))
+	}
+
+	if c.EarlyData != "" && !c.Allow0RTT {
+		return errors.E(op, errors.Str("early_data is set, but allow_0rtt is disabled"))
+	}
+
+	return nil
 }
@@ -29,7 +29,7 @@ func NewHTTP3server(handler http.Handler, acmeCfg *acme.Config, cfg *Config, log
 		server: &http3.Server{
 			Addr:       cfg.Address,
 			Handler:    handler,
-			QUICConfig: &quic.Config{},
+			QUICConfig: &quic.Config{Allow0RTT: cfg.Allow0RTT},
 			TLSConfig:  tlsconf.DefaultTLSConfig(),
 		},
 	}
@@ -64,7 +64,7 @@ func (s *Server) Serve(mdwr map[string]common.Middleware, order []string) error
 		applyMiddleware(s.server, mdwr, order, s.log)
 	}
 
-	s.log.Debug("http3 server was started", zap.String("address", s.server.Addr))
+	s.log.Debug("http3 server was started", zap.String("address", s.server.Addr), zap.Bool("0rtt", s.cfg.Allow0RTT), zap.String("early_data", string(s.cfg.EarlyData)))
 	err := s.server.ListenAndServeTLS(s.cfg.Cert, s.cfg.Key)
 	if err != nil {
 		return errors.E(op, err)
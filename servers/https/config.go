@@ -1,12 +1,15 @@
 package https
 
 import (
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/roadrunner-server/errors"
 	"github.com/roadrunner-server/http/v5/acme"
+	"golang.org/x/net/http2"
 )
 
 type ClientAuthType string
@@ -35,6 +38,17 @@ type HTTP2 struct {
 
 	// MaxConcurrentStreams defaults to 128.
 	MaxConcurrentStreams uint32 `mapstructure:"max_concurrent_streams"`
+	// InitialConnWindowSize is the flow control window of the connection (upload buffer), 64KB-2GB. Defaults to 1MB.
+	InitialConnWindowSize uint32 `mapstructure:"initial_conn_window_size"`
+	// InitialStreamWindowSize is the initial flow control window of the streams (upload buffer), 64KB-2GB. Defaults
+	// to 1MB.
+	InitialStreamWindowSize uint32 `mapstructure:"initial_stream_window_size"`
+	// MaxFrameSize is the max frame size the server reads, 16KB-16MB. Defaults to 1MB.
+	MaxFrameSize uint32 `mapstructure:"max_frame_size"`
+	// MaxHeaderListSize is the max size of the request headers, applied to HTTP/1 as well. Defaults to 1MB.
+	MaxHeaderListSize uint32 `mapstructure:"max_header_list_size"`
+	// IdleTimeout closes the idle HTTP/2 connections, defaults to the server idle timeout.
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
 }
 
 func (h2 *HTTP2) EnableHTTP2() bool {
@@ -57,6 +71,17 @@ type SSL struct {
 	RootCA string `mapstructure:"root_ca"`
 	// mTLS auth
 	AuthType ClientAuthType `mapstructure:"client_auth_type"`
+	// Fingerprint computes the JA3/JA4 fingerprints of the clients, passed to the workers as the X-TLS-JA3 and
+	// X-TLS-JA4 headers and the tls_ja3 and tls_ja4 attributes. The client-provided headers are removed on all
+	// listeners
+	Fingerprint bool `mapstructure:"fingerprint"`
+	// ClientCert passes the verified client certificate subject, SANs, serial and fingerprint to the workers as the
+	// X-Client-Cert-* headers and the tls_client_* attributes, requires the verifying client_auth_type. The
+	// client-provided headers are removed on all listeners
+	ClientCert bool `mapstructure:"client_cert"`
+	// ClientCertPEM also passes the whole client certificate as the Client-Cert header and the tls_client_cert
+	// attribute (PEM)
+	ClientCertPEM bool `mapstructure:"client_cert_pem"`
 	// internal
 	host string
 	// internal
@@ -69,9 +94,55 @@ func (h2 *HTTP2) InitDefaults() error {
 		h2.MaxConcurrentStreams = 128
 	}
 
+	return h2.Valid()
+}
+
+// Valid validates the HTTP/2 limits (RFC 9113, section 6.5.2), 0 - the default value.
+func (h2 *HTTP2) Valid() error {
+	const op = errors.Op("http2_validation")
+	const (
+		minWindow    uint32 = 1<<16 - 1
+		maxWindow    uint32 = 1<<31 - 1
+		minFrameSize uint32 = 1 << 14
+		maxFrameSize uint32 = 1<<24 - 1
+	)
+
+	for _, window := range []uint32{h2.InitialConnWindowSize, h2.InitialStreamWindowSize} {
+		if window != 0 && (window < minWindow || window > maxWindow) {
+			return errors.E(op, errors.Errorf("window size should be between %d and %d", minWindow, maxWindow))
+		}
+	}
+
+	if h2.MaxFrameSize != 0 && (h2.MaxFrameSize < minFrameSize || h2.MaxFrameSize > maxFrameSize) {
+		return errors.E(op, errors.Errorf("max_frame_size should be between %d and %d", minFrameSize, maxFrameSize))
+	}
+
+	if h2.IdleTimeout < 0 {
+		return errors.E(op, errors.Str("idle_timeout should be positive"))
+	}
+
 	return nil
 }
 
+// Server returns the HTTP/2 server with the configured limits.
+func (h2 *HTTP2) Server() *http2.Server {
+	return &http2.Server{
+		MaxConcurrentStreams:         h2.MaxConcurrentStreams,
+		MaxUploadBufferPerConnection: int32(h2.InitialConnWindowSize),   //nolint:gosec
+		MaxUploadBufferPerStream:     int32(h2.InitialStreamWindowSize), //nolint:gosec
+		MaxReadFrameSize:             h2.MaxFrameSize,
+		IdleTimeout:                  h2.IdleTimeout,
+		PermitProhibitedCipherSuites: false,
+	}
+}
+
+// Apply sets the HTTP/2 limits kept by the HTTP server.
+func (h2 *HTTP2) Apply(server *http.Server) {
+	if h2.MaxHeaderListSize > 0 {
+		server.MaxHeaderBytes = int(h2.MaxHeaderListSize)
+	}
+}
+
 func (s *SSL) InitDefaults() error {
 	if s.Acme != nil {
 		err := s.Acme.InitDefaults()
@@ -137,6 +208,12 @@ func (s *SSL) Valid() error {
 		}
 	}
 
+	if s.ClientCert || s.ClientCertPEM {
+		if s.RootCA == "" || (s.AuthType != VerifyClientCertIfGiven && s.AuthType != RequireAndVerifyClientCert) {
+			return errors.E(op, errors.Str("client_cert requires the root_ca and the verify_client_cert_if_given or require_and_verify_client_cert client_auth_type"))
+		}
+	}
+
 	// RootCA is optional, but if provided - check it
 	if s.RootCA != "" {
 		if _, err := os.Stat(s.RootCA); err != nil {
@@ -1,6 +1,7 @@
 package https
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -114,3 +115,20 @@ func TestSSL_Valid7(t *testing.T) {
 	err := conf.Valid()
 	assert.Error(t, err)
 }
+
+func TestHTTP2Limits(t *testing.T) {
+	h2 := &HTTP2{InitialStreamWindowSize: 1 << 20, MaxFrameSize: 1 << 16, MaxHeaderListSize: 1 << 15}
+	assert.NoError(t, h2.InitDefaults())
+
+	srv := h2.Server()
+	assert.Equal(t, uint32(128), srv.MaxConcurrentStreams)
+	assert.Equal(t, int32(1<<20), srv.MaxUploadBufferPerStream)
+	assert.Equal(t, uint32(1<<16), srv.MaxReadFrameSize)
+
+	hs := &http.Server{}
+	h2.Apply(hs)
+	assert.Equal(t, 1<<15, hs.MaxHeaderBytes)
+
+	assert.Error(t, (&HTTP2{MaxFrameSize: 1024}).Valid())
+	assert.Error(t, (&HTTP2{InitialConnWindowSize: 1024}).Valid())
+}
@@ -7,8 +7,7 @@ import (
 )
 
 // init http/2 server
-func initHTTP2(server *http.Server, streams uint32) error {
-	return http2.ConfigureServer(server, &http2.Server{
-		MaxConcurrentStreams: streams,
-	})
+func initHTTP2(server *http.Server, cfg *HTTP2) error {
+	cfg.Apply(server)
+	return http2.ConfigureServer(server, cfg.Server())
 }
@@ -1,11 +1,13 @@
 package https
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	stderr "errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -15,8 +17,10 @@ import (
 
 	"github.com/roadrunner-server/http/v5/acme"
 	"github.com/roadrunner-server/http/v5/common"
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
 	"github.com/roadrunner-server/http/v5/servers"
 	"github.com/roadrunner-server/http/v5/tlsconf"
+	"github.com/roadrunner-server/http/v5/tlsfp"
 
 	"github.com/mholt/acmez"
 	"github.com/roadrunner-server/errors"
@@ -25,12 +29,13 @@ import (
 
 type Server struct {
 	cfg   *SSL
+	proto *Protocols
 	log   *zap.Logger
 	https *http.Server
 }
 
-func NewHTTPSServer(handler http.Handler, cfg *SSL, cfgHTTP2 *HTTP2, errLog *log.Logger, logger *zap.Logger) (servers.InternalServer[any], error) {
-	httpsServer := initTLS(handler, errLog, cfg.Address, cfg.Port)
+func NewHTTPSServer(handler http.Handler, cfg *SSL, cfgHTTP2 *HTTP2, proto *Protocols, errLog *log.Logger, logger *zap.Logger) (servers.InternalServer[any], error) {
+	httpsServer := initTLS(proto.Handler(handler), errLog, cfg.Address, cfg.Port)
 
 	if cfg.RootCA != "" {
 		pool, err := createCertPool(cfg.RootCA)
@@ -78,8 +83,13 @@ func NewHTTPSServer(handler http.Handler, cfg *SSL, cfgHTTP2 *HTTP2, errLog *log
 		httpsServer.TLSConfig.NextProtos = append(httpsServer.TLSConfig.NextProtos, acmez.ACMETLS1Protocol)
 	}
 
-	if cfgHTTP2 != nil && cfgHTTP2.EnableHTTP2() {
-		err := initHTTP2(httpsServer, cfgHTTP2.MaxConcurrentStreams)
+	// the HTTP/2 is negotiated over TLS, the limits are applied whenever configured
+	switch {
+	case !proto.Enabled(H2):
+		// the empty map disables the HTTP/2 configured by default
+		httpsServer.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	case cfgHTTP2 != nil:
+		err := initHTTP2(httpsServer, cfgHTTP2)
 		if err != nil {
 			return nil, err
 		}
@@ -87,6 +97,7 @@ func NewHTTPSServer(handler http.Handler, cfg *SSL, cfgHTTP2 *HTTP2, errLog *log
 
 	return &Server{
 		cfg:   cfg,
+		proto: proto,
 		log:   logger,
 		https: httpsServer,
 	}, nil
@@ -103,6 +114,31 @@ func (s *Server) Serve(mdwr map[string]common.Middleware, order []string) error
 		return errors.E(op, err)
 	}
 
+	// the certificate details are set before the user middleware
+	if s.cfg.ClientCert || s.cfg.ClientCertPEM {
+		s.https.Handler = bundledMw.ClientCert(s.https.Handler, s.cfg.ClientCertPEM)
+	}
+
+	if s.cfg.Fingerprint {
+		l = s.fingerprint(l)
+	}
+
+	certFile, keyFile := s.cfg.Cert, s.cfg.Key
+	if s.proto != nil && (len(s.proto.Versions) > 0 || len(s.proto.h2Nets) > 0) {
+		// the per-client configs are cloned with the certificates, so they are loaded before the ALPN is configured
+		if !s.cfg.EnableACME() {
+			cert, errC := tls.LoadX509KeyPair(certFile, keyFile)
+			if errC != nil {
+				return errors.E(op, errC)
+			}
+
+			s.https.TLSConfig.Certificates = []tls.Certificate{cert}
+			certFile, keyFile = "", ""
+		}
+
+		s.proto.alpn(s.https.TLSConfig)
+	}
+
 	/*
 		ACME powered server
 	*/
@@ -123,8 +159,8 @@ func (s *Server) Serve(mdwr map[string]common.Middleware, order []string) error
 	s.log.Debug("https server was started", zap.String("address", s.cfg.Address))
 	err = s.https.ServeTLS(
 		l,
-		s.cfg.Cert,
-		s.cfg.Key,
+		certFile,
+		keyFile,
 	)
 
 	if err != nil && !stderr.Is(err, http.ErrServerClosed) {
@@ -134,6 +170,22 @@ func (s *Server) Serve(mdwr map[string]common.Middleware, order []string) error
 	return nil
 }
 
+// fingerprint records the ClientHello of the connections, the fingerprint is set before the user middleware, so it
+// can be used by the WAF or the rate limiters
+func (s *Server) fingerprint(l net.Listener) net.Listener {
+	connContext := s.https.ConnContext
+	s.https.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		if connContext != nil {
+			ctx = connContext(ctx, c)
+		}
+
+		return tlsfp.ConnContext(ctx, c)
+	}
+	s.https.Handler = bundledMw.TLSFingerprint(s.https.Handler)
+
+	return tlsfp.NewListener(l)
+}
+
 func (s *Server) Server() any {
 	return s.https
 }
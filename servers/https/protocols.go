@@ -0,0 +1,145 @@
+package https
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"slices"
+
+	"github.com/roadrunner-server/errors"
+)
+
+// HTTP versions of the listeners, the ALPN protocol IDs
+const (
+	HTTP1 string = "http/1.1"
+	H2    string = "h2"
+	H2C   string = "h2c"
+)
+
+// Protocols is the HTTP version policy of the listener.
+type Protocols struct {
+	// Versions enabled on the listener: http/1.1 and h2 for the https listener, http/1.1 and h2c for the http
+	// listener. Empty - all the versions configured for the listener.
+	Versions []string `mapstructure:"versions"`
+	// H2Clients are the CIDRs of the clients offered HTTP/2, the other clients are served over HTTP/1.1, e.g. to
+	// keep the proxies with the broken HTTP/2 support on HTTP/1.1. Empty - any client.
+	H2Clients []string `mapstructure:"h2_clients"`
+	// internal
+	h2Nets []*net.IPNet
+}
+
+// InitDefaults parses the client subnets.
+func (p *Protocols) InitDefaults() error {
+	const op = errors.Op("protocols_init")
+	p.h2Nets = make([]*net.IPNet, 0, len(p.H2Clients))
+	for i := 0; i < len(p.H2Clients); i++ {
+		_, n, err := net.ParseCIDR(p.H2Clients[i])
+		if err != nil {
+			return errors.E(op, err)
+		}
+
+		p.h2Nets = append(p.h2Nets, n)
+	}
+
+	return nil
+}
+
+// Valid validates the policy of the listener serving HTTP/2 as h2 or h2c.
+func (p *Protocols) Valid(h2 string) error {
+	const op = errors.Op("protocols_validation")
+	for i := 0; i < len(p.Versions); i++ {
+		if p.Versions[i] != HTTP1 && p.Versions[i] != h2 {
+			return errors.E(op, errors.Errorf("unknown version %q, should be %s or %s", p.Versions[i], HTTP1, h2))
+		}
+	}
+
+	if len(p.H2Clients) > 0 && (!p.Enabled(HTTP1) || !p.Enabled(h2)) {
+		return errors.E(op, errors.Errorf("h2_clients requires both %s and %s versions", HTTP1, h2))
+	}
+
+	return nil
+}
+
+// Enabled reports whether the version is enabled, the nil policy enables all the versions.
+func (p *Protocols) Enabled(version string) bool {
+	return p == nil || len(p.Versions) == 0 || slices.Contains(p.Versions, version)
+}
+
+// H2Client reports whether the client with the address (host:port) is offered HTTP/2.
+func (p *Protocols) H2Client(addr string) bool {
+	if p == nil || len(p.h2Nets) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for i := 0; i < len(p.h2Nets); i++ {
+		if p.h2Nets[i].Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handler rejects the HTTP/1 requests with 505 when HTTP/1.1 is disabled, e.g. the TLS clients without ALPN.
+func (p *Protocols) Handler(next http.Handler) http.Handler {
+	if p.Enabled(HTTP1) {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 2 {
+			w.Header().Set("Connection", "close")
+			http.Error(w, http.StatusText(http.StatusHTTPVersionNotSupported), http.StatusHTTPVersionNotSupported)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// alpn offers the enabled versions by the client address. The configs are cloned when the server starts, after the
+// certificates and the HTTP/2 are configured.
+func (p *Protocols) alpn(base *tls.Config) {
+	protos := func(h2 bool) []string {
+		var next []string
+		if h2 {
+			next = append(next, H2)
+		}
+
+		if p.Enabled(HTTP1) {
+			next = append(next, HTTP1)
+		}
+
+		// keep the ACME challenge protocol
+		for i := 0; i < len(base.NextProtos); i++ {
+			if base.NextProtos[i] != H2 && base.NextProtos[i] != HTTP1 {
+				next = append(next, base.NextProtos[i])
+			}
+		}
+
+		return next
+	}
+
+	h2 := base.Clone()
+	h2.NextProtos = protos(p.Enabled(H2))
+	h1 := base.Clone()
+	h1.NextProtos = protos(false)
+
+	base.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if hello.Conn != nil && !p.H2Client(hello.Conn.RemoteAddr().String()) {
+			return h1, nil
+		}
+
+		return h2, nil
+	}
+}
@@ -0,0 +1,70 @@
+package https
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type addrConn struct {
+	net.Conn
+	addr string
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	addr, _ := net.ResolveTCPAddr("tcp", c.addr)
+	return addr
+}
+
+func TestProtocolsALPN(t *testing.T) {
+	p := &Protocols{H2Clients: []string{"10.0.0.0/8"}}
+	require.NoError(t, p.InitDefaults())
+	require.NoError(t, p.Valid(H2))
+
+	base := &tls.Config{NextProtos: []string{H2, HTTP1, "acme-tls/1"}} //nolint:gosec
+	p.alpn(base)
+
+	cfg, err := base.GetConfigForClient(&tls.ClientHelloInfo{Conn: &addrConn{addr: "10.1.2.3:5000"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{H2, HTTP1, "acme-tls/1"}, cfg.NextProtos)
+
+	cfg, err = base.GetConfigForClient(&tls.ClientHelloInfo{Conn: &addrConn{addr: "192.168.1.1:5000"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{HTTP1, "acme-tls/1"}, cfg.NextProtos)
+
+	p = &Protocols{Versions: []string{H2}}
+	require.NoError(t, p.Valid(H2))
+	p.alpn(base)
+	cfg, err = base.GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{H2, "acme-tls/1"}, cfg.NextProtos)
+
+	assert.Error(t, (&Protocols{Versions: []string{H2C}}).Valid(H2))
+	assert.Error(t, (&Protocols{Versions: []string{H2}, H2Clients: []string{"10.0.0.0/8"}}).Valid(H2))
+}
+
+func TestProtocolsHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	var p *Protocols
+	assert.True(t, p.Enabled(H2))
+	assert.True(t, p.H2Client("192.168.1.1:5000"))
+
+	h := (&Protocols{Versions: []string{H2}}).Handler(next)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusHTTPVersionNotSupported, w.Code)
+
+	r.ProtoMajor, r.ProtoMinor = 2, 0
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
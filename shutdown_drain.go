@@ -0,0 +1,45 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// drainStatus serves the drain status endpoint if configured.
+func (p *Plugin) drainStatus(next http.Handler) http.Handler {
+	if p.cfg.ShutdownDrain == nil {
+		return next
+	}
+
+	return bundledMw.DrainStatus(next, p.cfg.ShutdownDrain.Path, &p.draining)
+}
+
+// drain flips the drain status and the readiness to 503, closes the keep-alive connections after the responses, so
+// the clients reconnect to the other instances, and waits for the drain delay or the shutdown timeout.
+func (p *Plugin) drain(ctx context.Context) {
+	if p.cfg == nil || p.cfg.ShutdownDrain == nil || p.draining.Swap(true) {
+		return
+	}
+
+	p.log.Info("draining before the shutdown", zap.Duration("delay", p.cfg.ShutdownDrain.Delay))
+	for i := 0; i < len(p.servers); i++ {
+		if p.servers[i] == nil {
+			continue
+		}
+
+		if srv, ok := p.servers[i].Server().(*http.Server); ok {
+			srv.SetKeepAlivesEnabled(false)
+		}
+	}
+
+	timer := time.NewTimer(p.cfg.ShutdownDrain.Delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
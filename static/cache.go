@@ -0,0 +1,183 @@
+package static
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+// CacheStats contains the static cache counters.
+type CacheStats struct {
+	// Hits is the number of the files served from the cache.
+	Hits atomic.Uint64
+	// Misses is the number of the files served from the disk with the cache enabled.
+	Misses atomic.Uint64
+	// Bytes is the total size of the cached files.
+	Bytes atomic.Int64
+}
+
+// cached is the cached file with the headers of the served variant.
+type cached struct {
+	key         string
+	name        string
+	modTime     time.Time
+	data        []byte
+	etag        string
+	contentType string
+	encoding    string
+	expires     time.Time
+}
+
+// cache is the LRU cache of the small files with TTL and the total size limit.
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+
+	maxFileSize int64
+	maxSize     int64
+	ttl         time.Duration
+	stats       *CacheStats
+}
+
+func newCache(cfg *config.StaticCache) *cache {
+	return &cache{
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		maxFileSize: cfg.MaxFileSize,
+		maxSize:     cfg.MaxSize,
+		ttl:         cfg.TTL,
+		stats:       &CacheStats{},
+	}
+}
+
+// cacheKey returns the key of the file variant, with the pre-compressed files the served variant depends on the
+// encodings accepted by the client.
+func cacheKey(fp string, r *http.Request, precompressed bool) string {
+	if !precompressed {
+		return fp
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fp)
+	accept := r.Header.Get("Accept-Encoding")
+	for i := 0; i < len(sidecars); i++ {
+		if accept != "" && accepts(accept, sidecars[i].encoding) {
+			sb.WriteByte(0)
+			sb.WriteString(sidecars[i].encoding)
+		}
+	}
+
+	return sb.String()
+}
+
+// get returns the not expired file.
+func (c *cache) get(key string) *cached {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := el.Value.(*cached)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return nil
+	}
+
+	c.lru.MoveToFront(el)
+	return e
+}
+
+// load reads the file into the cache, returns nil if the file is too large.
+func (c *cache) load(key string, f io.ReadSeeker, st fs.FileInfo, h http.Header) *cached {
+	if st.Size() > c.maxFileSize {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, c.maxFileSize+1))
+	if err != nil || int64(len(data)) > c.maxFileSize {
+		// the file has been changed, rewind it to serve from the disk
+		_, _ = f.Seek(0, io.SeekStart)
+		return nil
+	}
+
+	e := &cached{
+		key:         key,
+		name:        st.Name(),
+		modTime:     st.ModTime(),
+		data:        data,
+		etag:        h.Get("ETag"),
+		contentType: h.Get("Content-Type"),
+		encoding:    h.Get("Content-Encoding"),
+		expires:     time.Now().Add(c.ttl),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	c.entries[key] = c.lru.PushFront(e)
+	c.size += int64(len(data))
+
+	// evict the least recently used files
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+
+	c.stats.Bytes.Store(c.size)
+	return e
+}
+
+// purge removes all the files, returns the number of removed files.
+func (c *cache) purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.entries)
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.size = 0
+	c.stats.Bytes.Store(0)
+
+	return n
+}
+
+// remove should be called under the lock.
+func (c *cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*cached)
+	delete(c.entries, e.key)
+	c.size -= int64(len(e.data))
+	c.stats.Bytes.Store(c.size)
+}
+
+// serve writes the cached file, the conditional and range requests are handled by the ServeContent.
+func (e *cached) serve(w http.ResponseWriter, r *http.Request) {
+	if e.contentType != "" {
+		w.Header().Set("Content-Type", e.contentType)
+	}
+
+	if e.encoding != "" {
+		w.Header().Set("Content-Encoding", e.encoding)
+	}
+
+	if e.etag != "" {
+		w.Header().Set("ETag", e.etag)
+	}
+
+	http.ServeContent(w, r, e.name, e.modTime, bytes.NewReader(e.data))
+}
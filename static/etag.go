@@ -0,0 +1,58 @@
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+)
+
+// hashKey identifies the file version, the hash is recalculated when the file changes.
+type hashKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// etags calculates the strong ETags of the files.
+type etags struct {
+	mode   config.ETagMode
+	hashes sync.Map
+}
+
+// etag returns the quoted ETag of the file, empty if disabled or failed.
+func (e *etags) etag(fp string, f io.ReadSeeker, st fs.FileInfo) string {
+	// the embedded files have no modification time
+	mode := e.mode
+	if mode == config.ETagMtime && st.ModTime().IsZero() {
+		mode = config.ETagHash
+	}
+
+	switch mode {
+	case config.ETagOff:
+		return ""
+	case config.ETagHash:
+		key := hashKey{path: fp, size: st.Size(), modTime: st.ModTime()}
+		if tag, ok := e.hashes.Load(key); ok {
+			return tag.(string)
+		}
+
+		h := sha256.New()
+		_, err := io.Copy(h, f)
+		// rewind the file to serve it
+		if _, errS := f.Seek(0, io.SeekStart); err != nil || errS != nil {
+			return ""
+		}
+
+		tag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+		e.hashes.Store(key, tag)
+		return tag
+	default:
+		return `"` + strconv.FormatInt(st.Size(), 16) + "-" + strconv.FormatInt(st.ModTime().UnixNano(), 16) + `"`
+	}
+}
@@ -0,0 +1,119 @@
+package static
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	"go.uber.org/zap"
+)
+
+// diskFS is the document root on the disk with the symlinks policy applied.
+type diskFS struct {
+	root     string
+	symlinks config.SymlinkPolicy
+	log      *zap.Logger
+}
+
+func newDiskFS(dir string, symlinks config.SymlinkPolicy, log *zap.Logger) *diskFS {
+	d := &diskFS{root: dir, symlinks: symlinks, log: log}
+	// the root is resolved unless all the symlinks are followed
+	if symlinks != config.SymlinksAlways {
+		root, err := filepath.Abs(dir)
+		if err == nil {
+			root, err = filepath.EvalSymlinks(root)
+		}
+
+		if err != nil {
+			log.Warn("failed to resolve the static root", zap.String("dir", dir), zap.Error(err))
+		} else {
+			d.root = root
+		}
+	}
+
+	return d
+}
+
+// Open opens the file, the files with the not allowed symlinks are not found.
+func (d *diskFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	full, ok := d.resolve(filepath.Join(d.root, filepath.FromSlash(name)))
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return os.Open(full)
+}
+
+// resolve returns the real path of the file according to the symlinks policy, false if the file is not served.
+func (d *diskFS) resolve(full string) (string, bool) {
+	if d.symlinks == config.SymlinksAlways || d.symlinks == "" {
+		return full, true
+	}
+
+	real, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		return "", false
+	}
+
+	if d.symlinks == config.SymlinksNever {
+		return real, real == full
+	}
+
+	rel, err := filepath.Rel(d.root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		d.log.Debug("symlink outside of the static root", zap.String("path", full), zap.String("real", real))
+		return "", false
+	}
+
+	return real, true
+}
+
+// OpenArchive reads the zip archive into the memory, so the files are served without the open file descriptor.
+func OpenArchive(name string) (fs.FS, error) {
+	const op = errors.Op("static_open_archive")
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return zr, nil
+}
+
+// fsName converts the cleaned URL path into the fs.FS name.
+func fsName(fp string) string {
+	name := strings.TrimPrefix(fp, "/")
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
+
+// seeker returns the seekable content of the file, the archived files are read into the memory.
+func seeker(f fs.File) (io.ReadSeeker, error) {
+	if rs, ok := f.(io.ReadSeeker); ok {
+		return rs, nil
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
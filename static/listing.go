@@ -0,0 +1,89 @@
+package static
+
+import (
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// noListing is the marker file which disables the listing of the directory.
+const noListing string = ".nolisting"
+
+// listingPage renders the HTML directory listing.
+var listingPage = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{range .Entries}}<tr><td><a href="{{.Name}}{{if .Dir}}/{{end}}">{{.Name}}{{if .Dir}}/{{end}}</a></td><td>{{if not .Dir}}{{.Size}}{{end}}</td><td>{{.ModTime.Format "2006-01-02 15:04:05"}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`)) //nolint:gochecknoglobals
+
+// entry is the directory listing entry.
+type entry struct {
+	Name    string    `json:"name"`
+	Dir     bool      `json:"dir"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modified"`
+}
+
+// listing renders the directory listing, returns false if the listing is disabled for the directory.
+func (s *Static) listing(w http.ResponseWriter, r *http.Request, fp, name string) bool {
+	if _, err := fs.Stat(s.fsys, path.Join(name, noListing)); err == nil {
+		return false
+	}
+
+	dirEntries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		return false
+	}
+
+	entries := make([]entry, 0, len(dirEntries))
+	for i := 0; i < len(dirEntries); i++ {
+		name := dirEntries[i].Name()
+		// hidden, forbidden and not allowed files are not listed
+		rel := path.Join(fp, name)
+		if strings.HasPrefix(name, ".") || s.access.Forbidden(rel) || (!dirEntries[i].IsDir() && !s.access.Allow(rel)) {
+			continue
+		}
+
+		info, errI := dirEntries[i].Info()
+		if errI != nil {
+			continue
+		}
+
+		entries = append(entries, entry{Name: name, Dir: info.IsDir(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Dir != entries[j].Dir {
+			return entries[i].Dir
+		}
+
+		return entries[i].Name < entries[j].Name
+	})
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(entries)
+		return true
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = listingPage.Execute(w, struct {
+		Path    string
+		Entries []entry
+	}{Path: path.Clean(fp + "/"), Entries: entries})
+
+	return true
+}
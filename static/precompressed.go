@@ -0,0 +1,82 @@
+package static
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// sidecar is the pre-compressed version of the file.
+type sidecar struct {
+	encoding string
+	ext      string
+}
+
+// sidecars in the order of preference
+var sidecars = [...]sidecar{ //nolint:gochecknoglobals
+	{encoding: "br", ext: ".br"},
+	{encoding: "zstd", ext: ".zst"},
+	{encoding: "gzip", ext: ".gz"},
+}
+
+// openSidecar opens the pre-compressed file accepted by the client, returns nil if there is none.
+func (s *Static) openSidecar(name string, r *http.Request) (fs.File, fs.FileInfo, string) {
+	accept := r.Header.Get("Accept-Encoding")
+	if accept == "" {
+		return nil, nil, ""
+	}
+
+	for i := 0; i < len(sidecars); i++ {
+		if !accepts(accept, sidecars[i].encoding) {
+			continue
+		}
+
+		f, err := s.fsys.Open(name + sidecars[i].ext)
+		if err != nil {
+			continue
+		}
+
+		st, err := f.Stat()
+		if err != nil || !st.Mode().IsRegular() {
+			_ = f.Close()
+			continue
+		}
+
+		return f, st, sidecars[i].encoding
+	}
+
+	return nil, nil, ""
+}
+
+// accepts returns true if the Accept-Encoding header contains the encoding with the non-zero quality.
+func accepts(accept, encoding string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+
+		_, q, ok := strings.Cut(strings.ReplaceAll(params, " ", ""), "q=")
+		if !ok {
+			return true
+		}
+
+		qv, err := strconv.ParseFloat(q, 64)
+		return err == nil && qv > 0
+	}
+
+	return false
+}
+
+// setContentType sets the Content-Type of the original file, the ServeContent would detect the compressed one.
+func setContentType(w http.ResponseWriter, fp string) {
+	if ct := mime.TypeByExtension(path.Ext(fp)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+}
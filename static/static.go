@@ -0,0 +1,198 @@
+package static
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"go.uber.org/zap"
+)
+
+// Static serves the files from the document root before the requests are sent to the workers.
+type Static struct {
+	// document root, archive or the file system provided by another plugin
+	fsys   fs.FS
+	access *config.Access
+	etags  *etags
+	// serve the .br, .zst and .gz sidecar files
+	precompressed bool
+	// render the directory listings
+	listings bool
+	// single page application index and the paths passed to the workers
+	spaIndex   string
+	spaExclude []string
+	// in-memory cache of the small files, nil if disabled
+	cache *cache
+	log   *zap.Logger
+}
+
+// NewStatic creates the static files handler.
+func NewStatic(cfg *config.Static, log *zap.Logger) *Static {
+	s := &Static{
+		fsys:          cfg.FileSystem,
+		access:        cfg.Access(),
+		etags:         &etags{mode: cfg.ETag},
+		precompressed: cfg.Precompressed,
+		listings:      cfg.Listings,
+		spaExclude:    cfg.SPAExclude,
+		log:           log,
+	}
+
+	if cfg.Cache != nil {
+		s.cache = newCache(cfg.Cache)
+	}
+
+	if s.fsys == nil {
+		s.fsys = newDiskFS(cfg.Dir, cfg.Symlinks, log)
+	}
+
+	if cfg.SPAIndex != "" {
+		s.spaIndex = path.Clean("/" + cfg.SPAIndex)
+	}
+
+	return s
+}
+
+// Serve serves the requested file, returns false if the request should be passed to the workers.
+func (s *Static) Serve(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	// the cleaned path never goes above the root
+	fp := path.Clean("/" + r.URL.Path)
+	if s.serveFile(w, r, fp) {
+		return true
+	}
+
+	// single page application routes
+	if s.spaIndex != "" && s.spaRoute(r, fp) {
+		return s.serveFile(w, r, s.spaIndex)
+	}
+
+	return false
+}
+
+// serveFile serves the file or the directory listing, returns false if there is nothing to serve.
+func (s *Static) serveFile(w http.ResponseWriter, r *http.Request, fp string) bool {
+	var key string
+	if s.cache != nil {
+		key = cacheKey(fp, r, s.precompressed)
+		if e := s.cache.get(key); e != nil {
+			if s.precompressed {
+				w.Header().Add("Vary", "Accept-Encoding")
+			}
+
+			e.serve(w, r)
+			s.cache.stats.Hits.Add(1)
+			s.log.Debug("static file served from the cache", zap.String("path", fp))
+			return true
+		}
+	}
+
+	name := fsName(fp)
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return false
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	st, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	if st.IsDir() {
+		// directories are listed only with the trailing slash, the relative links would be broken otherwise
+		if !s.listings || !strings.HasSuffix(r.URL.Path, "/") || s.access.Forbidden(fp) {
+			return false
+		}
+
+		return s.listing(w, r, fp, name)
+	}
+
+	if !s.access.Allow(fp) {
+		return false
+	}
+
+	content, err := seeker(f)
+	if err != nil {
+		return false
+	}
+
+	etagPath := fp
+	if s.precompressed {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if cf, cst, encoding := s.openSidecar(name, r); cf != nil {
+			defer func() {
+				_ = cf.Close()
+			}()
+
+			cc, errS := seeker(cf)
+			if errS != nil {
+				return false
+			}
+
+			setContentType(w, fp)
+			w.Header().Set("Content-Encoding", encoding)
+			content, st, etagPath = cc, cst, fp+"."+encoding
+		}
+	}
+
+	// If-None-Match and If-Modified-Since are handled by the ServeContent with the 304 response
+	if tag := s.etags.etag(etagPath, content, st); tag != "" {
+		w.Header().Set("ETag", tag)
+	}
+
+	if s.cache != nil {
+		s.cache.stats.Misses.Add(1)
+		if e := s.cache.load(key, content, st, w.Header()); e != nil {
+			e.serve(w, r)
+			s.log.Debug("static file cached", zap.String("path", fp))
+			return true
+		}
+	}
+
+	http.ServeContent(w, r, st.Name(), st.ModTime(), content)
+	s.log.Debug("static file served", zap.String("path", fp))
+	return true
+}
+
+// spaRoute returns true for the browser navigation requests to the paths without an extension, except the
+// excluded prefixes (API).
+func (s *Static) spaRoute(r *http.Request, fp string) bool {
+	if r.Method != http.MethodGet || path.Ext(fp) != "" || !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		return false
+	}
+
+	for i := 0; i < len(s.spaExclude); i++ {
+		if strings.HasPrefix(fp, s.spaExclude[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CacheStats returns the cache counters, nil if the cache is disabled.
+func (s *Static) CacheStats() *CacheStats {
+	if s.cache == nil {
+		return nil
+	}
+
+	return s.cache.stats
+}
+
+// Purge removes all the files from the cache, returns the number of removed files.
+func (s *Static) Purge() int {
+	if s.cache == nil {
+		return 0
+	}
+
+	return s.cache.purge()
+}
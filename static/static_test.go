@@ -0,0 +1,278 @@
+package static
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goccy/go-json"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.css"), []byte("body{}"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.php"), []byte("<?php"), 0o600))
+
+	cfg := &config.Static{Dir: dir}
+	require.NoError(t, cfg.InitDefaults())
+	s := NewStatic(cfg, zap.NewNop())
+
+	tests := map[string]bool{
+		"/app.css":           true,
+		"/index.php":         false,
+		"/missing.css":       false,
+		"/":                  false,
+		"/../../app.css":     true,
+		"/%2e%2e/etc/passwd": false,
+	}
+
+	for uri, served := range tests {
+		w := httptest.NewRecorder()
+		assert.Equal(t, served, s.Serve(w, httptest.NewRequest(http.MethodGet, uri, nil)), uri)
+	}
+
+	assert.False(t, s.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/app.css", nil)))
+}
+
+func TestConditional(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("alert(1)"), 0o600))
+
+	for _, mode := range []config.ETagMode{config.ETagMtime, config.ETagHash} {
+		cfg := &config.Static{Dir: dir, ETag: mode}
+		require.NoError(t, cfg.InitDefaults())
+		s := NewStatic(cfg, zap.NewNop())
+
+		w := httptest.NewRecorder()
+		require.True(t, s.Serve(w, httptest.NewRequest(http.MethodGet, "/app.js", nil)))
+		etag := w.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+		assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+		assert.Equal(t, "alert(1)", w.Body.String())
+
+		r := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+		r.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		require.True(t, s.Serve(w, r))
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	}
+}
+
+func TestPrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("alert(1)"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js.gz"), []byte("gzipped"), 0o600))
+
+	cfg := &config.Static{Dir: dir, Precompressed: true}
+	require.NoError(t, cfg.InitDefaults())
+	s := NewStatic(cfg, zap.NewNop())
+
+	r := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	r.Header.Set("Accept-Encoding", "br;q=0, gzip")
+	w := httptest.NewRecorder()
+	require.True(t, s.Serve(w, r))
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, "text/javascript; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "gzipped", w.Body.String())
+
+	w = httptest.NewRecorder()
+	require.True(t, s.Serve(w, httptest.NewRequest(http.MethodGet, "/app.js", nil)))
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "alert(1)", w.Body.String())
+}
+
+func TestListings(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "private"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "private", noListing), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.css"), []byte("body{}"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.php"), []byte("<?php"), 0o600))
+
+	cfg := &config.Static{Dir: dir, Listings: true}
+	require.NoError(t, cfg.InitDefaults())
+	s := NewStatic(cfg, zap.NewNop())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	require.True(t, s.Serve(w, r))
+	assert.JSONEq(t, `["private","sub","app.css"]`, names(t, w.Body.Bytes()))
+
+	w = httptest.NewRecorder()
+	require.True(t, s.Serve(w, httptest.NewRequest(http.MethodGet, "/sub/", nil)))
+	assert.Contains(t, w.Body.String(), "Index of /sub")
+
+	assert.False(t, s.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/private/", nil)))
+	assert.False(t, s.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/sub", nil)))
+}
+
+func names(t *testing.T, body []byte) string {
+	var entries []entry
+	require.NoError(t, json.Unmarshal(body, &entries))
+
+	list := make([]string, 0, len(entries))
+	for i := 0; i < len(entries); i++ {
+		list = append(list, `"`+entries[i].Name+`"`)
+	}
+
+	return "[" + strings.Join(list, ",") + "]"
+}
+
+func TestSPAFallback(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<app>"), 0o600))
+
+	cfg := &config.Static{Dir: dir, SPAIndex: "index.html", SPAExclude: []string{"/api"}}
+	require.NoError(t, cfg.InitDefaults())
+	s := NewStatic(cfg, zap.NewNop())
+
+	serve := func(uri string) bool {
+		r := httptest.NewRequest(http.MethodGet, uri, nil)
+		r.Header.Set("Accept", "text/html,*/*")
+		return s.Serve(httptest.NewRecorder(), r)
+	}
+
+	assert.True(t, serve("/users/1"))
+	assert.False(t, serve("/api/users"))
+	assert.False(t, serve("/missing.js"))
+	assert.False(t, s.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil)))
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.css"), []byte("body{}"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.js"), []byte(strings.Repeat("a", 64)), 0o600))
+
+	cfg := &config.Static{Dir: dir, Cache: &config.StaticCache{MaxFileSize: 32}}
+	require.NoError(t, cfg.InitDefaults())
+	s := NewStatic(cfg, zap.NewNop())
+
+	get := func(uri string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		require.True(t, s.Serve(w, httptest.NewRequest(http.MethodGet, uri, nil)))
+		return w
+	}
+
+	first := get("/app.css")
+	// served from the memory after the file is changed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.css"), []byte("p{}"), 0o600))
+	second := get("/app.css")
+	assert.Equal(t, "body{}", second.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+	assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+
+	// too large to be cached
+	get("/big.js")
+	get("/big.js")
+
+	stats := s.CacheStats()
+	assert.Equal(t, uint64(1), stats.Hits.Load())
+	assert.Equal(t, uint64(3), stats.Misses.Load())
+	assert.Equal(t, int64(6), stats.Bytes.Load())
+
+	assert.Equal(t, 1, s.Purge())
+	assert.Equal(t, "p{}", get("/app.css").Body.String())
+}
+
+func TestRanges(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "video.mp4"), []byte("0123456789"), 0o600))
+
+	for _, cache := range []*config.StaticCache{nil, {}} {
+		cfg := &config.Static{Dir: dir, Cache: cache}
+		require.NoError(t, cfg.InitDefaults())
+		s := NewStatic(cfg, zap.NewNop())
+
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest(http.MethodGet, "/video.mp4", nil)
+			r.Header.Set("Range", "bytes=5-")
+			w := httptest.NewRecorder()
+			require.True(t, s.Serve(w, r))
+			assert.Equal(t, http.StatusPartialContent, w.Code)
+			assert.Equal(t, "bytes 5-9/10", w.Header().Get("Content-Range"))
+			assert.Equal(t, "56789", w.Body.String())
+		}
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o600))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.css"), []byte("body{}"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "app.css"), filepath.Join(dir, "inside.css")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "outside.txt")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "shared")))
+
+	tests := map[config.SymlinkPolicy]map[string]bool{
+		config.SymlinksNever:    {"/app.css": true, "/inside.css": false, "/outside.txt": false, "/shared/secret.txt": false},
+		config.SymlinksSameRoot: {"/app.css": true, "/inside.css": true, "/outside.txt": false, "/shared/secret.txt": false},
+		config.SymlinksAlways:   {"/app.css": true, "/inside.css": true, "/outside.txt": true, "/shared/secret.txt": true},
+	}
+
+	for policy, uris := range tests {
+		cfg := &config.Static{Dir: dir, Symlinks: policy}
+		require.NoError(t, cfg.InitDefaults())
+		s := NewStatic(cfg, zap.NewNop())
+
+		for uri, served := range uris {
+			assert.Equal(t, served, s.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, uri, nil)), string(policy)+uri)
+		}
+	}
+}
+
+func TestFileSystems(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	fw, err := zw.Create("assets/app.js")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte("app()"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	archive := filepath.Join(t.TempDir(), "public.zip")
+	require.NoError(t, os.WriteFile(archive, buf.Bytes(), 0o600))
+	zfs, err := OpenArchive(archive)
+	require.NoError(t, err)
+
+	// embed.FS files have no modification time
+	mfs := fstest.MapFS{"assets/app.js": {Data: []byte("app()")}}
+
+	for _, fsys := range []fs.FS{zfs, mfs} {
+		cfg := &config.Static{FS: "assets", FileSystem: fsys, Listings: true}
+		require.NoError(t, cfg.InitDefaults())
+		s := NewStatic(cfg, zap.NewNop())
+
+		w := httptest.NewRecorder()
+		require.True(t, s.Serve(w, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil)))
+		assert.Equal(t, "app()", w.Body.String())
+		assert.NotEmpty(t, w.Header().Get("ETag"))
+
+		r := httptest.NewRequest(http.MethodGet, "/assets/app.js", nil)
+		r.Header.Set("Range", "bytes=0-2")
+		w = httptest.NewRecorder()
+		require.True(t, s.Serve(w, r))
+		assert.Equal(t, "app", w.Body.String())
+
+		w = httptest.NewRecorder()
+		require.True(t, s.Serve(w, httptest.NewRequest(http.MethodGet, "/assets/", nil)))
+		assert.Contains(t, w.Body.String(), "app.js")
+
+		assert.False(t, s.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing.js", nil)))
+	}
+}
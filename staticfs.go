@@ -0,0 +1,30 @@
+package http
+
+import (
+	"github.com/roadrunner-server/errors"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/static"
+)
+
+// staticRoot sets the file system of the static root served from the archive or provided by another plugin.
+func (p *Plugin) staticRoot(cfg *config.Static) error {
+	const op = errors.Op("http_static_root")
+	switch {
+	case cfg.FS != "":
+		fsys, ok := p.staticFS[cfg.FS]
+		if !ok {
+			return errors.E(op, errors.Errorf("static fs plugin not found: %s", cfg.FS))
+		}
+
+		cfg.FileSystem = fsys
+	case cfg.Archive != "":
+		fsys, err := static.OpenArchive(cfg.Archive)
+		if err != nil {
+			return errors.E(op, err)
+		}
+
+		cfg.FileSystem = fsys
+	}
+
+	return nil
+}
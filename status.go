@@ -12,7 +12,7 @@ func (p *Plugin) Status() (*status.Status, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	workers := p.pool.Workers()
+	workers := p.pool.Load().Workers()
 
 	for i := 0; i < len(workers); i++ {
 		if workers[i].State().IsActive() {
@@ -29,10 +29,17 @@ func (p *Plugin) Status() (*status.Status, error) {
 
 // Ready return readiness status of the particular plugin
 func (p *Plugin) Ready() (*status.Status, error) {
+	// the handler lock is taken by the shutdown after the drain window
+	if p.draining.Load() {
+		return &status.Status{
+			Code: http.StatusServiceUnavailable,
+		}, nil
+	}
+
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	workers := p.pool.Workers()
+	workers := p.pool.Load().Workers()
 
 	for i := 0; i < len(workers); i++ {
 		// If state of the worker is ready (at least 1)
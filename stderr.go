@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"os/exec"
+	"sync/atomic"
+
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/goridge/v3/pkg/relay"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/roadrunner-server/pool/worker"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// stderrFactory spawns the workers with the stderr correlated with the requests: the worker logs the stderr lines with
+// the own logger, the exec windows are opened by the worker relay.
+type stderrFactory struct {
+	pool.Factory
+	stderr *handler.Stderr
+	log    *zap.Logger
+}
+
+// SpawnWorkerWithContext spawns the worker with the stderr logger and the relay opening the exec windows.
+func (f *stderrFactory) SpawnWorkerWithContext(ctx context.Context, cmd *exec.Cmd, options ...worker.Options) (*worker.Process, error) {
+	core := &stderrCore{pid: new(atomic.Int64), stderr: f.stderr}
+	log := f.log.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		core.Core = c
+		return core
+	}))
+
+	// the last logger option wins
+	w, err := f.Factory.SpawnWorkerWithContext(ctx, cmd, append(options, worker.WithLog(log))...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stderr read before the pid is known is logged as is
+	core.pid.Store(w.Pid())
+	w.AttachRelay(&stderrRelay{Relay: w.Relay(), pid: w.Pid(), stderr: f.stderr})
+	return w, nil
+}
+
+// stderrRelay opens the exec window of the worker when the request payload is sent.
+type stderrRelay struct {
+	relay.Relay
+	pid    int64
+	stderr *handler.Stderr
+}
+
+// Send opens the exec window before the payload frame is sent, the control frames are skipped.
+func (r *stderrRelay) Send(fr *frame.Frame) error {
+	if fr.ReadFlags()&frame.CONTROL == 0 {
+		options := fr.ReadOptions(fr.Header())
+		if len(options) == 1 && int(options[0]) <= len(fr.Payload()) {
+			r.stderr.Sent(r.pid, fr.Payload()[:options[0]])
+		}
+	}
+
+	return r.Relay.Send(fr)
+}
+
+// Close closes the exec window of the stopped worker.
+func (r *stderrRelay) Close() error {
+	r.stderr.Closed(r.pid)
+	return r.Relay.Close()
+}
+
+// stderrCore tags the stderr lines of the worker with the pid and the ID of the executed request. The worker logs the
+// stderr lines at the info level without fields.
+type stderrCore struct {
+	zapcore.Core
+	pid    *atomic.Int64
+	stderr *handler.Stderr
+	// with is set for the derived loggers, their entries are not the stderr
+	with bool
+}
+
+// With derives the core, the entries logged with the fields are not the stderr.
+func (c *stderrCore) With(fields []zapcore.Field) zapcore.Core {
+	return &stderrCore{Core: c.Core.With(fields), pid: c.pid, stderr: c.stderr, with: true}
+}
+
+// Check adds the core to the checked entry, the wrapped core would write the entry without the tags.
+func (c *stderrCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write tags the stderr line with the worker pid and the request ID.
+func (c *stderrCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	pid := c.pid.Load()
+	if c.with || ent.Level != zapcore.InfoLevel || len(fields) != 0 || pid == 0 {
+		return c.Core.Write(ent, fields)
+	}
+
+	fields = []zapcore.Field{zap.Int64("pid", pid)}
+	if id := c.stderr.Write(pid, ent.Message); id != "" {
+		fields = append(fields, zap.String(handler.RequestIDAttr, id))
+	}
+
+	return c.Core.Write(ent, fields)
+}
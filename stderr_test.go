@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/http/v5/handler"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWorkerStderr(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	cfg := &config.Config{
+		Uploads:           &config.Uploads{},
+		InternalErrorCode: 500,
+		RequestID:         &config.RequestID{Header: "X-Request-Id", WorkerStderr: true},
+		Relay:             &config.PoolRelay{Relay: config.RelayPipes},
+		Pool:              &pool.Config{NumWorkers: 1, AllocateTimeout: time.Second, DestroyTimeout: time.Second},
+	}
+	p := &Plugin{log: zap.New(core), cfg: cfg, server: &testServer{t: t}, stderr: handler.NewStderr()}
+
+	require.NoError(t, p.initRelay())
+	defer func() { _ = p.relay.Close() }()
+	pl, err := p.newPool(cfg.Pool)
+	require.NoError(t, err)
+	defer pl.Destroy(context.Background())
+
+	h, err := handler.NewHandler(cfg, pl, p.log)
+	require.NoError(t, err)
+	h.CaptureStderr(p.stderr)
+
+	serve := func(path, id string) {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("X-Request-Id", id)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+	}
+
+	serve("/fail?error=first", "req-1")
+	serve("/fail?error=second", "req-2")
+
+	// the stderr line is tagged with the request executed by the worker
+	for id, line := range map[string]string{"req-1": "PHP Fatal error: first", "req-2": "PHP Fatal error: second"} {
+		entries := logs.FilterMessage(line).All()
+		require.Len(t, entries, 1)
+		assert.Equal(t, id, entries[0].ContextMap()[handler.RequestIDAttr])
+		assert.NotZero(t, entries[0].ContextMap()["pid"])
+	}
+
+	// the captured stderr is attached to the error log of the request
+	entries := logs.FilterMessage("execute").All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "PHP Fatal error: first", entries[0].ContextMap()["stderr"])
+	assert.Equal(t, "PHP Fatal error: second", entries[1].ContextMap()["stderr"])
+}
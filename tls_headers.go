@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	httpsServer "github.com/roadrunner-server/http/v5/servers/https"
+)
+
+// tlsHeadersName is the internal middleware removing the TLS headers set by the HTTPS listener from the requests of
+// the other listeners, applied after the middleware of the listener
+const tlsHeadersName string = "rr_tls_headers"
+
+// tlsHeaders removes the client-provided TLS headers, so the workers can trust them regardless of the listener
+type tlsHeaders struct {
+	headers []string
+}
+
+func (th *tlsHeaders) Middleware(next http.Handler) http.Handler {
+	return bundledMw.StripHeaders(next, th.headers)
+}
+
+func (th *tlsHeaders) Name() string {
+	return tlsHeadersName
+}
+
+// initTLSHeaders registers the middleware when the HTTPS listener passes the client certificate details or the TLS
+// fingerprints
+func (p *Plugin) initTLSHeaders(cfg *httpsServer.SSL) {
+	if cfg == nil {
+		return
+	}
+
+	var headers []string
+	if cfg.ClientCert || cfg.ClientCertPEM {
+		headers = append(headers, bundledMw.ClientCertHeaders...)
+	}
+
+	if cfg.Fingerprint {
+		headers = append(headers, bundledMw.TLSFingerprintHeaders...)
+	}
+
+	if len(headers) == 0 {
+		return
+	}
+
+	p.tlsHeaders = &tlsHeaders{headers: headers}
+	p.mdwr[tlsHeadersName] = p.tlsHeaders
+}
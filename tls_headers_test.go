@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	httpServer "github.com/roadrunner-server/http/v5/servers/http11"
+	httpsServer "github.com/roadrunner-server/http/v5/servers/https"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSHeaders(t *testing.T) {
+	p := &Plugin{
+		cfg:  &config.Config{Middleware: []string{"auth"}},
+		mdwr: make(map[string]common.Middleware),
+	}
+
+	// nothing is set by the HTTPS listener
+	p.initTLSHeaders(&httpsServer.SSL{})
+	assert.Nil(t, p.tlsHeaders)
+	assert.Equal(t, []string{"auth"}, p.middlewareOrder(&httpServer.Server{}))
+
+	p.initTLSHeaders(&httpsServer.SSL{ClientCert: true})
+	require.NotNil(t, p.tlsHeaders)
+
+	// the headers are removed before the listener middleware, the HTTPS listener sets them itself
+	assert.Equal(t, []string{"auth", tlsHeadersName}, p.middlewareOrder(&httpServer.Server{}))
+	assert.Equal(t, []string{"auth"}, p.middlewareOrder(&httpsServer.Server{}))
+
+	var got http.Header
+	h := p.mdwr[tlsHeadersName].Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(bundledMw.ClientCertSubjectHeader, "CN=admin")
+	r.Header.Set(bundledMw.ClientCertHeader, ":AAAA:")
+	r.Header.Set("X-Custom", "kept")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Empty(t, got.Get(bundledMw.ClientCertSubjectHeader))
+	assert.Empty(t, got.Get(bundledMw.ClientCertHeader))
+	assert.Equal(t, "kept", got.Get("X-Custom"))
+
+	// the fingerprints set by the HTTPS listener
+	p.initTLSHeaders(&httpsServer.SSL{Fingerprint: true})
+	h = p.mdwr[tlsHeadersName].Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(bundledMw.JA3Header, "spoofed")
+	r.Header.Set(bundledMw.JA4Header, "spoofed")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Empty(t, got.Get(bundledMw.JA3Header))
+	assert.Empty(t, got.Get(bundledMw.JA4Header))
+}
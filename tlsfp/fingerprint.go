@@ -0,0 +1,319 @@
+// Package tlsfp computes the JA3 and JA4 fingerprints of the TLS clients from the ClientHello.
+package tlsfp
+
+import (
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	extServerName          uint16 = 0x0000
+	extSupportedGroups     uint16 = 0x000a
+	extPointFormats        uint16 = 0x000b
+	extSignatureAlgorithms uint16 = 0x000d
+	extALPN                uint16 = 0x0010
+	extSupportedVersions   uint16 = 0x002b
+)
+
+// Fingerprint is the TLS fingerprint of the client.
+type Fingerprint struct {
+	// JA3 is the JA3 string: version,ciphers,extensions,curves,point formats
+	JA3 string
+	// JA3Hash is the MD5 of the JA3 string, the commonly used form
+	JA3Hash string
+	// JA4 is the JA4 fingerprint (TCP)
+	JA4 string
+}
+
+// clientHello contains the ClientHello fields used by the fingerprints, the GREASE values are removed.
+type clientHello struct {
+	version    uint16
+	ciphers    []uint16
+	extensions []uint16
+	curves     []uint16
+	points     []uint8
+	sigAlgs    []uint16
+	versions   []uint16
+	alpn       string
+	sni        bool
+}
+
+// isGREASE checks the RFC 8701 reserved values (0x0a0a, 0x1a1a ... 0xfafa).
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// parseClientHello parses the handshake message body (without the type and the length).
+func parseClientHello(data []byte) (*clientHello, bool) {
+	s := cryptobyte.String(data)
+	ch := &clientHello{}
+
+	var random, sessionID, ciphers, compression cryptobyte.String
+	if !s.ReadUint16(&ch.version) || !s.ReadBytes((*[]byte)(&random), 32) ||
+		!s.ReadUint8LengthPrefixed(&sessionID) || !s.ReadUint16LengthPrefixed(&ciphers) ||
+		!s.ReadUint8LengthPrefixed(&compression) {
+		return nil, false
+	}
+
+	for !ciphers.Empty() {
+		var c uint16
+		if !ciphers.ReadUint16(&c) {
+			return nil, false
+		}
+
+		if !isGREASE(c) {
+			ch.ciphers = append(ch.ciphers, c)
+		}
+	}
+
+	// no extensions
+	if s.Empty() {
+		return ch, true
+	}
+
+	var extensions cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&extensions) {
+		return nil, false
+	}
+
+	for !extensions.Empty() {
+		var ext uint16
+		var body cryptobyte.String
+		if !extensions.ReadUint16(&ext) || !extensions.ReadUint16LengthPrefixed(&body) {
+			return nil, false
+		}
+
+		if isGREASE(ext) {
+			continue
+		}
+
+		ch.extensions = append(ch.extensions, ext)
+		if !ch.parseExtension(ext, body) {
+			return nil, false
+		}
+	}
+
+	return ch, true
+}
+
+func (ch *clientHello) parseExtension(ext uint16, body cryptobyte.String) bool {
+	var list cryptobyte.String
+	switch ext {
+	case extServerName:
+		ch.sni = true
+	case extSupportedGroups:
+		if !body.ReadUint16LengthPrefixed(&list) {
+			return false
+		}
+		return readUint16s(list, &ch.curves)
+	case extPointFormats:
+		if !body.ReadUint8LengthPrefixed(&list) {
+			return false
+		}
+		ch.points = append(ch.points, list...)
+	case extSignatureAlgorithms:
+		if !body.ReadUint16LengthPrefixed(&list) {
+			return false
+		}
+		return readUint16s(list, &ch.sigAlgs)
+	case extSupportedVersions:
+		if !body.ReadUint8LengthPrefixed(&list) {
+			return false
+		}
+		return readUint16s(list, &ch.versions)
+	case extALPN:
+		var proto cryptobyte.String
+		// only the first protocol is used
+		if !body.ReadUint16LengthPrefixed(&list) || !list.ReadUint8LengthPrefixed(&proto) {
+			return false
+		}
+		ch.alpn = string(proto)
+	}
+
+	return true
+}
+
+// readUint16s reads the list skipping the GREASE values.
+func readUint16s(s cryptobyte.String, out *[]uint16) bool {
+	for !s.Empty() {
+		var v uint16
+		if !s.ReadUint16(&v) {
+			return false
+		}
+
+		if !isGREASE(v) {
+			*out = append(*out, v)
+		}
+	}
+
+	return true
+}
+
+func (ch *clientHello) fingerprint() *Fingerprint {
+	ja3 := ch.ja3()
+	sum := md5.Sum([]byte(ja3)) //nolint:gosec
+
+	return &Fingerprint{
+		JA3:     ja3,
+		JA3Hash: hex.EncodeToString(sum[:]),
+		JA4:     ch.ja4(),
+	}
+}
+
+// ja3 builds the JA3 string with the decimal values: SSLVersion,Ciphers,Extensions,EllipticCurves,PointFormats.
+func (ch *clientHello) ja3() string {
+	var sb strings.Builder
+	sb.WriteString(strconv.Itoa(int(ch.version)))
+	sb.WriteByte(',')
+	joinDecimal(&sb, ch.ciphers)
+	sb.WriteByte(',')
+	joinDecimal(&sb, ch.extensions)
+	sb.WriteByte(',')
+	joinDecimal(&sb, ch.curves)
+	sb.WriteByte(',')
+	for i := 0; i < len(ch.points); i++ {
+		if i > 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteString(strconv.Itoa(int(ch.points[i])))
+	}
+
+	return sb.String()
+}
+
+// ja4 builds the JA4 fingerprint: t<version><sni><ciphers count><extensions count><alpn>_<ciphers hash>_<extensions hash>.
+func (ch *clientHello) ja4() string {
+	var sb strings.Builder
+	sb.WriteByte('t')
+	sb.WriteString(ch.ja4Version())
+	if ch.sni {
+		sb.WriteByte('d')
+	} else {
+		sb.WriteByte('i')
+	}
+	sb.WriteString(count(len(ch.ciphers)))
+	sb.WriteString(count(len(ch.extensions)))
+	sb.WriteString(ch.ja4ALPN())
+
+	ciphers := slices.Clone(ch.ciphers)
+	slices.Sort(ciphers)
+	sb.WriteByte('_')
+	sb.WriteString(truncatedHash(joinHex(ciphers)))
+
+	// the SNI and the ALPN are excluded from the sorted extensions
+	extensions := make([]uint16, 0, len(ch.extensions))
+	for i := 0; i < len(ch.extensions); i++ {
+		if ch.extensions[i] != extServerName && ch.extensions[i] != extALPN {
+			extensions = append(extensions, ch.extensions[i])
+		}
+	}
+	slices.Sort(extensions)
+
+	sb.WriteByte('_')
+	if len(extensions) == 0 {
+		sb.WriteString(emptyHash)
+		return sb.String()
+	}
+
+	// the signature algorithms are kept in the original order
+	ext := joinHex(extensions)
+	if len(ch.sigAlgs) > 0 {
+		ext += "_" + joinHex(ch.sigAlgs)
+	}
+	sb.WriteString(truncatedHash(ext))
+
+	return sb.String()
+}
+
+func (ch *clientHello) ja4Version() string {
+	version := ch.version
+	if len(ch.versions) > 0 {
+		version = slices.Max(ch.versions)
+	}
+
+	switch version {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	default:
+		return "00"
+	}
+}
+
+// ja4ALPN returns the first and the last characters of the first ALPN protocol, the hex of the first and the last
+// bytes for the non-alphanumeric values.
+func (ch *clientHello) ja4ALPN() string {
+	if ch.alpn == "" {
+		return "00"
+	}
+
+	first, last := ch.alpn[0], ch.alpn[len(ch.alpn)-1]
+	if !isAlnum(first) || !isAlnum(last) {
+		return hex.EncodeToString([]byte{first})[:1] + hex.EncodeToString([]byte{last})[1:]
+	}
+
+	return string([]byte{first, last})
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// count formats the number of the ciphers or the extensions with 2 digits, max 99.
+func count(n int) string {
+	if n > 99 {
+		n = 99
+	}
+
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+
+	return strconv.Itoa(n)
+}
+
+const emptyHash = "000000000000"
+
+// truncatedHash returns the first 12 hex characters of the SHA256 of the value.
+func truncatedHash(s string) string {
+	if s == "" {
+		return emptyHash
+	}
+
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func joinDecimal(sb *strings.Builder, values []uint16) {
+	for i := 0; i < len(values); i++ {
+		if i > 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteString(strconv.Itoa(int(values[i])))
+	}
+}
+
+func joinHex(values []uint16) string {
+	var sb strings.Builder
+	for i := 0; i < len(values); i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(hex.EncodeToString([]byte{byte(values[i] >> 8), byte(values[i])}))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,127 @@
+package tlsfp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// clientHelloMessage builds the handshake message of the JA4 specification example with the GREASE values.
+func clientHelloMessage() []byte {
+	ciphers := []uint16{0x0a0a, 0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xc02c, 0xc030, 0xcca9, 0xcca8, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035}
+	extensions := []uint16{0x1a1a, 0x0000, 0x0017, 0xff01, 0x000a, 0x000b, 0x0023, 0x0010, 0x0005, 0x000d, 0x0012, 0x0033, 0x002d, 0x002b, 0x001b, 0x4469, 0x0015}
+
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint8(handshakeTypeHello)
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(tls.VersionTLS12)
+		b.AddBytes(make([]byte, 32))
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(make([]byte, 32)) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, c := range ciphers {
+				b.AddUint16(c)
+			}
+		})
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, ext := range extensions {
+				b.AddUint16(ext)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					switch ext {
+					case extServerName:
+						b.AddBytes([]byte{0, 14, 0, 0, 11, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm'})
+					case extSupportedGroups:
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint16(0x2a2a)
+							b.AddUint16(0x001d)
+							b.AddUint16(0x0017)
+							b.AddUint16(0x0018)
+						})
+					case extPointFormats:
+						b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+					case extSignatureAlgorithms:
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							for _, s := range []uint16{0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601} {
+								b.AddUint16(s)
+							}
+						})
+					case extALPN:
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("h2")) })
+							b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("http/1.1")) })
+						})
+					case extSupportedVersions:
+						b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint16(0x3a3a)
+							b.AddUint16(tls.VersionTLS13)
+							b.AddUint16(tls.VersionTLS12)
+						})
+					}
+				})
+			}
+		})
+	})
+
+	return b.BytesOrPanic()
+}
+
+func record(fragment []byte) []byte {
+	return append([]byte{recordTypeHandshake, 3, 1, byte(len(fragment) >> 8), byte(len(fragment))}, fragment...)
+}
+
+func TestFingerprint(t *testing.T) {
+	msg := clientHelloMessage()
+
+	// the message is fragmented across two records and read byte by byte
+	data := append(record(msg[:100]), record(msg[100:])...)
+	c := &Conn{}
+	for i := 0; i < len(data); i++ {
+		require.Nil(t, c.Fingerprint())
+		c.record(data[i : i+1])
+	}
+
+	fp := c.Fingerprint()
+	require.NotNil(t, fp)
+	assert.Equal(t, "t13d1516h2_8daaf6152771_e5627efa2ab1", fp.JA4)
+	assert.Equal(t, "771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,"+
+		"0-23-65281-10-11-35-16-5-13-18-51-45-43-27-17513-21,29-23-24,0", fp.JA3)
+	assert.Len(t, fp.JA3Hash, 32)
+
+	// not a TLS connection
+	c = &Conn{}
+	c.record([]byte("GET / HTTP/1.1\r\n"))
+	assert.True(t, c.done)
+	assert.Nil(t, c.Fingerprint())
+}
+
+func TestListener(t *testing.T) {
+	server, client := net.Pipe()
+	go func() {
+		_ = tls.Client(client, &tls.Config{ServerName: "example.com", NextProtos: []string{"h2"}}).Handshake() //nolint:gosec
+	}()
+	t.Cleanup(func() {
+		_ = server.Close()
+		_ = client.Close()
+	})
+
+	c := &Conn{Conn: server}
+	buf := make([]byte, 1024)
+	for c.Fingerprint() == nil && !c.done {
+		_, err := c.Read(buf)
+		require.NoError(t, err)
+	}
+
+	ctx := ConnContext(context.Background(), tls.Server(c, &tls.Config{})) //nolint:gosec
+	fp := FromContext(ctx)
+	require.NotNil(t, fp)
+	assert.True(t, strings.HasPrefix(fp.JA4, "t13d"), fp.JA4)
+	assert.True(t, strings.HasPrefix(fp.JA3, "771,"), fp.JA3)
+
+	assert.Nil(t, FromContext(context.Background()))
+}
@@ -0,0 +1,138 @@
+package tlsfp
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+)
+
+const (
+	recordTypeHandshake uint8 = 22
+	handshakeTypeHello  uint8 = 1
+	recordHeaderLen           = 5
+	handshakeHeaderLen        = 4
+	// the ClientHello with the post-quantum key shares is ~2KB, the larger ones are not fingerprinted
+	maxClientHelloLen = 16 * 1024
+)
+
+// Listener records the ClientHello of the accepted connections.
+type Listener struct {
+	net.Listener
+}
+
+// NewListener wraps the TCP listener, it must be used below the TLS listener.
+func NewListener(l net.Listener) *Listener {
+	return &Listener{Listener: l}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{Conn: c}, nil
+}
+
+// Conn records the first bytes read from the connection until the ClientHello is parsed.
+type Conn struct {
+	net.Conn
+	// read by the handshake only
+	buf  []byte
+	done bool
+
+	fp atomic.Pointer[Fingerprint]
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 && !c.done {
+		c.record(p[:n])
+	}
+
+	return n, err
+}
+
+// Fingerprint returns the client fingerprint, nil if the ClientHello was not received or is malformed.
+func (c *Conn) Fingerprint() *Fingerprint {
+	return c.fp.Load()
+}
+
+func (c *Conn) record(p []byte) {
+	c.buf = append(c.buf, p...)
+
+	hello, complete, ok := readClientHello(c.buf)
+	if ok && !complete && len(c.buf) <= maxClientHelloLen {
+		return
+	}
+
+	c.done = true
+	c.buf = nil
+	if !ok {
+		return
+	}
+
+	ch, ok := parseClientHello(hello)
+	if ok {
+		c.fp.Store(ch.fingerprint())
+	}
+}
+
+// readClientHello assembles the ClientHello body from the handshake records, the message might be fragmented
+// across several records.
+func readClientHello(buf []byte) ([]byte, bool, bool) {
+	var msg []byte
+	for len(buf) >= recordHeaderLen {
+		if buf[0] != recordTypeHandshake {
+			return nil, false, false
+		}
+
+		n := int(buf[3])<<8 | int(buf[4])
+		if len(buf) < recordHeaderLen+n {
+			break
+		}
+
+		msg = append(msg, buf[recordHeaderLen:recordHeaderLen+n]...)
+		buf = buf[recordHeaderLen+n:]
+
+		if len(msg) < handshakeHeaderLen {
+			continue
+		}
+
+		if msg[0] != handshakeTypeHello {
+			return nil, false, false
+		}
+
+		length := int(msg[1])<<16 | int(msg[2])<<8 | int(msg[3])
+		if len(msg) >= handshakeHeaderLen+length {
+			return msg[handshakeHeaderLen : handshakeHeaderLen+length], true, true
+		}
+	}
+
+	return nil, false, true
+}
+
+type connKey struct{}
+
+// ConnContext attaches the connection to the context, used with the http.Server ConnContext.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	if nc, ok := c.(interface{ NetConn() net.Conn }); ok {
+		c = nc.NetConn()
+	}
+
+	if fc, ok := c.(*Conn); ok {
+		return context.WithValue(ctx, connKey{}, fc)
+	}
+
+	return ctx
+}
+
+// FromContext returns the client fingerprint, the handshake is done before the requests are served.
+func FromContext(ctx context.Context) *Fingerprint {
+	c, ok := ctx.Value(connKey{}).(*Conn)
+	if !ok {
+		return nil
+	}
+
+	return c.Fingerprint()
+}
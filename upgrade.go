@@ -0,0 +1,94 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/common"
+	"go.uber.org/zap"
+)
+
+// addUpgrader registers the protocols claimed by the plugin, the first plugin claiming the protocol wins.
+func (p *Plugin) addUpgrader(u common.Upgrader) {
+	protocols := u.Protocols()
+	for i := 0; i < len(protocols); i++ {
+		name := strings.ToLower(strings.TrimSpace(protocols[i]))
+		if prev, ok := p.upgraders[name]; ok {
+			p.log.Warn("upgrade protocol is already claimed", zap.String("protocol", name), zap.String("plugin", prev.Name()), zap.String("ignored", u.Name()))
+			continue
+		}
+
+		p.upgraders[name] = u
+	}
+}
+
+// upgrade hands the HTTP/1.1 connection over to the plugin claiming one of the requested Upgrade protocols, false
+// when the request is not upgraded.
+func (p *Plugin) upgrade(w http.ResponseWriter, r *http.Request) bool {
+	if len(p.upgraders) == 0 || r.ProtoMajor != 1 || !hasToken(r.Header.Values("Connection"), "upgrade") {
+		return false
+	}
+
+	u, protocol := p.upgrader(r.Header.Values("Upgrade"))
+	if u == nil {
+		return false
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+		return true
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		p.log.Error("failed to hijack the upgraded connection", zap.String("protocol", protocol), zap.Error(err))
+		return true
+	}
+
+	// the server read and write timeouts are not applied to the custom protocol
+	_ = conn.SetDeadline(time.Time{})
+
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + protocol + "\r\n\r\n")
+	err = rw.Flush()
+	if err != nil {
+		p.log.Debug("failed to switch the protocol", zap.String("protocol", protocol), zap.Error(err))
+		_ = conn.Close()
+		return true
+	}
+
+	p.log.Debug("connection upgraded", zap.String("protocol", protocol), zap.String("plugin", u.Name()), zap.String("remote", r.RemoteAddr))
+	u.Upgrade(conn, rw, r)
+
+	return true
+}
+
+// upgrader returns the plugin for the first claimed protocol in the client preference order and the protocol as
+// requested, the version (mqtt/5) is not matched.
+func (p *Plugin) upgrader(values []string) (common.Upgrader, string) {
+	for i := 0; i < len(values); i++ {
+		for _, protocol := range strings.Split(values[i], ",") {
+			protocol = strings.TrimSpace(protocol)
+			name, _, _ := strings.Cut(protocol, "/")
+			if u, ok := p.upgraders[strings.ToLower(name)]; ok {
+				return u, protocol
+			}
+		}
+	}
+
+	return nil, ""
+}
+
+// hasToken reports whether the comma-separated header values contain the token, case-insensitive.
+func hasToken(values []string, token string) bool {
+	for i := 0; i < len(values); i++ {
+		for _, v := range strings.Split(values[i], ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// echoUpgrader answers the first line of the upgraded connection with the line prefixed by its name.
+type echoUpgrader struct {
+	name      string
+	protocols []string
+}
+
+func (e *echoUpgrader) Protocols() []string { return e.protocols }
+func (e *echoUpgrader) Name() string        { return e.name }
+
+func (e *echoUpgrader) Upgrade(conn net.Conn, rw *bufio.ReadWriter, _ *http.Request) {
+	defer func() { _ = conn.Close() }()
+	line, err := rw.ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	_, _ = rw.WriteString(e.name + ":" + line)
+	_ = rw.Flush()
+}
+
+func TestUpgrade(t *testing.T) {
+	p := &Plugin{log: zap.NewNop(), upgraders: make(map[string]common.Upgrader)}
+	p.addUpgrader(&echoUpgrader{name: "broker", protocols: []string{"MQTT", "amqp"}})
+	// the first plugin claiming the protocol wins
+	p.addUpgrader(&echoUpgrader{name: "other", protocols: []string{"mqtt", "stomp"}})
+	assert.Equal(t, "broker", p.upgraders["mqtt"].Name())
+	assert.Equal(t, "other", p.upgraders["stomp"].Name())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.upgrade(w, r) {
+			_, _ = w.Write([]byte("http"))
+		}
+	}))
+	defer srv.Close()
+
+	// send writes the upgrade request and the first line of the custom protocol
+	send := func(connection, upgrade string) (*http.Response, string) {
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: " + connection + "\r\nUpgrade: " + upgrade + "\r\n\r\nhello\n"))
+		require.NoError(t, err)
+
+		br := bufio.NewReader(conn)
+		rsp, err := http.ReadResponse(br, nil)
+		require.NoError(t, err)
+		if rsp.StatusCode != http.StatusSwitchingProtocols {
+			body, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+			return rsp, string(body)
+		}
+
+		line, err := br.ReadString('\n')
+		require.NoError(t, err)
+		return rsp, line
+	}
+
+	// the client preference order, the version is kept in the response
+	rsp, line := send("keep-alive, Upgrade", "h2c, mqtt/5, amqp")
+	assert.Equal(t, http.StatusSwitchingProtocols, rsp.StatusCode)
+	assert.Equal(t, "mqtt/5", rsp.Header.Get("Upgrade"))
+	assert.Equal(t, "broker:hello\n", line)
+
+	_, line = send("upgrade", "STOMP")
+	assert.Equal(t, "other:hello\n", line)
+
+	// the unclaimed protocols and the requests without the Connection: upgrade are served by the handler
+	rsp, body := send("upgrade", "websocket")
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, "http", body)
+
+	rsp, body = send("keep-alive", "mqtt")
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, "http", body)
+}
@@ -0,0 +1,253 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/roadrunner-server/http/v5/config"
+	bundledMw "github.com/roadrunner-server/http/v5/middleware"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidateConfig validates the candidate configuration without applying it, so the configuration changes can be
+// checked before the deploy. The candidate is the RR configuration (YAML or JSON) with the http section, the
+// ${VAR} environment variables are not expanded: the candidate comes from the RPC client and must not read the
+// environment of the server. Besides the validation done on Init, the listen addresses are bound,
+// the certificates are parsed, the uploads directory is checked for writes and the middleware names are resolved.
+// The addresses the plugin is listening on are not bound. Returns the found problems, empty if the configuration is
+// valid.
+func (p *Plugin) ValidateConfig(data []byte) []string {
+	var problems []string
+	cfg, err := parseCandidate(data)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+
+	if cfg.AccessLogFormat != "" {
+		_, err = bundledMw.ParseLogFormat(cfg.AccessLogFormat)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	problems = append(problems, p.checkAddresses(cfg)...)
+	problems = append(problems, checkCertificates(cfg)...)
+
+	err = checkWritable(cfg.Uploads.Dir)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("uploads dir %s is not writable: %v", cfg.Uploads.Dir, err))
+	}
+
+	for i := 0; i < len(cfg.Middleware); i++ {
+		if _, ok := p.mdwr[cfg.Middleware[i]]; !ok {
+			problems = append(problems, fmt.Sprintf("middleware %s is not found, the plugin is not registered", cfg.Middleware[i]))
+		}
+	}
+
+	return problems
+}
+
+// parseCandidate reads and validates the http section of the configuration.
+func parseCandidate(data []byte) (*config.Config, error) {
+	var raw map[string]any
+	err := yaml.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the configuration: %w", err)
+	}
+
+	rc := rawConfig(raw)
+	if !rc.Has(PluginName) {
+		return nil, fmt.Errorf("the %s section is missing", PluginName)
+	}
+
+	cfg, err := unmarshalConfig(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.InitDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// checkAddresses binds the listen addresses of the candidate which are not used by the running servers.
+func (p *Plugin) checkAddresses(cfg *config.Config) []string {
+	var problems []string
+	check := func(network, address string, current func(*config.Config) string) {
+		if address == "" || (p.cfg != nil && current(p.cfg) == address) {
+			return
+		}
+
+		err := checkBind(network, address)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("address %s is not bindable: %v", address, err))
+		}
+	}
+
+	check("tcp", cfg.Address, func(c *config.Config) string { return c.Address })
+	if cfg.EnableTLS() {
+		check("tcp", cfg.SSLConfig.Address, func(c *config.Config) string {
+			if c.SSLConfig == nil {
+				return ""
+			}
+
+			return c.SSLConfig.Address
+		})
+	}
+
+	if cfg.EnableFCGI() {
+		check("tcp", cfg.FCGIConfig.Address, func(c *config.Config) string {
+			if c.FCGIConfig == nil {
+				return ""
+			}
+
+			return c.FCGIConfig.Address
+		})
+	}
+
+	if cfg.EnableHTTP3() {
+		check("udp", cfg.HTTP3Config.Address, func(c *config.Config) string {
+			if c.HTTP3Config == nil {
+				return ""
+			}
+
+			return c.HTTP3Config.Address
+		})
+	}
+
+	return problems
+}
+
+// checkBind binds and closes the address, the unix sockets are not created, only the directory is checked.
+func checkBind(network, address string) error {
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		return checkWritable(filepath.Dir(path))
+	}
+
+	address = strings.TrimPrefix(address, "tcp://")
+	if network == "udp" {
+		pc, err := net.ListenPacket(network, address)
+		if err != nil {
+			return err
+		}
+
+		return pc.Close()
+	}
+
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	return l.Close()
+}
+
+// checkCertificates parses the certificates and the keys, the ACME certificates are issued on start.
+func checkCertificates(cfg *config.Config) []string {
+	var problems []string
+	if cfg.EnableTLS() && !cfg.SSLConfig.EnableACME() {
+		_, err := tls.LoadX509KeyPair(cfg.SSLConfig.Cert, cfg.SSLConfig.Key)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ssl certificate: %v", err))
+		}
+	}
+
+	if cfg.EnableTLS() && cfg.SSLConfig.RootCA != "" {
+		ca, err := os.ReadFile(cfg.SSLConfig.RootCA)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ssl root_ca: %v", err))
+		} else if !x509.NewCertPool().AppendCertsFromPEM(ca) {
+			problems = append(problems, fmt.Sprintf("ssl root_ca %s: no certificates found", cfg.SSLConfig.RootCA))
+		}
+	}
+
+	if cfg.EnableHTTP3() {
+		_, err := tls.LoadX509KeyPair(cfg.HTTP3Config.Cert, cfg.HTTP3Config.Key)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("http3 certificate: %v", err))
+		}
+	}
+
+	return problems
+}
+
+// checkWritable creates and removes the temporary file in the directory.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".rr-validate-*")
+	if err != nil {
+		return err
+	}
+
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// rawConfig is the parsed candidate configuration, the keys are decoded as by the config plugin.
+type rawConfig map[string]any
+
+func (c rawConfig) Experimental() bool {
+	return false
+}
+
+func (c rawConfig) Has(name string) bool {
+	_, ok := c.get(name)
+	return ok
+}
+
+func (c rawConfig) UnmarshalKey(name string, out any) error {
+	v, ok := c.get(name)
+	if !ok {
+		return nil
+	}
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Result:           out,
+	})
+	if err != nil {
+		return err
+	}
+
+	return dec.Decode(v)
+}
+
+// get returns the value of the dotted key, the keys are matched case-insensitively.
+func (c rawConfig) get(name string) (any, bool) {
+	var v any = map[string]any(c)
+	for _, part := range strings.Split(name, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		found := false
+		for k, val := range m {
+			if strings.EqualFold(k, part) {
+				v, found = val, true
+				break
+			}
+		}
+
+		if !found {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
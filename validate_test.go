@@ -0,0 +1,67 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/roadrunner-server/http/v5/common"
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+	p := &Plugin{mdwr: map[string]common.Middleware{"gzip": nil}}
+
+	candidate := func(address, extra string) []byte {
+		return []byte(fmt.Sprintf("http:\n  address: %s\n  uploads:\n    dir: %s\n%s", address, dir, extra))
+	}
+
+	assert.Empty(t, p.ValidateConfig(candidate("127.0.0.1:0", "  middleware: [gzip]\n")))
+
+	problems := p.ValidateConfig([]byte("http: ["))
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "failed to parse the configuration")
+	assert.Equal(t, []string{"the http section is missing"}, p.ValidateConfig([]byte("server:\n  command: php\n")))
+
+	// the environment variables are not expanded
+	t.Setenv("RR_TEST_UPLOADS", dir)
+	problems = p.ValidateConfig([]byte("http:\n  address: 127.0.0.1:0\n  uploads:\n    dir: ${RR_TEST_UPLOADS}\n"))
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "uploads dir ${RR_TEST_UPLOADS} is not writable")
+
+	// the Init validation
+	problems = p.ValidateConfig(candidate("127.0.0.1:0", "  response_cache:\n    default_ttl: -1s\n"))
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "default_ttl")
+
+	// the checks done without applying the configuration
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+
+	// the files exist, but are not the certificates
+	pem := filepath.Join(dir, "cert.pem")
+	require.NoError(t, os.WriteFile(pem, []byte("not a certificate"), 0o600))
+
+	problems = p.ValidateConfig(candidate(l.Addr().String(), `  access_log_format: "%{bad"
+  middleware: [unknown]
+  ssl:
+    address: 127.0.0.1:0
+    cert: `+pem+`
+    key: `+pem+`
+`))
+	require.Len(t, problems, 4)
+	assert.Contains(t, problems[0], "unterminated %{ in the log format")
+	assert.Contains(t, problems[1], "address "+l.Addr().String()+" is not bindable")
+	assert.Contains(t, problems[2], "ssl certificate")
+	assert.Equal(t, "middleware unknown is not found, the plugin is not registered", problems[3])
+
+	// the address of the running server is not bound again
+	p.cfg = &config.Config{Address: l.Addr().String()}
+	assert.Empty(t, p.ValidateConfig(candidate(l.Addr().String(), "")))
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"go.uber.org/zap"
+)
+
+// maxLoggedChanges limits the changed files in the log entry.
+const maxLoggedChanges int = 10
+
+// fileState is the modification time and the size of the watched file.
+type fileState struct {
+	mod  time.Time
+	size int64
+}
+
+// watch polls the watched files and resets the workers when they change. The reset is delayed until the files are
+// not changed for the interval, so the batch changes (e.g. git checkout) trigger a single reset.
+func (p *Plugin) watch(cfg *config.Watch, stopCh chan struct{}) {
+	tt := time.NewTicker(cfg.Interval)
+	defer tt.Stop()
+
+	files := scanFiles(cfg)
+	var changed []string
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-tt.C:
+			current := scanFiles(cfg)
+			diff := diffFiles(files, current)
+			files = current
+
+			if len(diff) > 0 {
+				changed = append(changed, diff...)
+				continue
+			}
+
+			if len(changed) == 0 {
+				continue
+			}
+
+			p.log.Info("watched files changed, resetting the workers",
+				zap.Strings("files", changed[:min(len(changed), maxLoggedChanges)]), zap.Int("changed", len(changed)))
+			changed = nil
+
+			err := p.reset(callerWatch)
+			if err != nil {
+				p.log.Error("failed to reset the workers", zap.Error(err))
+			}
+		}
+	}
+}
+
+// scanFiles returns the watched files by the path relative to the watched directory, unreadable entries are skipped.
+func scanFiles(cfg *config.Watch) map[string]fileState {
+	files := make(map[string]fileState)
+	_ = filepath.WalkDir(cfg.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr
+		}
+
+		rel, err := filepath.Rel(cfg.Dir, path)
+		if err != nil || rel == "." {
+			return nil //nolint:nilerr
+		}
+
+		rel = filepath.ToSlash(rel)
+		if cfg.IgnoredPatterns.Match(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() || !cfg.WatchedPatterns.Match(rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil //nolint:nilerr
+		}
+
+		files[rel] = fileState{mod: info.ModTime(), size: info.Size()}
+		return nil
+	})
+
+	return files
+}
+
+// diffFiles returns the created, modified and deleted files.
+func diffFiles(prev, current map[string]fileState) []string {
+	var diff []string
+	for name, st := range current {
+		if old, ok := prev[name]; !ok || old != st {
+			diff = append(diff, name)
+		}
+	}
+
+	for name := range prev {
+		if _, ok := current[name]; !ok {
+			diff = append(diff, name)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/http/v5/config"
+	"github.com/roadrunner-server/pool/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestScanFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600))
+	}
+
+	write("index.php", "<?php")
+	write("src/App.php", "<?php")
+	write("README.md", "readme")
+	write("vendor/lib/Lib.php", "<?php")
+
+	cfg := &config.Watch{Dir: dir}
+	require.NoError(t, cfg.InitDefaults())
+
+	files := scanFiles(cfg)
+	assert.Len(t, files, 2)
+	assert.Contains(t, files, "index.php")
+	assert.Contains(t, files, "src/App.php")
+
+	write("src/App.php", "<?php // changed")
+	write("src/New.php", "<?php")
+	require.NoError(t, os.Remove(filepath.Join(dir, "index.php")))
+	write("vendor/lib/Lib.php", "<?php // ignored")
+
+	assert.ElementsMatch(t, []string{"src/App.php", "src/New.php", "index.php"}, diffFiles(files, scanFiles(cfg)))
+}
+
+func TestWatchReset(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.php"), []byte("<?php"), 0o600))
+	cfg := &config.Watch{Dir: dir, Interval: 100 * time.Millisecond}
+	require.NoError(t, cfg.InitDefaults())
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	pl := newTestPool(t, "ok", 200, &pool.Config{NumWorkers: 1, AllocateTimeout: time.Second})
+	p := &Plugin{log: zap.NewNop(), auditLog: zap.New(core), cfg: &config.Config{}}
+	p.pool.Store(pl)
+	pid := pl.Workers()[0].Pid()
+
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		p.watch(cfg, stopCh)
+		close(done)
+	}()
+	defer func() {
+		close(stopCh)
+		<-done
+	}()
+
+	// the batch of the changes triggers a single reset after the quiet interval
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "batch.php"), []byte{byte('a' + i)}, 0o600))
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	require.Eventually(t, func() bool {
+		return logs.FilterField(zap.String("caller", callerWatch)).Len() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "reset", logs.All()[0].ContextMap()["operation"])
+	assert.NotEqual(t, pid, pl.Workers()[0].Pid())
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 1, logs.Len())
+}
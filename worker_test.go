@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	httpV1proto "github.com/roadrunner-server/api/v4/build/http/v1"
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/goridge/v3/pkg/pipe"
+	"github.com/roadrunner-server/goridge/v3/pkg/relay"
+	poolPipe "github.com/roadrunner-server/pool/ipc/pipe"
+	"github.com/roadrunner-server/pool/pool"
+	staticPool "github.com/roadrunner-server/pool/pool/static_pool"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// testWorkerEnv runs the test binary as the worker, the value is the response body
+	testWorkerEnv = "RR_HTTP_TEST_WORKER"
+	// testWorkerStatusEnv is the response status of the test worker, defaults to 200
+	testWorkerStatusEnv = "RR_HTTP_TEST_WORKER_STATUS"
+	// testWorkerPIDHeader is the response header with the test worker PID
+	testWorkerPIDHeader = "X-Worker-Pid"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testWorkerEnv) != "" {
+		serveTestWorker()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// newTestPool starts the pool of the test workers, see serveTestWorker.
+func newTestPool(t *testing.T, body string, status int, cfg *pool.Config) *staticPool.Pool {
+	cmd := func([]string) *exec.Cmd {
+		c := exec.Command(os.Args[0]) //nolint:gosec
+		c.Env = append(os.Environ(), testWorkerEnv+"="+body, testWorkerStatusEnv+"="+strconv.Itoa(status))
+		return c
+	}
+
+	cfg.Command = []string{os.Args[0]}
+	if cfg.DestroyTimeout == 0 {
+		cfg.DestroyTimeout = time.Second
+	}
+
+	p, err := staticPool.NewPool(context.Background(), cmd, poolPipe.NewPipeFactory(zap.NewNop()), cfg, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { p.Destroy(context.Background()) })
+	return p
+}
+
+// testServer creates the pools of the test workers, the first word of the command is the response body, the
+// "broken" workers respond with 500.
+type testServer struct {
+	t *testing.T
+}
+
+func (s *testServer) UID() int { return -1 }
+func (s *testServer) GID() int { return -1 }
+
+func (s *testServer) NewPool(_ context.Context, cfg *pool.Config, _ map[string]string, _ *zap.Logger) (*staticPool.Pool, error) {
+	status := http.StatusOK
+	if cfg.Command[0] == "broken" {
+		status = http.StatusInternalServerError
+	}
+
+	c := *cfg
+	return newTestPool(s.t, cfg.Command[0], status, &c), nil
+}
+
+// CmdFactory prepares the workers answering with the "relay" body, the env is passed to the workers.
+func (s *testServer) CmdFactory(env map[string]string) func() *exec.Cmd {
+	return func() *exec.Cmd {
+		c := exec.Command(os.Args[0]) //nolint:gosec
+		c.Env = append(os.Environ(), testWorkerEnv+"=relay")
+		for k, v := range env {
+			c.Env = append(c.Env, k+"="+v)
+		}
+		return c
+	}
+}
+
+// serveTestWorker serves the requests like the PHP worker: the /hang path is never answered, the /stream-hang path
+// starts the stream and hangs ignoring the stop, the /fail path writes the error to the stderr and fails the request,
+// the other paths are answered with the configured status and body and
+// the worker PID header.
+func serveTestWorker() {
+	rl := pipe.NewPipeRelay(os.Stdin, os.Stdout)
+	status, err := strconv.Atoi(os.Getenv(testWorkerStatusEnv))
+	if err != nil || status == 0 {
+		status = 200
+	}
+
+	for {
+		fr := frame.NewFrame()
+		if rl.Receive(fr) != nil {
+			return
+		}
+
+		if fr.ReadFlags()&frame.CONTROL != 0 {
+			cmd := map[string]any{}
+			_ = json.Unmarshal(fr.Payload(), &cmd)
+			if _, ok := cmd["stop"]; ok {
+				return
+			}
+
+			// the pid handshake
+			data, _ := json.Marshal(map[string]int{"pid": os.Getpid()})
+			sendTestFrame(rl, data, 0, frame.CONTROL, frame.CodecJSON)
+			continue
+		}
+
+		req := &httpV1proto.Request{}
+		_ = proto.Unmarshal(fr.Payload()[:fr.ReadOptions(fr.Header())[0]], req)
+		u, err := url.Parse(req.GetUri())
+		if err == nil && u.Path == "/hang" {
+			select {}
+		}
+
+		if err == nil && u.Path == "/stream-hang" {
+			ctx, _ := proto.Marshal(&httpV1proto.Response{Status: int64(status)})
+			sf := frame.NewFrame()
+			sf.WriteVersion(sf.Header(), frame.Version1)
+			sf.WriteFlags(sf.Header(), frame.CodecProto)
+			sf.WriteOptions(sf.HeaderPtr(), uint32(len(ctx))) //nolint:gosec
+			sf.SetStreamFlag(sf.Header())
+			sf.WritePayloadLen(sf.Header(), uint32(len(ctx))) //nolint:gosec
+			sf.WritePayload(ctx)
+			sf.WriteCRC(sf.Header())
+			_ = rl.Send(sf)
+			select {}
+		}
+
+		if err == nil && u.Path == "/fail" {
+			_, _ = os.Stderr.WriteString("PHP Fatal error: " + u.Query().Get("error"))
+			// the stderr is read before the response
+			time.Sleep(100 * time.Millisecond)
+			sendTestFrame(rl, []byte("fatal error"), 0, frame.ERROR)
+			continue
+		}
+
+		ctx, _ := proto.Marshal(&httpV1proto.Response{
+			Status:  int64(status),
+			Headers: map[string]*httpV1proto.HeaderValue{testWorkerPIDHeader: {Value: []string{strconv.Itoa(os.Getpid())}}},
+		})
+		sendTestFrame(rl, append(ctx, os.Getenv(testWorkerEnv)...), uint32(len(ctx)), frame.CodecProto) //nolint:gosec
+	}
+}
+
+func sendTestFrame(rl relay.Relay, data []byte, ctxLen uint32, flags ...byte) {
+	fr := frame.NewFrame()
+	fr.WriteVersion(fr.Header(), frame.Version1)
+	fr.WriteFlags(fr.Header(), flags...)
+	if flags[0] != frame.CONTROL {
+		fr.WriteOptions(fr.HeaderPtr(), ctxLen)
+	}
+	fr.WritePayloadLen(fr.Header(), uint32(len(data))) //nolint:gosec
+	fr.WritePayload(data)
+	fr.WriteCRC(fr.Header())
+	_ = rl.Send(fr)
+}
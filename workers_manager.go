@@ -7,11 +7,17 @@ import (
 func (p *Plugin) AddWorker() error {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	return p.pool.AddWorker()
+
+	err := p.pool.Load().AddWorker()
+	p.audit("add_worker", callerPlugin, err)
+	return err
 }
 
 func (p *Plugin) RemoveWorker(ctx context.Context) error {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	return p.pool.RemoveWorker(ctx)
+
+	err := p.pool.Load().RemoveWorker(ctx)
+	p.audit("remove_worker", callerPlugin, err)
+	return err
 }